@@ -19,13 +19,28 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	OrderService_CreateOrder_FullMethodName     = "/order.OrderService/CreateOrder"
-	OrderService_SettleOrder_FullMethodName     = "/order.OrderService/SettleOrder"
-	OrderService_GetOrderDetails_FullMethodName = "/order.OrderService/GetOrderDetails"
-	OrderService_GetOrder_FullMethodName        = "/order.OrderService/GetOrder"
-	OrderService_GetUserOrders_FullMethodName   = "/order.OrderService/GetUserOrders"
-	OrderService_UpdateOrder_FullMethodName     = "/order.OrderService/UpdateOrder"
-	OrderService_CancelOrder_FullMethodName     = "/order.OrderService/CancelOrder"
+	OrderService_CreateOrder_FullMethodName            = "/order.OrderService/CreateOrder"
+	OrderService_SettleOrder_FullMethodName            = "/order.OrderService/SettleOrder"
+	OrderService_GetOrderDetails_FullMethodName        = "/order.OrderService/GetOrderDetails"
+	OrderService_GetOrder_FullMethodName               = "/order.OrderService/GetOrder"
+	OrderService_GetUserOrders_FullMethodName          = "/order.OrderService/GetUserOrders"
+	OrderService_UpdateOrder_FullMethodName            = "/order.OrderService/UpdateOrder"
+	OrderService_CancelOrder_FullMethodName            = "/order.OrderService/CancelOrder"
+	OrderService_UpdateOrderItems_FullMethodName       = "/order.OrderService/UpdateOrderItems"
+	OrderService_CancelOrderItems_FullMethodName       = "/order.OrderService/CancelOrderItems"
+	OrderService_MarkOrderPaymentFailed_FullMethodName = "/order.OrderService/MarkOrderPaymentFailed"
+	OrderService_StartRefund_FullMethodName            = "/order.OrderService/StartRefund"
+	OrderService_CompleteRefund_FullMethodName         = "/order.OrderService/CompleteRefund"
+	OrderService_GetOrderHistory_FullMethodName        = "/order.OrderService/GetOrderHistory"
+	OrderService_CreateOrderFromCart_FullMethodName    = "/order.OrderService/CreateOrderFromCart"
+	OrderService_CreateShipment_FullMethodName         = "/order.OrderService/CreateShipment"
+	OrderService_UpdateShipmentTracking_FullMethodName = "/order.OrderService/UpdateShipmentTracking"
+	OrderService_ConfirmDelivery_FullMethodName        = "/order.OrderService/ConfirmDelivery"
+	OrderService_GetOrderShipments_FullMethodName      = "/order.OrderService/GetOrderShipments"
+	OrderService_SearchOrders_FullMethodName           = "/order.OrderService/SearchOrders"
+	OrderService_ExportOrders_FullMethodName           = "/order.OrderService/ExportOrders"
+	OrderService_QuoteOrder_FullMethodName             = "/order.OrderService/QuoteOrder"
+	OrderService_GetUserOrderSummary_FullMethodName    = "/order.OrderService/GetUserOrderSummary"
 )
 
 // OrderServiceClient is the client API for OrderService service.
@@ -48,6 +63,36 @@ type OrderServiceClient interface {
 	UpdateOrder(ctx context.Context, in *UpdateOrderRequest, opts ...grpc.CallOption) (*UpdateOrderResponse, error)
 	// 取消订单
 	CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*CancelOrderResponse, error)
+	// 修改待支付订单的商品数量或删除商品，按当前商品价格重新计算订单金额
+	UpdateOrderItems(ctx context.Context, in *UpdateOrderItemsRequest, opts ...grpc.CallOption) (*UpdateOrderItemsResponse, error)
+	// 取消已支付订单中尚未发货的部分商品，退还对应金额并归还库存
+	CancelOrderItems(ctx context.Context, in *CancelOrderItemsRequest, opts ...grpc.CallOption) (*CancelOrderItemsResponse, error)
+	// 标记订单支付失败
+	MarkOrderPaymentFailed(ctx context.Context, in *MarkOrderPaymentFailedRequest, opts ...grpc.CallOption) (*MarkOrderPaymentFailedResponse, error)
+	// 开始退款
+	StartRefund(ctx context.Context, in *StartRefundRequest, opts ...grpc.CallOption) (*StartRefundResponse, error)
+	// 完成退款
+	CompleteRefund(ctx context.Context, in *CompleteRefundRequest, opts ...grpc.CallOption) (*CompleteRefundResponse, error)
+	// 获取订单状态变更历史
+	GetOrderHistory(ctx context.Context, in *GetOrderHistoryRequest, opts ...grpc.CallOption) (*GetOrderHistoryResponse, error)
+	// 从购物车创建订单
+	CreateOrderFromCart(ctx context.Context, in *CreateOrderFromCartRequest, opts ...grpc.CallOption) (*CreateOrderFromCartResponse, error)
+	// 创建发货单，一个订单可以拆分为多个发货单
+	CreateShipment(ctx context.Context, in *CreateShipmentRequest, opts ...grpc.CallOption) (*CreateShipmentResponse, error)
+	// 更新发货单物流轨迹
+	UpdateShipmentTracking(ctx context.Context, in *UpdateShipmentTrackingRequest, opts ...grpc.CallOption) (*UpdateShipmentTrackingResponse, error)
+	// 确认发货单已送达
+	ConfirmDelivery(ctx context.Context, in *ConfirmDeliveryRequest, opts ...grpc.CallOption) (*ConfirmDeliveryResponse, error)
+	// 获取订单的所有发货单
+	GetOrderShipments(ctx context.Context, in *GetOrderShipmentsRequest, opts ...grpc.CallOption) (*GetOrderShipmentsResponse, error)
+	// 按条件搜索订单，使用游标分页；不指定用户时搜索所有用户的订单
+	SearchOrders(ctx context.Context, in *SearchOrdersRequest, opts ...grpc.CallOption) (*SearchOrdersResponse, error)
+	// 按创建时间升序导出订单及订单项，订单以流的形式逐个返回
+	ExportOrders(ctx context.Context, in *ExportOrdersRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportOrdersResponse], error)
+	// 按当前商品价格、优惠券、税费和运费对商品或购物车计价，不创建订单
+	QuoteOrder(ctx context.Context, in *QuoteOrderRequest, opts ...grpc.CallOption) (*QuoteOrderResponse, error)
+	// 获取用户的订单统计：各状态订单数、累计消费和最近一笔订单
+	GetUserOrderSummary(ctx context.Context, in *GetUserOrderSummaryRequest, opts ...grpc.CallOption) (*GetUserOrderSummaryResponse, error)
 }
 
 type orderServiceClient struct {
@@ -128,6 +173,165 @@ func (c *orderServiceClient) CancelOrder(ctx context.Context, in *CancelOrderReq
 	return out, nil
 }
 
+func (c *orderServiceClient) UpdateOrderItems(ctx context.Context, in *UpdateOrderItemsRequest, opts ...grpc.CallOption) (*UpdateOrderItemsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateOrderItemsResponse)
+	err := c.cc.Invoke(ctx, OrderService_UpdateOrderItems_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) CancelOrderItems(ctx context.Context, in *CancelOrderItemsRequest, opts ...grpc.CallOption) (*CancelOrderItemsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelOrderItemsResponse)
+	err := c.cc.Invoke(ctx, OrderService_CancelOrderItems_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) MarkOrderPaymentFailed(ctx context.Context, in *MarkOrderPaymentFailedRequest, opts ...grpc.CallOption) (*MarkOrderPaymentFailedResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MarkOrderPaymentFailedResponse)
+	err := c.cc.Invoke(ctx, OrderService_MarkOrderPaymentFailed_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) StartRefund(ctx context.Context, in *StartRefundRequest, opts ...grpc.CallOption) (*StartRefundResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StartRefundResponse)
+	err := c.cc.Invoke(ctx, OrderService_StartRefund_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) CompleteRefund(ctx context.Context, in *CompleteRefundRequest, opts ...grpc.CallOption) (*CompleteRefundResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CompleteRefundResponse)
+	err := c.cc.Invoke(ctx, OrderService_CompleteRefund_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) GetOrderHistory(ctx context.Context, in *GetOrderHistoryRequest, opts ...grpc.CallOption) (*GetOrderHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOrderHistoryResponse)
+	err := c.cc.Invoke(ctx, OrderService_GetOrderHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) CreateOrderFromCart(ctx context.Context, in *CreateOrderFromCartRequest, opts ...grpc.CallOption) (*CreateOrderFromCartResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateOrderFromCartResponse)
+	err := c.cc.Invoke(ctx, OrderService_CreateOrderFromCart_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) CreateShipment(ctx context.Context, in *CreateShipmentRequest, opts ...grpc.CallOption) (*CreateShipmentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateShipmentResponse)
+	err := c.cc.Invoke(ctx, OrderService_CreateShipment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) UpdateShipmentTracking(ctx context.Context, in *UpdateShipmentTrackingRequest, opts ...grpc.CallOption) (*UpdateShipmentTrackingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateShipmentTrackingResponse)
+	err := c.cc.Invoke(ctx, OrderService_UpdateShipmentTracking_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) ConfirmDelivery(ctx context.Context, in *ConfirmDeliveryRequest, opts ...grpc.CallOption) (*ConfirmDeliveryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ConfirmDeliveryResponse)
+	err := c.cc.Invoke(ctx, OrderService_ConfirmDelivery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) GetOrderShipments(ctx context.Context, in *GetOrderShipmentsRequest, opts ...grpc.CallOption) (*GetOrderShipmentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOrderShipmentsResponse)
+	err := c.cc.Invoke(ctx, OrderService_GetOrderShipments_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) SearchOrders(ctx context.Context, in *SearchOrdersRequest, opts ...grpc.CallOption) (*SearchOrdersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchOrdersResponse)
+	err := c.cc.Invoke(ctx, OrderService_SearchOrders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) ExportOrders(ctx context.Context, in *ExportOrdersRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportOrdersResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OrderService_ServiceDesc.Streams[0], OrderService_ExportOrders_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportOrdersRequest, ExportOrdersResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OrderService_ExportOrdersClient = grpc.ServerStreamingClient[ExportOrdersResponse]
+
+func (c *orderServiceClient) QuoteOrder(ctx context.Context, in *QuoteOrderRequest, opts ...grpc.CallOption) (*QuoteOrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QuoteOrderResponse)
+	err := c.cc.Invoke(ctx, OrderService_QuoteOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) GetUserOrderSummary(ctx context.Context, in *GetUserOrderSummaryRequest, opts ...grpc.CallOption) (*GetUserOrderSummaryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUserOrderSummaryResponse)
+	err := c.cc.Invoke(ctx, OrderService_GetUserOrderSummary_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // OrderServiceServer is the server API for OrderService service.
 // All implementations must embed UnimplementedOrderServiceServer
 // for forward compatibility.
@@ -148,6 +352,36 @@ type OrderServiceServer interface {
 	UpdateOrder(context.Context, *UpdateOrderRequest) (*UpdateOrderResponse, error)
 	// 取消订单
 	CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error)
+	// 修改待支付订单的商品数量或删除商品，按当前商品价格重新计算订单金额
+	UpdateOrderItems(context.Context, *UpdateOrderItemsRequest) (*UpdateOrderItemsResponse, error)
+	// 取消已支付订单中尚未发货的部分商品，退还对应金额并归还库存
+	CancelOrderItems(context.Context, *CancelOrderItemsRequest) (*CancelOrderItemsResponse, error)
+	// 标记订单支付失败
+	MarkOrderPaymentFailed(context.Context, *MarkOrderPaymentFailedRequest) (*MarkOrderPaymentFailedResponse, error)
+	// 开始退款
+	StartRefund(context.Context, *StartRefundRequest) (*StartRefundResponse, error)
+	// 完成退款
+	CompleteRefund(context.Context, *CompleteRefundRequest) (*CompleteRefundResponse, error)
+	// 获取订单状态变更历史
+	GetOrderHistory(context.Context, *GetOrderHistoryRequest) (*GetOrderHistoryResponse, error)
+	// 从购物车创建订单
+	CreateOrderFromCart(context.Context, *CreateOrderFromCartRequest) (*CreateOrderFromCartResponse, error)
+	// 创建发货单，一个订单可以拆分为多个发货单
+	CreateShipment(context.Context, *CreateShipmentRequest) (*CreateShipmentResponse, error)
+	// 更新发货单物流轨迹
+	UpdateShipmentTracking(context.Context, *UpdateShipmentTrackingRequest) (*UpdateShipmentTrackingResponse, error)
+	// 确认发货单已送达
+	ConfirmDelivery(context.Context, *ConfirmDeliveryRequest) (*ConfirmDeliveryResponse, error)
+	// 获取订单的所有发货单
+	GetOrderShipments(context.Context, *GetOrderShipmentsRequest) (*GetOrderShipmentsResponse, error)
+	// 按条件搜索订单，使用游标分页；不指定用户时搜索所有用户的订单
+	SearchOrders(context.Context, *SearchOrdersRequest) (*SearchOrdersResponse, error)
+	// 按创建时间升序导出订单及订单项，订单以流的形式逐个返回
+	ExportOrders(*ExportOrdersRequest, grpc.ServerStreamingServer[ExportOrdersResponse]) error
+	// 按当前商品价格、优惠券、税费和运费对商品或购物车计价，不创建订单
+	QuoteOrder(context.Context, *QuoteOrderRequest) (*QuoteOrderResponse, error)
+	// 获取用户的订单统计：各状态订单数、累计消费和最近一笔订单
+	GetUserOrderSummary(context.Context, *GetUserOrderSummaryRequest) (*GetUserOrderSummaryResponse, error)
 	mustEmbedUnimplementedOrderServiceServer()
 }
 
@@ -179,6 +413,51 @@ func (UnimplementedOrderServiceServer) UpdateOrder(context.Context, *UpdateOrder
 func (UnimplementedOrderServiceServer) CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CancelOrder not implemented")
 }
+func (UnimplementedOrderServiceServer) UpdateOrderItems(context.Context, *UpdateOrderItemsRequest) (*UpdateOrderItemsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateOrderItems not implemented")
+}
+func (UnimplementedOrderServiceServer) CancelOrderItems(context.Context, *CancelOrderItemsRequest) (*CancelOrderItemsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOrderItems not implemented")
+}
+func (UnimplementedOrderServiceServer) MarkOrderPaymentFailed(context.Context, *MarkOrderPaymentFailedRequest) (*MarkOrderPaymentFailedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MarkOrderPaymentFailed not implemented")
+}
+func (UnimplementedOrderServiceServer) StartRefund(context.Context, *StartRefundRequest) (*StartRefundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartRefund not implemented")
+}
+func (UnimplementedOrderServiceServer) CompleteRefund(context.Context, *CompleteRefundRequest) (*CompleteRefundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompleteRefund not implemented")
+}
+func (UnimplementedOrderServiceServer) GetOrderHistory(context.Context, *GetOrderHistoryRequest) (*GetOrderHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderHistory not implemented")
+}
+func (UnimplementedOrderServiceServer) CreateOrderFromCart(context.Context, *CreateOrderFromCartRequest) (*CreateOrderFromCartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateOrderFromCart not implemented")
+}
+func (UnimplementedOrderServiceServer) CreateShipment(context.Context, *CreateShipmentRequest) (*CreateShipmentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateShipment not implemented")
+}
+func (UnimplementedOrderServiceServer) UpdateShipmentTracking(context.Context, *UpdateShipmentTrackingRequest) (*UpdateShipmentTrackingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateShipmentTracking not implemented")
+}
+func (UnimplementedOrderServiceServer) ConfirmDelivery(context.Context, *ConfirmDeliveryRequest) (*ConfirmDeliveryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmDelivery not implemented")
+}
+func (UnimplementedOrderServiceServer) GetOrderShipments(context.Context, *GetOrderShipmentsRequest) (*GetOrderShipmentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderShipments not implemented")
+}
+func (UnimplementedOrderServiceServer) SearchOrders(context.Context, *SearchOrdersRequest) (*SearchOrdersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchOrders not implemented")
+}
+func (UnimplementedOrderServiceServer) ExportOrders(*ExportOrdersRequest, grpc.ServerStreamingServer[ExportOrdersResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ExportOrders not implemented")
+}
+func (UnimplementedOrderServiceServer) QuoteOrder(context.Context, *QuoteOrderRequest) (*QuoteOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QuoteOrder not implemented")
+}
+func (UnimplementedOrderServiceServer) GetUserOrderSummary(context.Context, *GetUserOrderSummaryRequest) (*GetUserOrderSummaryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserOrderSummary not implemented")
+}
 func (UnimplementedOrderServiceServer) mustEmbedUnimplementedOrderServiceServer() {}
 func (UnimplementedOrderServiceServer) testEmbeddedByValue()                      {}
 
@@ -326,6 +605,269 @@ func _OrderService_CancelOrder_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _OrderService_UpdateOrderItems_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateOrderItemsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).UpdateOrderItems(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_UpdateOrderItems_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).UpdateOrderItems(ctx, req.(*UpdateOrderItemsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_CancelOrderItems_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOrderItemsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).CancelOrderItems(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_CancelOrderItems_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).CancelOrderItems(ctx, req.(*CancelOrderItemsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_MarkOrderPaymentFailed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarkOrderPaymentFailedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).MarkOrderPaymentFailed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_MarkOrderPaymentFailed_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).MarkOrderPaymentFailed(ctx, req.(*MarkOrderPaymentFailedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_StartRefund_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartRefundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).StartRefund(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_StartRefund_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).StartRefund(ctx, req.(*StartRefundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_CompleteRefund_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompleteRefundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).CompleteRefund(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_CompleteRefund_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).CompleteRefund(ctx, req.(*CompleteRefundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_GetOrderHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).GetOrderHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_GetOrderHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).GetOrderHistory(ctx, req.(*GetOrderHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_CreateOrderFromCart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateOrderFromCartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).CreateOrderFromCart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_CreateOrderFromCart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).CreateOrderFromCart(ctx, req.(*CreateOrderFromCartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_CreateShipment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateShipmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).CreateShipment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_CreateShipment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).CreateShipment(ctx, req.(*CreateShipmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_UpdateShipmentTracking_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateShipmentTrackingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).UpdateShipmentTracking(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_UpdateShipmentTracking_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).UpdateShipmentTracking(ctx, req.(*UpdateShipmentTrackingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_ConfirmDelivery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfirmDeliveryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).ConfirmDelivery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_ConfirmDelivery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).ConfirmDelivery(ctx, req.(*ConfirmDeliveryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_GetOrderShipments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderShipmentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).GetOrderShipments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_GetOrderShipments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).GetOrderShipments(ctx, req.(*GetOrderShipmentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_SearchOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).SearchOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_SearchOrders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).SearchOrders(ctx, req.(*SearchOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_ExportOrders_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportOrdersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OrderServiceServer).ExportOrders(m, &grpc.GenericServerStream[ExportOrdersRequest, ExportOrdersResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OrderService_ExportOrdersServer = grpc.ServerStreamingServer[ExportOrdersResponse]
+
+func _OrderService_QuoteOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuoteOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).QuoteOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_QuoteOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).QuoteOrder(ctx, req.(*QuoteOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_GetUserOrderSummary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserOrderSummaryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).GetUserOrderSummary(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_GetUserOrderSummary_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).GetUserOrderSummary(ctx, req.(*GetUserOrderSummaryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // OrderService_ServiceDesc is the grpc.ServiceDesc for OrderService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -361,7 +903,69 @@ var OrderService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CancelOrder",
 			Handler:    _OrderService_CancelOrder_Handler,
 		},
+		{
+			MethodName: "UpdateOrderItems",
+			Handler:    _OrderService_UpdateOrderItems_Handler,
+		},
+		{
+			MethodName: "CancelOrderItems",
+			Handler:    _OrderService_CancelOrderItems_Handler,
+		},
+		{
+			MethodName: "MarkOrderPaymentFailed",
+			Handler:    _OrderService_MarkOrderPaymentFailed_Handler,
+		},
+		{
+			MethodName: "StartRefund",
+			Handler:    _OrderService_StartRefund_Handler,
+		},
+		{
+			MethodName: "CompleteRefund",
+			Handler:    _OrderService_CompleteRefund_Handler,
+		},
+		{
+			MethodName: "GetOrderHistory",
+			Handler:    _OrderService_GetOrderHistory_Handler,
+		},
+		{
+			MethodName: "CreateOrderFromCart",
+			Handler:    _OrderService_CreateOrderFromCart_Handler,
+		},
+		{
+			MethodName: "CreateShipment",
+			Handler:    _OrderService_CreateShipment_Handler,
+		},
+		{
+			MethodName: "UpdateShipmentTracking",
+			Handler:    _OrderService_UpdateShipmentTracking_Handler,
+		},
+		{
+			MethodName: "ConfirmDelivery",
+			Handler:    _OrderService_ConfirmDelivery_Handler,
+		},
+		{
+			MethodName: "GetOrderShipments",
+			Handler:    _OrderService_GetOrderShipments_Handler,
+		},
+		{
+			MethodName: "SearchOrders",
+			Handler:    _OrderService_SearchOrders_Handler,
+		},
+		{
+			MethodName: "QuoteOrder",
+			Handler:    _OrderService_QuoteOrder_Handler,
+		},
+		{
+			MethodName: "GetUserOrderSummary",
+			Handler:    _OrderService_GetUserOrderSummary_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportOrders",
+			Handler:       _OrderService_ExportOrders_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "idl/order.proto",
 }
@@ -23,6 +23,9 @@ const (
 	PaymentService_QueryPayment_FullMethodName               = "/payment.PaymentService/QueryPayment"
 	PaymentService_ProcessPaymentNotification_FullMethodName = "/payment.PaymentService/ProcessPaymentNotification"
 	PaymentService_SimulatePaymentCallback_FullMethodName    = "/payment.PaymentService/SimulatePaymentCallback"
+	PaymentService_RequestRefund_FullMethodName              = "/payment.PaymentService/RequestRefund"
+	PaymentService_ProcessRefundNotification_FullMethodName  = "/payment.PaymentService/ProcessRefundNotification"
+	PaymentService_CancelOrderPayment_FullMethodName         = "/payment.PaymentService/CancelOrderPayment"
 )
 
 // PaymentServiceClient is the client API for PaymentService service.
@@ -33,6 +36,9 @@ type PaymentServiceClient interface {
 	QueryPayment(ctx context.Context, in *QueryPaymentRequest, opts ...grpc.CallOption) (*QueryPaymentResponse, error)
 	ProcessPaymentNotification(ctx context.Context, in *PaymentNotificationRequest, opts ...grpc.CallOption) (*PaymentNotificationResponse, error)
 	SimulatePaymentCallback(ctx context.Context, in *SimulatePaymentCallbackRequest, opts ...grpc.CallOption) (*SimulatePaymentCallbackResponse, error)
+	RequestRefund(ctx context.Context, in *RequestRefundRequest, opts ...grpc.CallOption) (*RequestRefundResponse, error)
+	ProcessRefundNotification(ctx context.Context, in *RefundNotificationRequest, opts ...grpc.CallOption) (*RefundNotificationResponse, error)
+	CancelOrderPayment(ctx context.Context, in *CancelOrderPaymentRequest, opts ...grpc.CallOption) (*CancelOrderPaymentResponse, error)
 }
 
 type paymentServiceClient struct {
@@ -83,6 +89,36 @@ func (c *paymentServiceClient) SimulatePaymentCallback(ctx context.Context, in *
 	return out, nil
 }
 
+func (c *paymentServiceClient) RequestRefund(ctx context.Context, in *RequestRefundRequest, opts ...grpc.CallOption) (*RequestRefundResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RequestRefundResponse)
+	err := c.cc.Invoke(ctx, PaymentService_RequestRefund_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ProcessRefundNotification(ctx context.Context, in *RefundNotificationRequest, opts ...grpc.CallOption) (*RefundNotificationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RefundNotificationResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ProcessRefundNotification_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CancelOrderPayment(ctx context.Context, in *CancelOrderPaymentRequest, opts ...grpc.CallOption) (*CancelOrderPaymentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelOrderPaymentResponse)
+	err := c.cc.Invoke(ctx, PaymentService_CancelOrderPayment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PaymentServiceServer is the server API for PaymentService service.
 // All implementations must embed UnimplementedPaymentServiceServer
 // for forward compatibility.
@@ -91,6 +127,9 @@ type PaymentServiceServer interface {
 	QueryPayment(context.Context, *QueryPaymentRequest) (*QueryPaymentResponse, error)
 	ProcessPaymentNotification(context.Context, *PaymentNotificationRequest) (*PaymentNotificationResponse, error)
 	SimulatePaymentCallback(context.Context, *SimulatePaymentCallbackRequest) (*SimulatePaymentCallbackResponse, error)
+	RequestRefund(context.Context, *RequestRefundRequest) (*RequestRefundResponse, error)
+	ProcessRefundNotification(context.Context, *RefundNotificationRequest) (*RefundNotificationResponse, error)
+	CancelOrderPayment(context.Context, *CancelOrderPaymentRequest) (*CancelOrderPaymentResponse, error)
 	mustEmbedUnimplementedPaymentServiceServer()
 }
 
@@ -113,6 +152,15 @@ func (UnimplementedPaymentServiceServer) ProcessPaymentNotification(context.Cont
 func (UnimplementedPaymentServiceServer) SimulatePaymentCallback(context.Context, *SimulatePaymentCallbackRequest) (*SimulatePaymentCallbackResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SimulatePaymentCallback not implemented")
 }
+func (UnimplementedPaymentServiceServer) RequestRefund(context.Context, *RequestRefundRequest) (*RequestRefundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestRefund not implemented")
+}
+func (UnimplementedPaymentServiceServer) ProcessRefundNotification(context.Context, *RefundNotificationRequest) (*RefundNotificationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProcessRefundNotification not implemented")
+}
+func (UnimplementedPaymentServiceServer) CancelOrderPayment(context.Context, *CancelOrderPaymentRequest) (*CancelOrderPaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOrderPayment not implemented")
+}
 func (UnimplementedPaymentServiceServer) mustEmbedUnimplementedPaymentServiceServer() {}
 func (UnimplementedPaymentServiceServer) testEmbeddedByValue()                        {}
 
@@ -206,6 +254,60 @@ func _PaymentService_SimulatePaymentCallback_Handler(srv interface{}, ctx contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PaymentService_RequestRefund_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestRefundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).RequestRefund(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_RequestRefund_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).RequestRefund(ctx, req.(*RequestRefundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ProcessRefundNotification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefundNotificationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ProcessRefundNotification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ProcessRefundNotification_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ProcessRefundNotification(ctx, req.(*RefundNotificationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CancelOrderPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOrderPaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CancelOrderPayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CancelOrderPayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CancelOrderPayment(ctx, req.(*CancelOrderPaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // PaymentService_ServiceDesc is the grpc.ServiceDesc for PaymentService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -229,6 +331,18 @@ var PaymentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SimulatePaymentCallback",
 			Handler:    _PaymentService_SimulatePaymentCallback_Handler,
 		},
+		{
+			MethodName: "RequestRefund",
+			Handler:    _PaymentService_RequestRefund_Handler,
+		},
+		{
+			MethodName: "ProcessRefundNotification",
+			Handler:    _PaymentService_ProcessRefundNotification_Handler,
+		},
+		{
+			MethodName: "CancelOrderPayment",
+			Handler:    _PaymentService_CancelOrderPayment_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "idl/payment.proto",
@@ -56,7 +56,9 @@ func (s *authServiceServer) VerifyTokenByRPC(ctx context.Context, req *authapi.V
 	
 	// Convert internal response to API response
 	return &authapi.VerifyResp{
-		Res: internalResp.Res,
+		Res:     internalResp.Res,
+		UserId:  internalResp.UserId,
+		Expired: internalResp.Expired,
 	}, nil
 }
 
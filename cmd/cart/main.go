@@ -12,6 +12,7 @@ import (
 
 	cartapi "github.com/bytedance-youthcamp/demo/api/cart"
 	"github.com/bytedance-youthcamp/demo/internal/config"
+	"github.com/bytedance-youthcamp/demo/internal/outbox"
 	cart "github.com/bytedance-youthcamp/demo/internal/service/cart"
 
 	"github.com/spf13/viper"
@@ -99,6 +100,23 @@ func main() {
 	// 创建包装后的服务
 	server := &cartServiceServer{CartService: service}
 
+	// 启动发件箱中继，将购物车事件发布到 NATS
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	if cartConfig.Events.NatsURL != "" {
+		broker, err := outbox.NewNATSBroker(cartConfig.Events.NatsURL, "cart-service")
+		if err != nil {
+			log.Fatalf("Failed to create event broker: %v", err)
+		}
+		defer broker.Close()
+
+		outbox.NewRelay(sqlDB, broker,
+			outbox.WithAggregateTypes(outbox.AggregateCart),
+			outbox.WithInterval(cartConfig.Events.RelayInterval),
+			outbox.WithBatchSize(cartConfig.Events.BatchSize),
+		).Start(relayCtx)
+	}
+
 	// 启动 gRPC 服务器
 	lis, err := net.Listen("tcp", ":50055")
 	if err != nil {
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	cartapi "github.com/bytedance-youthcamp/demo/api/cart"
+	checkoutapi "github.com/bytedance-youthcamp/demo/api/checkout"
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	paymentapi "github.com/bytedance-youthcamp/demo/api/payment"
+	productapi "github.com/bytedance-youthcamp/demo/api/product"
+	"github.com/bytedance-youthcamp/demo/internal/config"
+	checkoutService "github.com/bytedance-youthcamp/demo/internal/service/checkout"
+	orderService "github.com/bytedance-youthcamp/demo/internal/service/order"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func main() {
+	// 加载配置
+	viper.SetConfigName("checkout")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath("/Users/Apple/Desktop/demo/configs")
+
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("Error reading config file: %v", err)
+	}
+
+	var checkoutConfig config.CheckoutConfig
+	if err := viper.Unmarshal(&checkoutConfig); err != nil {
+		log.Fatalf("Error unmarshaling config: %v", err)
+	}
+
+	// 设置数据库连接
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		checkoutConfig.Database.User,
+		checkoutConfig.Database.Password,
+		checkoutConfig.Database.Host,
+		checkoutConfig.Database.Port,
+		checkoutConfig.Database.Name,
+	)
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect database: %v", err)
+	}
+	defer db.Close()
+
+	// 连接下游服务
+	dial := func(name, addr string, opts ...grpc.DialOption) *grpc.ClientConn {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		conn, err := grpc.NewClient(addr, opts...)
+		if err != nil {
+			log.Fatalf("Failed to connect to %s service: %v", name, err)
+		}
+		return conn
+	}
+	cartConn := dial("cart", checkoutConfig.Services.Cart)
+	defer cartConn.Close()
+	orderConn := dial("order", checkoutConfig.Services.Order,
+		grpc.WithUnaryInterceptor(orderService.ServiceTokenInterceptor(checkoutConfig.Services.OrderToken)))
+	defer orderConn.Close()
+	productConn := dial("product", checkoutConfig.Services.Product)
+	defer productConn.Close()
+	paymentConn := dial("payment", checkoutConfig.Services.Payment)
+	defer paymentConn.Close()
+
+	// 创建服务实例
+	service, err := checkoutService.NewCheckoutService(
+		checkoutService.WithDatabase(db),
+		checkoutService.WithCartClient(cartapi.NewCartServiceClient(cartConn)),
+		checkoutService.WithOrderClient(orderapi.NewOrderServiceClient(orderConn)),
+		checkoutService.WithProductClient(productapi.NewProductServiceClient(productConn)),
+		checkoutService.WithPaymentClient(paymentapi.NewPaymentServiceClient(paymentConn)),
+		checkoutService.WithStepTimeout(checkoutConfig.Checkout.StepTimeout),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create checkout service: %v", err)
+	}
+
+	// 恢复上次退出时未完成的结账，并定期检查中断的流程
+	if _, err := service.RecoverCheckouts(context.Background()); err != nil {
+		log.Printf("Error recovering checkouts: %v", err)
+	}
+	recoveryInterval := checkoutConfig.Checkout.RecoveryInterval
+	if recoveryInterval <= 0 {
+		recoveryInterval = time.Minute
+	}
+	service.StartRecoveryTask(recoveryInterval)
+
+	// 启动 gRPC 服务器
+	lis, err := net.Listen("tcp", ":50056")
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+
+	grpcServer := grpc.NewServer()
+	checkoutapi.RegisterCheckoutServiceServer(grpcServer, service)
+
+	// 处理优雅关闭
+	go func() {
+		log.Println("Checkout Service started on :50056")
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Fatalf("Failed to serve: %v", err)
+		}
+	}()
+
+	// 优雅关闭
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	log.Println("Shutting down Checkout Service...")
+	grpcServer.GracefulStop()
+	log.Println("Checkout Service stopped")
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bytedance-youthcamp/demo/internal/config"
+	"github.com/bytedance-youthcamp/demo/internal/report"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// exportBatchSize 导出订单明细时每次从数据库查询的订单数
+const exportBatchSize = 500
+
+// order-report 导出订单明细或输出汇总报表，默认统计昨天的数据，例如：
+//
+//	order-report -report orders -format csv -from 2024-06-01 -to 2024-06-08 -out orders.csv
+//	order-report -report revenue-by-day -format ndjson
+//
+// 可用的报表：orders（订单明细，每个订单项一行）、revenue-by-day、revenue-by-category、
+// revenue-by-payment-method、cancellation-rate。订单库和支付库的连接信息读取自配置目录中的 order.yaml 和 payment.yaml
+func main() {
+	configDir := flag.String("config", "configs", "directory containing order.yaml and payment.yaml")
+	reportName := flag.String("report", "orders", "report to generate")
+	formatName := flag.String("format", "csv", "output format: csv or ndjson")
+	fromDate := flag.String("from", "", "start date (inclusive), YYYY-MM-DD, defaults to yesterday")
+	toDate := flag.String("to", "", "end date (exclusive), YYYY-MM-DD, defaults to the day after -from")
+	outPath := flag.String("out", "", "output file, defaults to stdout")
+	flag.Parse()
+
+	format, err := report.ParseFormat(*formatName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	from, to, err := dateRange(*fromDate, *toDate)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	orderConfig, err := config.LoadOrderConfig(filepath.Join(*configDir, "order.yaml"))
+	if err != nil {
+		log.Fatalf("Failed to load order config: %v", err)
+	}
+	orderDB, err := openDatabase(orderConfig.Database.User, orderConfig.Database.Password,
+		orderConfig.Database.Host, orderConfig.Database.Port, orderConfig.Database.Name)
+	if err != nil {
+		log.Fatalf("Failed to connect to order database: %v", err)
+	}
+	defer orderDB.Close()
+
+	// 支付库只用于按支付方式统计
+	var paymentDB *sql.DB
+	if *reportName == "revenue-by-payment-method" {
+		paymentConfig, err := config.LoadPaymentConfig(filepath.Join(*configDir, "payment.yaml"))
+		if err != nil {
+			log.Fatalf("Failed to load payment config: %v", err)
+		}
+		paymentDB, err = openDatabase(paymentConfig.Database.User, paymentConfig.Database.Password,
+			paymentConfig.Database.Host, paymentConfig.Database.Port, paymentConfig.Database.Name)
+		if err != nil {
+			log.Fatalf("Failed to connect to payment database: %v", err)
+		}
+		defer paymentDB.Close()
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	ctx := context.Background()
+	w := report.NewWriter(out, format)
+	reporter := report.NewReporter(orderDB, paymentDB)
+
+	switch *reportName {
+	case "orders":
+		filter := repository.OrderSearchFilter{
+			CreatedFrom: &from,
+			CreatedTo:   &to,
+			SortBy:      repository.OrderSortByCreatedAt,
+			Ascending:   true,
+		}
+		err = repository.ForEachOrder(ctx, repository.NewMySQLOrderRepository(orderDB), filter, exportBatchSize,
+			func(order *repository.Order) error {
+				return report.WriteOrder(w, order)
+			})
+	case "revenue-by-day":
+		err = writeRows(w, func() ([]report.DailyRevenue, error) { return reporter.RevenueByDay(ctx, from, to) })
+	case "revenue-by-category":
+		err = writeRows(w, func() ([]report.CategoryRevenue, error) { return reporter.RevenueByCategory(ctx, from, to) })
+	case "revenue-by-payment-method":
+		err = writeRows(w, func() ([]report.PaymentMethodRevenue, error) { return reporter.RevenueByPaymentMethod(ctx, from, to) })
+	case "cancellation-rate":
+		err = writeRows(w, func() ([]report.CancellationRate, error) { return reporter.CancellationRates(ctx, from, to) })
+	default:
+		log.Fatalf("Unknown report %q", *reportName)
+	}
+	if err != nil {
+		log.Fatalf("Failed to generate %s report: %v", *reportName, err)
+	}
+
+	if err := w.Flush(); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+}
+
+// dateRange 解析本地时区的日期范围，未指定开始日期时统计昨天，未指定结束日期时统计一天
+func dateRange(fromDate, toDate string) (time.Time, time.Time, error) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.Local)
+	if fromDate != "" {
+		var err error
+		if from, err = time.ParseInLocation("2006-01-02", fromDate, time.Local); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -from date: %w", err)
+		}
+	}
+
+	to := from.AddDate(0, 0, 1)
+	if toDate != "" {
+		var err error
+		if to, err = time.ParseInLocation("2006-01-02", toDate, time.Local); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -to date: %w", err)
+		}
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("-from must be before -to")
+	}
+	return from, to, nil
+}
+
+func openDatabase(user, password, host string, port int, name string) (*sql.DB, error) {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		user, password, host, port, name)
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func writeRows[T report.Row](w *report.Writer, query func() ([]T, error)) error {
+	rows, err := query()
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/bytedance-youthcamp/demo/internal/config"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// order-summary-rebuild 根据订单表重新计算用户订单统计，用于修复统计或初始化历史数据，例如：
+//
+//	order-summary-rebuild            # 重新计算所有用户
+//	order-summary-rebuild -user 42   # 只重新计算一个用户
+//
+// 订单库的连接信息读取自配置目录中的 order.yaml
+func main() {
+	configDir := flag.String("config", "configs", "directory containing order.yaml")
+	userID := flag.String("user", "", "rebuild only this user, defaults to all users")
+	flag.Parse()
+
+	orderConfig, err := config.LoadOrderConfig(filepath.Join(*configDir, "order.yaml"))
+	if err != nil {
+		log.Fatalf("Failed to load order config: %v", err)
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		orderConfig.Database.User,
+		orderConfig.Database.Password,
+		orderConfig.Database.Host,
+		orderConfig.Database.Port,
+		orderConfig.Database.Name,
+	)
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to order database: %v", err)
+	}
+	defer db.Close()
+
+	users, err := repository.NewMySQLOrderRepository(db).RebuildUserOrderSummaries(context.Background(), *userID)
+	if err != nil {
+		log.Fatalf("Failed to rebuild user order summaries: %v", err)
+	}
+	log.Printf("Rebuilt order summaries of %d users", users)
+}
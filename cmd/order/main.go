@@ -10,12 +10,22 @@ import (
 	"syscall"
 	"time"
 
+	authapi "github.com/bytedance-youthcamp/demo/api/auth"
+	cartapi "github.com/bytedance-youthcamp/demo/api/cart"
 	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	paymentapi "github.com/bytedance-youthcamp/demo/api/payment"
+	productapi "github.com/bytedance-youthcamp/demo/api/product"
+	promotionapi "github.com/bytedance-youthcamp/demo/api/promotion"
+	userapi "github.com/bytedance-youthcamp/demo/api/user"
 	"github.com/bytedance-youthcamp/demo/internal/config"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/outbox"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
 	orderService "github.com/bytedance-youthcamp/demo/internal/service/order"
 
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -35,6 +45,13 @@ func main() {
 		log.Fatalf("Error unmarshaling config: %v", err)
 	}
 
+	// 服务令牌可以访问所有订单，可以猜测的令牌会让任何人冒充内部服务
+	for name, token := range orderConfig.Auth.ServiceTokens {
+		if token != "" && len(token) < orderService.MinServiceTokenLength {
+			log.Fatalf("Service token of %s must be at least %d characters", name, orderService.MinServiceTokenLength)
+		}
+	}
+
 	// 设置数据库连接
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
 		orderConfig.Database.User,
@@ -55,21 +72,102 @@ func main() {
 		log.Fatalf("Failed to get sql.DB: %v", err)
 	}
 
+	// 连接商品服务，订单价格以商品服务为准
+	productConn, err := grpc.NewClient(orderConfig.Services.Product, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("Failed to connect to product service: %v", err)
+	}
+	defer productConn.Close()
+
+	// 连接购物车服务，用于从购物车创建订单
+	cartConn, err := grpc.NewClient(orderConfig.Services.Cart, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("Failed to connect to cart service: %v", err)
+	}
+	defer cartConn.Close()
+
+	// 连接认证服务和用户服务，用于识别调用方和检查订单权限
+	authConn, err := grpc.NewClient(orderConfig.Services.Auth, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("Failed to connect to auth service: %v", err)
+	}
+	defer authConn.Close()
+
+	userConn, err := grpc.NewClient(orderConfig.Services.User, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("Failed to connect to user service: %v", err)
+	}
+	defer userConn.Close()
+
+	// 连接支付服务，取消已支付订单的商品时退款，修改订单商品时关闭待支付的支付
+	paymentConn, err := grpc.NewClient(orderConfig.Services.Payment, grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(orderService.ServiceTokenInterceptor(orderConfig.Services.PaymentToken)))
+	if err != nil {
+		log.Fatalf("Failed to connect to payment service: %v", err)
+	}
+	defer paymentConn.Close()
+
+	// 连接促销服务，下单时使用优惠券
+	promotionConn, err := grpc.NewClient(orderConfig.Services.Promotion, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("Failed to connect to promotion service: %v", err)
+	}
+	defer promotionConn.Close()
+
+	// 根据配置组装定价流水线
+	priceCalculators, err := pricingCalculators(&orderConfig)
+	if err != nil {
+		log.Fatalf("Invalid pricing config: %v", err)
+	}
+
 	// 创建服务实例
 	service, err := orderService.NewOrderService(
+		orderService.WithOrderRepository(repository.NewMySQLOrderRepository(sqlDB)),
+		orderService.WithShipmentRepository(repository.NewMySQLShipmentRepository(sqlDB)),
+		orderService.WithIdempotencyRepository(repository.NewMySQLIdempotencyRepository(sqlDB)),
+		orderService.WithProductClient(productapi.NewProductServiceClient(productConn)),
+		orderService.WithCartClient(cartapi.NewCartServiceClient(cartConn)),
+		orderService.WithAuthClient(authapi.NewAuthServiceClient(authConn)),
+		orderService.WithUserClient(userapi.NewUserServiceClient(userConn)),
+		orderService.WithPaymentClient(paymentapi.NewPaymentServiceClient(paymentConn)),
+		orderService.WithPromotionClient(promotionapi.NewPromotionServiceClient(promotionConn)),
+		orderService.WithServiceTokens(orderConfig.Auth.ServiceTokens),
 		orderService.WithTestDatabase(sqlDB),
+		orderService.WithPricePrecision(orderConfig.Order.PricePrecision),
+		orderService.WithAutoCancelTimeout(time.Duration(orderConfig.Order.AutoCancelMinutes)*time.Minute),
+		orderService.WithPriceCalculators(priceCalculators...),
 	)
 	if err != nil {
 		log.Fatalf("Failed to create order service: %v", err)
 	}
 
+	// 启动发件箱中继，将订单事件发布到 NATS
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	if orderConfig.Events.NatsURL != "" {
+		broker, err := outbox.NewNATSBroker(orderConfig.Events.NatsURL, "order-service")
+		if err != nil {
+			log.Fatalf("Failed to create event broker: %v", err)
+		}
+		defer broker.Close()
+
+		outbox.NewRelay(sqlDB, broker,
+			outbox.WithAggregateTypes(outbox.AggregateOrder),
+			outbox.WithInterval(orderConfig.Events.RelayInterval),
+			outbox.WithBatchSize(orderConfig.Events.BatchSize),
+		).Start(relayCtx)
+	}
+
 	// 启动 gRPC 服务器
 	lis, err := net.Listen("tcp", ":50053")
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(service.UnaryAuthInterceptor()),
+		grpc.StreamInterceptor(service.StreamAuthInterceptor()),
+	)
 	orderapi.RegisterOrderServiceServer(grpcServer, service)
 
 	// 处理优雅关闭
@@ -95,3 +193,39 @@ func main() {
 	grpcServer.GracefulStop()
 	log.Println("Order Service stopped")
 }
+
+// pricingCalculators 按配置组装定价流水线，先计算税费再计算运费
+func pricingCalculators(cfg *config.OrderConfig) ([]orderService.PriceCalculator, error) {
+	tax, err := orderService.NewTaxCalculator(cfg.Pricing.Tax.DefaultRate, cfg.Pricing.Tax.Rates)
+	if err != nil {
+		return nil, err
+	}
+	calculators := []orderService.PriceCalculator{tax}
+
+	shipping := cfg.Pricing.Shipping
+	currency := shipping.Currency
+	switch shipping.Mode {
+	case "":
+	case "flat":
+		calculators = append(calculators, orderService.NewFlatShippingCalculator(
+			money.FromFloat(shipping.FlatFee, currency),
+			money.FromFloat(shipping.FreeThreshold, currency),
+		))
+	case "weight":
+		calculator, err := orderService.NewWeightShippingCalculator(orderService.WeightShippingRule{
+			BaseFee:          money.FromFloat(shipping.BaseFee, currency),
+			PerKgFee:         money.FromFloat(shipping.PerKgFee, currency),
+			DefaultItemGrams: shipping.DefaultItemGrams,
+			CategoryGrams:    shipping.CategoryGrams,
+			FreeThreshold:    money.FromFloat(shipping.FreeThreshold, currency),
+		})
+		if err != nil {
+			return nil, err
+		}
+		calculators = append(calculators, calculator)
+	default:
+		return nil, fmt.Errorf("unknown shipping mode %q", shipping.Mode)
+	}
+
+	return calculators, nil
+}
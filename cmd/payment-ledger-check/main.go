@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/bytedance-youthcamp/demo/internal/config"
+	paymentService "github.com/bytedance-youthcamp/demo/internal/service/payment"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// payment-ledger-check 校验支付账本的不变量，账本不平衡时以非零状态退出，例如：
+//
+//	payment-ledger-check             # 只校验账本
+//	payment-ledger-check -balances   # 校验后输出所有账户的余额
+//
+// 支付库的连接信息读取自配置目录中的 payment.yaml
+func main() {
+	configDir := flag.String("config", "configs", "directory containing payment.yaml")
+	balances := flag.Bool("balances", false, "print the balance of every ledger account")
+	flag.Parse()
+
+	paymentConfig, err := config.LoadPaymentConfig(filepath.Join(*configDir, "payment.yaml"))
+	if err != nil {
+		log.Fatalf("Failed to load payment config: %v", err)
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		paymentConfig.Database.User,
+		paymentConfig.Database.Password,
+		paymentConfig.Database.Host,
+		paymentConfig.Database.Port,
+		paymentConfig.Database.Name,
+	)
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("Failed to connect to payment database: %v", err)
+	}
+
+	ctx := context.Background()
+	service := paymentService.NewPaymentService(db)
+	result, err := service.CheckLedger(ctx)
+	if err != nil {
+		log.Fatalf("Failed to check ledger: %v", err)
+	}
+
+	if *balances {
+		accounts, err := service.LedgerBalances(ctx)
+		if err != nil {
+			log.Fatalf("Failed to query ledger balances: %v", err)
+		}
+		for _, account := range accounts {
+			fmt.Printf("%-32s %-9s %s\n", account.Account, account.Type, account.Balance)
+		}
+	}
+
+	for _, violation := range result.Violations {
+		fmt.Fprintln(os.Stderr, violation)
+	}
+	if len(result.Violations) > 0 {
+		log.Fatalf("Ledger check failed: %d violations in %d journal entries and %d postings",
+			len(result.Violations), result.Entries, result.Postings)
+	}
+	log.Printf("Ledger is balanced: %d journal entries, %d postings", result.Entries, result.Postings)
+}
@@ -5,17 +5,24 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
 	paymentapi "github.com/bytedance-youthcamp/demo/api/payment"
 	"github.com/bytedance-youthcamp/demo/internal/config"
+	"github.com/bytedance-youthcamp/demo/internal/outbox"
+	"github.com/bytedance-youthcamp/demo/internal/report"
+	orderService "github.com/bytedance-youthcamp/demo/internal/service/order"
 	paymentService "github.com/bytedance-youthcamp/demo/internal/service/payment"
 
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -35,6 +42,13 @@ func main() {
 		log.Fatalf("Error unmarshaling config: %v", err)
 	}
 
+	// 服务令牌可以调用内部 RPC，可以猜测的令牌会让任何人伪造支付结果
+	for name, token := range paymentConfig.Auth.ServiceTokens {
+		if token != "" && len(token) < orderService.MinServiceTokenLength {
+			log.Fatalf("Service token of %s must be at least %d characters", name, orderService.MinServiceTokenLength)
+		}
+	}
+
 	// 设置数据库连接
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
 		paymentConfig.Database.User,
@@ -60,8 +74,78 @@ func main() {
 	sqlDB.SetMaxOpenConns(paymentConfig.Database.MaxOpenConnections)
 	sqlDB.SetConnMaxLifetime(paymentConfig.Database.ConnectionMaxLifetime)
 
+	// 自动迁移数据库模型
+	if err := paymentService.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	// 连接订单服务，支付结果需要同步到订单状态
+	orderConn, err := grpc.NewClient(paymentConfig.Services.Order,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(orderService.ServiceTokenInterceptor(paymentConfig.Services.OrderToken)),
+	)
+	if err != nil {
+		log.Fatalf("Failed to connect to order service: %v", err)
+	}
+	defer orderConn.Close()
+
 	// 创建服务实例
-	service := paymentService.NewPaymentService(db)
+	opts := []paymentService.Option{
+		paymentService.WithOrderClient(orderapi.NewOrderServiceClient(orderConn)),
+		paymentService.WithCallbackReplayWindow(paymentConfig.Webhook.ReplayWindow),
+		paymentService.WithPaymentTTL(paymentConfig.Payment.PaymentTTL),
+		paymentService.WithReconcileAfter(paymentConfig.Payment.ReconcileAfter),
+		paymentService.WithServiceTokens(paymentConfig.Auth.ServiceTokens),
+	}
+	opts = append(opts, paymentProviders(&paymentConfig)...)
+	service := paymentService.NewPaymentService(db, opts...)
+
+	// 定期重试未同步到订单的支付结果
+	orderSyncInterval := paymentConfig.Payment.OrderSyncInterval
+	if orderSyncInterval <= 0 {
+		orderSyncInterval = time.Minute
+	}
+	service.StartOrderSyncTask(orderSyncInterval)
+
+	// 定期向渠道查询长时间未收到回调的支付，并使超过有效期的支付过期
+	reconcileInterval := paymentConfig.Payment.ReconcileInterval
+	if reconcileInterval <= 0 {
+		reconcileInterval = time.Minute
+	}
+	service.StartReconcileTask(reconcileInterval)
+
+	// 每日生成支付与订单的对账报表
+	if reportDir := paymentConfig.Payment.ReportDir; reportDir != "" {
+		formatName := paymentConfig.Payment.ReportFormat
+		if formatName == "" {
+			formatName = string(report.FormatCSV)
+		}
+		format, err := report.ParseFormat(formatName)
+		if err != nil {
+			log.Fatalf("Invalid reconciliation report format: %v", err)
+		}
+		if err := os.MkdirAll(reportDir, 0o755); err != nil {
+			log.Fatalf("Failed to create report directory: %v", err)
+		}
+		service.StartDailyReconciliationTask(reportDir, format)
+	}
+
+	// 启动发件箱中继，将支付和退款事件发布到 NATS
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	if paymentConfig.Events.NatsURL != "" {
+		broker, err := outbox.NewNATSBroker(paymentConfig.Events.NatsURL, "payment-service")
+		if err != nil {
+			log.Fatalf("Failed to create event broker: %v", err)
+		}
+		defer broker.Close()
+
+		outbox.NewRelay(sqlDB, broker,
+			outbox.WithAggregateTypes(outbox.AggregatePayment, outbox.AggregateRefund),
+			outbox.WithInterval(paymentConfig.Events.RelayInterval),
+			outbox.WithBatchSize(paymentConfig.Events.BatchSize),
+		).Start(relayCtx)
+	}
 
 	// 启动 gRPC 服务器
 	lis, err := net.Listen("tcp", ":50054")
@@ -69,7 +153,8 @@ func main() {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	// 修改支付结果的 RPC 只对持有服务令牌的内部服务开放
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(service.UnaryAuthInterceptor()))
 	paymentapi.RegisterPaymentServiceServer(grpcServer, service)
 
 	// 处理优雅关闭
@@ -80,6 +165,23 @@ func main() {
 		}
 	}()
 
+	// 启动接收支付渠道回调的 HTTP 服务
+	webhookAddr := paymentConfig.Webhook.Addr
+	if webhookAddr == "" {
+		webhookAddr = ":8084"
+	}
+	webhookServer := &http.Server{
+		Addr:              webhookAddr,
+		Handler:           service.WebhookHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Printf("Payment webhook listening on %s", webhookAddr)
+		if err := webhookServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to serve webhook: %v", err)
+		}
+	}()
+
 	// 优雅关闭
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
@@ -89,10 +191,58 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Use ctx for any cleanup operations that need a timeout
-	_ = ctx
-
 	log.Println("Shutting down Payment Service...")
+	if err := webhookServer.Shutdown(ctx); err != nil {
+		log.Printf("Failed to shut down webhook server: %v", err)
+	}
 	grpcServer.GracefulStop()
 	log.Println("Payment Service stopped")
 }
+
+// paymentProviders 按配置为各支付方式创建渠道，所有使用沙箱的支付方式共用一个沙箱渠道，没有配置渠道时拒绝启动
+func paymentProviders(cfg *config.PaymentConfig) []paymentService.Option {
+	// 沙箱回调接口对外开放，可以猜测的密钥会让任何人伪造支付成功的回调
+	if secret := cfg.Payment.Sandbox.CallbackSecret; secret != "" && len(secret) < paymentService.MinSandboxSecretLength {
+		log.Fatalf("Sandbox callback secret must be at least %d characters, leave it empty to use a random secret",
+			paymentService.MinSandboxSecretLength)
+	}
+	sandboxOpts := []paymentService.SandboxOption{
+		paymentService.WithSandboxSecret(cfg.Payment.Sandbox.CallbackSecret),
+		paymentService.WithSandboxPayURL(cfg.Payment.Sandbox.PayURL),
+	}
+	if keyFile := cfg.Payment.Sandbox.RSAPrivateKeyFile; keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			log.Fatalf("Failed to read sandbox RSA key: %v", err)
+		}
+		key, err := paymentService.ParseRSAPrivateKey(data)
+		if err != nil {
+			log.Fatalf("Failed to parse sandbox RSA key: %v", err)
+		}
+		sandboxOpts = append(sandboxOpts, paymentService.WithSandboxRSAKey(key))
+	}
+	if cfg.Payment.Sandbox.CallbackDelay > 0 {
+		sandboxOpts = append(sandboxOpts, paymentService.WithSandboxAutoComplete(cfg.Payment.Sandbox.CallbackDelay))
+	}
+	sandbox := paymentService.NewSandboxProvider(sandboxOpts...)
+
+	// 只注册配置了渠道的支付方式，未配置的支付方式不能用于支付
+	if len(cfg.Payment.Providers) == 0 {
+		log.Fatalf("No payment providers configured")
+	}
+	var opts []paymentService.Option
+	for methodName, providerType := range cfg.Payment.Providers {
+		method, ok := paymentapi.PaymentMethod_value["PAYMENT_METHOD_"+strings.ToUpper(methodName)]
+		if !ok {
+			log.Fatalf("Unknown payment method %q in provider config", methodName)
+		}
+		switch providerType {
+		case "sandbox":
+			opts = append(opts, paymentService.WithProvider(paymentapi.PaymentMethod(method), sandbox))
+		default:
+			log.Fatalf("Unknown payment provider %q for method %s", providerType, methodName)
+		}
+	}
+
+	return opts
+}
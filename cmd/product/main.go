@@ -12,6 +12,7 @@ import (
 
 	productapi "github.com/bytedance-youthcamp/demo/api/product"
 	"github.com/bytedance-youthcamp/demo/internal/config"
+	"github.com/bytedance-youthcamp/demo/internal/outbox"
 	product "github.com/bytedance-youthcamp/demo/internal/service/product"
 
 	"github.com/spf13/viper"
@@ -47,6 +48,30 @@ func (s *productServiceServer) DeleteProduct(ctx context.Context, req *productap
 	return s.ProductService.DeleteProduct(ctx, req)
 }
 
+func (s *productServiceServer) ReduceStock(ctx context.Context, req *productapi.ReduceStockRequest) (*productapi.ReduceStockResponse, error) {
+	return s.ProductService.ReduceStock(ctx, req)
+}
+
+func (s *productServiceServer) ReserveStock(ctx context.Context, req *productapi.ReserveStockRequest) (*productapi.ReserveStockResponse, error) {
+	return s.ProductService.ReserveStock(ctx, req)
+}
+
+func (s *productServiceServer) ReleaseStock(ctx context.Context, req *productapi.ReleaseStockRequest) (*productapi.ReleaseStockResponse, error) {
+	return s.ProductService.ReleaseStock(ctx, req)
+}
+
+func (s *productServiceServer) CommitReservation(ctx context.Context, req *productapi.CommitReservationRequest) (*productapi.CommitReservationResponse, error) {
+	return s.ProductService.CommitReservation(ctx, req)
+}
+
+func (s *productServiceServer) RestoreStock(ctx context.Context, req *productapi.RestoreStockRequest) (*productapi.RestoreStockResponse, error) {
+	return s.ProductService.RestoreStock(ctx, req)
+}
+
+func (s *productServiceServer) AdjustReservation(ctx context.Context, req *productapi.AdjustReservationRequest) (*productapi.AdjustReservationResponse, error) {
+	return s.ProductService.AdjustReservation(ctx, req)
+}
+
 // The UnimplementedProductServiceServer is embedded in the struct, so we don't need this method
 
 func main() {
@@ -95,6 +120,23 @@ func main() {
 	// 创建包装后的服务
 	server := &productServiceServer{ProductService: service}
 
+	// 启动发件箱中继，将库存事件发布到 NATS
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	if productConfig.Events.NatsURL != "" {
+		broker, err := outbox.NewNATSBroker(productConfig.Events.NatsURL, "product-service")
+		if err != nil {
+			log.Fatalf("Failed to create event broker: %v", err)
+		}
+		defer broker.Close()
+
+		outbox.NewRelay(sqlDB, broker,
+			outbox.WithAggregateTypes(outbox.AggregateStock),
+			outbox.WithInterval(productConfig.Events.RelayInterval),
+			outbox.WithBatchSize(productConfig.Events.BatchSize),
+		).Start(relayCtx)
+	}
+
 	// 启动 gRPC 服务器
 	lis, err := net.Listen("tcp", ":50052")
 	if err != nil {
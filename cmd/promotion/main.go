@@ -0,0 +1,102 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	cartapi "github.com/bytedance-youthcamp/demo/api/cart"
+	productapi "github.com/bytedance-youthcamp/demo/api/product"
+	promotionapi "github.com/bytedance-youthcamp/demo/api/promotion"
+	"github.com/bytedance-youthcamp/demo/internal/config"
+	promotionService "github.com/bytedance-youthcamp/demo/internal/service/promotion"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func main() {
+	// 加载配置
+	viper.SetConfigName("promotion")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath("/Users/Apple/Desktop/demo/configs")
+
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("Error reading config file: %v", err)
+	}
+
+	var promotionConfig config.PromotionConfig
+	if err := viper.Unmarshal(&promotionConfig); err != nil {
+		log.Fatalf("Error unmarshaling config: %v", err)
+	}
+
+	// 设置数据库连接
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		promotionConfig.Database.User,
+		promotionConfig.Database.Password,
+		promotionConfig.Database.Host,
+		promotionConfig.Database.Port,
+		promotionConfig.Database.Name,
+	)
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect database: %v", err)
+	}
+	defer db.Close()
+
+	// 连接下游服务
+	dial := func(name, addr string) *grpc.ClientConn {
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			log.Fatalf("Failed to connect to %s service: %v", name, err)
+		}
+		return conn
+	}
+	cartConn := dial("cart", promotionConfig.Services.Cart)
+	defer cartConn.Close()
+	productConn := dial("product", promotionConfig.Services.Product)
+	defer productConn.Close()
+
+	// 创建服务实例
+	service, err := promotionService.NewPromotionService(
+		promotionService.WithDatabase(db),
+		promotionService.WithCartClient(cartapi.NewCartServiceClient(cartConn)),
+		promotionService.WithProductClient(productapi.NewProductServiceClient(productConn)),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create promotion service: %v", err)
+	}
+
+	// 启动 gRPC 服务器
+	lis, err := net.Listen("tcp", ":50057")
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+
+	grpcServer := grpc.NewServer()
+	promotionapi.RegisterPromotionServiceServer(grpcServer, service)
+
+	// 处理优雅关闭
+	go func() {
+		log.Println("Promotion Service started on :50057")
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Fatalf("Failed to serve: %v", err)
+		}
+	}()
+
+	// 优雅关闭
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	log.Println("Shutting down Promotion Service...")
+	grpcServer.GracefulStop()
+	log.Println("Promotion Service stopped")
+}
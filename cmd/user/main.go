@@ -15,6 +15,7 @@ import (
 
 	userapi "github.com/bytedance-youthcamp/demo/api/user"
 	"github.com/bytedance-youthcamp/demo/internal/config"
+	"github.com/bytedance-youthcamp/demo/internal/outbox"
 	userService "github.com/bytedance-youthcamp/demo/internal/service/user"
 	"google.golang.org/grpc"
 	"gorm.io/driver/mysql"
@@ -58,6 +59,11 @@ func (s *userServiceServer) UpdateUserInfo(ctx context.Context, req *userapi.Upd
 	return s.UserService.UpdateUserInfo(ctx, req)
 }
 
+// Implement the CheckPermission method
+func (s *userServiceServer) CheckPermission(ctx context.Context, req *userapi.CheckPermissionRequest) (*userapi.CheckPermissionResponse, error) {
+	return s.UserService.CheckPermission(ctx, req)
+}
+
 func main() {
 	// 加载配置
 	configPath := "/Users/Apple/Desktop/demo/configs/user.yaml"
@@ -175,6 +181,23 @@ func main() {
 		log.Fatalf("Failed to create user service: %v", err)
 	}
 
+	// 启动发件箱中继，将用户事件发布到 NATS
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	if userConfig.Events.NatsURL != "" {
+		broker, err := outbox.NewNATSBroker(userConfig.Events.NatsURL, "user-service")
+		if err != nil {
+			log.Fatalf("Failed to create event broker: %v", err)
+		}
+		defer broker.Close()
+
+		outbox.NewRelay(sqlDB, broker,
+			outbox.WithAggregateTypes(outbox.AggregateUser),
+			outbox.WithInterval(userConfig.Events.RelayInterval),
+			outbox.WithBatchSize(userConfig.Events.BatchSize),
+		).Start(relayCtx)
+	}
+
 	// 创建 gRPC 服务器
 	lis, err := net.Listen("tcp", ":50051")
 	if err != nil {
@@ -35,6 +35,13 @@ type CartConfig struct {
 		ItemQuantityLimit int `mapstructure:"item_quantity_limit"`
 		PricePrecision    int `mapstructure:"price_precision"`
 	} `mapstructure:"cart"`
+
+	// 领域事件发布，nats_url 为空时不启动发件箱中继
+	Events struct {
+		NatsURL       string        `mapstructure:"nats_url"`
+		RelayInterval time.Duration `mapstructure:"relay_interval"`
+		BatchSize     int           `mapstructure:"batch_size"`
+	} `mapstructure:"events"`
 }
 
 var (
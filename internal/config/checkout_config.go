@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type CheckoutConfig struct {
+	ServiceName    string `mapstructure:"service_name"`
+	ServiceVersion string `mapstructure:"service_version"`
+
+	Database struct {
+		Host     string `mapstructure:"host"`
+		Port     int    `mapstructure:"port"`
+		Name     string `mapstructure:"name"`
+		User     string `mapstructure:"user"`
+		Password string `mapstructure:"password"`
+	} `mapstructure:"database"`
+
+	Registration struct {
+		Etcd struct {
+			Endpoints   []string      `mapstructure:"endpoints"`
+			DialTimeout time.Duration `mapstructure:"dial_timeout"`
+		} `mapstructure:"etcd"`
+	} `mapstructure:"registration"`
+
+	// 下游服务地址
+	Services struct {
+		Cart       string `mapstructure:"cart"`
+		Order      string `mapstructure:"order"`
+		OrderToken string `mapstructure:"order_token"` // 调用订单服务使用的服务令牌
+		Product    string `mapstructure:"product"`
+		Payment    string `mapstructure:"payment"`
+	} `mapstructure:"services"`
+
+	Checkout struct {
+		RecoveryInterval time.Duration `mapstructure:"recovery_interval"` // 未完成结账的恢复间隔
+		StepTimeout      time.Duration `mapstructure:"step_timeout"`      // 单个步骤的超时时间
+	} `mapstructure:"checkout"`
+}
+
+var (
+	checkoutConfig     *CheckoutConfig
+	checkoutConfigLock sync.RWMutex
+)
+
+func LoadCheckoutConfig(path string) (*CheckoutConfig, error) {
+	viper.SetConfigFile(path)
+	viper.SetConfigType("yaml")
+
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config CheckoutConfig
+	if err := viper.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	checkoutConfigLock.Lock()
+	defer checkoutConfigLock.Unlock()
+	checkoutConfig = &config
+
+	log.Printf("Loaded checkout config from %s", path)
+	return &config, nil
+}
+
+func GetCheckoutConfig() *CheckoutConfig {
+	checkoutConfigLock.RLock()
+	defer checkoutConfigLock.RUnlock()
+	return checkoutConfig
+}
@@ -28,12 +28,62 @@ type OrderConfig struct {
 		} `mapstructure:"etcd"`
 	} `mapstructure:"registration"`
 
+	Services struct {
+		Product   string `mapstructure:"product"`
+		Cart      string `mapstructure:"cart"`
+		Auth      string `mapstructure:"auth"`
+		User      string `mapstructure:"user"`
+		Payment   string `mapstructure:"payment"`
+		Promotion string `mapstructure:"promotion"`
+		// 调用支付服务内部 RPC 使用的服务令牌
+		PaymentToken string `mapstructure:"payment_token"`
+	} `mapstructure:"services"`
+
+	Auth struct {
+		// 内部服务调用订单服务时使用的服务令牌，键为服务名
+		ServiceTokens map[string]string `mapstructure:"service_tokens"`
+	} `mapstructure:"auth"`
+
 	Order struct {
 		DefaultPageSize   int `mapstructure:"default_page_size"`
 		MaxQueryLimit     int `mapstructure:"max_query_limit"`
 		AutoCancelMinutes int `mapstructure:"auto_cancel_minutes"`
 		PricePrecision    int `mapstructure:"price_precision"`
 	} `mapstructure:"order"`
+
+	// 定价流水线，按税费、运费的顺序计算
+	Pricing struct {
+		Tax struct {
+			// 未配置税率的地区使用的税率，如 0.06 表示 6%
+			DefaultRate float64 `mapstructure:"default_rate"`
+			// 按地区代码配置的税率，地区代码不区分大小写
+			Rates map[string]float64 `mapstructure:"rates"`
+		} `mapstructure:"tax"`
+
+		Shipping struct {
+			// 运费计算方式：flat 固定运费，weight 按重量计算，为空时不收运费
+			Mode     string `mapstructure:"mode"`
+			Currency string `mapstructure:"currency"`
+			// 固定运费
+			FlatFee float64 `mapstructure:"flat_fee"`
+			// 按重量计算时的基础运费和每公斤运费
+			BaseFee  float64 `mapstructure:"base_fee"`
+			PerKgFee float64 `mapstructure:"per_kg_fee"`
+			// 未配置分类重量的商品的单件重量（克）
+			DefaultItemGrams int64 `mapstructure:"default_item_grams"`
+			// 按商品分类配置的单件重量（克）
+			CategoryGrams map[string]int64 `mapstructure:"category_grams"`
+			// 优惠后的商品金额达到该金额时免运费，为零时不免运费
+			FreeThreshold float64 `mapstructure:"free_threshold"`
+		} `mapstructure:"shipping"`
+	} `mapstructure:"pricing"`
+
+	// 领域事件发布，nats_url 为空时不启动发件箱中继
+	Events struct {
+		NatsURL       string        `mapstructure:"nats_url"`
+		RelayInterval time.Duration `mapstructure:"relay_interval"`
+		BatchSize     int           `mapstructure:"batch_size"`
+	} `mapstructure:"events"`
 }
 
 var (
@@ -31,12 +31,52 @@ type PaymentConfig struct {
 		} `mapstructure:"etcd"`
 	} `mapstructure:"registration"`
 
+	// 下游服务地址
+	Services struct {
+		Order      string `mapstructure:"order"`
+		OrderToken string `mapstructure:"order_token"` // 调用订单服务使用的服务令牌
+	} `mapstructure:"services"`
+
+	Auth struct {
+		// 可以调用 ProcessPaymentNotification 等内部 RPC 的服务令牌，键为服务名
+		ServiceTokens map[string]string `mapstructure:"service_tokens"`
+	} `mapstructure:"auth"`
+
 	Payment struct {
 		DefaultPageSize   int `mapstructure:"default_page_size"`
 		MaxQueryLimit     int `mapstructure:"max_query_limit"`
 		TransactionTimeout time.Duration `mapstructure:"transaction_timeout"`
 		PricePrecision    int `mapstructure:"price_precision"`
+		OrderSyncInterval time.Duration `mapstructure:"order_sync_interval"` // 未同步订单的支付结果重试间隔
+		PaymentTTL        time.Duration `mapstructure:"payment_ttl"`         // 支付有效期，超过后仍未支付的支付过期
+		ReconcileInterval time.Duration `mapstructure:"reconcile_interval"`  // 待支付支付的对账间隔
+		ReconcileAfter    time.Duration `mapstructure:"reconcile_after"`     // 待支付多久后向渠道查询支付结果
+		ReportDir         string        `mapstructure:"report_dir"`          // 每日对账报表的输出目录，为空时不生成
+		ReportFormat      string        `mapstructure:"report_format"`       // 对账报表格式：csv 或 ndjson
+		// 各支付方式使用的渠道类型，键为 alipay、wechat、credit_card，未配置的支付方式不可用
+		Providers map[string]string `mapstructure:"providers"`
+		// 本地沙箱渠道，callback_delay 大于 0 时按金额自动完成收款和退款
+		Sandbox struct {
+			CallbackSecret string        `mapstructure:"callback_secret"`
+			CallbackDelay  time.Duration `mapstructure:"callback_delay"`
+			PayURL         string        `mapstructure:"pay_url"`
+			// 设置后使用 RSA 私钥为回调签名，替代 callback_secret 的 HMAC 签名
+			RSAPrivateKeyFile string `mapstructure:"rsa_private_key_file"`
+		} `mapstructure:"sandbox"`
 	} `mapstructure:"payment"`
+
+	// 接收支付渠道回调的 HTTP 服务
+	Webhook struct {
+		Addr         string        `mapstructure:"addr"`
+		ReplayWindow time.Duration `mapstructure:"replay_window"` // 回调时间与当前时间允许的最大偏差
+	} `mapstructure:"webhook"`
+
+	// 领域事件发布，nats_url 为空时不启动发件箱中继
+	Events struct {
+		NatsURL       string        `mapstructure:"nats_url"`
+		RelayInterval time.Duration `mapstructure:"relay_interval"`
+		BatchSize     int           `mapstructure:"batch_size"`
+	} `mapstructure:"events"`
 }
 
 var (
@@ -35,6 +35,13 @@ type ProductConfig struct {
 		MaxImageSize         int      `mapstructure:"max_image_size"`
 		AllowedImageFormats  []string `mapstructure:"allowed_image_formats"`
 	} `mapstructure:"product"`
+
+	// 领域事件发布，nats_url 为空时不启动发件箱中继
+	Events struct {
+		NatsURL       string        `mapstructure:"nats_url"`
+		RelayInterval time.Duration `mapstructure:"relay_interval"`
+		BatchSize     int           `mapstructure:"batch_size"`
+	} `mapstructure:"events"`
 }
 
 var (
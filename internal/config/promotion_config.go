@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type PromotionConfig struct {
+	ServiceName    string `mapstructure:"service_name"`
+	ServiceVersion string `mapstructure:"service_version"`
+
+	Database struct {
+		Host     string `mapstructure:"host"`
+		Port     int    `mapstructure:"port"`
+		Name     string `mapstructure:"name"`
+		User     string `mapstructure:"user"`
+		Password string `mapstructure:"password"`
+	} `mapstructure:"database"`
+
+	Registration struct {
+		Etcd struct {
+			Endpoints   []string      `mapstructure:"endpoints"`
+			DialTimeout time.Duration `mapstructure:"dial_timeout"`
+		} `mapstructure:"etcd"`
+	} `mapstructure:"registration"`
+
+	// 下游服务地址，用于按购物车计价
+	Services struct {
+		Cart    string `mapstructure:"cart"`
+		Product string `mapstructure:"product"`
+	} `mapstructure:"services"`
+}
+
+var (
+	promotionConfig     *PromotionConfig
+	promotionConfigLock sync.RWMutex
+)
+
+func LoadPromotionConfig(path string) (*PromotionConfig, error) {
+	viper.SetConfigFile(path)
+	viper.SetConfigType("yaml")
+
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config PromotionConfig
+	if err := viper.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	promotionConfigLock.Lock()
+	defer promotionConfigLock.Unlock()
+	promotionConfig = &config
+
+	log.Printf("Loaded promotion config from %s", path)
+	return &config, nil
+}
+
+func GetPromotionConfig() *PromotionConfig {
+	promotionConfigLock.RLock()
+	defer promotionConfigLock.RUnlock()
+	return promotionConfig
+}
@@ -44,6 +44,13 @@ type UserConfig struct {
 		PasswordMinLength         int           `mapstructure:"password_min_length"`
 		PasswordComplexityEnabled bool          `mapstructure:"password_complexity_enabled"`
 	} `mapstructure:"security"`
+
+	// 领域事件发布，nats_url 为空时不启动发件箱中继
+	Events struct {
+		NatsURL       string        `mapstructure:"nats_url"`
+		RelayInterval time.Duration `mapstructure:"relay_interval"`
+		BatchSize     int           `mapstructure:"batch_size"`
+	} `mapstructure:"events"`
 }
 
 var (
@@ -0,0 +1,194 @@
+// Package money 提供以最小货币单位（如分）存储的精确金额类型，避免浮点数计算误差
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency 未指定币种时使用的默认币种
+const DefaultCurrency = "CNY"
+
+// ErrCurrencyMismatch 表示参与运算的金额币种不同
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// ErrInvalidAmount 表示金额字符串无法解析
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// currencyExponents 记录各币种最小单位的小数位数，未列出的币种按两位小数处理
+var currencyExponents = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"BHD": 3,
+	"KWD": 3,
+}
+
+// Money 以最小货币单位表示的金额
+type Money struct {
+	Amount   int64  // 最小货币单位的数量，如人民币的分
+	Currency string // ISO 4217 币种代码
+}
+
+// New 创建金额，amount 为最小货币单位的数量
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: normalizeCurrency(currency)}
+}
+
+// Zero 返回指定币种的零金额
+func Zero(currency string) Money {
+	return New(0, currency)
+}
+
+// FromFloat 将浮点金额按币种精度四舍五入为精确金额，仅用于兼容旧的浮点字段
+func FromFloat(amount float64, currency string) Money {
+	currency = normalizeCurrency(currency)
+	factor := math.Pow10(Exponent(currency))
+	return Money{Amount: int64(math.Round(amount * factor)), Currency: currency}
+}
+
+// Parse 解析十进制金额字符串，如 "199.98"，小数位数不能超过币种精度
+func Parse(amount string, currency string) (Money, error) {
+	currency = normalizeCurrency(currency)
+	exponent := Exponent(currency)
+
+	s := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" || len(fracPart) > exponent {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	fracPart += strings.Repeat("0", exponent-len(fracPart))
+
+	minor, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// Exponent 返回币种最小单位的小数位数
+func Exponent(currency string) int {
+	if exponent, ok := currencyExponents[normalizeCurrency(currency)]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// Float64 返回金额的浮点表示，仅用于兼容旧的浮点字段
+func (m Money) Float64() float64 {
+	return float64(m.Amount) / math.Pow10(Exponent(m.Currency))
+}
+
+// String 返回十进制金额和币种，如 "199.98 CNY"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// Decimal 返回十进制金额字符串，如 "199.98"
+func (m Money) Decimal() string {
+	exponent := Exponent(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	if exponent == 0 {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+
+	factor := int64(math.Pow10(exponent))
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/factor, exponent, amount%factor)
+}
+
+// IsZero 判断金额是否为零
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative 判断金额是否为负数
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// IsPositive 判断金额是否大于零
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// Add 返回两个金额之和，币种不同时返回 ErrCurrencyMismatch
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Sub 返回两个金额之差，币种不同时返回 ErrCurrencyMismatch
+func (m Money) Sub(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount - other.Amount, Currency: m.Currency}, nil
+}
+
+// Mul 返回金额乘以数量的结果
+func (m Money) Mul(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+// Cmp 比较两个金额，小于、等于、大于时分别返回 -1、0、1，币种不同时返回 ErrCurrencyMismatch
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Equal 判断两个金额的数量和币种是否都相同
+func (m Money) Equal(other Money) bool {
+	return m.Amount == other.Amount && normalizeCurrency(m.Currency) == normalizeCurrency(other.Currency)
+}
+
+// Sum 计算多个同币种金额之和
+func Sum(currency string, amounts ...Money) (Money, error) {
+	total := Zero(currency)
+	for _, amount := range amounts {
+		var err error
+		if total, err = total.Add(amount); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+func (m Money) checkCurrency(other Money) error {
+	if normalizeCurrency(m.Currency) != normalizeCurrency(other.Currency) {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return nil
+}
+
+func normalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return DefaultCurrency
+	}
+	return currency
+}
@@ -0,0 +1,52 @@
+package money
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromFloat(t *testing.T) {
+	assert.Equal(t, New(19998, "CNY"), FromFloat(199.98, "cny"))
+	assert.Equal(t, New(30, "CNY"), FromFloat(0.1+0.2, ""))
+	assert.Equal(t, New(1200, "JPY"), FromFloat(1199.6, "JPY"))
+}
+
+func TestParse(t *testing.T) {
+	m, err := Parse("199.9", "CNY")
+	require.NoError(t, err)
+	assert.Equal(t, int64(19990), m.Amount)
+
+	m, err = Parse("-0.05", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, New(-5, "USD"), m)
+
+	_, err = Parse("1.005", "CNY")
+	assert.True(t, errors.Is(err, ErrInvalidAmount))
+
+	_, err = Parse("abc", "CNY")
+	assert.True(t, errors.Is(err, ErrInvalidAmount))
+}
+
+func TestArithmetic(t *testing.T) {
+	price := FromFloat(99.99, "CNY")
+	total, err := Sum("CNY", price.Mul(2), FromFloat(0.02, "CNY"))
+	require.NoError(t, err)
+	assert.Equal(t, "200.00", total.Decimal())
+	assert.Equal(t, "200.00 CNY", total.String())
+	assert.Equal(t, 200.0, total.Float64())
+
+	diff, err := total.Sub(FromFloat(200.01, "CNY"))
+	require.NoError(t, err)
+	assert.True(t, diff.IsNegative())
+	assert.Equal(t, "-0.01", diff.Decimal())
+
+	_, err = total.Add(New(1, "USD"))
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+
+	cmp, err := New(100, "CNY").Cmp(New(99, "CNY"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, cmp)
+}
@@ -0,0 +1,66 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+)
+
+// Broker 消息代理，Publish 返回 nil 表示代理已接收事件
+type Broker interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// Handler 处理订阅到的事件
+type Handler func(ctx context.Context, event *Event)
+
+// MemoryBroker 进程内的消息代理，同步调用订阅者并保存已发布的事件，用于测试和单进程部署
+type MemoryBroker struct {
+	mu        sync.Mutex
+	events    []*Event
+	handlers  map[string][]Handler
+	publishFn func(event *Event) error
+}
+
+// NewMemoryBroker 创建进程内消息代理
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe 订阅指定类型的事件，eventType 为空时订阅所有事件
+func (b *MemoryBroker) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// FailWith 设置发布前的检查，返回错误时事件不会被发布，用于模拟代理不可用
+func (b *MemoryBroker) FailWith(fn func(event *Event) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.publishFn = fn
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, event *Event) error {
+	b.mu.Lock()
+	if b.publishFn != nil {
+		if err := b.publishFn(event); err != nil {
+			b.mu.Unlock()
+			return err
+		}
+	}
+	b.events = append(b.events, event)
+	handlers := append(append([]Handler(nil), b.handlers[event.Type]...), b.handlers[""]...)
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(ctx, event)
+	}
+	return nil
+}
+
+// Events 返回已发布的事件，按发布顺序排列
+func (b *MemoryBroker) Events() []*Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*Event(nil), b.events...)
+}
@@ -0,0 +1,83 @@
+// Package outbox 实现事务性发件箱：领域事件与业务数据在同一个数据库事务中写入 outbox_events 表，
+// 再由 Relay 异步发布到消息代理。事件至少投递一次，消费者需要按事件ID去重
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 聚合类型，发布时作为消息主题的一部分
+const (
+	AggregateOrder   = "order"
+	AggregatePayment = "payment"
+	AggregateRefund  = "refund"
+	AggregateStock   = "stock"
+	AggregateCart    = "cart"
+	AggregateUser    = "user"
+)
+
+// 事件类型
+const (
+	OrderCreated       = "OrderCreated"
+	OrderPaid          = "OrderPaid"
+	OrderShipped       = "OrderShipped"
+	OrderDelivered     = "OrderDelivered"
+	OrderCancelled     = "OrderCancelled"
+	OrderRefunding     = "OrderRefunding"
+	OrderRefunded      = "OrderRefunded"
+	OrderStatusChanged = "OrderStatusChanged"
+
+	PaymentSucceeded  = "PaymentSucceeded"
+	PaymentFailed     = "PaymentFailed"
+	PaymentExpired    = "PaymentExpired"
+	PaymentSuperseded = "PaymentSuperseded"
+	RefundSucceeded   = "RefundSucceeded"
+
+	StockReserved = "StockReserved"
+	StockReduced  = "StockReduced"
+	StockReleased = "StockReleased"
+	StockRestored = "StockRestored"
+
+	CartItemAdded   = "CartItemAdded"
+	CartItemRemoved = "CartItemRemoved"
+	CartCleared     = "CartCleared"
+
+	UserRegistered = "UserRegistered"
+	UserDeleted    = "UserDeleted"
+)
+
+// Event 领域事件，Payload 为 JSON 编码的事件内容
+type Event struct {
+	ID            string
+	Type          string
+	AggregateType string
+	AggregateID   string
+	Payload       json.RawMessage
+	OccurredAt    time.Time
+}
+
+// NewEvent 创建领域事件，payload 编码为 JSON
+func NewEvent(aggregateType, aggregateID, eventType string, payload interface{}) (*Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	return &Event{
+		ID:            uuid.New().String(),
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       data,
+		OccurredAt:    time.Now(),
+	}, nil
+}
+
+// Subject 返回事件发布的主题，如 events.order.OrderPaid
+func (e *Event) Subject() string {
+	return fmt.Sprintf("events.%s.%s", e.AggregateType, e.Type)
+}
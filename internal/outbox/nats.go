@@ -0,0 +1,157 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultNATSTimeout = 5 * time.Second
+
+// envelope 发布到消息代理的事件格式
+type envelope struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// NATSBroker 使用 NATS 核心协议将事件发布到 Event.Subject() 主题。
+// 每次发布后发送 PING 并等待 PONG，收到 PONG 说明服务器已处理之前的 PUB
+type NATSBroker struct {
+	addr    string
+	name    string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewNATSBroker 创建 NATS 消息代理，rawURL 形如 nats://localhost:4222，首次发布时建立连接
+func NewNATSBroker(rawURL, clientName string) (*NATSBroker, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid NATS URL %q", rawURL)
+	}
+	return &NATSBroker{addr: u.Host, name: clientName, timeout: defaultNATSTimeout}, nil
+}
+
+func (b *NATSBroker) Publish(ctx context.Context, event *Event) error {
+	data, err := json.Marshal(envelope{
+		ID:            event.ID,
+		Type:          event.Type,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		OccurredAt:    event.OccurredAt,
+		Payload:       event.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event %s: %w", event.ID, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.publish(ctx, event.Subject(), data); err != nil {
+		// 连接状态未知，下次发布时重新连接
+		b.closeLocked()
+		return err
+	}
+	return nil
+}
+
+func (b *NATSBroker) publish(ctx context.Context, subject string, data []byte) error {
+	if b.conn == nil {
+		if err := b.connect(ctx); err != nil {
+			return err
+		}
+	}
+	b.conn.SetDeadline(b.deadline(ctx))
+
+	msg := fmt.Sprintf("PUB %s %d\r\n%s\r\nPING\r\n", subject, len(data), data)
+	if _, err := b.conn.Write([]byte(msg)); err != nil {
+		return fmt.Errorf("failed to write to NATS: %w", err)
+	}
+	return b.waitPong()
+}
+
+func (b *NATSBroker) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: b.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", b.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	b.conn = conn
+	b.reader = bufio.NewReader(conn)
+	conn.SetDeadline(b.deadline(ctx))
+
+	// 服务器连接后先发送 INFO
+	line, err := b.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read NATS server info: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO") {
+		return fmt.Errorf("unexpected NATS greeting: %s", strings.TrimSpace(line))
+	}
+
+	options, _ := json.Marshal(map[string]interface{}{"verbose": false, "pedantic": false, "name": b.name})
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", options); err != nil {
+		return fmt.Errorf("failed to write to NATS: %w", err)
+	}
+	return b.waitPong()
+}
+
+// waitPong 读取服务器的回复直到 PONG，服务器的 PING 需要回复 PONG
+func (b *NATSBroker) waitPong() error {
+	for {
+		line, err := b.reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read from NATS: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := b.conn.Write([]byte("PONG\r\n")); err != nil {
+				return fmt.Errorf("failed to write to NATS: %w", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (b *NATSBroker) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(b.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		return d
+	}
+	return deadline
+}
+
+// Close 关闭与 NATS 服务器的连接
+func (b *NATSBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closeLocked()
+}
+
+func (b *NATSBroker) closeLocked() error {
+	if b.conn == nil {
+		return nil
+	}
+	err := b.conn.Close()
+	b.conn = nil
+	b.reader = nil
+	return err
+}
@@ -0,0 +1,41 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Execer 执行写操作，由 *sql.DB、*sql.Tx 以及 gorm 事务的 Statement.ConnPool 实现
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Append 在调用方的事务中写入事件，事件随业务数据一起提交或回滚
+func Append(ctx context.Context, tx Execer, events ...*Event) error {
+	for _, event := range events {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO outbox_events (event_id, event_type, aggregate_type, aggregate_id, payload, occurred_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			event.ID,
+			event.Type,
+			event.AggregateType,
+			event.AggregateID,
+			string(event.Payload),
+			event.OccurredAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to write %s event: %w", event.Type, err)
+		}
+	}
+	return nil
+}
+
+// Record 创建事件并在调用方的事务中写入
+func Record(ctx context.Context, tx Execer, aggregateType, aggregateID, eventType string, payload interface{}) error {
+	event, err := NewEvent(aggregateType, aggregateID, eventType, payload)
+	if err != nil {
+		return err
+	}
+	return Append(ctx, tx, event)
+}
@@ -0,0 +1,170 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE outbox_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id TEXT UNIQUE NOT NULL,
+			event_type TEXT NOT NULL,
+			aggregate_type TEXT NOT NULL,
+			aggregate_id TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			occurred_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			published_at DATETIME,
+			attempts INTEGER DEFAULT 0,
+			last_error TEXT DEFAULT ''
+		)
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+type testPayload struct {
+	OrderID string `json:"order_id"`
+}
+
+func recordEvents(t *testing.T, db *sql.DB, events ...[3]string) {
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	for _, e := range events {
+		require.NoError(t, Record(ctx, tx, e[0], e[1], e[2], testPayload{OrderID: e[1]}))
+	}
+	require.NoError(t, tx.Commit())
+}
+
+func countPending(t *testing.T, db *sql.DB) int {
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM outbox_events WHERE published_at IS NULL").Scan(&count))
+	return count
+}
+
+func TestRecordRollsBackWithTransaction(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, Record(ctx, tx, AggregateOrder, "1", OrderCreated, testPayload{OrderID: "1"}))
+	require.NoError(t, tx.Rollback())
+
+	assert.Equal(t, 0, countPending(t, db))
+}
+
+func TestPublishPending(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	recordEvents(t, db,
+		[3]string{AggregateOrder, "1", OrderCreated},
+		[3]string{AggregateOrder, "1", OrderPaid},
+		[3]string{AggregateOrder, "2", OrderCreated},
+	)
+
+	broker := NewMemoryBroker()
+	var received []string
+	broker.Subscribe(OrderPaid, func(ctx context.Context, event *Event) {
+		received = append(received, event.AggregateID)
+	})
+
+	relay := NewRelay(db, broker, WithBatchSize(2))
+	published, err := relay.PublishPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, published)
+	assert.Equal(t, []string{"1"}, received)
+
+	events := broker.Events()
+	require.Len(t, events, 3)
+	assert.Equal(t, OrderCreated, events[0].Type)
+	assert.Equal(t, OrderPaid, events[1].Type)
+	assert.Equal(t, "2", events[2].AggregateID)
+	assert.Equal(t, "events.order.OrderPaid", events[1].Subject())
+
+	var payload testPayload
+	require.NoError(t, json.Unmarshal(events[2].Payload, &payload))
+	assert.Equal(t, "2", payload.OrderID)
+
+	// 已发布的事件不会重复发布
+	published, err = relay.PublishPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+	assert.Len(t, broker.Events(), 3)
+}
+
+func TestPublishPendingStopsAtFailure(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	recordEvents(t, db,
+		[3]string{AggregateOrder, "1", OrderCreated},
+		[3]string{AggregateOrder, "1", OrderPaid},
+		[3]string{AggregateOrder, "1", OrderShipped},
+	)
+
+	broker := NewMemoryBroker()
+	broker.FailWith(func(event *Event) error {
+		if event.Type == OrderPaid {
+			return errors.New("broker unavailable")
+		}
+		return nil
+	})
+
+	relay := NewRelay(db, broker)
+	published, err := relay.PublishPending(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, 2, countPending(t, db))
+
+	var attempts int
+	var lastError string
+	require.NoError(t, db.QueryRow(
+		"SELECT attempts, last_error FROM outbox_events WHERE event_type = ?", OrderPaid,
+	).Scan(&attempts, &lastError))
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, "broker unavailable", lastError)
+
+	// 代理恢复后按原顺序继续发布
+	broker.FailWith(nil)
+	published, err = relay.PublishPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+
+	events := broker.Events()
+	require.Len(t, events, 3)
+	assert.Equal(t, OrderPaid, events[1].Type)
+	assert.Equal(t, OrderShipped, events[2].Type)
+}
+
+func TestPublishPendingFiltersAggregateTypes(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	recordEvents(t, db,
+		[3]string{AggregateOrder, "1", OrderCreated},
+		[3]string{AggregateStock, "1", StockReserved},
+	)
+
+	broker := NewMemoryBroker()
+	published, err := NewRelay(db, broker, WithAggregateTypes(AggregateStock)).PublishPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+
+	events := broker.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, StockReserved, events[0].Type)
+	assert.Equal(t, 1, countPending(t, db))
+}
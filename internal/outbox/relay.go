@@ -0,0 +1,181 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRelayBatchSize = 100
+	defaultRelayInterval  = time.Second
+	// maxErrorLength last_error 列保存的错误信息最大长度
+	maxErrorLength = 255
+)
+
+// Relay 按写入顺序将未发布的事件发布到消息代理。
+// 发布失败时停止本轮发布并在下一轮从失败的事件重试，保证同一数据库中的事件按顺序发布
+type Relay struct {
+	db        *sql.DB
+	broker    Broker
+	batchSize int
+	interval  time.Duration
+	// 只发布这些聚合类型的事件，为空时发布所有事件
+	aggregateTypes []string
+}
+
+type RelayOption func(*Relay)
+
+// WithBatchSize 设置每次查询的事件数
+func WithBatchSize(size int) RelayOption {
+	return func(r *Relay) {
+		if size > 0 {
+			r.batchSize = size
+		}
+	}
+}
+
+// WithInterval 设置检查未发布事件的间隔
+func WithInterval(interval time.Duration) RelayOption {
+	return func(r *Relay) {
+		if interval > 0 {
+			r.interval = interval
+		}
+	}
+}
+
+// WithAggregateTypes 只发布指定聚合类型的事件，多个服务共用一个数据库时每个服务只发布自己的事件
+func WithAggregateTypes(types ...string) RelayOption {
+	return func(r *Relay) {
+		r.aggregateTypes = types
+	}
+}
+
+// NewRelay 创建发件箱中继，db 为写入事件的业务数据库
+func NewRelay(db *sql.DB, broker Broker, opts ...RelayOption) *Relay {
+	r := &Relay{
+		db:        db,
+		broker:    broker,
+		batchSize: defaultRelayBatchSize,
+		interval:  defaultRelayInterval,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Start 定时发布未发布的事件，ctx 取消后停止
+func (r *Relay) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := r.PublishPending(ctx); err != nil {
+					log.Printf("Error publishing outbox events: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// PublishPending 发布所有未发布的事件，返回发布的事件数
+func (r *Relay) PublishPending(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		events, err := r.pending(ctx)
+		if err != nil {
+			return total, err
+		}
+
+		for _, event := range events {
+			if err := r.broker.Publish(ctx, event.Event); err != nil {
+				if markErr := r.markFailed(ctx, event.id, err); markErr != nil {
+					log.Printf("Failed to record publish failure of event %s: %v", event.ID, markErr)
+				}
+				return total, fmt.Errorf("failed to publish event %s: %w", event.ID, err)
+			}
+			if err := r.markPublished(ctx, event.id); err != nil {
+				// 事件已发布但未标记，下一轮会重复发布
+				return total, err
+			}
+			total++
+		}
+
+		if len(events) < r.batchSize {
+			return total, nil
+		}
+	}
+}
+
+// storedEvent 发件箱中的事件及其行ID
+type storedEvent struct {
+	*Event
+	id int64
+}
+
+func (r *Relay) pending(ctx context.Context) ([]storedEvent, error) {
+	query := `SELECT id, event_id, event_type, aggregate_type, aggregate_id, payload, occurred_at
+		FROM outbox_events
+		WHERE published_at IS NULL`
+	var args []interface{}
+	if len(r.aggregateTypes) > 0 {
+		query += " AND aggregate_type IN (?" + strings.Repeat(", ?", len(r.aggregateTypes)-1) + ")"
+		for _, aggregateType := range r.aggregateTypes {
+			args = append(args, aggregateType)
+		}
+	}
+	query += " ORDER BY id LIMIT ?"
+	args = append(args, r.batchSize)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []storedEvent
+	for rows.Next() {
+		event := storedEvent{Event: &Event{}}
+		var payload string
+		if err := rows.Scan(&event.id, &event.ID, &event.Type, &event.AggregateType, &event.AggregateID, &payload, &event.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		event.Payload = []byte(payload)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox events: %w", err)
+	}
+	return events, nil
+}
+
+func (r *Relay) markPublished(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE outbox_events SET published_at = ?, attempts = attempts + 1 WHERE id = ?",
+		time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event %d published: %w", id, err)
+	}
+	return nil
+}
+
+func (r *Relay) markFailed(ctx context.Context, id int64, publishErr error) error {
+	message := publishErr.Error()
+	if len(message) > maxErrorLength {
+		message = message[:maxErrorLength]
+	}
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE outbox_events SET attempts = attempts + 1, last_error = ? WHERE id = ?",
+		message, id,
+	)
+	return err
+}
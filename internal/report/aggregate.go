@@ -0,0 +1,253 @@
+package report
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	paymentpb "github.com/bytedance-youthcamp/demo/api/payment"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+)
+
+const dayLayout = "2006-01-02"
+
+// Reporter 从订单库和支付库统计汇总报表，统计时间范围均为 [from, to)。
+// 销售额按订单支付时间统计，已全额退款的订单不计入
+type Reporter struct {
+	orderDB   *sql.DB
+	paymentDB *sql.DB
+}
+
+// NewReporter 创建 Reporter，paymentDB 只用于按支付方式统计，不需要该报表时可以为 nil
+func NewReporter(orderDB, paymentDB *sql.DB) *Reporter {
+	return &Reporter{orderDB: orderDB, paymentDB: paymentDB}
+}
+
+// DailyRevenue 每日每个币种的销售额
+type DailyRevenue struct {
+	Day     string
+	Orders  int64
+	Revenue money.Money
+}
+
+func (r DailyRevenue) Fields() []Field {
+	return []Field{
+		{"day", r.Day},
+		{"currency", r.Revenue.Currency},
+		{"orders", r.Orders},
+		{"revenue", amount(r.Revenue)},
+	}
+}
+
+// RevenueByDay 统计每日已支付订单的数量和金额
+func (r *Reporter) RevenueByDay(ctx context.Context, from, to time.Time) ([]DailyRevenue, error) {
+	rows, err := r.orderDB.QueryContext(ctx, `
+		SELECT DATE(paid_at), currency, COUNT(*), SUM(total_amount_minor)
+		FROM orders
+		WHERE paid_at >= ? AND paid_at < ? AND status <> ?
+		GROUP BY DATE(paid_at), currency
+		ORDER BY DATE(paid_at), currency`,
+		from, to, repository.OrderStatusRefunded,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revenue by day: %w", err)
+	}
+	defer rows.Close()
+
+	var result []DailyRevenue
+	for rows.Next() {
+		var day time.Time
+		var currency string
+		var row DailyRevenue
+		var revenue int64
+		if err := rows.Scan(&day, &currency, &row.Orders, &revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan revenue by day: %w", err)
+		}
+		row.Day = day.Format(dayLayout)
+		row.Revenue = money.New(revenue, currency)
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating revenue by day: %w", err)
+	}
+	return result, nil
+}
+
+// CategoryRevenue 每个商品分类每个币种的销售额
+type CategoryRevenue struct {
+	Category string
+	Quantity int64
+	Revenue  money.Money
+}
+
+func (r CategoryRevenue) Fields() []Field {
+	return []Field{
+		{"category", r.Category},
+		{"currency", r.Revenue.Currency},
+		{"quantity", r.Quantity},
+		{"revenue", amount(r.Revenue)},
+	}
+}
+
+// RevenueByCategory 按下单时的商品分类统计已支付订单的销量和金额，按金额降序排列。
+// 金额扣除分摊到商品上的优惠，记录商品分类之前创建的订单项分类为空
+func (r *Reporter) RevenueByCategory(ctx context.Context, from, to time.Time) ([]CategoryRevenue, error) {
+	rows, err := r.orderDB.QueryContext(ctx, `
+		SELECT oi.category, o.currency, SUM(oi.quantity), SUM(oi.price_minor * oi.quantity - oi.discount_minor) AS revenue
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE o.paid_at >= ? AND o.paid_at < ? AND o.status <> ?
+		GROUP BY oi.category, o.currency
+		ORDER BY revenue DESC, oi.category, o.currency`,
+		from, to, repository.OrderStatusRefunded,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revenue by category: %w", err)
+	}
+	defer rows.Close()
+
+	var result []CategoryRevenue
+	for rows.Next() {
+		var currency string
+		var row CategoryRevenue
+		var revenue int64
+		if err := rows.Scan(&row.Category, &currency, &row.Quantity, &revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan revenue by category: %w", err)
+		}
+		row.Revenue = money.New(revenue, currency)
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating revenue by category: %w", err)
+	}
+	return result, nil
+}
+
+// PaymentMethodRevenue 每种支付方式每个币种的收款和退款金额
+type PaymentMethodRevenue struct {
+	Method   string
+	Payments int64
+	Amount   money.Money
+	Refunded money.Money
+}
+
+func (r PaymentMethodRevenue) Fields() []Field {
+	net, _ := r.Amount.Sub(r.Refunded)
+	return []Field{
+		{"method", r.Method},
+		{"currency", r.Amount.Currency},
+		{"payments", r.Payments},
+		{"amount", amount(r.Amount)},
+		{"refunded", amount(r.Refunded)},
+		{"net", amount(net)},
+	}
+}
+
+// RevenueByPaymentMethod 按支付方式统计支付成功的支付单，包括之后已退款的支付单，退款金额只统计退款成功的部分
+func (r *Reporter) RevenueByPaymentMethod(ctx context.Context, from, to time.Time) ([]PaymentMethodRevenue, error) {
+	if r.paymentDB == nil {
+		return nil, fmt.Errorf("payment database is not configured")
+	}
+
+	rows, err := r.paymentDB.QueryContext(ctx, `
+		SELECT p.method, p.currency, COUNT(*), SUM(p.amount_minor), COALESCE(SUM(r.refunded), 0)
+		FROM payments p
+		LEFT JOIN (
+			SELECT payment_id, SUM(amount_minor) AS refunded
+			FROM refunds
+			WHERE status = ? AND deleted_at IS NULL
+			GROUP BY payment_id
+		) r ON r.payment_id = p.payment_id
+		WHERE p.status IN (?, ?) AND p.created_at >= ? AND p.created_at < ? AND p.deleted_at IS NULL
+		GROUP BY p.method, p.currency
+		ORDER BY p.method, p.currency`,
+		paymentpb.RefundStatus_REFUND_STATUS_SUCCESS,
+		paymentpb.PaymentStatus_PAYMENT_STATUS_SUCCESS, paymentpb.PaymentStatus_PAYMENT_STATUS_REFUNDED,
+		from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revenue by payment method: %w", err)
+	}
+	defer rows.Close()
+
+	var result []PaymentMethodRevenue
+	for rows.Next() {
+		var method int32
+		var currency string
+		var row PaymentMethodRevenue
+		var paid, refunded int64
+		if err := rows.Scan(&method, &currency, &row.Payments, &paid, &refunded); err != nil {
+			return nil, fmt.Errorf("failed to scan revenue by payment method: %w", err)
+		}
+		row.Method = paymentpb.PaymentMethod(method).String()
+		row.Amount = money.New(paid, currency)
+		row.Refunded = money.New(refunded, currency)
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating revenue by payment method: %w", err)
+	}
+	return result, nil
+}
+
+// CancellationRate 每日创建的订单数、取消的订单数和取消率
+type CancellationRate struct {
+	Day       string // 汇总行为 "total"
+	Orders    int64
+	Cancelled int64
+}
+
+// Rate 返回取消订单占全部订单的比例
+func (r CancellationRate) Rate() float64 {
+	if r.Orders == 0 {
+		return 0
+	}
+	return float64(r.Cancelled) / float64(r.Orders)
+}
+
+func (r CancellationRate) Fields() []Field {
+	return []Field{
+		{"day", r.Day},
+		{"orders", r.Orders},
+		{"cancelled", r.Cancelled},
+		{"rate", json.Number(strconv.FormatFloat(r.Rate(), 'f', 4, 64))},
+	}
+}
+
+// CancellationRates 按订单创建日期统计取消率，最后一行为整个时间范围的汇总
+func (r *Reporter) CancellationRates(ctx context.Context, from, to time.Time) ([]CancellationRate, error) {
+	rows, err := r.orderDB.QueryContext(ctx, `
+		SELECT DATE(created_at), COUNT(*), SUM(status = ?)
+		FROM orders
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY DATE(created_at)
+		ORDER BY DATE(created_at)`,
+		repository.OrderStatusCancelled, from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cancellation rate: %w", err)
+	}
+	defer rows.Close()
+
+	var result []CancellationRate
+	total := CancellationRate{Day: "total"}
+	for rows.Next() {
+		var day time.Time
+		var row CancellationRate
+		if err := rows.Scan(&day, &row.Orders, &row.Cancelled); err != nil {
+			return nil, fmt.Errorf("failed to scan cancellation rate: %w", err)
+		}
+		row.Day = day.Format(dayLayout)
+		total.Orders += row.Orders
+		total.Cancelled += row.Cancelled
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cancellation rate: %w", err)
+	}
+	return append(result, total), nil
+}
@@ -0,0 +1,55 @@
+package report
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+)
+
+// OrderItemRow 订单明细导出中的一行，对应一个订单项，订单字段在同一订单的每个订单项中重复
+type OrderItemRow struct {
+	Order *repository.Order
+	Item  *repository.OrderItem
+}
+
+func (r OrderItemRow) Fields() []Field {
+	return []Field{
+		{"order_id", r.Order.ID},
+		{"user_id", r.Order.UserID},
+		{"status", r.Order.Status.String()},
+		{"created_at", r.Order.CreatedAt.Format(time.RFC3339)},
+		{"paid_at", formatTime(r.Order.PaidAt)},
+		{"currency", r.Order.TotalAmount.Currency},
+		{"order_total", amount(r.Order.TotalAmount)},
+		{"product_id", r.Item.ProductID},
+		{"product_name", r.Item.ProductName},
+		{"category", r.Item.Category},
+		{"quantity", r.Item.Quantity},
+		{"unit_price", amount(r.Item.Price)},
+		{"line_total", amount(r.Item.Price.Mul(int64(r.Item.Quantity)))},
+	}
+}
+
+// WriteOrder 输出订单的所有订单项
+func WriteOrder(w *Writer, order *repository.Order) error {
+	for _, item := range order.Items {
+		if err := w.Write(OrderItemRow{Order: order, Item: item}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// amount 返回金额的十进制表示，NDJSON 中输出为数字而不是字符串
+func amount(m money.Money) json.Number {
+	return json.Number(m.Decimal())
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,123 @@
+// Package report 导出订单明细并统计销售额、取消率等汇总报表，报表以 CSV 或 NDJSON 格式输出
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Format 报表输出格式
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson" // 每行一个 JSON 对象
+)
+
+// ParseFormat 解析输出格式名称
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(name); format {
+	case FormatCSV, FormatNDJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported report format %q", name)
+	}
+}
+
+// Field 报表中一行的一个字段
+type Field struct {
+	Name  string
+	Value interface{}
+}
+
+// Row 报表中的一行，字段顺序即 CSV 的列顺序，同一报表的所有行字段相同
+type Row interface {
+	Fields() []Field
+}
+
+// Writer 按指定格式逐行输出报表，CSV 格式在第一行之前输出表头
+type Writer struct {
+	format        Format
+	out           io.Writer
+	csv           *csv.Writer
+	headerWritten bool
+}
+
+// NewWriter 创建输出到 out 的报表 Writer，输出完成后需要调用 Flush
+func NewWriter(out io.Writer, format Format) *Writer {
+	w := &Writer{format: format, out: out}
+	if format == FormatCSV {
+		w.csv = csv.NewWriter(out)
+	}
+	return w
+}
+
+// Write 输出一行
+func (w *Writer) Write(row Row) error {
+	fields := row.Fields()
+	if w.format == FormatNDJSON {
+		return w.writeJSON(fields)
+	}
+
+	if !w.headerWritten {
+		header := make([]string, len(fields))
+		for i, field := range fields {
+			header[i] = field.Name
+		}
+		if err := w.csv.Write(header); err != nil {
+			return fmt.Errorf("failed to write csv header: %w", err)
+		}
+		w.headerWritten = true
+	}
+
+	record := make([]string, len(fields))
+	for i, field := range fields {
+		if field.Value != nil {
+			record[i] = fmt.Sprint(field.Value)
+		}
+	}
+	if err := w.csv.Write(record); err != nil {
+		return fmt.Errorf("failed to write csv record: %w", err)
+	}
+	return nil
+}
+
+// writeJSON 按字段顺序输出一个 JSON 对象
+func (w *Writer) writeJSON(fields []Field) error {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(field.Name)
+		if err != nil {
+			return fmt.Errorf("failed to encode field name: %w", err)
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return fmt.Errorf("failed to encode field %s: %w", field.Name, err)
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteString("}\n")
+
+	if _, err := w.out.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write json record: %w", err)
+	}
+	return nil
+}
+
+// Flush 将缓冲的数据写入输出
+func (w *Writer) Flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
@@ -0,0 +1,88 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testOrder() *repository.Order {
+	createdAt := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	paidAt := createdAt.Add(time.Minute)
+	return &repository.Order{
+		ID:          "42",
+		UserID:      "7",
+		Status:      repository.OrderStatusPaid,
+		TotalAmount: money.New(25050, "CNY"),
+		CreatedAt:   createdAt,
+		PaidAt:      &paidAt,
+		Items: []*repository.OrderItem{
+			{ProductID: "1", ProductName: "Keyboard, mechanical", Category: "Electronics", Quantity: 2, Price: money.New(10000, "CNY")},
+			{ProductID: "2", ProductName: "Mouse pad", Quantity: 1, Price: money.New(5050, "CNY")},
+		},
+	}
+}
+
+func TestWriteOrderCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, FormatCSV)
+	require.NoError(t, WriteOrder(w, testOrder()))
+	require.NoError(t, w.Flush())
+
+	assert.Equal(t,
+		"order_id,user_id,status,created_at,paid_at,currency,order_total,product_id,product_name,category,quantity,unit_price,line_total\n"+
+			"42,7,PAID,2024-06-01T10:00:00Z,2024-06-01T10:01:00Z,CNY,250.50,1,\"Keyboard, mechanical\",Electronics,2,100.00,200.00\n"+
+			"42,7,PAID,2024-06-01T10:00:00Z,2024-06-01T10:01:00Z,CNY,250.50,2,Mouse pad,,1,50.50,50.50\n",
+		buf.String())
+}
+
+func TestWriteOrderNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, FormatNDJSON)
+	order := testOrder()
+	order.PaidAt = nil
+	order.Items = order.Items[:1]
+	require.NoError(t, WriteOrder(w, order))
+	require.NoError(t, w.Flush())
+
+	assert.Equal(t,
+		`{"order_id":"42","user_id":"7","status":"PAID","created_at":"2024-06-01T10:00:00Z","paid_at":"","currency":"CNY",`+
+			`"order_total":250.50,"product_id":"1","product_name":"Keyboard, mechanical","category":"Electronics",`+
+			`"quantity":2,"unit_price":100.00,"line_total":200.00}`+"\n",
+		buf.String())
+}
+
+func TestAggregateRows(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, FormatCSV)
+	require.NoError(t, w.Write(CancellationRate{Day: "2024-06-01", Orders: 8, Cancelled: 1}))
+	require.NoError(t, w.Write(CancellationRate{Day: "total", Orders: 0, Cancelled: 0}))
+	require.NoError(t, w.Flush())
+	assert.Equal(t, "day,orders,cancelled,rate\n2024-06-01,8,1,0.1250\ntotal,0,0,0.0000\n", buf.String())
+
+	buf.Reset()
+	w = NewWriter(&buf, FormatNDJSON)
+	require.NoError(t, w.Write(PaymentMethodRevenue{
+		Method:   "PAYMENT_METHOD_ALIPAY",
+		Payments: 3,
+		Amount:   money.New(30000, "CNY"),
+		Refunded: money.New(4500, "CNY"),
+	}))
+	assert.Equal(t,
+		`{"method":"PAYMENT_METHOD_ALIPAY","currency":"CNY","payments":3,"amount":300.00,"refunded":45.00,"net":255.00}`+"\n",
+		buf.String())
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("ndjson")
+	require.NoError(t, err)
+	assert.Equal(t, FormatNDJSON, format)
+
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
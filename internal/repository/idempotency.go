@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrIdempotencyKeyConflict 表示幂等键已被请求内容不同的请求使用
+var ErrIdempotencyKeyConflict = errors.New("idempotency key was used with a different request")
+
+// ErrIdempotencyKeyInProgress 表示使用相同幂等键的请求仍在处理中
+var ErrIdempotencyKeyInProgress = errors.New("request with the same idempotency key is in progress")
+
+// IdempotencyRecord 记录一个幂等键对应的请求摘要和处理完成后的响应
+type IdempotencyRecord struct {
+	Scope       string
+	Key         string
+	RequestHash string
+	Response    []byte // 请求仍在处理中时为 nil
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+type IdempotencyRepository interface {
+	// Begin 占用幂等键。首次使用时返回 nil；请求已处理完成时返回保存的记录；
+	// 请求摘要不同时返回 ErrIdempotencyKeyConflict；请求仍在处理中时返回 ErrIdempotencyKeyInProgress。
+	// 超过 lockTimeout 仍未完成的占用视为处理请求的实例已退出，由本次请求接管
+	Begin(ctx context.Context, scope, key, requestHash string, lockTimeout time.Duration) (*IdempotencyRecord, error)
+	// Complete 保存请求的响应，之后的重试直接返回该响应
+	Complete(ctx context.Context, scope, key string, response []byte) error
+	// Release 释放未完成的幂等键，使相同幂等键的请求可以重新处理
+	Release(ctx context.Context, scope, key string) error
+}
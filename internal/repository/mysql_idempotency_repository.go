@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type MySQLIdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewMySQLIdempotencyRepository(db *sql.DB) *MySQLIdempotencyRepository {
+	return &MySQLIdempotencyRepository{db: db}
+}
+
+// Begin claims the key with INSERT IGNORE. When the key already exists the stored record
+// decides the outcome: a completed record is returned for replay, a different request hash
+// is a conflict, and an unfinished claim older than lockTimeout is taken over.
+func (r *MySQLIdempotencyRepository) Begin(ctx context.Context, scope, key, requestHash string, lockTimeout time.Duration) (*IdempotencyRecord, error) {
+	now := time.Now()
+	result, err := r.db.ExecContext(
+		ctx,
+		"INSERT IGNORE INTO idempotency_keys (scope, idempotency_key, request_hash, created_at) VALUES (?, ?, ?, ?)",
+		scope,
+		key,
+		requestHash,
+		now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 1 {
+		return nil, nil
+	}
+
+	record := IdempotencyRecord{Scope: scope, Key: key}
+	var completedAt sql.NullTime
+	err = r.db.QueryRowContext(
+		ctx,
+		"SELECT request_hash, response, created_at, completed_at FROM idempotency_keys WHERE scope = ? AND idempotency_key = ?",
+		scope,
+		key,
+	).Scan(&record.RequestHash, &record.Response, &record.CreatedAt, &completedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Released between the insert and the select
+			return nil, ErrIdempotencyKeyInProgress
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	record.CompletedAt = timePtr(completedAt)
+
+	if record.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyConflict
+	}
+	if record.CompletedAt != nil {
+		return &record, nil
+	}
+
+	// Take over a claim whose owner did not finish in time
+	result, err = r.db.ExecContext(
+		ctx,
+		`UPDATE idempotency_keys SET created_at = ?
+		WHERE scope = ? AND idempotency_key = ? AND completed_at IS NULL AND created_at = ? AND created_at < ?`,
+		now,
+		scope,
+		key,
+		record.CreatedAt,
+		now.Add(-lockTimeout),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take over idempotency key: %w", err)
+	}
+	affected, err = result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	return nil, nil
+}
+
+// Complete stores the response of a claimed key
+func (r *MySQLIdempotencyRepository) Complete(ctx context.Context, scope, key string, response []byte) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"UPDATE idempotency_keys SET response = ?, completed_at = ? WHERE scope = ? AND idempotency_key = ?",
+		response,
+		time.Now(),
+		scope,
+		key,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Release deletes an unfinished claim so the request can be retried with the same key
+func (r *MySQLIdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"DELETE FROM idempotency_keys WHERE scope = ? AND idempotency_key = ? AND completed_at IS NULL",
+		scope,
+		key,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
@@ -3,10 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/bytedance-youthcamp/demo/internal/money"
+
 	_ "github.com/go-sql-driver/mysql"
 )
 
@@ -18,43 +20,63 @@ func NewMySQLOrderRepository(db *sql.DB) *MySQLOrderRepository {
 	return &MySQLOrderRepository{db: db}
 }
 
-// Create inserts a new order into the database
+// Create inserts a new order and its items into the database
 func (r *MySQLOrderRepository) Create(ctx context.Context, order *Order) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
 	// Insert order
-	var orderID int64
-	itemsJSON, err := json.Marshal(order.Items)
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to marshal order items: %w", err)
-	}
-
 	result, err := tx.ExecContext(
 		ctx,
-		"INSERT INTO orders (user_id, total_amount, status, shipping_address, items, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		`INSERT INTO orders (user_id, total_amount_minor, currency, status, shipping_address, contact_name, contact_phone,
+			cancel_reason, paid_at, cancelled_at, completed_at, auto_cancel_at, coupon_code, coupon_redemption_id,
+			discount_amount_minor, region, subtotal_amount_minor, tax_amount_minor, shipping_fee_minor, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		order.UserID,
-		order.TotalAmount,
+		order.TotalAmount.Amount,
+		order.TotalAmount.Currency,
 		order.Status,
 		order.ShippingAddress,
-		itemsJSON,
+		order.ContactName,
+		order.ContactPhone,
+		order.CancelReason,
+		nullTime(order.PaidAt),
+		nullTime(order.CancelledAt),
+		nullTime(order.CompletedAt),
+		nullTime(order.AutoCancelAt),
+		order.CouponCode,
+		order.CouponRedemptionID,
+		order.DiscountAmount.Amount,
+		order.Region,
+		order.SubtotalAmount.Amount,
+		order.TaxAmount.Amount,
+		order.ShippingFee.Amount,
 		order.CreatedAt,
 		order.UpdatedAt,
 	)
 	if err != nil {
-		tx.Rollback()
 		return fmt.Errorf("failed to insert order: %w", err)
 	}
 
-	orderID, err = result.LastInsertId()
+	orderID, err := result.LastInsertId()
 	if err != nil {
-		tx.Rollback()
 		return fmt.Errorf("failed to get last insert ID: %w", err)
 	}
 
+	if err := insertOrderItems(ctx, tx, orderID, order.Items); err != nil {
+		return err
+	}
+
+	if err := recordOrderCreated(ctx, tx, fmt.Sprintf("%d", orderID), order); err != nil {
+		return err
+	}
+	if err := addOrderToSummary(ctx, tx, orderID, order); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -64,28 +86,84 @@ func (r *MySQLOrderRepository) Create(ctx context.Context, order *Order) error {
 	return nil
 }
 
-// Get retrieves an order by ID
+// Get retrieves an order and its items by ID
 func (r *MySQLOrderRepository) Get(ctx context.Context, orderID string) (*Order, error) {
-	// Get order details
+	return getOrder(ctx, r.db, orderID)
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// orderColumns lists the orders columns read by scanOrder, in scan order
+const orderColumns = `id, user_id, total_amount_minor, currency, status, shipping_address, contact_name, contact_phone,
+	cancel_reason, paid_at, cancelled_at, completed_at, auto_cancel_at, coupon_code, coupon_redemption_id,
+	discount_amount_minor, region, subtotal_amount_minor, tax_amount_minor, shipping_fee_minor, created_at, updated_at`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanOrder scans a row selected with orderColumns, without items
+func scanOrder(row rowScanner) (*Order, error) {
 	var order Order
 	var statusInt int
-	var itemsJSON []byte
+	var totalAmount, discountAmount, subtotalAmount, taxAmount, shippingFee int64
+	var currency string
+	var shippingAddress, contactName, contactPhone, cancelReason sql.NullString
+	var paidAt, cancelledAt, completedAt, autoCancelAt sql.NullTime
 
-	err := r.db.QueryRowContext(
-		ctx,
-		"SELECT id, user_id, total_amount, status, shipping_address, items, created_at, updated_at FROM orders WHERE id = ?",
-		orderID,
-	).Scan(
+	err := row.Scan(
 		&order.ID,
 		&order.UserID,
-		&order.TotalAmount,
+		&totalAmount,
+		&currency,
 		&statusInt,
-		&order.ShippingAddress,
-		&itemsJSON,
+		&shippingAddress,
+		&contactName,
+		&contactPhone,
+		&cancelReason,
+		&paidAt,
+		&cancelledAt,
+		&completedAt,
+		&autoCancelAt,
+		&order.CouponCode,
+		&order.CouponRedemptionID,
+		&discountAmount,
+		&order.Region,
+		&subtotalAmount,
+		&taxAmount,
+		&shippingFee,
 		&order.CreatedAt,
 		&order.UpdatedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
 
+	order.Status = OrderStatus(statusInt)
+	order.TotalAmount = money.New(totalAmount, currency)
+	order.DiscountAmount = money.New(discountAmount, currency)
+	order.SubtotalAmount = money.New(subtotalAmount, currency)
+	order.TaxAmount = money.New(taxAmount, currency)
+	order.ShippingFee = money.New(shippingFee, currency)
+	order.ShippingAddress = shippingAddress.String
+	order.ContactName = contactName.String
+	order.ContactPhone = contactPhone.String
+	order.CancelReason = cancelReason.String
+	order.PaidAt = timePtr(paidAt)
+	order.CancelledAt = timePtr(cancelledAt)
+	order.CompletedAt = timePtr(completedAt)
+	order.AutoCancelAt = timePtr(autoCancelAt)
+	return &order, nil
+}
+
+func getOrder(ctx context.Context, q queryer, orderID string) (*Order, error) {
+	// Get order details
+	order, err := scanOrder(q.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders WHERE id = ?", orderID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("order not found: %s", orderID)
@@ -93,48 +171,398 @@ func (r *MySQLOrderRepository) Get(ctx context.Context, orderID string) (*Order,
 		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
 
-	order.Status = OrderStatus(statusInt)
+	// Get order items
+	if err := loadOrderItems(ctx, q, []*Order{order}); err != nil {
+		return nil, err
+	}
 
-	if err := json.Unmarshal(itemsJSON, &order.Items); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal order items: %w", err)
+	return order, nil
+}
+
+// loadOrderItems loads the items of all given orders with a single query
+func loadOrderItems(ctx context.Context, q queryer, orders []*Order) error {
+	if len(orders) == 0 {
+		return nil
 	}
 
-	return &order, nil
+	byID := make(map[string]*Order, len(orders))
+	placeholders := make([]string, len(orders))
+	args := make([]interface{}, len(orders))
+	for i, order := range orders {
+		byID[order.ID] = order
+		placeholders[i] = "?"
+		args[i] = order.ID
+	}
+
+	rows, err := q.QueryContext(
+		ctx,
+		"SELECT order_id, product_id, product_name, category, quantity, cancelled_quantity, price_minor, discount_minor FROM order_items WHERE order_id IN ("+strings.Join(placeholders, ", ")+") ORDER BY id",
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get order items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID string
+		var item OrderItem
+		var price, discount int64
+		if err := rows.Scan(&orderID, &item.ProductID, &item.ProductName, &item.Category, &item.Quantity, &item.CancelledQuantity, &price, &discount); err != nil {
+			return fmt.Errorf("failed to scan order item: %w", err)
+		}
+		order, ok := byID[orderID]
+		if !ok {
+			continue
+		}
+		item.Price = money.New(price, order.TotalAmount.Currency)
+		item.Discount = money.New(discount, order.TotalAmount.Currency)
+		order.Items = append(order.Items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating order items: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateContact updates only the shipping address and contact details of an order.
+// Status, amounts and items are never touched, so concurrent status changes and item edits are preserved.
+func (r *MySQLOrderRepository) UpdateContact(ctx context.Context, order *Order) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE orders SET shipping_address = ?, contact_name = ?, contact_phone = ?, updated_at = ? WHERE id = ?",
+		order.ShippingAddress, order.ContactName, order.ContactPhone, order.UpdatedAt, order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update order contact: %w", err)
+	}
+	return nil
 }
 
-// Update updates an existing order
-func (r *MySQLOrderRepository) Update(ctx context.Context, order *Order) error {
+// UpdateStatus locks the order with SELECT ... FOR UPDATE and, if it is still in the from status,
+// writes the new status, its timestamps and the cancel reason, recording the status change, its
+// domain event and the user order aggregates in the same transaction. The other columns and the
+// amounts recorded come from the locked row, so a concurrent UpdateItems or UpdateContact is never
+// overwritten. On success order is replaced by the saved order.
+func (r *MySQLOrderRepository) UpdateStatus(ctx context.Context, order *Order, from OrderStatus, actor, reason string) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM orders WHERE id = ? FOR UPDATE", order.ID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("order not found: %s", order.ID)
+		}
+		return fmt.Errorf("failed to lock order: %w", err)
+	}
+
+	current, err := getOrder(ctx, tx, order.ID)
+	if err != nil {
+		return err
+	}
+	if current.Status != from {
+		return ErrStatusConflict
+	}
+
+	current.Status = order.Status
+	current.CancelReason = order.CancelReason
+	current.PaidAt = order.PaidAt
+	current.CancelledAt = order.CancelledAt
+	current.CompletedAt = order.CompletedAt
+	current.UpdatedAt = order.UpdatedAt
+	affected, err := updateOrderStatus(ctx, tx, current, from)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrStatusConflict
+	}
+
+	if err := insertStatusHistory(ctx, tx, current, from, actor, reason); err != nil {
+		return err
+	}
+	if err := recordOrderStatusChanged(ctx, tx, current, from, actor, reason); err != nil {
+		return err
+	}
+	if err := moveOrderInSummary(ctx, tx, current, from, current.TotalAmount); err != nil {
+		return err
+	}
 
-	// Update order
-	itemsJSON, err := json.Marshal(order.Items)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	*order = *current
+	return nil
+}
+
+// updateOrderStatus writes only the status, its timestamps and the cancel reason of the order,
+// and only when the order is still in the from status
+func updateOrderStatus(ctx context.Context, tx *sql.Tx, order *Order, from OrderStatus) (int64, error) {
+	result, err := tx.ExecContext(ctx,
+		`UPDATE orders SET status = ?, cancel_reason = ?, paid_at = ?, cancelled_at = ?, completed_at = ?, updated_at = ?
+		WHERE id = ? AND status = ?`,
+		order.Status,
+		order.CancelReason,
+		nullTime(order.PaidAt),
+		nullTime(order.CancelledAt),
+		nullTime(order.CompletedAt),
+		order.UpdatedAt,
+		order.ID,
+		from,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
 	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to marshal order items: %w", err)
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
 	}
 
-	_, err = tx.ExecContext(
+	return affected, nil
+}
+
+// insertStatusHistory records a change of the order from the from status to its current status
+func insertStatusHistory(ctx context.Context, tx *sql.Tx, order *Order, from OrderStatus, actor, reason string) error {
+	_, err := tx.ExecContext(
 		ctx,
-		"UPDATE orders SET status = ?, shipping_address = ?, items = ?, updated_at = ? WHERE id = ?",
+		"INSERT INTO order_status_history (order_id, from_status, to_status, actor, reason, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		order.ID,
+		from,
+		order.Status,
+		actor,
+		reason,
+		order.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert order status history: %w", err)
+	}
+	return nil
+}
+
+// updateOrderRow writes all order columns, the caller must hold the row lock
+func updateOrderRow(ctx context.Context, tx *sql.Tx, order *Order) error {
+	query := `UPDATE orders SET total_amount_minor = ?, currency = ?, status = ?, shipping_address = ?, contact_name = ?,
+		contact_phone = ?, cancel_reason = ?, paid_at = ?, cancelled_at = ?, completed_at = ?, auto_cancel_at = ?, coupon_code = ?,
+		coupon_redemption_id = ?, discount_amount_minor = ?, region = ?, subtotal_amount_minor = ?, tax_amount_minor = ?,
+		shipping_fee_minor = ?, updated_at = ?
+		WHERE id = ?`
+	args := []interface{}{
+		order.TotalAmount.Amount,
+		order.TotalAmount.Currency,
 		order.Status,
 		order.ShippingAddress,
-		itemsJSON,
+		order.ContactName,
+		order.ContactPhone,
+		order.CancelReason,
+		nullTime(order.PaidAt),
+		nullTime(order.CancelledAt),
+		nullTime(order.CompletedAt),
+		nullTime(order.AutoCancelAt),
+		order.CouponCode,
+		order.CouponRedemptionID,
+		order.DiscountAmount.Amount,
+		order.Region,
+		order.SubtotalAmount.Amount,
+		order.TaxAmount.Amount,
+		order.ShippingFee.Amount,
 		order.UpdatedAt,
 		order.ID,
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+	return nil
+}
+
+func insertOrderItems(ctx context.Context, tx *sql.Tx, orderID int64, items []*OrderItem) error {
+	for _, item := range items {
+		_, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO order_items (order_id, product_id, product_name, category, quantity, cancelled_quantity, price_minor, discount_minor) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			orderID,
+			item.ProductID,
+			item.ProductName,
+			item.Category,
+			item.Quantity,
+			item.CancelledQuantity,
+			item.Price.Amount,
+			item.Discount.Amount,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert order item: %w", err)
+		}
+	}
+	return nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// ListStatusHistory retrieves the status changes of an order in chronological order
+func (r *MySQLOrderRepository) ListStatusHistory(ctx context.Context, orderID string) ([]*OrderStatusHistory, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		"SELECT id, order_id, from_status, to_status, actor, reason, created_at FROM order_status_history WHERE order_id = ? ORDER BY id",
+		orderID,
 	)
 	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to update order: %w", err)
+		return nil, fmt.Errorf("failed to list order status history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*OrderStatusHistory
+	for rows.Next() {
+		var h OrderStatusHistory
+		var reason sql.NullString
+		if err := rows.Scan(&h.ID, &h.OrderID, &h.FromStatus, &h.ToStatus, &h.Actor, &reason, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan order status history: %w", err)
+		}
+		h.Reason = reason.String
+		history = append(history, &h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order status history: %w", err)
+	}
+
+	return history, nil
+}
+
+// UpdateItems locks the order with SELECT ... FOR UPDATE, lets update modify it and saves the
+// order row, its items and the returned item changes in the same transaction
+func (r *MySQLOrderRepository) UpdateItems(ctx context.Context, orderID string, update func(ctx context.Context, order *Order) ([]*OrderItemChange, error)) (*Order, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("order not found: %s", orderID)
+		}
+		return nil, fmt.Errorf("failed to lock order: %w", err)
+	}
+
+	order, err := getOrder(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	fromStatus, fromAmount := order.Status, order.TotalAmount
+	changes, err := update(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := updateOrderRow(ctx, tx, order); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM order_items WHERE order_id = ?", orderID); err != nil {
+		return nil, fmt.Errorf("failed to delete order items: %w", err)
+	}
+	if err := insertOrderItems(ctx, tx, id, order.Items); err != nil {
+		return nil, err
+	}
+	if err := moveOrderInSummary(ctx, tx, order, fromStatus, fromAmount); err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		change.OrderID = orderID
+		result, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO order_item_changes (order_id, product_id, change_type, old_quantity, new_quantity, amount_minor,
+				refund_id, actor, reason, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			orderID,
+			change.ProductID,
+			change.Type,
+			change.OldQuantity,
+			change.NewQuantity,
+			change.Amount.Amount,
+			change.RefundID,
+			change.Actor,
+			change.Reason,
+			change.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert order item change: %w", err)
+		}
+		if change.ID, err = result.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return nil
+	return order, nil
+}
+
+// ListItemChanges retrieves the item changes of an order in chronological order
+func (r *MySQLOrderRepository) ListItemChanges(ctx context.Context, orderID string) ([]*OrderItemChange, error) {
+	var currency string
+	err := r.db.QueryRowContext(ctx, "SELECT currency FROM orders WHERE id = ?", orderID).Scan(&currency)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("order not found: %s", orderID)
+		}
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT id, order_id, product_id, change_type, old_quantity, new_quantity, amount_minor, refund_id, actor, reason, created_at
+		FROM order_item_changes WHERE order_id = ? ORDER BY id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order item changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []*OrderItemChange
+	for rows.Next() {
+		var c OrderItemChange
+		var amount int64
+		var refundID, reason sql.NullString
+		if err := rows.Scan(&c.ID, &c.OrderID, &c.ProductID, &c.Type, &c.OldQuantity, &c.NewQuantity, &amount,
+			&refundID, &c.Actor, &reason, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan order item change: %w", err)
+		}
+		c.Amount = money.New(amount, currency)
+		c.RefundID = refundID.String
+		c.Reason = reason.String
+		changes = append(changes, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order item changes: %w", err)
+	}
+
+	return changes, nil
 }
 
 // List retrieves orders for a user with optional status filter
@@ -178,32 +606,90 @@ func (r *MySQLOrderRepository) List(ctx context.Context, userID string, status *
 	return orders, nil
 }
 
-// ListPendingOrdersOlderThan retrieves pending orders older than the specified duration
-func (r *MySQLOrderRepository) ListPendingOrdersOlderThan(ctx context.Context, duration time.Duration) ([]*Order, error) {
-	cutoffTime := time.Now().Add(-duration)
+// CancelExpiredOrders locks up to limit unpaid orders whose auto_cancel_at has passed with
+// SELECT ... FOR UPDATE SKIP LOCKED and cancels them in one short transaction, recording the
+// status history and the domain event. Concurrent callers never claim the same order.
+// auto_cancel_at is kept so that the caller can release stock and coupons after commit.
+func (r *MySQLOrderRepository) CancelExpiredOrders(ctx context.Context, now time.Time, limit int, actor, reason string) ([]*Order, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	rows, err := r.db.QueryContext(
+	rows, err := tx.QueryContext(
 		ctx,
-		"SELECT id FROM orders WHERE status = ? AND created_at < ?",
+		`SELECT id FROM orders
+		WHERE status IN (?, ?) AND auto_cancel_at IS NOT NULL AND auto_cancel_at <= ?
+		ORDER BY auto_cancel_at
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED`,
 		OrderStatusPending,
-		cutoffTime,
+		OrderStatusPaymentFailed,
+		now,
+		limit,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list pending orders: %w", err)
+		return nil, fmt.Errorf("failed to claim expired orders: %w", err)
+	}
+	orderIDs, err := scanOrderIDs(rows)
+	if err != nil {
+		return nil, err
 	}
-	defer rows.Close()
 
-	var orderIDs []string
-	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
-			return nil, fmt.Errorf("failed to scan order ID: %w", err)
+	cancelled := make([]*Order, 0, len(orderIDs))
+	for _, id := range orderIDs {
+		order, err := getOrder(ctx, tx, id)
+		if err != nil {
+			return nil, err
 		}
-		orderIDs = append(orderIDs, id)
+
+		from := order.Status
+		order.Status = OrderStatusCancelled
+		order.CancelReason = reason
+		order.CancelledAt = &now
+		order.UpdatedAt = now
+		if _, err := updateOrderStatus(ctx, tx, order, from); err != nil {
+			return nil, err
+		}
+		if err := insertStatusHistory(ctx, tx, order, from, actor, reason); err != nil {
+			return nil, err
+		}
+		if err := recordOrderStatusChanged(ctx, tx, order, from, actor, reason); err != nil {
+			return nil, err
+		}
+		if err := moveOrderInSummary(ctx, tx, order, from, order.TotalAmount); err != nil {
+			return nil, err
+		}
+		cancelled = append(cancelled, order)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating order IDs: %w", err)
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return cancelled, nil
+}
+
+// ListUnreleasedOrders returns up to limit cancelled orders with an id greater than afterID that still
+// have auto_cancel_at set, i.e. whose reserved stock and coupon have not been released yet, ordered by id
+func (r *MySQLOrderRepository) ListUnreleasedOrders(ctx context.Context, afterID int64, limit int) ([]*Order, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT id FROM orders
+		WHERE status = ? AND auto_cancel_at IS NOT NULL AND id > ?
+		ORDER BY id
+		LIMIT ?`,
+		OrderStatusCancelled,
+		afterID,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unreleased orders: %w", err)
+	}
+	orderIDs, err := scanOrderIDs(rows)
+	if err != nil {
+		return nil, err
 	}
 
 	orders := make([]*Order, 0, len(orderIDs))
@@ -214,10 +700,37 @@ func (r *MySQLOrderRepository) ListPendingOrdersOlderThan(ctx context.Context, d
 		}
 		orders = append(orders, order)
 	}
-
 	return orders, nil
 }
 
+// MarkOrderReleased clears auto_cancel_at of a cancelled order once its stock and coupon are released
+func (r *MySQLOrderRepository) MarkOrderReleased(ctx context.Context, orderID string) error {
+	if _, err := r.db.ExecContext(ctx,
+		"UPDATE orders SET auto_cancel_at = NULL WHERE id = ? AND status = ?",
+		orderID, OrderStatusCancelled); err != nil {
+		return fmt.Errorf("failed to mark order released: %w", err)
+	}
+	return nil
+}
+
+// scanOrderIDs reads the id column of rows and closes them
+func scanOrderIDs(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+
+	var orderIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan order ID: %w", err)
+		}
+		orderIDs = append(orderIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order IDs: %w", err)
+	}
+	return orderIDs, nil
+}
+
 // GetUserOrders retrieves paginated orders for a user with status filter
 func (r *MySQLOrderRepository) GetUserOrders(ctx context.Context, userID string, page, pageSize int, status OrderStatus) ([]*Order, int, error) {
 	// Calculate total count
@@ -243,10 +756,10 @@ func (r *MySQLOrderRepository) GetUserOrders(ctx context.Context, userID string,
 		offset = 0
 	}
 
-	// Get paginated order IDs
+	// Get paginated orders
 	rows, err := r.db.QueryContext(
 		ctx,
-		"SELECT id FROM orders WHERE user_id = ? AND status = ? ORDER BY created_at DESC LIMIT ? OFFSET ?",
+		"SELECT "+orderColumns+" FROM orders WHERE user_id = ? AND status = ? ORDER BY created_at DESC LIMIT ? OFFSET ?",
 		userID,
 		status,
 		pageSize,
@@ -257,27 +770,113 @@ func (r *MySQLOrderRepository) GetUserOrders(ctx context.Context, userID string,
 	}
 	defer rows.Close()
 
-	var orderIDs []string
+	orders := []*Order{}
 	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
-			return nil, 0, fmt.Errorf("failed to scan order ID: %w", err)
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan order: %w", err)
 		}
-		orderIDs = append(orderIDs, id)
+		orders = append(orders, order)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, 0, fmt.Errorf("error iterating order IDs: %w", err)
+		return nil, 0, fmt.Errorf("error iterating orders: %w", err)
 	}
 
-	orders := make([]*Order, 0, len(orderIDs))
-	for _, id := range orderIDs {
-		order, err := r.Get(ctx, id)
+	if err := loadOrderItems(ctx, r.db, orders); err != nil {
+		return nil, 0, err
+	}
+
+	return orders, total, nil
+}
+
+// SearchOrders returns up to filter.Limit orders matching the filter, ordered by the sort
+// field and then by ID so that keyset pagination with filter.After is stable. Items of all
+// returned orders are loaded with one batched query.
+func (r *MySQLOrderRepository) SearchOrders(ctx context.Context, filter OrderSearchFilter) ([]*Order, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.UserID != "" {
+		conditions = append(conditions, "o.user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if len(filter.Statuses) > 0 {
+		placeholders := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			placeholders[i] = "?"
+			args = append(args, status)
+		}
+		conditions = append(conditions, "o.status IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "o.created_at >= ?")
+		args = append(args, *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "o.created_at < ?")
+		args = append(args, *filter.CreatedTo)
+	}
+	if filter.MinAmount != nil {
+		conditions = append(conditions, "o.total_amount_minor >= ? AND o.currency = ?")
+		args = append(args, filter.MinAmount.Amount, filter.MinAmount.Currency)
+	}
+	if filter.MaxAmount != nil {
+		conditions = append(conditions, "o.total_amount_minor <= ? AND o.currency = ?")
+		args = append(args, filter.MaxAmount.Amount, filter.MaxAmount.Currency)
+	}
+	if filter.ProductID != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = o.id AND oi.product_id = ?)")
+		args = append(args, filter.ProductID)
+	}
+
+	sortColumn := "o.created_at"
+	if filter.SortBy == OrderSortByTotalAmount {
+		sortColumn = "o.total_amount_minor"
+	}
+	direction, compare := "DESC", "<"
+	if filter.Ascending {
+		direction, compare = "ASC", ">"
+	}
+
+	if filter.After != nil {
+		var sortValue interface{} = filter.After.CreatedAt
+		if filter.SortBy == OrderSortByTotalAmount {
+			sortValue = filter.After.TotalAmount
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s %s ? OR (%s = ? AND o.id %s ?))", sortColumn, compare, sortColumn, compare))
+		args = append(args, sortValue, sortValue, filter.After.ID)
+	}
+
+	query := "SELECT " + orderColumns + " FROM orders o"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, o.id %s LIMIT ?", sortColumn, direction, direction)
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []*Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
 		if err != nil {
-			return nil, 0, err
+			return nil, fmt.Errorf("failed to scan order: %w", err)
 		}
 		orders = append(orders, order)
 	}
 
-	return orders, total, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating orders: %w", err)
+	}
+
+	if err := loadOrderItems(ctx, r.db, orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
 }
\ No newline at end of file
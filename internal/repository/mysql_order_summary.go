@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+// GetUserOrderSummary reads the per-status aggregates and the last order of a user
+func (r *MySQLOrderRepository) GetUserOrderSummary(ctx context.Context, userID string) (*UserOrderSummary, error) {
+	summary := &UserOrderSummary{UserID: userID}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT status, currency, order_count, amount_minor FROM user_order_stats
+		WHERE user_id = ? AND order_count <> 0
+		ORDER BY status, currency`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user order stats: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stat UserOrderStat
+		var currency string
+		var amount int64
+		if err := rows.Scan(&stat.Status, &currency, &stat.Count, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan user order stats: %w", err)
+		}
+		stat.Amount = money.New(amount, currency)
+		summary.Stats = append(summary.Stats, stat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user order stats: %w", err)
+	}
+
+	var lastOrderID int64
+	var lastOrderAt time.Time
+	err = r.db.QueryRowContext(
+		ctx,
+		"SELECT last_order_id, last_order_at FROM user_order_summaries WHERE user_id = ?",
+		userID,
+	).Scan(&lastOrderID, &lastOrderAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to query user order summary: %w", err)
+	}
+	if err == nil {
+		summary.LastOrderID = strconv.FormatInt(lastOrderID, 10)
+		summary.LastOrderAt = &lastOrderAt
+	}
+
+	return summary, nil
+}
+
+// RebuildUserOrderSummaries recomputes the aggregates from the orders table in one transaction,
+// for a single user or for all users when userID is empty, and returns the number of users rebuilt
+func (r *MySQLOrderRepository) RebuildUserOrderSummaries(ctx context.Context, userID string) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	where := ""
+	var args []interface{}
+	if userID != "" {
+		where = " WHERE user_id = ?"
+		args = append(args, userID)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_order_stats"+where, args...); err != nil {
+		return 0, fmt.Errorf("failed to delete user order stats: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_order_summaries"+where, args...); err != nil {
+		return 0, fmt.Errorf("failed to delete user order summaries: %w", err)
+	}
+
+	now := time.Now()
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO user_order_stats (user_id, status, currency, order_count, amount_minor, updated_at)
+		SELECT user_id, status, currency, COUNT(*), SUM(total_amount_minor), ?
+		FROM orders`+where+`
+		GROUP BY user_id, status, currency`,
+		append([]interface{}{now}, args...)...,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild user order stats: %w", err)
+	}
+
+	// Order IDs increase with creation time, so the last order is the one with the largest ID
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO user_order_summaries (user_id, last_order_id, last_order_at, updated_at)
+		SELECT o.user_id, o.id, o.created_at, ?
+		FROM orders o
+		JOIN (SELECT user_id, MAX(id) AS id FROM orders`+where+` GROUP BY user_id) latest ON latest.id = o.id`,
+		append([]interface{}{now}, args...)...,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild user order summaries: %w", err)
+	}
+
+	users, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return int(users), nil
+}
+
+// addOrderToSummary counts a newly created order in the aggregates of its user
+func addOrderToSummary(ctx context.Context, tx *sql.Tx, orderID int64, order *Order) error {
+	if err := updateOrderStats(ctx, tx, order.UserID, order.Status, order.TotalAmount, 1); err != nil {
+		return err
+	}
+
+	// last_order_at is assigned before last_order_id so that it still compares against the old ID
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO user_order_summaries (user_id, last_order_id, last_order_at, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			last_order_at = IF(VALUES(last_order_id) > last_order_id, VALUES(last_order_at), last_order_at),
+			last_order_id = GREATEST(last_order_id, VALUES(last_order_id)),
+			updated_at = VALUES(updated_at)`,
+		order.UserID,
+		orderID,
+		order.CreatedAt,
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user order summary: %w", err)
+	}
+	return nil
+}
+
+// moveOrderInSummary moves an order between aggregates after its status or total amount changed
+func moveOrderInSummary(ctx context.Context, tx *sql.Tx, order *Order, fromStatus OrderStatus, fromAmount money.Money) error {
+	if fromStatus == order.Status && fromAmount == order.TotalAmount {
+		return nil
+	}
+	if err := updateOrderStats(ctx, tx, order.UserID, fromStatus, fromAmount, -1); err != nil {
+		return err
+	}
+	return updateOrderStats(ctx, tx, order.UserID, order.Status, order.TotalAmount, 1)
+}
+
+// updateOrderStats adds count orders with the given total amount each to the aggregate of a user's status
+func updateOrderStats(ctx context.Context, tx *sql.Tx, userID string, status OrderStatus, amount money.Money, count int) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO user_order_stats (user_id, status, currency, order_count, amount_minor, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			order_count = order_count + VALUES(order_count),
+			amount_minor = amount_minor + VALUES(amount_minor),
+			updated_at = VALUES(updated_at)`,
+		userID,
+		status,
+		amount.Currency,
+		count,
+		amount.Amount*int64(count),
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user order stats: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+type MySQLShipmentRepository struct {
+	db *sql.DB
+}
+
+func NewMySQLShipmentRepository(db *sql.DB) *MySQLShipmentRepository {
+	return &MySQLShipmentRepository{db: db}
+}
+
+// Create inserts a shipment and its items. The order row is locked while the shipped
+// quantities are checked so concurrent shipments cannot ship more than was ordered.
+func (r *MySQLShipmentRepository) Create(ctx context.Context, shipment *Shipment) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var orderID string
+	err = tx.QueryRowContext(ctx, "SELECT id FROM orders WHERE id = ? FOR UPDATE", shipment.OrderID).Scan(&orderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("order not found: %s", shipment.OrderID)
+		}
+		return fmt.Errorf("failed to lock order: %w", err)
+	}
+
+	remaining, err := unshippedQuantities(ctx, tx, shipment.OrderID)
+	if err != nil {
+		return err
+	}
+	for _, item := range shipment.Items {
+		remaining[item.ProductID] -= item.Quantity
+		if remaining[item.ProductID] < 0 {
+			return ErrShipmentQuantityExceeded
+		}
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO shipments (order_id, carrier, tracking_number, status, shipped_at, delivered_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		shipment.OrderID,
+		shipment.Carrier,
+		shipment.TrackingNumber,
+		shipment.Status,
+		nullTime(shipment.ShippedAt),
+		nullTime(shipment.DeliveredAt),
+		shipment.CreatedAt,
+		shipment.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert shipment: %w", err)
+	}
+
+	shipmentID, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+
+	for _, item := range shipment.Items {
+		_, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO shipment_items (shipment_id, product_id, quantity) VALUES (?, ?, ?)",
+			shipmentID,
+			item.ProductID,
+			item.Quantity,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert shipment item: %w", err)
+		}
+	}
+
+	for _, event := range shipment.Events {
+		event.ShipmentID = shipmentID
+		if err := insertShipmentEvent(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	shipment.ID = shipmentID
+	return nil
+}
+
+// unshippedQuantities returns the ordered quantity of each product minus the quantity cancelled or already shipped
+func unshippedQuantities(ctx context.Context, tx *sql.Tx, orderID string) (map[string]int32, error) {
+	remaining := make(map[string]int32)
+
+	rows, err := tx.QueryContext(ctx, "SELECT product_id, quantity - cancelled_quantity FROM order_items WHERE order_id = ?", orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+	for rows.Next() {
+		var productID string
+		var quantity int32
+		if err := rows.Scan(&productID, &quantity); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan order item: %w", err)
+		}
+		remaining[productID] += quantity
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order items: %w", err)
+	}
+
+	rows, err = tx.QueryContext(
+		ctx,
+		`SELECT si.product_id, si.quantity FROM shipment_items si
+		JOIN shipments s ON s.id = si.shipment_id
+		WHERE s.order_id = ?`,
+		orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipped items: %w", err)
+	}
+	for rows.Next() {
+		var productID string
+		var quantity int32
+		if err := rows.Scan(&productID, &quantity); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan shipped item: %w", err)
+		}
+		remaining[productID] -= quantity
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shipped items: %w", err)
+	}
+
+	return remaining, nil
+}
+
+// Get retrieves a shipment with its items and tracking events
+func (r *MySQLShipmentRepository) Get(ctx context.Context, shipmentID int64) (*Shipment, error) {
+	shipments, err := r.query(ctx, "WHERE id = ?", shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(shipments) == 0 {
+		return nil, ErrShipmentNotFound
+	}
+	return shipments[0], nil
+}
+
+// ListByOrderID retrieves all shipments of an order in creation order
+func (r *MySQLShipmentRepository) ListByOrderID(ctx context.Context, orderID string) ([]*Shipment, error) {
+	return r.query(ctx, "WHERE order_id = ?", orderID)
+}
+
+func (r *MySQLShipmentRepository) query(ctx context.Context, where string, args ...interface{}) ([]*Shipment, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT id, order_id, carrier, tracking_number, status, shipped_at, delivered_at, created_at, updated_at
+		FROM shipments `+where+` ORDER BY id`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipments: %w", err)
+	}
+	defer rows.Close()
+
+	var shipments []*Shipment
+	for rows.Next() {
+		var shipment Shipment
+		var trackingNumber sql.NullString
+		var shippedAt, deliveredAt sql.NullTime
+		if err := rows.Scan(
+			&shipment.ID,
+			&shipment.OrderID,
+			&shipment.Carrier,
+			&trackingNumber,
+			&shipment.Status,
+			&shippedAt,
+			&deliveredAt,
+			&shipment.CreatedAt,
+			&shipment.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment: %w", err)
+		}
+		shipment.TrackingNumber = trackingNumber.String
+		shipment.ShippedAt = timePtr(shippedAt)
+		shipment.DeliveredAt = timePtr(deliveredAt)
+		shipments = append(shipments, &shipment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shipments: %w", err)
+	}
+
+	for _, shipment := range shipments {
+		if err := r.loadDetails(ctx, shipment); err != nil {
+			return nil, err
+		}
+	}
+
+	return shipments, nil
+}
+
+// loadDetails loads the items and tracking events of a shipment
+func (r *MySQLShipmentRepository) loadDetails(ctx context.Context, shipment *Shipment) error {
+	rows, err := r.db.QueryContext(ctx, "SELECT product_id, quantity FROM shipment_items WHERE shipment_id = ? ORDER BY id", shipment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get shipment items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item ShipmentItem
+		if err := rows.Scan(&item.ProductID, &item.Quantity); err != nil {
+			return fmt.Errorf("failed to scan shipment item: %w", err)
+		}
+		shipment.Items = append(shipment.Items, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating shipment items: %w", err)
+	}
+
+	eventRows, err := r.db.QueryContext(
+		ctx,
+		"SELECT id, shipment_id, status, location, description, event_time FROM shipment_events WHERE shipment_id = ? ORDER BY event_time, id",
+		shipment.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get shipment events: %w", err)
+	}
+	defer eventRows.Close()
+
+	for eventRows.Next() {
+		var event ShipmentEvent
+		var location, description sql.NullString
+		if err := eventRows.Scan(&event.ID, &event.ShipmentID, &event.Status, &location, &description, &event.EventTime); err != nil {
+			return fmt.Errorf("failed to scan shipment event: %w", err)
+		}
+		event.Location = location.String
+		event.Description = description.String
+		shipment.Events = append(shipment.Events, &event)
+	}
+	if err := eventRows.Err(); err != nil {
+		return fmt.Errorf("error iterating shipment events: %w", err)
+	}
+
+	return nil
+}
+
+// AddEvent saves the shipment status and records the tracking event in one transaction
+func (r *MySQLShipmentRepository) AddEvent(ctx context.Context, shipment *Shipment, event *ShipmentEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE shipments SET carrier = ?, tracking_number = ?, status = ?, shipped_at = ?, delivered_at = ?, updated_at = ?
+		WHERE id = ?`,
+		shipment.Carrier,
+		shipment.TrackingNumber,
+		shipment.Status,
+		nullTime(shipment.ShippedAt),
+		nullTime(shipment.DeliveredAt),
+		shipment.UpdatedAt,
+		shipment.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update shipment: %w", err)
+	}
+
+	event.ShipmentID = shipment.ID
+	if err := insertShipmentEvent(ctx, tx, event); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	shipment.Events = append(shipment.Events, event)
+	return nil
+}
+
+func insertShipmentEvent(ctx context.Context, tx *sql.Tx, event *ShipmentEvent) error {
+	result, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO shipment_events (shipment_id, status, location, description, event_time) VALUES (?, ?, ?, ?, ?)",
+		event.ShipmentID,
+		event.Status,
+		event.Location,
+		event.Description,
+		event.EventTime,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert shipment event: %w", err)
+	}
+
+	event.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	return nil
+}
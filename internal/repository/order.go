@@ -2,9 +2,17 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
 	"time"
+
+	"github.com/bytedance-youthcamp/demo/internal/money"
 )
 
+// ErrStatusConflict 表示订单状态已被其他请求修改，本次状态变更未生效
+var ErrStatusConflict = errors.New("order status was changed concurrently")
+
 type OrderStatus int32
 
 const (
@@ -13,31 +21,211 @@ const (
 	OrderStatusShipped
 	OrderStatusDelivered
 	OrderStatusCancelled
+	OrderStatusRefunding
+	OrderStatusRefunded
+	OrderStatusPaymentFailed
 )
 
+func (s OrderStatus) String() string {
+	switch s {
+	case OrderStatusPending:
+		return "PENDING"
+	case OrderStatusPaid:
+		return "PAID"
+	case OrderStatusShipped:
+		return "SHIPPING"
+	case OrderStatusDelivered:
+		return "COMPLETED"
+	case OrderStatusCancelled:
+		return "CANCELLED"
+	case OrderStatusRefunding:
+		return "REFUNDING"
+	case OrderStatusRefunded:
+		return "REFUNDED"
+	case OrderStatusPaymentFailed:
+		return "PAYMENT_FAILED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
 type Order struct {
 	ID              string
 	UserID          string
 	Items           []*OrderItem
-	TotalAmount     float64
+	TotalAmount     money.Money
 	Status          OrderStatus
 	ShippingAddress string
+	ContactName     string
+	ContactPhone    string
+	CancelReason    string
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
+	PaidAt          *time.Time
+	CancelledAt     *time.Time
+	CompletedAt     *time.Time
+	AutoCancelAt    *time.Time
+	// 使用的优惠券，TotalAmount 为扣除 DiscountAmount 后的应付金额
+	CouponCode         string
+	CouponRedemptionID string
+	DiscountAmount     money.Money
+	// 价格明细，TotalAmount = SubtotalAmount - DiscountAmount + TaxAmount + ShippingFee。
+	// 引入价格明细之前创建的订单 SubtotalAmount 为零
+	Region         string
+	SubtotalAmount money.Money
+	TaxAmount      money.Money
+	ShippingFee    money.Money
 }
 
 type OrderItem struct {
 	ProductID   string
 	Quantity    int32
-	Price       float64
+	Price       money.Money // 与订单总金额币种相同
 	ProductName string
+	Category    string // 下单时的商品分类
+	// 支付后取消的数量，取消的商品不再发货
+	CancelledQuantity int32
+	// 分摊到该商品所有数量的优惠金额
+	Discount money.Money
+}
+
+type OrderItemChangeType int32
+
+const (
+	OrderItemChangeQuantity  OrderItemChangeType = iota // 支付前修改数量
+	OrderItemChangeRemoved                              // 支付前删除商品
+	OrderItemChangeCancelled                            // 支付后取消商品并退款
+)
+
+func (t OrderItemChangeType) String() string {
+	switch t {
+	case OrderItemChangeQuantity:
+		return "QUANTITY_CHANGED"
+	case OrderItemChangeRemoved:
+		return "REMOVED"
+	case OrderItemChangeCancelled:
+		return "CANCELLED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// OrderItemChange 记录一次订单项变更。支付前修改时 OldQuantity 和 NewQuantity 为订单项数量，
+// 支付后取消时为取消前后的有效数量（订单数量减去已取消数量）
+type OrderItemChange struct {
+	ID          int64
+	OrderID     string
+	ProductID   string
+	Type        OrderItemChangeType
+	OldQuantity int32
+	NewQuantity int32
+	Amount      money.Money // 订单金额的变化，减少时为负数
+	RefundID    string      // 支付后取消时对应的退款单号
+	Actor       string
+	Reason      string
+	CreatedAt   time.Time
+}
+
+// OrderStatusHistory 记录一次订单状态变更
+type OrderStatusHistory struct {
+	ID         int64
+	OrderID    string
+	FromStatus OrderStatus
+	ToStatus   OrderStatus
+	Actor      string
+	Reason     string
+	CreatedAt  time.Time
+}
+
+type OrderSortField int32
+
+const (
+	OrderSortByCreatedAt OrderSortField = iota
+	OrderSortByTotalAmount
+)
+
+// OrderCursor 是上一页最后一个订单的排序字段值和 ID，只使用与排序字段对应的值
+type OrderCursor struct {
+	CreatedAt   time.Time
+	TotalAmount int64
+	ID          int64
+}
+
+// OrderSearchFilter 搜索订单的条件，零值字段表示不筛选
+type OrderSearchFilter struct {
+	UserID      string
+	Statuses    []OrderStatus
+	CreatedFrom *time.Time // 包含
+	CreatedTo   *time.Time // 不包含
+	MinAmount   *money.Money
+	MaxAmount   *money.Money
+	ProductID   string
+	SortBy      OrderSortField
+	Ascending   bool
+	Limit       int
+	After       *OrderCursor
 }
 
 type OrderRepository interface {
 	Create(ctx context.Context, order *Order) error
 	Get(ctx context.Context, orderID string) (*Order, error)
-	Update(ctx context.Context, order *Order) error
+	// UpdateContact 只更新订单的收货地址和联系人，不修改状态、金额和订单项
+	UpdateContact(ctx context.Context, order *Order) error
 	List(ctx context.Context, userID string, status *OrderStatus) ([]*Order, error)
-	ListPendingOrdersOlderThan(ctx context.Context, duration time.Duration) ([]*Order, error)
+	// CancelExpiredOrders 在一个短事务中锁定并取消最多 limit 个已到自动取消时间的未支付订单，记录状态变更和领域事件。
+	// 使用 FOR UPDATE SKIP LOCKED 锁定订单，多个实例同时执行时每个订单只会被一个实例处理。
+	// 事务中不调用其他服务，预留库存和优惠券由调用方在提交后释放
+	CancelExpiredOrders(ctx context.Context, now time.Time, limit int, actor, reason string) ([]*Order, error)
+	// ListUnreleasedOrders 按订单 ID 顺序返回 ID 大于 afterID 的最多 limit 个已取消但尚未释放库存和优惠券的订单，
+	// 即自动取消时间仍未清除的订单
+	ListUnreleasedOrders(ctx context.Context, afterID int64, limit int) ([]*Order, error)
+	// MarkOrderReleased 在已取消订单的库存和优惠券释放后清除自动取消时间
+	MarkOrderReleased(ctx context.Context, orderID string) error
 	GetUserOrders(ctx context.Context, userID string, page, pageSize int, status OrderStatus) ([]*Order, int, error)
+	// SearchOrders 返回最多 filter.Limit 个符合条件的订单，按排序字段和订单 ID 排序，
+	// 设置 filter.After 时只返回排在该游标之后的订单
+	SearchOrders(ctx context.Context, filter OrderSearchFilter) ([]*Order, error)
+	// UpdateStatus 锁定订单，仅当订单当前状态为 from 时保存新的状态、状态时间和取消原因并记录状态变更，否则返回 ErrStatusConflict。
+	// 不修改订单的金额、联系人和订单项，成功后 order 更新为保存后的订单
+	UpdateStatus(ctx context.Context, order *Order, from OrderStatus, actor, reason string) error
+	ListStatusHistory(ctx context.Context, orderID string) ([]*OrderStatusHistory, error)
+	// UpdateItems 锁定订单后调用 update 修改订单项，在同一事务中保存订单、订单项和 update 返回的变更记录；
+	// update 返回错误时不做任何修改并返回该错误
+	UpdateItems(ctx context.Context, orderID string, update func(ctx context.Context, order *Order) ([]*OrderItemChange, error)) (*Order, error)
+	ListItemChanges(ctx context.Context, orderID string) ([]*OrderItemChange, error)
+	// GetUserOrderSummary 返回用户的订单统计，用户没有订单时返回空统计
+	GetUserOrderSummary(ctx context.Context, userID string) (*UserOrderSummary, error)
+}
+
+// ForEachOrder 按 filter 的排序方式分批查询所有符合条件的订单，并依次对每个订单调用 fn，每批最多查询 batchSize 个订单。
+// filter.Limit 和 filter.After 会被忽略；fn 返回错误时停止查询并返回该错误
+func ForEachOrder(ctx context.Context, repo OrderRepository, filter OrderSearchFilter, batchSize int, fn func(order *Order) error) error {
+	filter.Limit = batchSize
+	filter.After = nil
+
+	for {
+		orders, err := repo.SearchOrders(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+		if len(orders) < batchSize {
+			return nil
+		}
+
+		last := orders[len(orders)-1]
+		id, err := strconv.ParseInt(last.ID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid order ID %q: %w", last.ID, err)
+		}
+		filter.After = &OrderCursor{
+			CreatedAt:   last.CreatedAt,
+			TotalAmount: last.TotalAmount.Amount,
+			ID:          id,
+		}
+	}
 }
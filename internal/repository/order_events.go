@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/bytedance-youthcamp/demo/internal/outbox"
+)
+
+// OrderEvent 订单领域事件的内容
+type OrderEvent struct {
+	OrderID    string           `json:"order_id"`
+	UserID     string           `json:"user_id"`
+	Status     string           `json:"status"`
+	FromStatus string           `json:"from_status,omitempty"`
+	TotalMinor int64            `json:"total_amount_minor"`
+	Currency   string           `json:"currency"`
+	CouponCode string           `json:"coupon_code,omitempty"`
+	Actor      string           `json:"actor,omitempty"`
+	Reason     string           `json:"reason,omitempty"`
+	Items      []OrderEventItem `json:"items,omitempty"`
+}
+
+// OrderEventItem 订单创建事件中的订单项
+type OrderEventItem struct {
+	ProductID  string `json:"product_id"`
+	Quantity   int32  `json:"quantity"`
+	PriceMinor int64  `json:"price_minor"`
+}
+
+// orderStatusEvents 订单进入各状态时发布的事件，未列出的状态发布 OrderStatusChanged
+var orderStatusEvents = map[OrderStatus]string{
+	OrderStatusPaid:      outbox.OrderPaid,
+	OrderStatusShipped:   outbox.OrderShipped,
+	OrderStatusDelivered: outbox.OrderDelivered,
+	OrderStatusCancelled: outbox.OrderCancelled,
+	OrderStatusRefunding: outbox.OrderRefunding,
+	OrderStatusRefunded:  outbox.OrderRefunded,
+}
+
+func newOrderEvent(orderID string, order *Order) OrderEvent {
+	return OrderEvent{
+		OrderID:    orderID,
+		UserID:     order.UserID,
+		Status:     order.Status.String(),
+		TotalMinor: order.TotalAmount.Amount,
+		Currency:   order.TotalAmount.Currency,
+		CouponCode: order.CouponCode,
+	}
+}
+
+// recordOrderCreated 在创建订单的事务中写入 OrderCreated 事件，订单ID在事务提交后才设置到 order 上
+func recordOrderCreated(ctx context.Context, tx outbox.Execer, orderID string, order *Order) error {
+	event := newOrderEvent(orderID, order)
+	for _, item := range order.Items {
+		event.Items = append(event.Items, OrderEventItem{
+			ProductID:  item.ProductID,
+			Quantity:   item.Quantity,
+			PriceMinor: item.Price.Amount,
+		})
+	}
+	return outbox.Record(ctx, tx, outbox.AggregateOrder, orderID, outbox.OrderCreated, event)
+}
+
+// recordOrderStatusChanged 在修改订单状态的事务中写入状态对应的事件
+func recordOrderStatusChanged(ctx context.Context, tx outbox.Execer, order *Order, from OrderStatus, actor, reason string) error {
+	eventType, ok := orderStatusEvents[order.Status]
+	if !ok {
+		eventType = outbox.OrderStatusChanged
+	}
+
+	event := newOrderEvent(order.ID, order)
+	event.FromStatus = from.String()
+	event.Actor = actor
+	event.Reason = reason
+	return outbox.Record(ctx, tx, outbox.AggregateOrder, order.ID, eventType, event)
+}
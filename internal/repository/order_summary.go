@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+// UserOrderStat 用户某一状态、某一币种的订单数和订单金额合计
+type UserOrderStat struct {
+	Status OrderStatus
+	Count  int
+	Amount money.Money
+}
+
+// UserOrderSummary 用户订单的统计，随订单创建、状态变更和订单项修改在同一事务中增量更新
+type UserOrderSummary struct {
+	UserID      string
+	Stats       []UserOrderStat // 按状态和币种排序，不包含订单数为零的统计
+	LastOrderID string          // 最近创建的订单，没有订单时为空
+	LastOrderAt *time.Time
+}
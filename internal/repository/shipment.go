@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrShipmentNotFound 表示发货单不存在
+var ErrShipmentNotFound = errors.New("shipment not found")
+
+// ErrShipmentQuantityExceeded 表示发货数量超过订单中尚未发货的数量
+var ErrShipmentQuantityExceeded = errors.New("shipment quantity exceeds unshipped quantity")
+
+type ShipmentStatus int32
+
+const (
+	ShipmentStatusCreated ShipmentStatus = iota
+	ShipmentStatusInTransit
+	ShipmentStatusDelivered
+)
+
+func (s ShipmentStatus) String() string {
+	switch s {
+	case ShipmentStatusCreated:
+		return "CREATED"
+	case ShipmentStatusInTransit:
+		return "IN_TRANSIT"
+	case ShipmentStatusDelivered:
+		return "DELIVERED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Shipment 一个订单可以拆分为多个发货单，每个发货单包含订单中的部分商品
+type Shipment struct {
+	ID             int64
+	OrderID        string
+	Carrier        string
+	TrackingNumber string
+	Status         ShipmentStatus
+	Items          []*ShipmentItem
+	Events         []*ShipmentEvent
+	ShippedAt      *time.Time
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type ShipmentItem struct {
+	ProductID string
+	Quantity  int32
+}
+
+// ShipmentEvent 记录一条物流轨迹
+type ShipmentEvent struct {
+	ID          int64
+	ShipmentID  int64
+	Status      ShipmentStatus
+	Location    string
+	Description string
+	EventTime   time.Time
+}
+
+type ShipmentRepository interface {
+	// Create 保存发货单和发货商品，发货数量超过订单中尚未发货的数量时返回 ErrShipmentQuantityExceeded
+	Create(ctx context.Context, shipment *Shipment) error
+	// Get 返回发货单及其商品和物流轨迹，不存在时返回 ErrShipmentNotFound
+	Get(ctx context.Context, shipmentID int64) (*Shipment, error)
+	ListByOrderID(ctx context.Context, orderID string) ([]*Shipment, error)
+	// AddEvent 保存发货单的最新状态并记录物流轨迹
+	AddEvent(ctx context.Context, shipment *Shipment, event *ShipmentEvent) error
+}
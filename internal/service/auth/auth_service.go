@@ -32,7 +32,9 @@ type DeliveryResp struct {
 }
 
 type VerifyResp struct {
-	Res bool
+	Res     bool
+	UserId  int32
+	Expired bool
 }
 
 type RenewTokenResp struct {
@@ -148,14 +150,20 @@ func (s *AuthService) VerifyTokenByRPC(ctx context.Context, req *VerifyTokenReq)
         return &VerifyResp{Res: false}, nil
     }
 
+    // 令牌所属的用户，供调用方鉴权
+    var userID int32
+    if id, ok := claims["user_id"].(float64); ok {
+        userID = int32(id)
+    }
+
     // 对于过期的令牌，返回 true（支持续期）
     if token.Valid == false {
         log.Println("Token expired, needs renewal")
-        return &VerifyResp{Res: true}, nil
+        return &VerifyResp{Res: true, UserId: userID, Expired: true}, nil
     }
 
     log.Printf("Token verification result: %v", token.Valid)
-    return &VerifyResp{Res: token.Valid}, nil
+    return &VerifyResp{Res: token.Valid, UserId: userID}, nil
 }
 
 func (s *AuthService) RenewTokenByRPC(ctx context.Context, req *RenewTokenReq) (*RenewTokenResp, error) {
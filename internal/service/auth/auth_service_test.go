@@ -139,6 +139,7 @@ func TestVerifyTokenByRPC(t *testing.T) {
 			if tc.wantValid {
 				assert.NoError(t, err)
 				assert.True(t, resp.Res)
+				assert.Equal(t, int32(1), resp.UserId)
 			} else {
 				assert.False(t, resp.Res)
 			}
@@ -0,0 +1,22 @@
+package cart
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/bytedance-youthcamp/demo/internal/outbox"
+)
+
+// CartEvent 购物车领域事件的内容
+type CartEvent struct {
+	CartID     int32 `json:"cart_id"`
+	CartItemID int32 `json:"cart_item_id,omitempty"`
+	ProductID  int32 `json:"product_id,omitempty"`
+	Quantity   int32 `json:"quantity,omitempty"`
+}
+
+// recordCartEvent 在购物车事务中写入购物车事件
+func recordCartEvent(ctx context.Context, tx *sql.Tx, eventType string, event CartEvent) error {
+	return outbox.Record(ctx, tx, outbox.AggregateCart, strconv.Itoa(int(event.CartID)), eventType, event)
+}
@@ -10,6 +10,8 @@ import (
 	cartapi "github.com/bytedance-youthcamp/demo/api/cart"
 	productapi "github.com/bytedance-youthcamp/demo/api/product"
 	"github.com/bytedance-youthcamp/demo/internal/config"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/outbox"
 	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 	"google.golang.org/grpc"
@@ -97,7 +99,8 @@ func createTestTables(db *sql.DB) error {
 		CREATE TABLE IF NOT EXISTS carts (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			user_id INTEGER NOT NULL,
-			total_price REAL DEFAULT 0,
+			total_price_minor INTEGER DEFAULT 0,
+			currency TEXT NOT NULL DEFAULT 'CNY',
 			total_quantity INTEGER DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
@@ -114,13 +117,33 @@ func createTestTables(db *sql.DB) error {
 			cart_id INTEGER NOT NULL,
 			product_id INTEGER NOT NULL,
 			product_name TEXT NOT NULL,
-			price REAL NOT NULL,
+			price_minor INTEGER NOT NULL,
+			currency TEXT NOT NULL DEFAULT 'CNY',
 			quantity INTEGER NOT NULL,
 			image_url TEXT,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (cart_id) REFERENCES carts(id) ON DELETE CASCADE
 		);
 	`)
+	if err != nil {
+		return err
+	}
+
+	// 创建发件箱表
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS outbox_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id TEXT UNIQUE NOT NULL,
+			event_type TEXT NOT NULL,
+			aggregate_type TEXT NOT NULL,
+			aggregate_id TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			occurred_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			published_at DATETIME,
+			attempts INTEGER DEFAULT 0,
+			last_error TEXT DEFAULT ''
+		);
+	`)
 	return err
 }
 
@@ -219,12 +242,16 @@ func (s *CartService) ClearCart(ctx context.Context, req *cartapi.ClearCartReque
 
 	// 更新购物车总价和总数量
 	_, err = tx.ExecContext(ctx, 
-		"UPDATE carts SET total_price = 0, total_quantity = 0, updated_at = $1 WHERE id = $2", 
+		"UPDATE carts SET total_price_minor = 0, total_quantity = 0, updated_at = $1 WHERE id = $2", 
 		time.Now(), req.CartId)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update cart: %w", err)
 	}
 
+	if err := recordCartEvent(ctx, tx, outbox.CartCleared, CartEvent{CartID: req.CartId}); err != nil {
+		return nil, err
+	}
+
 	// 提交事务
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -247,15 +274,18 @@ func (s *CartService) GetCart(ctx context.Context, req *cartapi.GetCartRequest)
 	// 查询购物车基本信息
 	var cart cartapi.Cart
 	var createdAt, updatedAt time.Time
+	var totalPrice int64
+	var currency string
 	query := `
-		SELECT id, user_id, total_price, total_quantity, created_at, updated_at 
+		SELECT id, user_id, total_price_minor, currency, total_quantity, created_at, updated_at 
 		FROM carts 
 		WHERE id = $1
 	`
 	err := s.db.QueryRowContext(ctx, query, req.CartId).Scan(
 		&cart.Id,
 		&cart.UserId,
-		&cart.TotalPrice,
+		&totalPrice,
+		&currency,
 		&cart.TotalQuantity,
 		&createdAt,
 		&updatedAt,
@@ -271,10 +301,13 @@ func (s *CartService) GetCart(ctx context.Context, req *cartapi.GetCartRequest)
 
 	cart.CreatedAt = createdAt.Format(time.RFC3339)
 	cart.UpdatedAt = updatedAt.Format(time.RFC3339)
+	total := money.New(totalPrice, currency)
+	cart.TotalPrice = total.Float64()
+	cart.TotalPriceMoney = toAPIMoney(total)
 
 	// 查询购物车商品
 	query = `
-		SELECT id, product_id, product_name, price, quantity, image_url, created_at 
+		SELECT id, product_id, product_name, price_minor, currency, quantity, image_url, created_at 
 		FROM cart_items 
 		WHERE cart_id = $1
 	`
@@ -287,11 +320,14 @@ func (s *CartService) GetCart(ctx context.Context, req *cartapi.GetCartRequest)
 	for rows.Next() {
 		var item cartapi.CartItem
 		var itemCreatedAt time.Time
+		var price int64
+		var itemCurrency string
 		err := rows.Scan(
 			&item.Id,
 			&item.ProductId,
 			&item.ProductName,
-			&item.Price,
+			&price,
+			&itemCurrency,
 			&item.Quantity,
 			&item.ImageUrl,
 			&itemCreatedAt,
@@ -300,6 +336,9 @@ func (s *CartService) GetCart(ctx context.Context, req *cartapi.GetCartRequest)
 			return nil, fmt.Errorf("failed to scan cart item: %w", err)
 		}
 		item.CreatedAt = itemCreatedAt.Format(time.RFC3339)
+		itemPrice := money.New(price, itemCurrency)
+		item.Price = itemPrice.Float64()
+		item.PriceMoney = toAPIMoney(itemPrice)
 		cart.Items = append(cart.Items, &item)
 	}
 
@@ -372,6 +411,8 @@ func (s *CartService) AddToCart(ctx context.Context, req *cartapi.AddToCartReque
 		}, nil
 	}
 
+	price := productPrice(productResp.Product)
+
 	// 开始事务
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -379,6 +420,19 @@ func (s *CartService) AddToCart(ctx context.Context, req *cartapi.AddToCartReque
 	}
 	defer tx.Rollback()
 
+	// 购物车中的商品必须使用相同币种，以便精确计算总价
+	var mismatched bool
+	query = "SELECT EXISTS(SELECT 1 FROM cart_items WHERE cart_id = $1 AND currency <> $2)"
+	if err := tx.QueryRowContext(ctx, query, req.CartId, price.Currency).Scan(&mismatched); err != nil {
+		return nil, fmt.Errorf("failed to check cart currency: %w", err)
+	}
+	if mismatched {
+		return &cartapi.AddToCartResponse{
+			Success:      false,
+			ErrorMessage: "商品币种与购物车中的其他商品不一致",
+		}, nil
+	}
+
 	// 检查购物车中是否已有该商品
 	var existingItemId int32
 	var existingQuantity int32
@@ -389,15 +443,16 @@ func (s *CartService) AddToCart(ctx context.Context, req *cartapi.AddToCartReque
 	if err == sql.ErrNoRows {
 		// 商品不在购物车中，添加新商品
 	query = `
-		INSERT INTO cart_items (cart_id, product_id, product_name, price, quantity, image_url, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO cart_items (cart_id, product_id, product_name, price_minor, currency, quantity, image_url, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id
 	`
 		err = tx.QueryRowContext(ctx, query,
 			req.CartId,
 			req.ProductId,
 			productResp.Product.Name,
-			productResp.Product.Price,
+			price.Amount,
+			price.Currency,
 			req.Quantity,
 			productResp.Product.ImageUrl,
 			time.Now(),
@@ -422,16 +477,27 @@ func (s *CartService) AddToCart(ctx context.Context, req *cartapi.AddToCartReque
 	// 更新购物车总价和总数量
 	query = `
 		UPDATE carts
-		SET total_price = (SELECT SUM(price * quantity) FROM cart_items WHERE cart_id = $1),
+		SET total_price_minor = (SELECT SUM(price_minor * quantity) FROM cart_items WHERE cart_id = $1),
+		    currency = $3,
 		    total_quantity = (SELECT SUM(quantity) FROM cart_items WHERE cart_id = $1),
 		    updated_at = $2
 		WHERE id = $1
 	`
-	_, err = tx.ExecContext(ctx, query, req.CartId, time.Now())
+	_, err = tx.ExecContext(ctx, query, req.CartId, time.Now(), price.Currency)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update cart totals: %w", err)
 	}
 
+	err = recordCartEvent(ctx, tx, outbox.CartItemAdded, CartEvent{
+		CartID:     req.CartId,
+		CartItemID: itemId,
+		ProductID:  req.ProductId,
+		Quantity:   req.Quantity,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	// 提交事务
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -465,18 +531,18 @@ func (s *CartService) RemoveFromCart(ctx context.Context, req *cartapi.RemoveFro
 	defer tx.Rollback()
 
 	// 检查商品项是否存在于购物车中
-	var exists bool
-	query := "SELECT EXISTS(SELECT 1 FROM cart_items WHERE id = $1 AND cart_id = $2)"
-	err = tx.QueryRowContext(ctx, query, req.CartItemId, req.CartId).Scan(&exists)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check cart item existence: %w", err)
-	}
-	if !exists {
+	event := CartEvent{CartID: req.CartId, CartItemID: req.CartItemId}
+	query := "SELECT product_id, quantity FROM cart_items WHERE id = $1 AND cart_id = $2"
+	err = tx.QueryRowContext(ctx, query, req.CartItemId, req.CartId).Scan(&event.ProductID, &event.Quantity)
+	if err == sql.ErrNoRows {
 		return &cartapi.RemoveFromCartResponse{
 			Success:      false,
 			ErrorMessage: "商品项不存在于购物车中",
 		}, nil
 	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check cart item existence: %w", err)
+	}
 
 	// 删除商品项
 	_, err = tx.ExecContext(ctx, "DELETE FROM cart_items WHERE id = $1", req.CartItemId)
@@ -487,7 +553,7 @@ func (s *CartService) RemoveFromCart(ctx context.Context, req *cartapi.RemoveFro
 	// 更新购物车总价和总数量
 	query = `
 		UPDATE carts
-		SET total_price = (SELECT COALESCE(SUM(price * quantity), 0) FROM cart_items WHERE cart_id = $1),
+		SET total_price_minor = (SELECT COALESCE(SUM(price_minor * quantity), 0) FROM cart_items WHERE cart_id = $1),
 		    total_quantity = (SELECT COALESCE(SUM(quantity), 0) FROM cart_items WHERE cart_id = $1),
 		    updated_at = $2
 		WHERE id = $1
@@ -497,6 +563,10 @@ func (s *CartService) RemoveFromCart(ctx context.Context, req *cartapi.RemoveFro
 		return nil, fmt.Errorf("failed to update cart totals: %w", err)
 	}
 
+	if err := recordCartEvent(ctx, tx, outbox.CartItemRemoved, event); err != nil {
+		return nil, err
+	}
+
 	// 提交事务
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -505,4 +575,19 @@ func (s *CartService) RemoveFromCart(ctx context.Context, req *cartapi.RemoveFro
 	return &cartapi.RemoveFromCartResponse{
 		Success: true,
 	}, nil
-}
\ No newline at end of file
+}
+
+// productPrice 返回商品的精确价格，商品服务未返回精确价格时转换浮点价格
+func productPrice(product *productapi.Product) money.Money {
+	if product.PriceMoney != nil {
+		return money.New(product.PriceMoney.Amount, product.PriceMoney.Currency)
+	}
+	return money.FromFloat(product.Price, money.DefaultCurrency)
+}
+
+func toAPIMoney(m money.Money) *cartapi.Money {
+	return &cartapi.Money{
+		Amount:   m.Amount,
+		Currency: m.Currency,
+	}
+}
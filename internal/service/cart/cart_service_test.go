@@ -43,6 +43,36 @@ func (m *MockProductClient) DeleteProduct(ctx context.Context, req *productapi.D
 	return args.Get(0).(*productapi.DeleteProductResponse), args.Error(1)
 }
 
+func (m *MockProductClient) ReduceStock(ctx context.Context, req *productapi.ReduceStockRequest, opts ...grpc.CallOption) (*productapi.ReduceStockResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*productapi.ReduceStockResponse), args.Error(1)
+}
+
+func (m *MockProductClient) ReserveStock(ctx context.Context, req *productapi.ReserveStockRequest, opts ...grpc.CallOption) (*productapi.ReserveStockResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*productapi.ReserveStockResponse), args.Error(1)
+}
+
+func (m *MockProductClient) ReleaseStock(ctx context.Context, req *productapi.ReleaseStockRequest, opts ...grpc.CallOption) (*productapi.ReleaseStockResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*productapi.ReleaseStockResponse), args.Error(1)
+}
+
+func (m *MockProductClient) CommitReservation(ctx context.Context, req *productapi.CommitReservationRequest, opts ...grpc.CallOption) (*productapi.CommitReservationResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*productapi.CommitReservationResponse), args.Error(1)
+}
+
+func (m *MockProductClient) RestoreStock(ctx context.Context, req *productapi.RestoreStockRequest, opts ...grpc.CallOption) (*productapi.RestoreStockResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*productapi.RestoreStockResponse), args.Error(1)
+}
+
+func (m *MockProductClient) AdjustReservation(ctx context.Context, req *productapi.AdjustReservationRequest, opts ...grpc.CallOption) (*productapi.AdjustReservationResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*productapi.AdjustReservationResponse), args.Error(1)
+}
+
 func setupTestDB(t *testing.T) *sql.DB {
 	// Set test environment
 	os.Setenv("GO_TEST_ENV", "true")
@@ -0,0 +1,232 @@
+package checkout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	cartpb "github.com/bytedance-youthcamp/demo/api/cart"
+	checkoutapi "github.com/bytedance-youthcamp/demo/api/checkout"
+	orderpb "github.com/bytedance-youthcamp/demo/api/order"
+	paymentpb "github.com/bytedance-youthcamp/demo/api/payment"
+	productpb "github.com/bytedance-youthcamp/demo/api/product"
+)
+
+const (
+	defaultStepTimeout = 10 * time.Second
+	recoveryBatchSize  = 100
+)
+
+// CheckoutService 以 saga 的方式编排购物车、订单、商品和支付服务完成结账，
+// 每个步骤的结果都会持久化，失败时倒序执行补偿，进程崩溃后由恢复任务继续推进
+type CheckoutService struct {
+	checkoutapi.UnimplementedCheckoutServiceServer
+	cartClient    cartpb.CartServiceClient
+	orderClient   orderpb.OrderServiceClient
+	productClient productpb.ProductServiceClient
+	paymentClient paymentpb.PaymentServiceClient
+	store         *sagaStore
+	stepTimeout   time.Duration
+}
+
+type Option func(*CheckoutService)
+
+func WithDatabase(db *sql.DB) Option {
+	return func(s *CheckoutService) {
+		s.store = &sagaStore{db: db}
+	}
+}
+
+func WithCartClient(client cartpb.CartServiceClient) Option {
+	return func(s *CheckoutService) {
+		s.cartClient = client
+	}
+}
+
+func WithOrderClient(client orderpb.OrderServiceClient) Option {
+	return func(s *CheckoutService) {
+		s.orderClient = client
+	}
+}
+
+func WithProductClient(client productpb.ProductServiceClient) Option {
+	return func(s *CheckoutService) {
+		s.productClient = client
+	}
+}
+
+func WithPaymentClient(client paymentpb.PaymentServiceClient) Option {
+	return func(s *CheckoutService) {
+		s.paymentClient = client
+	}
+}
+
+// WithStepTimeout 设置单个步骤调用下游服务的超时时间
+func WithStepTimeout(timeout time.Duration) Option {
+	return func(s *CheckoutService) {
+		if timeout > 0 {
+			s.stepTimeout = timeout
+		}
+	}
+}
+
+func NewCheckoutService(opts ...Option) (*CheckoutService, error) {
+	service := &CheckoutService{
+		stepTimeout: defaultStepTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(service)
+	}
+
+	if service.store == nil {
+		return nil, errors.New("checkout service requires a database")
+	}
+	if service.cartClient == nil || service.orderClient == nil || service.productClient == nil || service.paymentClient == nil {
+		return nil, errors.New("checkout service requires cart, order, product and payment clients")
+	}
+
+	return service, nil
+}
+
+// Checkout 执行一次结账，流程完成或补偿结束后返回；
+// 若因下游暂时不可用而中断，返回结账ID，由恢复任务稍后继续
+func (s *CheckoutService) Checkout(ctx context.Context, req *checkoutapi.CheckoutRequest) (*checkoutapi.CheckoutResponse, error) {
+	if req.UserId <= 0 {
+		return &checkoutapi.CheckoutResponse{
+			Success:      false,
+			ErrorMessage: "用户ID无效",
+		}, nil
+	}
+	if req.CartId <= 0 {
+		return &checkoutapi.CheckoutResponse{
+			Success:      false,
+			ErrorMessage: "购物车ID无效",
+		}, nil
+	}
+	if _, ok := parsePaymentMethod(req.PaymentMethod); !ok {
+		return &checkoutapi.CheckoutResponse{
+			Success:      false,
+			ErrorMessage: "不支持的支付方式",
+		}, nil
+	}
+
+	sg := &saga{
+		UserID:        req.UserId,
+		CartID:        req.CartId,
+		PaymentMethod: req.PaymentMethod,
+		CouponCode:    req.CouponCode,
+		Status:        sagaStatusRunning,
+		CurrentStep:   stepLoadCart,
+	}
+	if err := s.store.create(ctx, sg); err != nil {
+		return nil, err
+	}
+
+	// 客户端断开不应打断已经开始的结账流程
+	runErr := s.run(context.WithoutCancel(ctx), sg)
+
+	resp := &checkoutapi.CheckoutResponse{
+		Success:      sg.Status == sagaStatusCompleted,
+		CheckoutId:   sg.ID,
+		OrderId:      sg.OrderID,
+		PaymentId:    sg.PaymentID,
+		PaymentUrl:   sg.PaymentURL,
+		Status:       toAPIStatus(sg.Status),
+		ErrorMessage: sg.ErrorMessage,
+	}
+	if runErr != nil {
+		resp.ErrorMessage = "结账尚未完成，稍后将自动重试"
+	}
+
+	return resp, nil
+}
+
+// GetCheckout 查询结账进度
+func (s *CheckoutService) GetCheckout(ctx context.Context, req *checkoutapi.GetCheckoutRequest) (*checkoutapi.GetCheckoutResponse, error) {
+	sg, err := s.store.get(ctx, req.CheckoutId)
+	if errors.Is(err, errSagaNotFound) {
+		return &checkoutapi.GetCheckoutResponse{
+			Success:      false,
+			ErrorMessage: "结账记录不存在",
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &checkoutapi.GetCheckoutResponse{
+		Success: true,
+		Checkout: &checkoutapi.CheckoutInfo{
+			CheckoutId:   sg.ID,
+			UserId:       sg.UserID,
+			CartId:       sg.CartID,
+			OrderId:      sg.OrderID,
+			PaymentId:    sg.PaymentID,
+			PaymentUrl:   sg.PaymentURL,
+			TotalAmount:  sg.TotalAmount.Float64(),
+			Status:       toAPIStatus(sg.Status),
+			CurrentStep:  sg.CurrentStep,
+			ErrorMessage: sg.ErrorMessage,
+			CreatedAt:    sg.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:    sg.UpdatedAt.Format(time.RFC3339),
+			CouponCode:   sg.CouponCode,
+		},
+	}, nil
+}
+
+// RecoverCheckouts 继续推进停止超过两个步骤超时时间的结账流程，返回处理的数量
+func (s *CheckoutService) RecoverCheckouts(ctx context.Context) (int, error) {
+	sagas, err := s.store.listStale(ctx, time.Now().Add(-2*s.stepTimeout), recoveryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for _, sg := range sagas {
+		// 先推进版本号认领该流程，避免多个实例同时恢复
+		if err := s.store.save(ctx, sg); err != nil {
+			if !errors.Is(err, errSagaConflict) {
+				log.Printf("Error claiming checkout %d: %v", sg.ID, err)
+			}
+			continue
+		}
+
+		if err := s.run(ctx, sg); err != nil {
+			log.Printf("Error recovering checkout %d: %v", sg.ID, err)
+			continue
+		}
+		recovered++
+		log.Printf("Recovered checkout %d with status %s", sg.ID, sg.Status)
+	}
+
+	return recovered, nil
+}
+
+// StartRecoveryTask 定期恢复中断的结账流程
+func (s *CheckoutService) StartRecoveryTask(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if _, err := s.RecoverCheckouts(context.Background()); err != nil {
+				log.Printf("Error recovering checkouts: %v", err)
+			}
+		}
+	}()
+}
+
+func toAPIStatus(status string) checkoutapi.CheckoutStatus {
+	switch status {
+	case sagaStatusCompensating:
+		return checkoutapi.CheckoutStatus_CHECKOUT_STATUS_COMPENSATING
+	case sagaStatusCompleted:
+		return checkoutapi.CheckoutStatus_CHECKOUT_STATUS_COMPLETED
+	case sagaStatusFailed:
+		return checkoutapi.CheckoutStatus_CHECKOUT_STATUS_FAILED
+	default:
+		return checkoutapi.CheckoutStatus_CHECKOUT_STATUS_RUNNING
+	}
+}
+
@@ -0,0 +1,312 @@
+package checkout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	cartpb "github.com/bytedance-youthcamp/demo/api/cart"
+	checkoutapi "github.com/bytedance-youthcamp/demo/api/checkout"
+	orderpb "github.com/bytedance-youthcamp/demo/api/order"
+	paymentpb "github.com/bytedance-youthcamp/demo/api/payment"
+	productpb "github.com/bytedance-youthcamp/demo/api/product"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type mockCartClient struct {
+	mock.Mock
+	cartpb.CartServiceClient
+}
+
+func (m *mockCartClient) GetCart(ctx context.Context, in *cartpb.GetCartRequest, opts ...grpc.CallOption) (*cartpb.GetCartResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*cartpb.GetCartResponse), args.Error(1)
+}
+
+func (m *mockCartClient) ClearCart(ctx context.Context, in *cartpb.ClearCartRequest, opts ...grpc.CallOption) (*cartpb.ClearCartResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*cartpb.ClearCartResponse), args.Error(1)
+}
+
+func (m *mockCartClient) AddToCart(ctx context.Context, in *cartpb.AddToCartRequest, opts ...grpc.CallOption) (*cartpb.AddToCartResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*cartpb.AddToCartResponse), args.Error(1)
+}
+
+type mockOrderClient struct {
+	mock.Mock
+	orderpb.OrderServiceClient
+}
+
+func (m *mockOrderClient) CreateOrder(ctx context.Context, in *orderpb.CreateOrderRequest, opts ...grpc.CallOption) (*orderpb.CreateOrderResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*orderpb.CreateOrderResponse), args.Error(1)
+}
+
+func (m *mockOrderClient) CancelOrder(ctx context.Context, in *orderpb.CancelOrderRequest, opts ...grpc.CallOption) (*orderpb.CancelOrderResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*orderpb.CancelOrderResponse), args.Error(1)
+}
+
+type mockProductClient struct {
+	mock.Mock
+	productpb.ProductServiceClient
+}
+
+func (m *mockProductClient) ReserveStock(ctx context.Context, in *productpb.ReserveStockRequest, opts ...grpc.CallOption) (*productpb.ReserveStockResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*productpb.ReserveStockResponse), args.Error(1)
+}
+
+func (m *mockProductClient) ReleaseStock(ctx context.Context, in *productpb.ReleaseStockRequest, opts ...grpc.CallOption) (*productpb.ReleaseStockResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*productpb.ReleaseStockResponse), args.Error(1)
+}
+
+type mockPaymentClient struct {
+	mock.Mock
+	paymentpb.PaymentServiceClient
+}
+
+func (m *mockPaymentClient) CreatePayment(ctx context.Context, in *paymentpb.CreatePaymentRequest, opts ...grpc.CallOption) (*paymentpb.CreatePaymentResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*paymentpb.CreatePaymentResponse), args.Error(1)
+}
+
+type testClients struct {
+	cart    *mockCartClient
+	order   *mockOrderClient
+	product *mockProductClient
+	payment *mockPaymentClient
+}
+
+func setupTestDatabase(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err, "Failed to open database")
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE checkout_sagas (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			cart_id INTEGER NOT NULL,
+			payment_method TEXT NOT NULL,
+			coupon_code TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			current_step TEXT NOT NULL,
+			items TEXT,
+			total_amount_minor INTEGER NOT NULL DEFAULT 0,
+			currency TEXT NOT NULL DEFAULT 'CNY',
+			order_id INTEGER NOT NULL DEFAULT 0,
+			payment_id TEXT NOT NULL DEFAULT '',
+			payment_url TEXT NOT NULL DEFAULT '',
+			error_message TEXT,
+			version INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	require.NoError(t, err, "Failed to create tables")
+
+	return db
+}
+
+func setupCheckoutService(t *testing.T, opts ...Option) (*CheckoutService, *testClients) {
+	clients := &testClients{
+		cart:    new(mockCartClient),
+		order:   new(mockOrderClient),
+		product: new(mockProductClient),
+		payment: new(mockPaymentClient),
+	}
+
+	opts = append([]Option{
+		WithDatabase(setupTestDatabase(t)),
+		WithCartClient(clients.cart),
+		WithOrderClient(clients.order),
+		WithProductClient(clients.product),
+		WithPaymentClient(clients.payment),
+	}, opts...)
+
+	service, err := NewCheckoutService(opts...)
+	require.NoError(t, err, "Failed to create CheckoutService")
+
+	return service, clients
+}
+
+func testCart() *cartpb.GetCartResponse {
+	return &cartpb.GetCartResponse{
+		Success: true,
+		Cart: &cartpb.Cart{
+			Id:     1,
+			UserId: 1,
+			Items: []*cartpb.CartItem{
+				{ProductId: 10, ProductName: "Product A", Price: 9.9, Quantity: 2},
+				{ProductId: 20, ProductName: "Product B", Price: 20, Quantity: 1},
+			},
+		},
+	}
+}
+
+func TestCheckout(t *testing.T) {
+	service, clients := setupCheckoutService(t)
+	ctx := context.Background()
+
+	clients.cart.On("GetCart", mock.Anything, mock.Anything).Return(testCart(), nil)
+	clients.order.On("CreateOrder", mock.Anything, mock.MatchedBy(func(req *orderpb.CreateOrderRequest) bool {
+		return req.UserId == 1 && len(req.Items) == 2 && req.TotalPrice == 39.8 &&
+			strings.HasPrefix(req.IdempotencyKey, "checkout-order-")
+	})).Return(&orderpb.CreateOrderResponse{Success: true, OrderId: 100}, nil)
+	clients.cart.On("ClearCart", mock.Anything, &cartpb.ClearCartRequest{CartId: 1}).
+		Return(&cartpb.ClearCartResponse{Success: true}, nil)
+	clients.product.On("ReserveStock", mock.Anything, mock.MatchedBy(func(req *productpb.ReserveStockRequest) bool {
+		return req.OrderId == 100 && len(req.Items) == 2
+	})).Return(&productpb.ReserveStockResponse{Success: true}, nil)
+	clients.payment.On("CreatePayment", mock.Anything, mock.MatchedBy(func(req *paymentpb.CreatePaymentRequest) bool {
+		return req.OrderId == 100 && req.Amount == 39.8 && req.Method == paymentpb.PaymentMethod_PAYMENT_METHOD_WECHAT &&
+			strings.HasPrefix(req.IdempotencyKey, "checkout-")
+	})).Return(&paymentpb.CreatePaymentResponse{Success: true, PaymentId: "pay-1", PaymentUrl: "https://pay"}, nil)
+
+	resp, err := service.Checkout(ctx, &checkoutapi.CheckoutRequest{
+		UserId:        1,
+		CartId:        1,
+		PaymentMethod: "wechat",
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+	assert.Equal(t, int32(100), resp.OrderId)
+	assert.Equal(t, "pay-1", resp.PaymentId)
+	assert.Equal(t, checkoutapi.CheckoutStatus_CHECKOUT_STATUS_COMPLETED, resp.Status)
+
+	getResp, err := service.GetCheckout(ctx, &checkoutapi.GetCheckoutRequest{CheckoutId: resp.CheckoutId})
+	require.NoError(t, err)
+	assert.True(t, getResp.Success)
+	assert.Equal(t, stepDone, getResp.Checkout.CurrentStep)
+	assert.Equal(t, 39.8, getResp.Checkout.TotalAmount)
+
+	clients.cart.AssertExpectations(t)
+	clients.order.AssertExpectations(t)
+	clients.product.AssertExpectations(t)
+	clients.payment.AssertExpectations(t)
+}
+
+func TestCheckoutCompensatesOnInsufficientStock(t *testing.T) {
+	service, clients := setupCheckoutService(t)
+	ctx := context.Background()
+
+	// 恢复购物车时购物车已被清空
+	clients.cart.On("GetCart", mock.Anything, mock.Anything).Return(testCart(), nil).Once()
+	clients.cart.On("GetCart", mock.Anything, mock.Anything).Return(&cartpb.GetCartResponse{
+		Success: true,
+		Cart:    &cartpb.Cart{Id: 1, UserId: 1},
+	}, nil).Once()
+	clients.order.On("CreateOrder", mock.Anything, mock.Anything).
+		Return(&orderpb.CreateOrderResponse{Success: true, OrderId: 100}, nil)
+	clients.cart.On("ClearCart", mock.Anything, mock.Anything).
+		Return(&cartpb.ClearCartResponse{Success: true}, nil)
+	clients.product.On("ReserveStock", mock.Anything, mock.Anything).
+		Return(&productpb.ReserveStockResponse{Success: false, ErrorMessage: "商品库存不足", FailedProductId: 20}, nil)
+	clients.cart.On("AddToCart", mock.Anything, &cartpb.AddToCartRequest{CartId: 1, ProductId: 10, Quantity: 2}).
+		Return(&cartpb.AddToCartResponse{Success: true}, nil)
+	clients.cart.On("AddToCart", mock.Anything, &cartpb.AddToCartRequest{CartId: 1, ProductId: 20, Quantity: 1}).
+		Return(&cartpb.AddToCartResponse{Success: true}, nil)
+	clients.order.On("CancelOrder", mock.Anything, mock.MatchedBy(func(req *orderpb.CancelOrderRequest) bool {
+		return req.OrderId == 100
+	})).Return(&orderpb.CancelOrderResponse{Success: true}, nil)
+
+	resp, err := service.Checkout(ctx, &checkoutapi.CheckoutRequest{UserId: 1, CartId: 1})
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.Equal(t, checkoutapi.CheckoutStatus_CHECKOUT_STATUS_FAILED, resp.Status)
+	assert.Contains(t, resp.ErrorMessage, "商品库存不足")
+
+	// 预留失败的步骤本身不需要释放库存
+	clients.product.AssertNotCalled(t, "ReleaseStock", mock.Anything, mock.Anything)
+	clients.payment.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything)
+	clients.cart.AssertExpectations(t)
+	clients.order.AssertExpectations(t)
+}
+
+func TestCheckoutResumesAfterInterruption(t *testing.T) {
+	service, clients := setupCheckoutService(t, WithStepTimeout(time.Millisecond))
+	ctx := context.Background()
+
+	clients.cart.On("GetCart", mock.Anything, mock.Anything).Return(testCart(), nil)
+	clients.order.On("CreateOrder", mock.Anything, mock.Anything).
+		Return(&orderpb.CreateOrderResponse{Success: true, OrderId: 100}, nil).Once()
+	clients.cart.On("ClearCart", mock.Anything, mock.Anything).
+		Return(&cartpb.ClearCartResponse{Success: true}, nil)
+	clients.product.On("ReserveStock", mock.Anything, mock.Anything).
+		Return(&productpb.ReserveStockResponse{Success: true}, nil)
+
+	// 支付服务暂时不可用，结账停留在支付步骤
+	clients.payment.On("CreatePayment", mock.Anything, mock.Anything).
+		Return((*paymentpb.CreatePaymentResponse)(nil), errors.New("connection refused")).Once()
+
+	resp, err := service.Checkout(ctx, &checkoutapi.CheckoutRequest{UserId: 1, CartId: 1})
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.Equal(t, checkoutapi.CheckoutStatus_CHECKOUT_STATUS_RUNNING, resp.Status)
+
+	getResp, err := service.GetCheckout(ctx, &checkoutapi.GetCheckoutRequest{CheckoutId: resp.CheckoutId})
+	require.NoError(t, err)
+	assert.Equal(t, stepCreatePayment, getResp.Checkout.CurrentStep)
+
+	// 恢复任务从支付步骤继续，不会重复创建订单
+	clients.payment.On("CreatePayment", mock.Anything, mock.Anything).
+		Return(&paymentpb.CreatePaymentResponse{Success: true, PaymentId: "pay-1"}, nil).Once()
+
+	time.Sleep(10 * time.Millisecond)
+	recovered, err := service.RecoverCheckouts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, recovered)
+
+	getResp, err = service.GetCheckout(ctx, &checkoutapi.GetCheckoutRequest{CheckoutId: resp.CheckoutId})
+	require.NoError(t, err)
+	assert.Equal(t, checkoutapi.CheckoutStatus_CHECKOUT_STATUS_COMPLETED, getResp.Checkout.Status)
+	assert.Equal(t, int32(100), getResp.Checkout.OrderId)
+	assert.Equal(t, "pay-1", getResp.Checkout.PaymentId)
+
+	clients.order.AssertNumberOfCalls(t, "CreateOrder", 1)
+	clients.payment.AssertExpectations(t)
+}
+
+func TestCheckoutWithCoupon(t *testing.T) {
+	service, clients := setupCheckoutService(t)
+	ctx := context.Background()
+
+	clients.cart.On("GetCart", mock.Anything, mock.Anything).Return(testCart(), nil)
+	// 订单按原价校验，返回优惠后的应付金额
+	clients.order.On("CreateOrder", mock.Anything, mock.MatchedBy(func(req *orderpb.CreateOrderRequest) bool {
+		return req.CouponCode == "SAVE5" && req.TotalMoney.Amount == 3980
+	})).Return(&orderpb.CreateOrderResponse{
+		Success:       true,
+		OrderId:       100,
+		TotalMoney:    &orderpb.Money{Amount: 3480, Currency: "CNY"},
+		DiscountMoney: &orderpb.Money{Amount: 500, Currency: "CNY"},
+	}, nil)
+	clients.cart.On("ClearCart", mock.Anything, mock.Anything).Return(&cartpb.ClearCartResponse{Success: true}, nil)
+	clients.product.On("ReserveStock", mock.Anything, mock.Anything).Return(&productpb.ReserveStockResponse{Success: true}, nil)
+	clients.payment.On("CreatePayment", mock.Anything, mock.MatchedBy(func(req *paymentpb.CreatePaymentRequest) bool {
+		return req.AmountMoney.Amount == 3480
+	})).Return(&paymentpb.CreatePaymentResponse{Success: true, PaymentId: "pay-1"}, nil)
+
+	resp, err := service.Checkout(ctx, &checkoutapi.CheckoutRequest{UserId: 1, CartId: 1, CouponCode: "SAVE5"})
+	require.NoError(t, err)
+	assert.True(t, resp.Success, resp.ErrorMessage)
+
+	getResp, err := service.GetCheckout(ctx, &checkoutapi.GetCheckoutRequest{CheckoutId: resp.CheckoutId})
+	require.NoError(t, err)
+	assert.Equal(t, "SAVE5", getResp.Checkout.CouponCode)
+	assert.Equal(t, 34.8, getResp.Checkout.TotalAmount)
+
+	clients.order.AssertExpectations(t)
+	clients.payment.AssertExpectations(t)
+}
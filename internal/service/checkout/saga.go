@@ -0,0 +1,194 @@
+package checkout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+// 结账流程状态
+const (
+	sagaStatusRunning      = "RUNNING"
+	sagaStatusCompensating = "COMPENSATING"
+	sagaStatusCompleted    = "COMPLETED"
+	sagaStatusFailed       = "FAILED"
+)
+
+// 结账步骤名称
+const (
+	stepLoadCart      = "load_cart"
+	stepCreateOrder   = "create_order"
+	stepClearCart     = "clear_cart"
+	stepReserveStock  = "reserve_stock"
+	stepCreatePayment = "create_payment"
+	stepDone          = "done"
+)
+
+// saga 是一次结账流程的持久化状态。
+// current_step 之前的步骤都已执行成功：执行中时 current_step 是下一个要执行的步骤，
+// 补偿中时则从 current_step 的前一个步骤开始倒序补偿
+type saga struct {
+	ID            int64
+	UserID        int32
+	CartID        int32
+	PaymentMethod string
+	CouponCode    string
+	Status        string
+	CurrentStep   string
+	Items         []*sagaItem
+	TotalAmount   money.Money
+	OrderID       int32
+	PaymentID     string
+	PaymentURL    string
+	ErrorMessage  string
+	Version       int
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// sagaItem 是结账时购物车商品的快照
+type sagaItem struct {
+	ProductID   int32  `json:"product_id"`
+	ProductName string `json:"product_name"`
+	PriceMinor  int64  `json:"price_minor"`
+	Currency    string `json:"currency"`
+	Quantity    int32  `json:"quantity"`
+}
+
+// price 返回商品的精确单价
+func (item *sagaItem) price() money.Money {
+	return money.New(item.PriceMinor, item.Currency)
+}
+
+// step 是结账流程中的一个步骤，action 和 compensate 都必须可以安全重试
+type step struct {
+	name       string
+	action     func(ctx context.Context, sg *saga) error
+	compensate func(ctx context.Context, sg *saga) error
+}
+
+// businessError 表示下游服务明确拒绝了请求，重试不会成功
+type businessError struct {
+	message string
+}
+
+func (e *businessError) Error() string {
+	return e.message
+}
+
+func newBusinessError(format string, args ...interface{}) error {
+	return &businessError{message: fmt.Sprintf(format, args...)}
+}
+
+// steps 返回结账流程的全部步骤。
+// 清空购物车放在预留库存之前，保证补偿时先释放库存再恢复购物车
+func (s *CheckoutService) steps() []step {
+	return []step{
+		{name: stepLoadCart, action: s.loadCart},
+		{name: stepCreateOrder, action: s.createOrder, compensate: s.cancelOrder},
+		{name: stepClearCart, action: s.clearCart, compensate: s.restoreCart},
+		{name: stepReserveStock, action: s.reserveStock, compensate: s.releaseStock},
+		{name: stepCreatePayment, action: s.createPayment},
+	}
+}
+
+func stepIndex(steps []step, name string) int {
+	if name == stepDone {
+		return len(steps)
+	}
+	for i, st := range steps {
+		if st.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// run 从持久化的状态继续推进结账流程，直到完成、补偿结束或出现需要稍后重试的错误
+func (s *CheckoutService) run(ctx context.Context, sg *saga) error {
+	steps := s.steps()
+	index := stepIndex(steps, sg.CurrentStep)
+	if index < 0 {
+		return fmt.Errorf("unknown checkout step %q", sg.CurrentStep)
+	}
+
+	if sg.Status == sagaStatusRunning {
+		for ; index < len(steps); index++ {
+			st := steps[index]
+
+			stepCtx, cancel := context.WithTimeout(ctx, s.stepTimeout)
+			err := st.action(stepCtx, sg)
+			cancel()
+			if err != nil {
+				var bizErr *businessError
+				if !errors.As(err, &bizErr) {
+					// 无法确认步骤结果时保持执行中状态，由恢复任务重试
+					log.Printf("Checkout %d step %s failed, will retry: %v", sg.ID, st.name, err)
+					return err
+				}
+
+				log.Printf("Checkout %d step %s rejected, compensating: %v", sg.ID, st.name, err)
+				sg.Status = sagaStatusCompensating
+				sg.ErrorMessage = bizErr.message
+				if err := s.store.save(ctx, sg); err != nil {
+					return err
+				}
+				break
+			}
+
+			// 记录步骤完成，崩溃恢复时从下一个步骤继续
+			if index+1 < len(steps) {
+				sg.CurrentStep = steps[index+1].name
+			} else {
+				sg.CurrentStep = stepDone
+				sg.Status = sagaStatusCompleted
+			}
+			if err := s.store.save(ctx, sg); err != nil {
+				return err
+			}
+		}
+	}
+
+	if sg.Status == sagaStatusCompensating {
+		return s.compensate(ctx, sg, steps, index)
+	}
+
+	return nil
+}
+
+// compensate 倒序撤销 index 之前已经完成的步骤，每完成一个补偿就持久化一次
+func (s *CheckoutService) compensate(ctx context.Context, sg *saga, steps []step, index int) error {
+	for i := index - 1; i >= 0; i-- {
+		st := steps[i]
+		if st.compensate != nil {
+			stepCtx, cancel := context.WithTimeout(ctx, s.stepTimeout)
+			err := st.compensate(stepCtx, sg)
+			cancel()
+			if err != nil {
+				log.Printf("Checkout %d compensation of step %s failed, will retry: %v", sg.ID, st.name, err)
+				return err
+			}
+		}
+
+		sg.CurrentStep = st.name
+		if i == 0 {
+			sg.Status = sagaStatusFailed
+		}
+		if err := s.store.save(ctx, sg); err != nil {
+			return err
+		}
+	}
+
+	if sg.Status != sagaStatusFailed {
+		sg.Status = sagaStatusFailed
+		if err := s.store.save(ctx, sg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package checkout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	cartpb "github.com/bytedance-youthcamp/demo/api/cart"
+	orderpb "github.com/bytedance-youthcamp/demo/api/order"
+	paymentpb "github.com/bytedance-youthcamp/demo/api/payment"
+	productpb "github.com/bytedance-youthcamp/demo/api/product"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+// loadCart 读取购物车并保存商品快照，后续步骤和补偿都基于快照执行
+func (s *CheckoutService) loadCart(ctx context.Context, sg *saga) error {
+	cartResp, err := s.cartClient.GetCart(ctx, &cartpb.GetCartRequest{CartId: sg.CartID})
+	if err != nil {
+		return fmt.Errorf("failed to get cart: %w", err)
+	}
+	if !cartResp.Success {
+		return newBusinessError("获取购物车失败: %s", cartResp.ErrorMessage)
+	}
+	if cartResp.Cart.UserId != sg.UserID {
+		return newBusinessError("无权访问该购物车")
+	}
+	if len(cartResp.Cart.Items) == 0 {
+		return newBusinessError("购物车为空")
+	}
+
+	items := make([]*sagaItem, len(cartResp.Cart.Items))
+	total := money.Zero(cartItemPrice(cartResp.Cart.Items[0]).Currency)
+	for i, item := range cartResp.Cart.Items {
+		price := cartItemPrice(item)
+		items[i] = &sagaItem{
+			ProductID:   item.ProductId,
+			ProductName: item.ProductName,
+			PriceMinor:  price.Amount,
+			Currency:    price.Currency,
+			Quantity:    item.Quantity,
+		}
+		total, err = total.Add(price.Mul(int64(item.Quantity)))
+		if errors.Is(err, money.ErrCurrencyMismatch) {
+			return newBusinessError("购物车中的商品币种不一致")
+		}
+	}
+
+	sg.Items = items
+	sg.TotalAmount = total
+	return nil
+}
+
+// createOrder 根据购物车快照创建待支付订单
+func (s *CheckoutService) createOrder(ctx context.Context, sg *saga) error {
+	if sg.OrderID != 0 {
+		return nil
+	}
+
+	items := make([]*orderpb.OrderItem, len(sg.Items))
+	for i, item := range sg.Items {
+		items[i] = &orderpb.OrderItem{
+			ProductId:   item.ProductID,
+			ProductName: item.ProductName,
+			Quantity:    item.Quantity,
+			Price:       item.price().Float64(),
+			PriceMoney:  &orderpb.Money{Amount: item.PriceMinor, Currency: item.Currency},
+		}
+	}
+
+	orderResp, err := s.orderClient.CreateOrder(ctx, &orderpb.CreateOrderRequest{
+		UserId:     sg.UserID,
+		Items:      items,
+		TotalPrice: sg.TotalAmount.Float64(),
+		TotalMoney: &orderpb.Money{Amount: sg.TotalAmount.Amount, Currency: sg.TotalAmount.Currency},
+		CouponCode: sg.CouponCode,
+		// 请求超时后重试时返回已创建的订单，避免留下无法补偿的订单
+		IdempotencyKey: fmt.Sprintf("checkout-order-%d", sg.ID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+	if !orderResp.Success {
+		return newBusinessError("创建订单失败: %s", orderResp.ErrorMessage)
+	}
+
+	sg.OrderID = orderResp.OrderId
+	// 使用优惠券时按订单的应付金额支付
+	if orderResp.TotalMoney != nil {
+		sg.TotalAmount = money.New(orderResp.TotalMoney.Amount, orderResp.TotalMoney.Currency)
+	}
+	return nil
+}
+
+// cancelOrder 取消结账创建的订单，订单已取消时视为补偿成功
+func (s *CheckoutService) cancelOrder(ctx context.Context, sg *saga) error {
+	if sg.OrderID == 0 {
+		return nil
+	}
+
+	cancelResp, err := s.orderClient.CancelOrder(ctx, &orderpb.CancelOrderRequest{
+		OrderId:      sg.OrderID,
+		CancelReason: "结账失败: " + sg.ErrorMessage,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cancel order: %w", err)
+	}
+	if cancelResp.Success {
+		return nil
+	}
+
+	// 重试时订单可能已经被取消
+	orderResp, err := s.orderClient.GetOrder(ctx, &orderpb.GetOrderRequest{OrderId: sg.OrderID})
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	if orderResp.Success && orderResp.Order.Status == orderpb.OrderStatus_CANCELLED {
+		return nil
+	}
+
+	return fmt.Errorf("failed to cancel order %d: %s", sg.OrderID, cancelResp.ErrorMessage)
+}
+
+// clearCart 清空购物车，重复清空没有副作用
+func (s *CheckoutService) clearCart(ctx context.Context, sg *saga) error {
+	clearResp, err := s.cartClient.ClearCart(ctx, &cartpb.ClearCartRequest{CartId: sg.CartID})
+	if err != nil {
+		return fmt.Errorf("failed to clear cart: %w", err)
+	}
+	if !clearResp.Success {
+		return newBusinessError("清空购物车失败: %s", clearResp.ErrorMessage)
+	}
+	return nil
+}
+
+// restoreCart 将快照中的商品加回购物车，只补足当前购物车中缺少的数量，保证重试不会重复添加
+func (s *CheckoutService) restoreCart(ctx context.Context, sg *saga) error {
+	cartResp, err := s.cartClient.GetCart(ctx, &cartpb.GetCartRequest{CartId: sg.CartID})
+	if err != nil {
+		return fmt.Errorf("failed to get cart: %w", err)
+	}
+	if !cartResp.Success {
+		// 购物车已不存在，无需恢复
+		log.Printf("Checkout %d skipped cart restore: %s", sg.ID, cartResp.ErrorMessage)
+		return nil
+	}
+
+	current := make(map[int32]int32)
+	for _, item := range cartResp.Cart.Items {
+		current[item.ProductId] += item.Quantity
+	}
+
+	for _, item := range sg.Items {
+		missing := item.Quantity - current[item.ProductID]
+		if missing <= 0 {
+			continue
+		}
+
+		addResp, err := s.cartClient.AddToCart(ctx, &cartpb.AddToCartRequest{
+			CartId:    sg.CartID,
+			ProductId: item.ProductID,
+			Quantity:  missing,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore cart item: %w", err)
+		}
+		if !addResp.Success {
+			// 商品已下架或库存不足时无法恢复，跳过该商品
+			log.Printf("Checkout %d skipped restoring product %d: %s", sg.ID, item.ProductID, addResp.ErrorMessage)
+		}
+	}
+
+	return nil
+}
+
+// reserveStock 按订单预留库存，订单ID保证重试不会重复预留
+func (s *CheckoutService) reserveStock(ctx context.Context, sg *saga) error {
+	items := make([]*productpb.StockItem, len(sg.Items))
+	for i, item := range sg.Items {
+		items[i] = &productpb.StockItem{
+			ProductId: item.ProductID,
+			Quantity:  item.Quantity,
+		}
+	}
+
+	reserveResp, err := s.productClient.ReserveStock(ctx, &productpb.ReserveStockRequest{
+		OrderId: sg.OrderID,
+		Items:   items,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reserve stock: %w", err)
+	}
+	if !reserveResp.Success {
+		return newBusinessError("预留库存失败: %s", reserveResp.ErrorMessage)
+	}
+	return nil
+}
+
+// releaseStock 释放订单预留的库存
+func (s *CheckoutService) releaseStock(ctx context.Context, sg *saga) error {
+	releaseResp, err := s.productClient.ReleaseStock(ctx, &productpb.ReleaseStockRequest{OrderId: sg.OrderID})
+	if err != nil {
+		return fmt.Errorf("failed to release stock: %w", err)
+	}
+	if !releaseResp.Success {
+		return fmt.Errorf("failed to release stock for order %d: %s", sg.OrderID, releaseResp.ErrorMessage)
+	}
+	return nil
+}
+
+// createPayment 为订单发起支付
+func (s *CheckoutService) createPayment(ctx context.Context, sg *saga) error {
+	if sg.PaymentID != "" {
+		return nil
+	}
+
+	method, ok := parsePaymentMethod(sg.PaymentMethod)
+	if !ok {
+		return newBusinessError("不支持的支付方式: %s", sg.PaymentMethod)
+	}
+
+	paymentResp, err := s.paymentClient.CreatePayment(ctx, &paymentpb.CreatePaymentRequest{
+		OrderId:     sg.OrderID,
+		Amount:      sg.TotalAmount.Float64(),
+		AmountMoney: &paymentpb.Money{Amount: sg.TotalAmount.Amount, Currency: sg.TotalAmount.Currency},
+		Method:      method,
+		// 重试该步骤时支付服务返回同一笔支付
+		IdempotencyKey: fmt.Sprintf("checkout-%d", sg.ID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create payment: %w", err)
+	}
+	if !paymentResp.Success {
+		return newBusinessError("创建支付失败: %s", paymentResp.ErrorMessage)
+	}
+
+	sg.PaymentID = paymentResp.PaymentId
+	sg.PaymentURL = paymentResp.PaymentUrl
+	return nil
+}
+
+// parsePaymentMethod 将 ALIPAY、WECHAT、CREDIT_CARD 转换为支付服务的枚举，空值默认为支付宝
+func parsePaymentMethod(method string) (paymentpb.PaymentMethod, bool) {
+	if method == "" {
+		return paymentpb.PaymentMethod_PAYMENT_METHOD_ALIPAY, true
+	}
+	value, ok := paymentpb.PaymentMethod_value["PAYMENT_METHOD_"+strings.ToUpper(method)]
+	return paymentpb.PaymentMethod(value), ok
+}
+
+// cartItemPrice 返回购物车商品的精确单价，购物车服务未返回精确金额时转换浮点单价
+func cartItemPrice(item *cartpb.CartItem) money.Money {
+	if item.PriceMoney != nil {
+		return money.New(item.PriceMoney.Amount, item.PriceMoney.Currency)
+	}
+	return money.FromFloat(item.Price, money.DefaultCurrency)
+}
@@ -0,0 +1,176 @@
+package checkout
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+// errSagaNotFound 表示结账记录不存在
+var errSagaNotFound = errors.New("checkout not found")
+
+// errSagaConflict 表示结账记录已被其他执行者推进，当前执行者应放弃
+var errSagaConflict = errors.New("checkout was updated concurrently")
+
+// sagaStore 负责结账流程状态的持久化，使用 version 字段做乐观锁，
+// 保证同一个结账流程同时只有一个执行者在推进
+type sagaStore struct {
+	db *sql.DB
+}
+
+func (st *sagaStore) create(ctx context.Context, sg *saga) error {
+	items, err := json.Marshal(sg.Items)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkout items: %w", err)
+	}
+
+	now := time.Now()
+	result, err := st.db.ExecContext(ctx, `
+		INSERT INTO checkout_sagas (user_id, cart_id, payment_method, coupon_code, status, current_step, items,
+			total_amount_minor, currency, order_id, payment_id, payment_url, error_message, version, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?)
+	`, sg.UserID, sg.CartID, sg.PaymentMethod, sg.CouponCode, sg.Status, sg.CurrentStep, string(items),
+		sg.TotalAmount.Amount, sg.TotalAmount.Currency, sg.OrderID, sg.PaymentID, sg.PaymentURL, sg.ErrorMessage, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create checkout: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get checkout id: %w", err)
+	}
+
+	sg.ID = id
+	sg.Version = 0
+	sg.CreatedAt = now
+	sg.UpdatedAt = now
+	return nil
+}
+
+// save 持久化结账状态，记录已被其他执行者修改时返回 errSagaConflict
+func (st *sagaStore) save(ctx context.Context, sg *saga) error {
+	items, err := json.Marshal(sg.Items)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkout items: %w", err)
+	}
+
+	now := time.Now()
+	result, err := st.db.ExecContext(ctx, `
+		UPDATE checkout_sagas
+		SET status = ?, current_step = ?, items = ?, total_amount_minor = ?, currency = ?, order_id = ?,
+			payment_id = ?, payment_url = ?, error_message = ?, version = version + 1, updated_at = ?
+		WHERE id = ? AND version = ?
+	`, sg.Status, sg.CurrentStep, string(items), sg.TotalAmount.Amount, sg.TotalAmount.Currency, sg.OrderID,
+		sg.PaymentID, sg.PaymentURL, sg.ErrorMessage, now, sg.ID, sg.Version)
+	if err != nil {
+		return fmt.Errorf("failed to save checkout: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return errSagaConflict
+	}
+
+	sg.Version++
+	sg.UpdatedAt = now
+	return nil
+}
+
+func (st *sagaStore) get(ctx context.Context, id int64) (*saga, error) {
+	row := st.db.QueryRowContext(ctx, `
+		SELECT id, user_id, cart_id, payment_method, coupon_code, status, current_step, items, total_amount_minor, currency,
+			order_id, payment_id, payment_url, error_message, version, created_at, updated_at
+		FROM checkout_sagas
+		WHERE id = ?
+	`, id)
+
+	sg, err := scanSaga(row)
+	if err == sql.ErrNoRows {
+		return nil, errSagaNotFound
+	}
+	return sg, err
+}
+
+// listStale 查询在 before 之前就停止推进的未完成结账，供恢复任务继续执行
+func (st *sagaStore) listStale(ctx context.Context, before time.Time, limit int) ([]*saga, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT id, user_id, cart_id, payment_method, coupon_code, status, current_step, items, total_amount_minor, currency,
+			order_id, payment_id, payment_url, error_message, version, created_at, updated_at
+		FROM checkout_sagas
+		WHERE status IN (?, ?) AND updated_at < ?
+		ORDER BY id
+		LIMIT ?
+	`, sagaStatusRunning, sagaStatusCompensating, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unfinished checkouts: %w", err)
+	}
+	defer rows.Close()
+
+	var sagas []*saga
+	for rows.Next() {
+		sg, err := scanSaga(rows)
+		if err != nil {
+			return nil, err
+		}
+		sagas = append(sagas, sg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating checkouts: %w", err)
+	}
+
+	return sagas, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSaga(row rowScanner) (*saga, error) {
+	var sg saga
+	var items, errorMessage sql.NullString
+	var totalAmount int64
+	var currency string
+	err := row.Scan(
+		&sg.ID,
+		&sg.UserID,
+		&sg.CartID,
+		&sg.PaymentMethod,
+		&sg.CouponCode,
+		&sg.Status,
+		&sg.CurrentStep,
+		&items,
+		&totalAmount,
+		&currency,
+		&sg.OrderID,
+		&sg.PaymentID,
+		&sg.PaymentURL,
+		&errorMessage,
+		&sg.Version,
+		&sg.CreatedAt,
+		&sg.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan checkout: %w", err)
+	}
+
+	if items.Valid && items.String != "" {
+		if err := json.Unmarshal([]byte(items.String), &sg.Items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal checkout items: %w", err)
+		}
+	}
+	sg.TotalAmount = money.New(totalAmount, currency)
+	sg.ErrorMessage = errorMessage.String
+
+	return &sg, nil
+}
@@ -0,0 +1,32 @@
+package order
+
+import (
+	context "context"
+
+	authpb "github.com/bytedance-youthcamp/demo/api/auth"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+)
+
+// MockAuthClient is a mock implementation of the authpb.AuthServiceClient interface
+type MockAuthClient struct {
+	mock.Mock
+}
+
+// DeliverTokenByRPC mocks the DeliverTokenByRPC method
+func (m *MockAuthClient) DeliverTokenByRPC(ctx context.Context, in *authpb.DeliverTokenReq, opts ...grpc.CallOption) (*authpb.DeliveryResp, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*authpb.DeliveryResp), args.Error(1)
+}
+
+// VerifyTokenByRPC mocks the VerifyTokenByRPC method
+func (m *MockAuthClient) VerifyTokenByRPC(ctx context.Context, in *authpb.VerifyTokenReq, opts ...grpc.CallOption) (*authpb.VerifyResp, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*authpb.VerifyResp), args.Error(1)
+}
+
+// RenewTokenByRPC mocks the RenewTokenByRPC method
+func (m *MockAuthClient) RenewTokenByRPC(ctx context.Context, in *authpb.RenewTokenReq, opts ...grpc.CallOption) (*authpb.RenewTokenResp, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*authpb.RenewTokenResp), args.Error(1)
+}
@@ -0,0 +1,50 @@
+package order
+
+import (
+	context "context"
+
+	cartpb "github.com/bytedance-youthcamp/demo/api/cart"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+)
+
+// MockCartClient is a mock implementation of the cartpb.CartServiceClient interface
+type MockCartClient struct {
+	mock.Mock
+}
+
+// CreateCart mocks the CreateCart method
+func (m *MockCartClient) CreateCart(ctx context.Context, in *cartpb.CreateCartRequest, opts ...grpc.CallOption) (*cartpb.CreateCartResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*cartpb.CreateCartResponse), args.Error(1)
+}
+
+// ClearCart mocks the ClearCart method
+func (m *MockCartClient) ClearCart(ctx context.Context, in *cartpb.ClearCartRequest, opts ...grpc.CallOption) (*cartpb.ClearCartResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*cartpb.ClearCartResponse), args.Error(1)
+}
+
+// GetCart mocks the GetCart method
+func (m *MockCartClient) GetCart(ctx context.Context, in *cartpb.GetCartRequest, opts ...grpc.CallOption) (*cartpb.GetCartResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*cartpb.GetCartResponse), args.Error(1)
+}
+
+// AddToCart mocks the AddToCart method
+func (m *MockCartClient) AddToCart(ctx context.Context, in *cartpb.AddToCartRequest, opts ...grpc.CallOption) (*cartpb.AddToCartResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*cartpb.AddToCartResponse), args.Error(1)
+}
+
+// RemoveFromCart mocks the RemoveFromCart method
+func (m *MockCartClient) RemoveFromCart(ctx context.Context, in *cartpb.RemoveFromCartRequest, opts ...grpc.CallOption) (*cartpb.RemoveFromCartResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*cartpb.RemoveFromCartResponse), args.Error(1)
+}
+
+// UpdateCartItem mocks the UpdateCartItem method
+func (m *MockCartClient) UpdateCartItem(ctx context.Context, in *cartpb.UpdateCartItemRequest, opts ...grpc.CallOption) (*cartpb.UpdateCartItemResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*cartpb.UpdateCartItemResponse), args.Error(1)
+}
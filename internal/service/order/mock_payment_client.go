@@ -0,0 +1,56 @@
+package order
+
+import (
+	context "context"
+
+	paymentpb "github.com/bytedance-youthcamp/demo/api/payment"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+)
+
+// MockPaymentClient is a mock implementation of the paymentpb.PaymentServiceClient interface
+type MockPaymentClient struct {
+	mock.Mock
+}
+
+// CreatePayment mocks the CreatePayment method
+func (m *MockPaymentClient) CreatePayment(ctx context.Context, in *paymentpb.CreatePaymentRequest, opts ...grpc.CallOption) (*paymentpb.CreatePaymentResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*paymentpb.CreatePaymentResponse), args.Error(1)
+}
+
+// QueryPayment mocks the QueryPayment method
+func (m *MockPaymentClient) QueryPayment(ctx context.Context, in *paymentpb.QueryPaymentRequest, opts ...grpc.CallOption) (*paymentpb.QueryPaymentResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*paymentpb.QueryPaymentResponse), args.Error(1)
+}
+
+// ProcessPaymentNotification mocks the ProcessPaymentNotification method
+func (m *MockPaymentClient) ProcessPaymentNotification(ctx context.Context, in *paymentpb.PaymentNotificationRequest, opts ...grpc.CallOption) (*paymentpb.PaymentNotificationResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*paymentpb.PaymentNotificationResponse), args.Error(1)
+}
+
+// SimulatePaymentCallback mocks the SimulatePaymentCallback method
+func (m *MockPaymentClient) SimulatePaymentCallback(ctx context.Context, in *paymentpb.SimulatePaymentCallbackRequest, opts ...grpc.CallOption) (*paymentpb.SimulatePaymentCallbackResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*paymentpb.SimulatePaymentCallbackResponse), args.Error(1)
+}
+
+// RequestRefund mocks the RequestRefund method
+func (m *MockPaymentClient) RequestRefund(ctx context.Context, in *paymentpb.RequestRefundRequest, opts ...grpc.CallOption) (*paymentpb.RequestRefundResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*paymentpb.RequestRefundResponse), args.Error(1)
+}
+
+// ProcessRefundNotification mocks the ProcessRefundNotification method
+func (m *MockPaymentClient) ProcessRefundNotification(ctx context.Context, in *paymentpb.RefundNotificationRequest, opts ...grpc.CallOption) (*paymentpb.RefundNotificationResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*paymentpb.RefundNotificationResponse), args.Error(1)
+}
+
+// CancelOrderPayment mocks the CancelOrderPayment method
+func (m *MockPaymentClient) CancelOrderPayment(ctx context.Context, in *paymentpb.CancelOrderPaymentRequest, opts ...grpc.CallOption) (*paymentpb.CancelOrderPaymentResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*paymentpb.CancelOrderPaymentResponse), args.Error(1)
+}
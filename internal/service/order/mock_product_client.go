@@ -43,5 +43,38 @@ func (m *MockProductClient) DeleteProduct(ctx context.Context, in *productpb.Del
 	return args.Get(0).(*productpb.DeleteProductResponse), args.Error(1)
 }
 
-// We don't need ReduceStock as it's not in the ProductServiceClient interface
-// Instead, we'll use UpdateProduct to handle stock reduction
\ No newline at end of file
+// ReduceStock mocks the ReduceStock method
+func (m *MockProductClient) ReduceStock(ctx context.Context, in *productpb.ReduceStockRequest, opts ...grpc.CallOption) (*productpb.ReduceStockResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*productpb.ReduceStockResponse), args.Error(1)
+}
+
+// ReserveStock mocks the ReserveStock method
+func (m *MockProductClient) ReserveStock(ctx context.Context, in *productpb.ReserveStockRequest, opts ...grpc.CallOption) (*productpb.ReserveStockResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*productpb.ReserveStockResponse), args.Error(1)
+}
+
+// ReleaseStock mocks the ReleaseStock method
+func (m *MockProductClient) ReleaseStock(ctx context.Context, in *productpb.ReleaseStockRequest, opts ...grpc.CallOption) (*productpb.ReleaseStockResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*productpb.ReleaseStockResponse), args.Error(1)
+}
+
+// CommitReservation mocks the CommitReservation method
+func (m *MockProductClient) CommitReservation(ctx context.Context, in *productpb.CommitReservationRequest, opts ...grpc.CallOption) (*productpb.CommitReservationResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*productpb.CommitReservationResponse), args.Error(1)
+}
+
+// RestoreStock mocks the RestoreStock method
+func (m *MockProductClient) RestoreStock(ctx context.Context, in *productpb.RestoreStockRequest, opts ...grpc.CallOption) (*productpb.RestoreStockResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*productpb.RestoreStockResponse), args.Error(1)
+}
+
+// AdjustReservation mocks the AdjustReservation method
+func (m *MockProductClient) AdjustReservation(ctx context.Context, in *productpb.AdjustReservationRequest, opts ...grpc.CallOption) (*productpb.AdjustReservationResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*productpb.AdjustReservationResponse), args.Error(1)
+}
@@ -0,0 +1,44 @@
+package order
+
+import (
+	context "context"
+
+	promotionpb "github.com/bytedance-youthcamp/demo/api/promotion"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+)
+
+// MockPromotionClient is a mock implementation of the promotionpb.PromotionServiceClient interface
+type MockPromotionClient struct {
+	mock.Mock
+}
+
+// CreateCoupon mocks the CreateCoupon method
+func (m *MockPromotionClient) CreateCoupon(ctx context.Context, in *promotionpb.CreateCouponRequest, opts ...grpc.CallOption) (*promotionpb.CreateCouponResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*promotionpb.CreateCouponResponse), args.Error(1)
+}
+
+// GetCoupon mocks the GetCoupon method
+func (m *MockPromotionClient) GetCoupon(ctx context.Context, in *promotionpb.GetCouponRequest, opts ...grpc.CallOption) (*promotionpb.GetCouponResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*promotionpb.GetCouponResponse), args.Error(1)
+}
+
+// PriceCart mocks the PriceCart method
+func (m *MockPromotionClient) PriceCart(ctx context.Context, in *promotionpb.PriceCartRequest, opts ...grpc.CallOption) (*promotionpb.PriceCartResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*promotionpb.PriceCartResponse), args.Error(1)
+}
+
+// ApplyCoupon mocks the ApplyCoupon method
+func (m *MockPromotionClient) ApplyCoupon(ctx context.Context, in *promotionpb.ApplyCouponRequest, opts ...grpc.CallOption) (*promotionpb.ApplyCouponResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*promotionpb.ApplyCouponResponse), args.Error(1)
+}
+
+// ReleaseCoupon mocks the ReleaseCoupon method
+func (m *MockPromotionClient) ReleaseCoupon(ctx context.Context, in *promotionpb.ReleaseCouponRequest, opts ...grpc.CallOption) (*promotionpb.ReleaseCouponResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*promotionpb.ReleaseCouponResponse), args.Error(1)
+}
@@ -59,4 +59,10 @@ func (m *MockUserClient) Logout(ctx context.Context, in *userpb.LogoutRequest, o
 func (m *MockUserClient) DeleteUser(ctx context.Context, in *userpb.DeleteUserRequest, opts ...grpc.CallOption) (*userpb.DeleteUserResponse, error) {
 	args := m.Called(ctx, in, opts)
 	return args.Get(0).(*userpb.DeleteUserResponse), args.Error(1)
+}
+
+// CheckPermission mocks the CheckPermission method
+func (m *MockUserClient) CheckPermission(ctx context.Context, in *userpb.CheckPermissionRequest, opts ...grpc.CallOption) (*userpb.CheckPermissionResponse, error) {
+	args := m.Called(ctx, in, opts)
+	return args.Get(0).(*userpb.CheckPermissionResponse), args.Error(1)
 }
\ No newline at end of file
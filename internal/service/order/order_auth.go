@@ -0,0 +1,197 @@
+package order
+
+import (
+	"context"
+	"crypto/subtle"
+	"strconv"
+	"strings"
+
+	authpb "github.com/bytedance-youthcamp/demo/api/auth"
+	userpb "github.com/bytedance-youthcamp/demo/api/user"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// authorizationHeader 携带用户令牌，格式为 "Bearer <token>"
+	authorizationHeader = "authorization"
+	// serviceTokenHeader 携带内部服务的服务令牌
+	serviceTokenHeader = "x-service-token"
+
+	// MinServiceTokenLength 服务令牌的最小长度，过短的令牌可以被猜测并用于冒充内部服务
+	MinServiceTokenLength = 32
+
+	permissionResource = "order"
+	permissionRead     = "read"
+	permissionWrite    = "write"
+)
+
+// errAuthNotConfigured 表示未启用鉴权，拦截器不允许未经识别的调用方访问订单
+var errAuthNotConfigured = status.Error(codes.Unauthenticated, "authentication is not configured")
+
+// Caller 是通过鉴权的调用方，用户和内部服务二者之一
+type Caller struct {
+	UserID  int32
+	Service string
+}
+
+type callerKey struct{}
+
+// ContextWithCaller 返回携带调用方的 context
+func ContextWithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext 返回 context 中的调用方
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
+
+// WithAuthClient 启用鉴权，用户令牌由认证服务校验。未配置时鉴权拦截器拒绝所有请求，
+// 业务方法不检查调用方，仅用于在进程内直接调用服务的测试
+func WithAuthClient(client authpb.AuthServiceClient) Option {
+	return func(s *orderService) {
+		s.authClient = client
+	}
+}
+
+// WithServiceTokens 设置内部服务的服务令牌，键为服务名。
+// 使用服务令牌的调用方可以访问所有订单，如支付服务结算订单、结账服务代用户下单
+func WithServiceTokens(tokens map[string]string) Option {
+	return func(s *orderService) {
+		s.serviceTokens = tokens
+	}
+}
+
+// UnaryAuthInterceptor 从请求元数据中识别调用方并放入 context，无法识别时返回 Unauthenticated
+func (s *orderService) UnaryAuthInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if s.authClient == nil {
+			return nil, errAuthNotConfigured
+		}
+
+		caller, err := s.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ContextWithCaller(ctx, caller), req)
+	}
+}
+
+// StreamAuthInterceptor 是流式 RPC 的鉴权拦截器，与 UnaryAuthInterceptor 使用相同的方式识别调用方
+func (s *orderService) StreamAuthInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if s.authClient == nil {
+			return errAuthNotConfigured
+		}
+
+		caller, err := s.authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &callerStream{ServerStream: ss, ctx: ContextWithCaller(ss.Context(), caller)})
+	}
+}
+
+// callerStream 替换流的 context，使处理函数可以读取调用方
+type callerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *callerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *orderService) authenticate(ctx context.Context) (Caller, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if values := md.Get(serviceTokenHeader); len(values) > 0 {
+		for name, token := range s.serviceTokens {
+			if token != "" && subtle.ConstantTimeCompare([]byte(values[0]), []byte(token)) == 1 {
+				return Caller{Service: name}, nil
+			}
+		}
+		return Caller{}, status.Error(codes.Unauthenticated, "invalid service token")
+	}
+
+	values := md.Get(authorizationHeader)
+	if len(values) == 0 {
+		return Caller{}, status.Error(codes.Unauthenticated, "missing authorization token")
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(values[0], "Bearer "))
+
+	resp, err := s.authClient.VerifyTokenByRPC(ctx, &authpb.VerifyTokenReq{Token: token})
+	if err != nil {
+		return Caller{}, status.Error(codes.Unavailable, "failed to verify token")
+	}
+	if !resp.Res || resp.Expired || resp.UserId <= 0 {
+		return Caller{}, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+
+	return Caller{UserID: resp.UserId}, nil
+}
+
+// authorizeOwner 允许订单所有者、拥有订单 action 权限的用户和内部服务访问，ownerID 为订单所属用户
+func (s *orderService) authorizeOwner(ctx context.Context, ownerID string, action string) error {
+	if s.authClient == nil {
+		return nil
+	}
+
+	caller, ok := CallerFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	if caller.Service != "" {
+		return nil
+	}
+	if ownerID != "" && ownerID == strconv.Itoa(int(caller.UserID)) {
+		return nil
+	}
+	return s.checkPermission(ctx, caller, action)
+}
+
+// authorizeAction 只允许拥有订单 action 权限的用户和内部服务访问
+func (s *orderService) authorizeAction(ctx context.Context, action string) error {
+	return s.authorizeOwner(ctx, "", action)
+}
+
+func (s *orderService) checkPermission(ctx context.Context, caller Caller, action string) error {
+	if s.userClient == nil {
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+
+	resp, err := s.userClient.CheckPermission(ctx, &userpb.CheckPermissionRequest{
+		UserId:   caller.UserID,
+		Resource: permissionResource,
+		Action:   action,
+	})
+	if err != nil {
+		return status.Error(codes.Unavailable, "failed to check permission")
+	}
+	if !resp.Success || !resp.Allowed {
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+	return nil
+}
+
+// ownerOf 将请求中的用户 ID 转换为订单所属用户，0 表示未指定用户
+func ownerOf(userID int32) string {
+	if userID == 0 {
+		return ""
+	}
+	return strconv.Itoa(int(userID))
+}
+
+// ServiceTokenInterceptor 为内部服务调用订单服务的请求附加服务令牌
+func ServiceTokenInterceptor(token string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, serviceTokenHeader, token)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,157 @@
+package order
+
+import (
+	"context"
+	"testing"
+
+	authpb "github.com/bytedance-youthcamp/demo/api/auth"
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	userpb "github.com/bytedance-youthcamp/demo/api/user"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// singleOrderRepository 只返回一个固定订单，用于不依赖数据库的测试
+type singleOrderRepository struct {
+	repository.OrderRepository
+	order *repository.Order
+}
+
+func (r *singleOrderRepository) Get(ctx context.Context, orderID string) (*repository.Order, error) {
+	return r.order, nil
+}
+
+func newAuthTestService() (*orderService, *MockAuthClient, *MockUserClient) {
+	authClient := new(MockAuthClient)
+	authClient.On("VerifyTokenByRPC", mock.Anything, &authpb.VerifyTokenReq{Token: "alice"}, mock.Anything).
+		Return(&authpb.VerifyResp{Res: true, UserId: 1}, nil)
+	authClient.On("VerifyTokenByRPC", mock.Anything, &authpb.VerifyTokenReq{Token: "bob"}, mock.Anything).
+		Return(&authpb.VerifyResp{Res: true, UserId: 2}, nil)
+	authClient.On("VerifyTokenByRPC", mock.Anything, &authpb.VerifyTokenReq{Token: "admin"}, mock.Anything).
+		Return(&authpb.VerifyResp{Res: true, UserId: 99}, nil)
+	authClient.On("VerifyTokenByRPC", mock.Anything, &authpb.VerifyTokenReq{Token: "expired"}, mock.Anything).
+		Return(&authpb.VerifyResp{Res: true, UserId: 1, Expired: true}, nil)
+
+	userClient := new(MockUserClient)
+	userClient.On("CheckPermission", mock.Anything, mock.MatchedBy(func(req *userpb.CheckPermissionRequest) bool {
+		return req.UserId == 99
+	}), mock.Anything).Return(&userpb.CheckPermissionResponse{Success: true, Allowed: true}, nil)
+	userClient.On("CheckPermission", mock.Anything, mock.Anything, mock.Anything).
+		Return(&userpb.CheckPermissionResponse{Success: true, Allowed: false}, nil)
+
+	service := &orderService{
+		authClient:    authClient,
+		userClient:    userClient,
+		serviceTokens: map[string]string{"payment": "payment-secret"},
+		orderRepo: &singleOrderRepository{order: &repository.Order{
+			ID:     "1",
+			UserID: "1",
+			Status: repository.OrderStatusPending,
+		}},
+	}
+	return service, authClient, userClient
+}
+
+// callWithMetadata 以 gRPC 服务端的方式调用 handler，请求先经过鉴权拦截器
+func callWithMetadata(service *orderService, md metadata.MD, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	return service.UnaryAuthInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+}
+
+func TestUnaryAuthInterceptor(t *testing.T) {
+	service, _, _ := newAuthTestService()
+	echoCaller := func(ctx context.Context, req interface{}) (interface{}, error) {
+		caller, _ := CallerFromContext(ctx)
+		return caller, nil
+	}
+
+	t.Run("identifies users by token", func(t *testing.T) {
+		caller, err := callWithMetadata(service, metadata.Pairs(authorizationHeader, "Bearer alice"), echoCaller)
+		require.NoError(t, err)
+		assert.Equal(t, Caller{UserID: 1}, caller)
+	})
+
+	t.Run("identifies internal services by service token", func(t *testing.T) {
+		caller, err := callWithMetadata(service, metadata.Pairs(serviceTokenHeader, "payment-secret"), echoCaller)
+		require.NoError(t, err)
+		assert.Equal(t, Caller{Service: "payment"}, caller)
+	})
+
+	t.Run("rejects missing, expired and unknown credentials", func(t *testing.T) {
+		for _, md := range []metadata.MD{
+			metadata.MD{},
+			metadata.Pairs(authorizationHeader, "Bearer expired"),
+			metadata.Pairs(serviceTokenHeader, "guess"),
+		} {
+			_, err := callWithMetadata(service, md, echoCaller)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		}
+	})
+
+	t.Run("rejects all requests when auth is not configured", func(t *testing.T) {
+		unconfigured := &orderService{serviceTokens: map[string]string{"payment": "payment-secret"}}
+		_, err := callWithMetadata(unconfigured, metadata.Pairs(serviceTokenHeader, "payment-secret"), echoCaller)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestOrderOwnership(t *testing.T) {
+	service, _, _ := newAuthTestService()
+	getOrder := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return service.GetOrder(ctx, &orderapi.GetOrderRequest{OrderId: 1})
+	}
+	cancelOrder := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return service.CancelOrder(ctx, &orderapi.CancelOrderRequest{OrderId: 1})
+	}
+
+	t.Run("owner can read the order", func(t *testing.T) {
+		resp, err := callWithMetadata(service, metadata.Pairs(authorizationHeader, "Bearer alice"), getOrder)
+		require.NoError(t, err)
+		assert.True(t, resp.(*orderapi.GetOrderResponse).Success)
+	})
+
+	t.Run("other users are denied", func(t *testing.T) {
+		_, err := callWithMetadata(service, metadata.Pairs(authorizationHeader, "Bearer bob"), getOrder)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = callWithMetadata(service, metadata.Pairs(authorizationHeader, "Bearer bob"), cancelOrder)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("admins with order permission are allowed", func(t *testing.T) {
+		resp, err := callWithMetadata(service, metadata.Pairs(authorizationHeader, "Bearer admin"), getOrder)
+		require.NoError(t, err)
+		assert.True(t, resp.(*orderapi.GetOrderResponse).Success)
+	})
+
+	t.Run("admins can read order details of other users", func(t *testing.T) {
+		resp, err := callWithMetadata(service, metadata.Pairs(authorizationHeader, "Bearer admin"),
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return service.GetOrderDetails(ctx, &orderapi.GetOrderDetailsRequest{OrderId: 1, UserId: 99})
+			})
+		require.NoError(t, err)
+		assert.True(t, resp.(*orderapi.GetOrderDetailsResponse).Success)
+	})
+
+	t.Run("owners cannot settle their own orders", func(t *testing.T) {
+		_, err := callWithMetadata(service, metadata.Pairs(authorizationHeader, "Bearer alice"),
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return service.SettleOrder(ctx, &orderapi.SettleOrderRequest{OrderId: 1, UserId: 1})
+			})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("users can only search their own orders", func(t *testing.T) {
+		_, err := callWithMetadata(service, metadata.Pairs(authorizationHeader, "Bearer alice"),
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return service.SearchOrders(ctx, &orderapi.SearchOrdersRequest{})
+			})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
@@ -0,0 +1,120 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	productpb "github.com/bytedance-youthcamp/demo/api/product"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+)
+
+const (
+	// autoCancelCheckInterval 检查超时未支付订单的间隔
+	autoCancelCheckInterval = time.Minute
+	// autoCancelBatchSize 每个事务最多取消的订单数
+	autoCancelBatchSize = 100
+	// autoCancelReason 超时取消订单的原因
+	autoCancelReason = "payment timeout"
+)
+
+// WithAutoCancelTimeout 设置订单创建后等待支付的时间，只影响之后创建的订单
+func WithAutoCancelTimeout(timeout time.Duration) Option {
+	return func(s *orderService) {
+		if timeout > 0 {
+			s.autoCancelTimeout = timeout
+		}
+	}
+}
+
+// StartOrderCancellationTask 定时取消已到自动取消时间仍未支付的订单。
+// 订单的取消时间保存在 auto_cancel_at 中，服务重启后不会丢失；
+// 多个实例同时运行时通过 FOR UPDATE SKIP LOCKED 保证每个订单只被一个实例处理
+func (s *orderService) StartOrderCancellationTask(interval time.Duration) {
+	if s.orderRepo == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if _, err := s.cancelExpiredOrders(context.Background(), time.Now()); err != nil {
+				log.Printf("Error cancelling expired orders: %v", err)
+			}
+		}
+	}()
+}
+
+// cancelExpiredOrders 分批取消到期未支付的订单，提交后再释放预留库存和优惠券，返回取消的订单数。
+// 取消订单的事务中不调用其他服务，释放失败的订单保留自动取消时间，下次检查时重试
+func (s *orderService) cancelExpiredOrders(ctx context.Context, now time.Time) (int, error) {
+	total := 0
+	for {
+		cancelled, err := s.orderRepo.CancelExpiredOrders(ctx, now, autoCancelBatchSize, actorSystem, autoCancelReason)
+		if err != nil {
+			return total, err
+		}
+
+		for _, order := range cancelled {
+			log.Printf("Auto-cancelled order %s due to payment timeout", order.ID)
+		}
+		total += len(cancelled)
+
+		// 本批未取满时说明已没有可取消的订单
+		if len(cancelled) < autoCancelBatchSize {
+			break
+		}
+	}
+
+	return total, s.releaseCancelledOrders(ctx)
+}
+
+// releaseCancelledOrders 按订单 ID 分批释放已取消但尚未释放库存和优惠券的订单。
+// 释放失败的订单留到下一轮重试，不影响同一轮中后面的订单
+func (s *orderService) releaseCancelledOrders(ctx context.Context) error {
+	var afterID int64
+	for {
+		orders, err := s.orderRepo.ListUnreleasedOrders(ctx, afterID, autoCancelBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, order := range orders {
+			if err := s.releaseCancelledOrder(ctx, order); err != nil {
+				log.Printf("Failed to release cancelled order %s, will retry: %v", order.ID, err)
+			}
+		}
+
+		if len(orders) < autoCancelBatchSize {
+			return nil
+		}
+		last := orders[len(orders)-1]
+		if afterID, err = strconv.ParseInt(last.ID, 10, 64); err != nil {
+			return fmt.Errorf("invalid order ID %q: %w", last.ID, err)
+		}
+	}
+}
+
+// releaseReservedStock 释放订单预留的库存，订单没有预留库存时视为成功
+func (s *orderService) releaseReservedStock(ctx context.Context, order *repository.Order) error {
+	if s.productClient == nil {
+		return nil
+	}
+
+	orderID, err := strconv.Atoi(order.ID)
+	if err != nil {
+		return fmt.Errorf("invalid order ID %s: %w", order.ID, err)
+	}
+
+	resp, err := s.productClient.ReleaseStock(ctx, &productpb.ReleaseStockRequest{OrderId: int32(orderID)})
+	if err == nil && !resp.Success {
+		err = fmt.Errorf("product service rejected release: %s", resp.ErrorMessage)
+	}
+	if err != nil {
+		log.Printf("Failed to release stock for order %s: %v", order.ID, err)
+		return err
+	}
+	return nil
+}
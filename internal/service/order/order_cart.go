@@ -0,0 +1,209 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	cartpb "github.com/bytedance-youthcamp/demo/api/cart"
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+)
+
+// CreateOrderFromCart 使用购物车商品的快照创建订单，并将已下单的商品从购物车中移除。
+// 移除失败时取消订单并将已移除的商品放回购物车，用户看到的结果要么是下单成功且商品已移出购物车，
+// 要么是下单失败且购物车保持不变
+func (s *orderService) CreateOrderFromCart(ctx context.Context, req *orderapi.CreateOrderFromCartRequest) (*orderapi.CreateOrderFromCartResponse, error) {
+	if err := s.authorizeOwner(ctx, ownerOf(req.UserId), permissionWrite); err != nil {
+		return nil, err
+	}
+
+	cartItems, errorMessage, err := s.loadCartItems(ctx, req.CartId, req.UserId, req.CartItemIds)
+	if err != nil {
+		return nil, err
+	}
+	if errorMessage != "" {
+		return &orderapi.CreateOrderFromCartResponse{
+			Success:      false,
+			ErrorMessage: errorMessage,
+		}, nil
+	}
+
+	// 按购物车中的价格下单，价格已变化时由 CreateOrder 返回价格不一致
+	items, total, errorMessage := cartOrderItems(cartItems)
+	if errorMessage != "" {
+		return &orderapi.CreateOrderFromCartResponse{
+			Success:      false,
+			ErrorMessage: errorMessage,
+		}, nil
+	}
+
+	createReq := &orderapi.CreateOrderRequest{
+		UserId:       req.UserId,
+		Items:        items,
+		TotalPrice:   total.Float64(),
+		TotalMoney:   toAPIMoney(total),
+		Address:      req.Address,
+		ContactName:  req.ContactName,
+		ContactPhone: req.ContactPhone,
+		CouponCode:   req.CouponCode,
+		Region:       req.Region,
+	}
+
+	createResp, err := s.CreateOrder(ctx, createReq)
+	if err != nil {
+		return nil, err
+	}
+	if !createResp.Success {
+		return &orderapi.CreateOrderFromCartResponse{
+			Success:       false,
+			ErrorMessage:  createResp.ErrorMessage,
+			PriceMismatch: createResp.PriceMismatch,
+		}, nil
+	}
+
+	// 将已下单的商品移出购物车，只移除快照中的商品，下单期间新加入的商品保留在购物车中
+	for i, item := range cartItems {
+		removeErr := s.removeCartItem(ctx, req.CartId, item.Id)
+		if removeErr == nil {
+			continue
+		}
+
+		log.Printf("Failed to remove item %d from cart %d for order %d: %v", item.Id, req.CartId, createResp.OrderId, removeErr)
+		s.revertCartOrder(ctx, createResp.OrderId, req.CartId, cartItems[:i])
+		return &orderapi.CreateOrderFromCartResponse{
+			Success:      false,
+			ErrorMessage: "Failed to check out cart",
+		}, nil
+	}
+
+	return &orderapi.CreateOrderFromCartResponse{
+		Success:        true,
+		OrderId:        createResp.OrderId,
+		AutoCancelTime: createResp.AutoCancelTime,
+		TotalMoney:     createResp.TotalMoney,
+		DiscountMoney:  createResp.DiscountMoney,
+		PriceBreakdown: createResp.PriceBreakdown,
+	}, nil
+}
+
+// loadCartItems 查询用户购物车中要下单的商品，返回的错误信息非空表示购物车不可用
+func (s *orderService) loadCartItems(ctx context.Context, cartID, userID int32, cartItemIDs []int32) ([]*cartpb.CartItem, string, error) {
+	if s.cartClient == nil {
+		return nil, "Cart service is not available", nil
+	}
+
+	cartResp, err := s.cartClient.GetCart(ctx, &cartpb.GetCartRequest{CartId: cartID})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get cart: %w", err)
+	}
+	if !cartResp.Success {
+		return nil, "Cart not found", nil
+	}
+	if cartResp.Cart.UserId != userID {
+		return nil, "Cart does not belong to user", nil
+	}
+
+	cartItems, errorMessage := selectCartItems(cartResp.Cart, cartItemIDs)
+	return cartItems, errorMessage, nil
+}
+
+// cartOrderItems 按购物车中的商品和价格生成下单请求的订单项，并计算原价合计
+func cartOrderItems(cartItems []*cartpb.CartItem) ([]*orderapi.OrderItem, money.Money, string) {
+	items := make([]*orderapi.OrderItem, len(cartItems))
+	total := money.Zero(cartItemPrice(cartItems[0]).Currency)
+	for i, item := range cartItems {
+		price := cartItemPrice(item)
+		items[i] = &orderapi.OrderItem{
+			ProductId:   item.ProductId,
+			ProductName: item.ProductName,
+			Quantity:    item.Quantity,
+			Price:       price.Float64(),
+			PriceMoney:  toAPIMoney(price),
+		}
+		var err error
+		if total, err = total.Add(price.Mul(int64(item.Quantity))); err != nil {
+			return nil, money.Money{}, "Products must use the same currency"
+		}
+	}
+	return items, total, ""
+}
+
+// selectCartItems 选出要下单的购物车商品，cartItemIDs 为空时选择全部商品
+func selectCartItems(cart *cartpb.Cart, cartItemIDs []int32) ([]*cartpb.CartItem, string) {
+	if len(cart.Items) == 0 {
+		return nil, "Cart is empty"
+	}
+	if len(cartItemIDs) == 0 {
+		return cart.Items, ""
+	}
+
+	byID := make(map[int32]*cartpb.CartItem, len(cart.Items))
+	for _, item := range cart.Items {
+		byID[item.Id] = item
+	}
+
+	selected := make([]*cartpb.CartItem, 0, len(cartItemIDs))
+	seen := make(map[int32]bool, len(cartItemIDs))
+	for _, id := range cartItemIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		item, ok := byID[id]
+		if !ok {
+			return nil, fmt.Sprintf("Cart item %d not found", id)
+		}
+		selected = append(selected, item)
+	}
+
+	return selected, ""
+}
+
+// cartItemPrice 返回购物车商品的精确单价，购物车服务未返回精确金额时转换浮点单价
+func cartItemPrice(item *cartpb.CartItem) money.Money {
+	if item.PriceMoney != nil {
+		return money.New(item.PriceMoney.Amount, item.PriceMoney.Currency)
+	}
+	return money.FromFloat(item.Price, money.DefaultCurrency)
+}
+
+func (s *orderService) removeCartItem(ctx context.Context, cartID, cartItemID int32) error {
+	resp, err := s.cartClient.RemoveFromCart(ctx, &cartpb.RemoveFromCartRequest{
+		CartId:     cartID,
+		CartItemId: cartItemID,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("cart service rejected removal: %s", resp.ErrorMessage)
+	}
+	return nil
+}
+
+// revertCartOrder 取消从购物车创建的订单，并将已移出购物车的商品放回
+func (s *orderService) revertCartOrder(ctx context.Context, orderID, cartID int32, removed []*cartpb.CartItem) {
+	order, err := s.orderRepo.Get(ctx, fmt.Sprint(orderID))
+	if err != nil {
+		log.Printf("Failed to load order %d for cart checkout rollback: %v", orderID, err)
+	} else {
+		order.CancelReason = "cart checkout failed"
+		if err := s.transition(ctx, order, repository.OrderStatusCancelled, actorSystem, order.CancelReason); err != nil {
+			log.Printf("Failed to cancel order %d after cart checkout failure: %v", orderID, err)
+		}
+	}
+
+	for _, item := range removed {
+		resp, err := s.cartClient.AddToCart(ctx, &cartpb.AddToCartRequest{
+			CartId:    cartID,
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+		})
+		if err != nil || !resp.Success {
+			log.Printf("Failed to restore product %d to cart %d: %v", item.ProductId, cartID, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	promotionpb "github.com/bytedance-youthcamp/demo/api/promotion"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+)
+
+// WithPromotionClient 注入促销服务客户端，未注入时不能使用优惠券下单
+func WithPromotionClient(client promotionpb.PromotionServiceClient) Option {
+	return func(s *orderService) {
+		s.promotionClient = client
+	}
+}
+
+// applyCoupon 通过促销服务使用优惠券并占用一次使用次数，优惠按促销服务的分摊结果记录到订单项上。
+// 返回使用记录ID和优惠金额，返回的错误信息非空表示优惠券不可用
+func (s *orderService) applyCoupon(ctx context.Context, userID int32, code string, items []*repository.OrderItem) (string, money.Money, string, error) {
+	currency := items[0].Price.Currency
+	if s.promotionClient == nil {
+		return "", money.Money{}, "Coupons are not available", nil
+	}
+
+	resp, err := s.promotionClient.ApplyCoupon(ctx, &promotionpb.ApplyCouponRequest{
+		UserId:     userID,
+		CouponCode: code,
+		Items:      pricingItems(items),
+	})
+	if err != nil {
+		return "", money.Money{}, "", fmt.Errorf("failed to apply coupon: %w", err)
+	}
+	if !resp.Success {
+		return "", money.Money{}, fmt.Sprintf("Coupon cannot be applied: %s", resp.ErrorMessage), nil
+	}
+
+	// 促销服务按商品币种计价，优惠金额与订单币种相同
+	discount := money.New(resp.Breakdown.GetDiscount().GetAmount(), currency)
+
+	// 同一商品出现多次时按顺序对应促销服务返回的分摊结果
+	discounts := make(map[string][]int64)
+	for _, item := range resp.Breakdown.ItemDiscounts {
+		productID := strconv.Itoa(int(item.ProductId))
+		discounts[productID] = append(discounts[productID], item.Amount.GetAmount())
+	}
+	for _, item := range items {
+		item.Discount = money.Zero(currency)
+		if pending := discounts[item.ProductID]; len(pending) > 0 {
+			item.Discount = money.New(pending[0], currency)
+			discounts[item.ProductID] = pending[1:]
+		}
+	}
+
+	return resp.RedemptionId, discount, "", nil
+}
+
+// previewCoupon 通过促销服务试算优惠券的优惠金额，不占用使用次数。
+// 返回的错误信息非空表示优惠券不可用
+func (s *orderService) previewCoupon(ctx context.Context, userID int32, code string, items []*repository.OrderItem) (money.Money, string, error) {
+	if s.promotionClient == nil {
+		return money.Money{}, "Coupons are not available", nil
+	}
+
+	resp, err := s.promotionClient.PriceCart(ctx, &promotionpb.PriceCartRequest{
+		UserId:     userID,
+		CouponCode: code,
+		Items:      pricingItems(items),
+	})
+	if err != nil {
+		return money.Money{}, "", fmt.Errorf("failed to price coupon: %w", err)
+	}
+	if !resp.Success {
+		return money.Money{}, fmt.Sprintf("Coupon cannot be applied: %s", resp.ErrorMessage), nil
+	}
+	return money.New(resp.Breakdown.GetDiscount().GetAmount(), items[0].Price.Currency), "", nil
+}
+
+// pricingItems 将订单项转换为促销服务的计价商品
+func pricingItems(items []*repository.OrderItem) []*promotionpb.PricingItem {
+	result := make([]*promotionpb.PricingItem, len(items))
+	for i, item := range items {
+		productID, _ := strconv.Atoi(item.ProductID)
+		result[i] = &promotionpb.PricingItem{
+			ProductId: int32(productID),
+			Category:  item.Category,
+			Quantity:  item.Quantity,
+			Price:     &promotionpb.Money{Amount: item.Price.Amount, Currency: item.Price.Currency},
+		}
+	}
+	return result
+}
+
+// releaseCoupon 释放订单占用的优惠券使用次数，订单未使用优惠券时直接返回。
+// 促销服务的释放是幂等的，可以重复调用
+func (s *orderService) releaseCoupon(ctx context.Context, order *repository.Order) error {
+	if order.CouponRedemptionID == "" || s.promotionClient == nil {
+		return nil
+	}
+
+	resp, err := s.promotionClient.ReleaseCoupon(ctx, &promotionpb.ReleaseCouponRequest{
+		RedemptionId: order.CouponRedemptionID,
+	})
+	if err == nil && !resp.Success {
+		err = fmt.Errorf("promotion service rejected release: %s", resp.ErrorMessage)
+	}
+	if err != nil {
+		log.Printf("Failed to release coupon %s for order %s: %v", order.CouponCode, order.ID, err)
+		return err
+	}
+	return nil
+}
+
+// releaseCancelledOrder 释放已取消订单预留的库存和占用的优惠券，全部成功后清除订单的自动取消时间。
+// 库存和优惠券的释放都是幂等的，任一失败时订单留到下次检查重试
+func (s *orderService) releaseCancelledOrder(ctx context.Context, order *repository.Order) error {
+	if err := s.releaseReservedStock(ctx, order); err != nil {
+		return err
+	}
+	if err := s.releaseCoupon(ctx, order); err != nil {
+		return err
+	}
+	return s.orderRepo.MarkOrderReleased(ctx, order.ID)
+}
+
+// itemDiscountShare 返回取消订单项中 quantity 件商品应退回的优惠金额。
+// 按累计取消数量计算，商品全部取消时退回的优惠合计正好等于该商品分摊的优惠
+func itemDiscountShare(item *repository.OrderItem, cancelled, quantity int32) money.Money {
+	if item.Discount.Amount == 0 || item.Quantity == 0 {
+		return money.Zero(item.Price.Currency)
+	}
+	total := int64(item.Quantity)
+	before := item.Discount.Amount * int64(cancelled) / total
+	after := item.Discount.Amount * int64(cancelled+quantity) / total
+	return money.New(after-before, item.Price.Currency)
+}
@@ -0,0 +1,244 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	productpb "github.com/bytedance-youthcamp/demo/api/product"
+	promotionpb "github.com/bytedance-youthcamp/demo/api/promotion"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// couponOrderRepository 在内存中保存创建的订单和最近一次状态变更的操作者，createErr 非空时创建失败
+type couponOrderRepository struct {
+	repository.OrderRepository
+	order     *repository.Order
+	actor     string
+	createErr error
+}
+
+func (r *couponOrderRepository) Create(ctx context.Context, order *repository.Order) error {
+	if r.createErr != nil {
+		return r.createErr
+	}
+	order.ID = "7"
+	r.order = order
+	return nil
+}
+
+func (r *couponOrderRepository) Get(ctx context.Context, orderID string) (*repository.Order, error) {
+	return copyOrder(r.order), nil
+}
+
+func (r *couponOrderRepository) UpdateStatus(ctx context.Context, order *repository.Order, from repository.OrderStatus, actor, reason string) error {
+	r.order = copyOrder(order)
+	r.actor = actor
+	return nil
+}
+
+func (r *couponOrderRepository) MarkOrderReleased(ctx context.Context, orderID string) error {
+	r.order.AutoCancelAt = nil
+	return nil
+}
+
+func newCouponTestService(repo repository.OrderRepository) (*orderService, *MockPromotionClient) {
+	productClient := new(MockProductClient)
+	productClient.On("GetProducts", mock.Anything, mock.Anything, mock.Anything).Return(&productpb.GetProductsResponse{
+		Success: true,
+		Products: []*productpb.Product{
+			{Id: 1, Name: "Keyboard", Category: "electronics", PriceMoney: &productpb.Money{Amount: 10000, Currency: "CNY"}},
+			{Id: 2, Name: "Novel", Category: "books", PriceMoney: &productpb.Money{Amount: 5000, Currency: "CNY"}},
+		},
+	}, nil)
+	promotionClient := new(MockPromotionClient)
+	return &orderService{orderRepo: repo, productClient: productClient, promotionClient: promotionClient}, promotionClient
+}
+
+func couponOrderRequest(couponCode string) *orderapi.CreateOrderRequest {
+	return &orderapi.CreateOrderRequest{
+		UserId: 1,
+		Items: []*orderapi.OrderItem{
+			{ProductId: 1, Quantity: 2, PriceMoney: &orderapi.Money{Amount: 10000, Currency: "CNY"}},
+			{ProductId: 2, Quantity: 1, PriceMoney: &orderapi.Money{Amount: 5000, Currency: "CNY"}},
+		},
+		TotalMoney: &orderapi.Money{Amount: 25000, Currency: "CNY"},
+		CouponCode: couponCode,
+	}
+}
+
+func TestCreateOrderWithCoupon(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("applies discount to order and items", func(t *testing.T) {
+		repo := &couponOrderRepository{}
+		service, promotionClient := newCouponTestService(repo)
+		promotionClient.On("ApplyCoupon", mock.Anything, mock.Anything, mock.Anything).Return(&promotionpb.ApplyCouponResponse{
+			Success:      true,
+			RedemptionId: "redemption-1",
+			Breakdown: &promotionpb.PriceBreakdown{
+				Subtotal:      &promotionpb.Money{Amount: 25000, Currency: "CNY"},
+				Discount:      &promotionpb.Money{Amount: 2000, Currency: "CNY"},
+				Total:         &promotionpb.Money{Amount: 23000, Currency: "CNY"},
+				ItemDiscounts: []*promotionpb.ItemDiscount{{ProductId: 1, Amount: &promotionpb.Money{Amount: 2000, Currency: "CNY"}}},
+			},
+		}, nil)
+
+		resp, err := service.createOrder(ctx, couponOrderRequest("SAVE20"))
+		require.NoError(t, err)
+		require.True(t, resp.Success, resp.ErrorMessage)
+		assert.Equal(t, int64(23000), resp.TotalMoney.Amount)
+		assert.Equal(t, int64(2000), resp.DiscountMoney.Amount)
+
+		req := promotionClient.Calls[0].Arguments.Get(1).(*promotionpb.ApplyCouponRequest)
+		assert.Equal(t, "SAVE20", req.CouponCode)
+		require.Len(t, req.Items, 2)
+		assert.Equal(t, "electronics", req.Items[0].Category)
+
+		assert.Equal(t, money.New(23000, "CNY"), repo.order.TotalAmount)
+		assert.Equal(t, money.New(2000, "CNY"), repo.order.DiscountAmount)
+		assert.Equal(t, "SAVE20", repo.order.CouponCode)
+		assert.Equal(t, "redemption-1", repo.order.CouponRedemptionID)
+		assert.Equal(t, money.New(2000, "CNY"), repo.order.Items[0].Discount)
+		assert.Equal(t, money.Zero("CNY"), repo.order.Items[1].Discount)
+	})
+
+	t.Run("reports unusable coupon", func(t *testing.T) {
+		repo := &couponOrderRepository{}
+		service, promotionClient := newCouponTestService(repo)
+		promotionClient.On("ApplyCoupon", mock.Anything, mock.Anything, mock.Anything).Return(&promotionpb.ApplyCouponResponse{
+			Success:      false,
+			ErrorMessage: "优惠券已过期",
+		}, nil)
+
+		resp, err := service.createOrder(ctx, couponOrderRequest("OLD"))
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Equal(t, "Coupon cannot be applied: 优惠券已过期", resp.ErrorMessage)
+		assert.Nil(t, repo.order)
+	})
+
+	t.Run("releases coupon when order cannot be saved", func(t *testing.T) {
+		repo := &couponOrderRepository{createErr: errors.New("database is down")}
+		service, promotionClient := newCouponTestService(repo)
+		promotionClient.On("ApplyCoupon", mock.Anything, mock.Anything, mock.Anything).Return(&promotionpb.ApplyCouponResponse{
+			Success:      true,
+			RedemptionId: "redemption-1",
+			Breakdown:    &promotionpb.PriceBreakdown{Discount: &promotionpb.Money{Amount: 1000, Currency: "CNY"}},
+		}, nil)
+		promotionClient.On("ReleaseCoupon", mock.Anything, mock.Anything, mock.Anything).Return(&promotionpb.ReleaseCouponResponse{Success: true}, nil)
+
+		resp, err := service.createOrder(ctx, couponOrderRequest("SAVE10"))
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+
+		req := promotionClient.Calls[1].Arguments.Get(1).(*promotionpb.ReleaseCouponRequest)
+		assert.Equal(t, "redemption-1", req.RedemptionId)
+	})
+}
+
+func TestCancelOrderReleasesStockAndCoupon(t *testing.T) {
+	order := newItemsTestOrder(repository.OrderStatusPending)
+	order.CouponCode = "SAVE20"
+	order.CouponRedemptionID = "redemption-1"
+	autoCancelAt := time.Now().Add(time.Hour)
+	order.AutoCancelAt = &autoCancelAt
+	repo := &couponOrderRepository{order: order}
+	service, promotionClient := newCouponTestService(repo)
+	productClient := service.productClient.(*MockProductClient)
+	productClient.On("ReleaseStock", mock.Anything, &productpb.ReleaseStockRequest{OrderId: 7}, mock.Anything).Return(&productpb.ReleaseStockResponse{Success: true}, nil)
+	promotionClient.On("ReleaseCoupon", mock.Anything, mock.Anything, mock.Anything).Return(&promotionpb.ReleaseCouponResponse{Success: true}, nil)
+
+	ctx := ContextWithCaller(context.Background(), Caller{UserID: 1})
+	resp, err := service.CancelOrder(ctx, &orderapi.CancelOrderRequest{OrderId: 7})
+	require.NoError(t, err)
+	require.True(t, resp.Success, resp.ErrorMessage)
+	assert.Equal(t, repository.OrderStatusCancelled, repo.order.Status)
+	assert.Equal(t, "user:1", repo.actor)
+	assert.Nil(t, repo.order.AutoCancelAt)
+
+	productClient.AssertCalled(t, "ReleaseStock", mock.Anything, &productpb.ReleaseStockRequest{OrderId: 7}, mock.Anything)
+	req := promotionClient.Calls[0].Arguments.Get(1).(*promotionpb.ReleaseCouponRequest)
+	assert.Equal(t, "redemption-1", req.RedemptionId)
+}
+
+// unreleasedOrderRepository 保存已取消但未释放的订单，failing 中的订单释放失败
+type unreleasedOrderRepository struct {
+	repository.OrderRepository
+	unreleased []int64
+	failing    map[string]bool
+}
+
+func (r *unreleasedOrderRepository) ListUnreleasedOrders(ctx context.Context, afterID int64, limit int) ([]*repository.Order, error) {
+	var orders []*repository.Order
+	for _, id := range r.unreleased {
+		if id > afterID && len(orders) < limit {
+			orders = append(orders, &repository.Order{ID: strconv.FormatInt(id, 10), Status: repository.OrderStatusCancelled})
+		}
+	}
+	return orders, nil
+}
+
+func (r *unreleasedOrderRepository) MarkOrderReleased(ctx context.Context, orderID string) error {
+	if r.failing[orderID] {
+		return errors.New("database unavailable")
+	}
+	for i, id := range r.unreleased {
+		if strconv.FormatInt(id, 10) == orderID {
+			r.unreleased = append(r.unreleased[:i], r.unreleased[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func TestReleaseCancelledOrdersSkipsFailures(t *testing.T) {
+	// 第一批订单全部释放失败时仍然释放后面的订单
+	repo := &unreleasedOrderRepository{failing: make(map[string]bool)}
+	for id := int64(1); id <= autoCancelBatchSize+1; id++ {
+		repo.unreleased = append(repo.unreleased, id)
+		if id <= autoCancelBatchSize {
+			repo.failing[strconv.FormatInt(id, 10)] = true
+		}
+	}
+	service := &orderService{orderRepo: repo}
+
+	require.NoError(t, service.releaseCancelledOrders(context.Background()))
+	assert.Len(t, repo.unreleased, autoCancelBatchSize)
+	assert.NotContains(t, repo.unreleased, int64(autoCancelBatchSize+1))
+}
+
+func TestCancelPaidOrderIsRejected(t *testing.T) {
+	order := newItemsTestOrder(repository.OrderStatusPaid)
+	order.CouponRedemptionID = "redemption-1"
+	repo := &couponOrderRepository{order: order}
+	service, promotionClient := newCouponTestService(repo)
+
+	// 已支付的订单需要走退款流程，不释放库存和优惠券
+	resp, err := service.CancelOrder(context.Background(), &orderapi.CancelOrderRequest{OrderId: 7})
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.Equal(t, repository.OrderStatusPaid, repo.order.Status)
+	promotionClient.AssertNotCalled(t, "ReleaseCoupon", mock.Anything, mock.Anything, mock.Anything)
+	service.productClient.(*MockProductClient).AssertNotCalled(t, "ReleaseStock", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestItemDiscountShare(t *testing.T) {
+	item := &repository.OrderItem{Quantity: 3, Price: money.New(1000, "CNY"), Discount: money.New(100, "CNY")}
+
+	// 逐件取消时退回的优惠合计等于分摊的优惠
+	var total int64
+	for cancelled := int32(0); cancelled < item.Quantity; cancelled++ {
+		total += itemDiscountShare(item, cancelled, 1).Amount
+	}
+	assert.Equal(t, int64(100), total)
+	assert.Equal(t, int64(66), itemDiscountShare(item, 0, 2).Amount)
+	assert.True(t, itemDiscountShare(&repository.OrderItem{Quantity: 1, Price: money.New(1000, "CNY")}, 0, 1).IsZero())
+}
@@ -0,0 +1,68 @@
+package order
+
+import (
+	"fmt"
+	"time"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// exportBatchSize 导出订单时每次从数据库查询的订单数
+const exportBatchSize = 500
+
+// ExportOrders 按创建时间升序导出订单，user_id 为 0 时导出所有用户的订单。
+// 订单分批查询并逐个发送，导出大量订单时不会一次性加载到内存中；请求无效时返回 InvalidArgument
+func (s *orderService) ExportOrders(req *orderapi.ExportOrdersRequest, stream orderapi.OrderService_ExportOrdersServer) error {
+	ctx := stream.Context()
+
+	// 用户只能导出自己的订单，导出其他用户或所有用户的订单需要订单查看权限
+	if err := s.authorizeOwner(ctx, ownerOf(req.UserId), permissionRead); err != nil {
+		return err
+	}
+
+	filter, errorMessage := exportFilter(req)
+	if errorMessage != "" {
+		return status.Error(codes.InvalidArgument, errorMessage)
+	}
+
+	err := repository.ForEachOrder(ctx, s.orderRepo, filter, exportBatchSize, func(order *repository.Order) error {
+		return stream.Send(&orderapi.ExportOrdersResponse{Order: toAPIOrder(order)})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export orders: %w", err)
+	}
+	return nil
+}
+
+// exportFilter 将导出请求转换为按创建时间升序的搜索条件，请求无效时返回错误信息
+func exportFilter(req *orderapi.ExportOrdersRequest) (repository.OrderSearchFilter, string) {
+	filter := repository.OrderSearchFilter{
+		UserID:    ownerOf(req.UserId),
+		SortBy:    repository.OrderSortByCreatedAt,
+		Ascending: true,
+	}
+
+	for _, status := range req.Statuses {
+		if _, ok := orderapi.OrderStatus_name[int32(status)]; !ok {
+			return filter, "Invalid order status"
+		}
+		filter.Statuses = append(filter.Statuses, toRepoStatus(status))
+	}
+
+	if req.CreatedFrom != 0 {
+		from := time.UnixMilli(req.CreatedFrom)
+		filter.CreatedFrom = &from
+	}
+	if req.CreatedTo != 0 {
+		to := time.UnixMilli(req.CreatedTo)
+		filter.CreatedTo = &to
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return filter, "Invalid date range"
+	}
+
+	return filter, ""
+}
@@ -0,0 +1,117 @@
+package order
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// searchableOrderRepository 在内存中按创建时间升序实现 SearchOrders，只支持创建时间筛选
+type searchableOrderRepository struct {
+	repository.OrderRepository
+	orders []*repository.Order
+}
+
+func (r *searchableOrderRepository) SearchOrders(ctx context.Context, filter repository.OrderSearchFilter) ([]*repository.Order, error) {
+	result := []*repository.Order{}
+	for _, order := range r.orders {
+		if filter.CreatedFrom != nil && order.CreatedAt.Before(*filter.CreatedFrom) {
+			continue
+		}
+		if filter.CreatedTo != nil && !order.CreatedAt.Before(*filter.CreatedTo) {
+			continue
+		}
+		if filter.After != nil {
+			id, _ := strconv.ParseInt(order.ID, 10, 64)
+			if order.CreatedAt.Before(filter.After.CreatedAt) ||
+				(order.CreatedAt.Equal(filter.After.CreatedAt) && id <= filter.After.ID) {
+				continue
+			}
+		}
+		result = append(result, order)
+		if len(result) == filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// exportStream 收集 ExportOrders 发送的订单
+type exportStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	orders []*orderapi.Order
+}
+
+func (s *exportStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *exportStream) Send(resp *orderapi.ExportOrdersResponse) error {
+	s.orders = append(s.orders, resp.Order)
+	return nil
+}
+
+func TestExportOrders(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := &searchableOrderRepository{}
+	// 同一秒内创建多个订单，分页时需要按订单 ID 区分
+	for i := 1; i <= exportBatchSize+10; i++ {
+		repo.orders = append(repo.orders, &repository.Order{
+			ID:          strconv.Itoa(i),
+			UserID:      "1",
+			TotalAmount: money.New(100, "CNY"),
+			CreatedAt:   start.Add(time.Duration(i/3) * time.Second),
+			Items: []*repository.OrderItem{
+				{ProductID: "1", Category: "Books", Quantity: 1, Price: money.New(100, "CNY")},
+			},
+		})
+	}
+	service := &orderService{orderRepo: repo}
+
+	t.Run("streams every order across batches", func(t *testing.T) {
+		stream := &exportStream{ctx: context.Background()}
+		require.NoError(t, service.ExportOrders(&orderapi.ExportOrdersRequest{}, stream))
+
+		require.Len(t, stream.orders, exportBatchSize+10)
+		for i, order := range stream.orders {
+			assert.Equal(t, int32(i+1), order.Id)
+		}
+		assert.Equal(t, "Books", stream.orders[0].Items[0].Category)
+	})
+
+	t.Run("filters by creation time", func(t *testing.T) {
+		stream := &exportStream{ctx: context.Background()}
+		err := service.ExportOrders(&orderapi.ExportOrdersRequest{
+			CreatedFrom: start.Add(time.Second).UnixMilli(),
+			CreatedTo:   start.Add(2 * time.Second).UnixMilli(),
+		}, stream)
+		require.NoError(t, err)
+
+		ids := make([]int32, len(stream.orders))
+		for i, order := range stream.orders {
+			ids[i] = order.Id
+		}
+		assert.Equal(t, []int32{3, 4, 5}, ids)
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		for _, req := range []*orderapi.ExportOrdersRequest{
+			{CreatedFrom: start.UnixMilli(), CreatedTo: start.UnixMilli()},
+			{Statuses: []orderapi.OrderStatus{orderapi.OrderStatus(99)}},
+		} {
+			err := service.ExportOrders(req, &exportStream{ctx: context.Background()})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		}
+	})
+}
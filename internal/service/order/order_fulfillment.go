@@ -0,0 +1,424 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+)
+
+// WithShipmentRepository 注入发货单仓储，未注入时发货相关接口不可用
+func WithShipmentRepository(repo repository.ShipmentRepository) Option {
+	return func(s *orderService) {
+		s.shipmentRepo = repo
+	}
+}
+
+// CreateShipment 为已支付的订单创建发货单，未指定商品时发出所有尚未发货的商品。
+// 订单的第一个发货单创建后订单进入配送中
+func (s *orderService) CreateShipment(ctx context.Context, req *orderapi.CreateShipmentRequest) (*orderapi.CreateShipmentResponse, error) {
+	if err := s.authorizeAction(ctx, permissionWrite); err != nil {
+		return nil, err
+	}
+
+	if s.shipmentRepo == nil {
+		return &orderapi.CreateShipmentResponse{
+			Success:      false,
+			ErrorMessage: "Fulfillment is not available",
+		}, nil
+	}
+	if req.Carrier == "" {
+		return &orderapi.CreateShipmentResponse{
+			Success:      false,
+			ErrorMessage: "Carrier is required",
+		}, nil
+	}
+
+	order, err := s.orderRepo.Get(ctx, fmt.Sprint(req.OrderId))
+	if err != nil {
+		return &orderapi.CreateShipmentResponse{
+			Success:      false,
+			ErrorMessage: "Order not found",
+		}, nil
+	}
+	if order.Status != repository.OrderStatusPaid && order.Status != repository.OrderStatusShipped {
+		return &orderapi.CreateShipmentResponse{
+			Success:      false,
+			ErrorMessage: "Order cannot be shipped",
+		}, nil
+	}
+
+	shipments, err := s.shipmentRepo.ListByOrderID(ctx, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shipments: %w", err)
+	}
+
+	items, errorMessage := shipmentItems(order, shipments, req.Items)
+	if errorMessage != "" {
+		return &orderapi.CreateShipmentResponse{
+			Success:      false,
+			ErrorMessage: errorMessage,
+		}, nil
+	}
+
+	now := time.Now()
+	shipment := &repository.Shipment{
+		OrderID:        order.ID,
+		Carrier:        req.Carrier,
+		TrackingNumber: req.TrackingNumber,
+		Status:         repository.ShipmentStatusCreated,
+		Items:          items,
+		Events: []*repository.ShipmentEvent{{
+			Status:      repository.ShipmentStatusCreated,
+			Description: "shipment created",
+			EventTime:   now,
+		}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.shipmentRepo.Create(ctx, shipment); err != nil {
+		if errors.Is(err, repository.ErrShipmentQuantityExceeded) {
+			return &orderapi.CreateShipmentResponse{
+				Success:      false,
+				ErrorMessage: "Shipment quantity exceeds unshipped quantity",
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to create shipment: %w", err)
+	}
+
+	status, err := s.syncFulfillment(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &orderapi.CreateShipmentResponse{
+		Success:     true,
+		ShipmentId:  shipment.ID,
+		OrderStatus: toAPIStatus(status),
+	}, nil
+}
+
+// UpdateShipmentTracking 记录发货单的物流轨迹，发货单状态只能前进，送达后不能再更新
+func (s *orderService) UpdateShipmentTracking(ctx context.Context, req *orderapi.UpdateShipmentTrackingRequest) (*orderapi.UpdateShipmentTrackingResponse, error) {
+	if err := s.authorizeAction(ctx, permissionWrite); err != nil {
+		return nil, err
+	}
+
+	eventTime := time.Now()
+	if req.EventTime > 0 {
+		eventTime = time.UnixMilli(req.EventTime)
+	}
+
+	status, errorMessage, err := s.addShipmentEvent(ctx, req.ShipmentId, &repository.ShipmentEvent{
+		Status:      fromAPIShipmentStatus(req.Status),
+		Location:    req.Location,
+		Description: req.Description,
+		EventTime:   eventTime,
+	}, req.TrackingNumber)
+	if err != nil {
+		return nil, err
+	}
+	if errorMessage != "" {
+		return &orderapi.UpdateShipmentTrackingResponse{
+			Success:      false,
+			ErrorMessage: errorMessage,
+		}, nil
+	}
+
+	return &orderapi.UpdateShipmentTrackingResponse{
+		Success:     true,
+		OrderStatus: toAPIStatus(status),
+	}, nil
+}
+
+// ConfirmDelivery 确认发货单已送达，订单的所有商品都已送达后订单完成，重复确认不会产生副作用
+func (s *orderService) ConfirmDelivery(ctx context.Context, req *orderapi.ConfirmDeliveryRequest) (*orderapi.ConfirmDeliveryResponse, error) {
+	// 收货人和运营人员都可以确认送达
+	if err := s.authorizeShipment(ctx, req.ShipmentId, permissionWrite); err != nil {
+		return nil, err
+	}
+
+	status, errorMessage, err := s.addShipmentEvent(ctx, req.ShipmentId, &repository.ShipmentEvent{
+		Status:      repository.ShipmentStatusDelivered,
+		Description: "delivery confirmed",
+		EventTime:   time.Now(),
+	}, "")
+	if err != nil {
+		return nil, err
+	}
+	if errorMessage != "" {
+		return &orderapi.ConfirmDeliveryResponse{
+			Success:      false,
+			ErrorMessage: errorMessage,
+		}, nil
+	}
+
+	return &orderapi.ConfirmDeliveryResponse{
+		Success:     true,
+		OrderStatus: toAPIStatus(status),
+	}, nil
+}
+
+// GetOrderShipments 返回订单的所有发货单及物流轨迹
+func (s *orderService) GetOrderShipments(ctx context.Context, req *orderapi.GetOrderShipmentsRequest) (*orderapi.GetOrderShipmentsResponse, error) {
+	if s.shipmentRepo == nil {
+		return &orderapi.GetOrderShipmentsResponse{
+			Success:      false,
+			ErrorMessage: "Fulfillment is not available",
+		}, nil
+	}
+
+	order, err := s.orderRepo.Get(ctx, fmt.Sprint(req.OrderId))
+	if err != nil {
+		return &orderapi.GetOrderShipmentsResponse{
+			Success:      false,
+			ErrorMessage: "Order not found",
+		}, nil
+	}
+	if err := s.authorizeOwner(ctx, order.UserID, permissionRead); err != nil {
+		return nil, err
+	}
+
+	shipments, err := s.shipmentRepo.ListByOrderID(ctx, fmt.Sprint(req.OrderId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shipments: %w", err)
+	}
+
+	pbShipments := make([]*orderapi.Shipment, len(shipments))
+	for i, shipment := range shipments {
+		pbShipments[i] = toAPIShipment(shipment)
+	}
+
+	return &orderapi.GetOrderShipmentsResponse{
+		Success:   true,
+		Shipments: pbShipments,
+	}, nil
+}
+
+// authorizeShipment 检查调用方是否可以访问发货单所属的订单，发货单不存在时由调用方处理
+func (s *orderService) authorizeShipment(ctx context.Context, shipmentID int64, action string) error {
+	if s.authClient == nil || s.shipmentRepo == nil {
+		return nil
+	}
+
+	shipment, err := s.shipmentRepo.Get(ctx, shipmentID)
+	if errors.Is(err, repository.ErrShipmentNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get shipment: %w", err)
+	}
+
+	order, err := s.orderRepo.Get(ctx, shipment.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	return s.authorizeOwner(ctx, order.UserID, action)
+}
+
+// addShipmentEvent 校验并记录物流轨迹，然后根据所有发货单的状态推进订单状态
+func (s *orderService) addShipmentEvent(ctx context.Context, shipmentID int64, event *repository.ShipmentEvent, trackingNumber string) (repository.OrderStatus, string, error) {
+	if s.shipmentRepo == nil {
+		return 0, "Fulfillment is not available", nil
+	}
+
+	shipment, err := s.shipmentRepo.Get(ctx, shipmentID)
+	if errors.Is(err, repository.ErrShipmentNotFound) {
+		return 0, "Shipment not found", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get shipment: %w", err)
+	}
+
+	// 重复确认送达时只需确保订单状态已同步
+	if shipment.Status == repository.ShipmentStatusDelivered {
+		if event.Status != repository.ShipmentStatusDelivered {
+			return 0, "Shipment has been delivered", nil
+		}
+		status, err := s.syncFulfillment(ctx, shipment.OrderID)
+		return status, "", err
+	}
+	if event.Status < shipment.Status {
+		return 0, fmt.Sprintf("Invalid shipment status transition from %s to %s", shipment.Status, event.Status), nil
+	}
+
+	shipment.Status = event.Status
+	shipment.UpdatedAt = time.Now()
+	if trackingNumber != "" {
+		shipment.TrackingNumber = trackingNumber
+	}
+	// 记录揽收和送达时间，直接送达的发货单揽收时间与送达时间相同
+	if event.Status >= repository.ShipmentStatusInTransit && shipment.ShippedAt == nil {
+		shipment.ShippedAt = &event.EventTime
+	}
+	if event.Status == repository.ShipmentStatusDelivered {
+		shipment.DeliveredAt = &event.EventTime
+	}
+
+	if err := s.shipmentRepo.AddEvent(ctx, shipment, event); err != nil {
+		return 0, "", fmt.Errorf("failed to update shipment: %w", err)
+	}
+
+	status, err := s.syncFulfillment(ctx, shipment.OrderID)
+	return status, "", err
+}
+
+// syncFulfillment 根据发货单推进订单状态：已支付的订单有发货单后进入配送中，
+// 所有商品都已发货且所有发货单都已送达后订单完成。返回订单的最新状态
+func (s *orderService) syncFulfillment(ctx context.Context, orderID string) (repository.OrderStatus, error) {
+	order, err := s.orderRepo.Get(ctx, orderID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	shipments, err := s.shipmentRepo.ListByOrderID(ctx, orderID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list shipments: %w", err)
+	}
+	if len(shipments) == 0 {
+		return order.Status, nil
+	}
+
+	if order.Status == repository.OrderStatusPaid {
+		if err := s.transition(ctx, order, repository.OrderStatusShipped, actorFulfillment, "shipment created"); err != nil {
+			return 0, fmt.Errorf("failed to mark order shipped: %w", err)
+		}
+	}
+
+	if order.Status == repository.OrderStatusShipped && fullyDelivered(order, shipments) {
+		if err := s.transition(ctx, order, repository.OrderStatusDelivered, actorFulfillment, "all shipments delivered"); err != nil {
+			return 0, fmt.Errorf("failed to mark order delivered: %w", err)
+		}
+	}
+
+	return order.Status, nil
+}
+
+// shipmentItems 校验要发货的商品，requested 为空时返回订单中所有尚未发货的商品
+func shipmentItems(order *repository.Order, shipments []*repository.Shipment, requested []*orderapi.ShipmentItem) ([]*repository.ShipmentItem, string) {
+	remaining := unshipped(order, shipments)
+
+	if len(requested) == 0 {
+		var items []*repository.ShipmentItem
+		for _, item := range order.Items {
+			if quantity := remaining[item.ProductID]; quantity > 0 {
+				items = append(items, &repository.ShipmentItem{ProductID: item.ProductID, Quantity: quantity})
+				remaining[item.ProductID] = 0
+			}
+		}
+		if len(items) == 0 {
+			return nil, "All items have been shipped"
+		}
+		return items, ""
+	}
+
+	items := make([]*repository.ShipmentItem, 0, len(requested))
+	for _, item := range requested {
+		productID := strconv.Itoa(int(item.ProductId))
+		left, ok := remaining[productID]
+		if !ok {
+			return nil, fmt.Sprintf("Product %d is not in the order", item.ProductId)
+		}
+		if item.Quantity <= 0 {
+			return nil, fmt.Sprintf("Invalid shipment quantity for product %d", item.ProductId)
+		}
+		if item.Quantity > left {
+			return nil, fmt.Sprintf("Shipment quantity exceeds unshipped quantity for product %d", item.ProductId)
+		}
+		remaining[productID] -= item.Quantity
+		items = append(items, &repository.ShipmentItem{ProductID: productID, Quantity: item.Quantity})
+	}
+	return items, ""
+}
+
+// unshipped 返回订单中每个商品尚未发货的数量
+func unshipped(order *repository.Order, shipments []*repository.Shipment) map[string]int32 {
+	remaining := make(map[string]int32, len(order.Items))
+	for _, item := range order.Items {
+		remaining[item.ProductID] += item.Quantity - item.CancelledQuantity
+	}
+	for _, shipment := range shipments {
+		for _, item := range shipment.Items {
+			remaining[item.ProductID] -= item.Quantity
+		}
+	}
+	return remaining
+}
+
+// fullyDelivered 判断订单的所有商品是否都已发货并送达
+func fullyDelivered(order *repository.Order, shipments []*repository.Shipment) bool {
+	for _, shipment := range shipments {
+		if shipment.Status != repository.ShipmentStatusDelivered {
+			return false
+		}
+	}
+	for _, quantity := range unshipped(order, shipments) {
+		if quantity > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func toAPIShipment(shipment *repository.Shipment) *orderapi.Shipment {
+	orderID, _ := strconv.Atoi(shipment.OrderID)
+
+	items := make([]*orderapi.ShipmentItem, len(shipment.Items))
+	for i, item := range shipment.Items {
+		productID, _ := strconv.Atoi(item.ProductID)
+		items[i] = &orderapi.ShipmentItem{
+			ProductId: int32(productID),
+			Quantity:  item.Quantity,
+		}
+	}
+
+	events := make([]*orderapi.ShipmentEvent, len(shipment.Events))
+	for i, event := range shipment.Events {
+		events[i] = &orderapi.ShipmentEvent{
+			Status:      toAPIShipmentStatus(event.Status),
+			Location:    event.Location,
+			Description: event.Description,
+			EventTime:   event.EventTime.Format(time.RFC3339),
+		}
+	}
+
+	return &orderapi.Shipment{
+		Id:             shipment.ID,
+		OrderId:        int32(orderID),
+		Carrier:        shipment.Carrier,
+		TrackingNumber: shipment.TrackingNumber,
+		Status:         toAPIShipmentStatus(shipment.Status),
+		Items:          items,
+		Events:         events,
+		ShippedAt:      formatTime(shipment.ShippedAt),
+		DeliveredAt:    formatTime(shipment.DeliveredAt),
+		CreatedAt:      shipment.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      shipment.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func fromAPIShipmentStatus(status orderapi.ShipmentStatus) repository.ShipmentStatus {
+	switch status {
+	case orderapi.ShipmentStatus_SHIPMENT_IN_TRANSIT:
+		return repository.ShipmentStatusInTransit
+	case orderapi.ShipmentStatus_SHIPMENT_DELIVERED:
+		return repository.ShipmentStatusDelivered
+	default:
+		return repository.ShipmentStatusCreated
+	}
+}
+
+func toAPIShipmentStatus(status repository.ShipmentStatus) orderapi.ShipmentStatus {
+	switch status {
+	case repository.ShipmentStatusInTransit:
+		return orderapi.ShipmentStatus_SHIPMENT_IN_TRANSIT
+	case repository.ShipmentStatusDelivered:
+		return orderapi.ShipmentStatus_SHIPMENT_DELIVERED
+	default:
+		return orderapi.ShipmentStatus_SHIPMENT_CREATED
+	}
+}
@@ -0,0 +1,111 @@
+package order
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	idempotencyScopeCreateOrder = "create_order"
+	idempotencyScopeSettleOrder = "settle_order"
+
+	// idempotencyLockTimeout 超过该时间仍未完成的请求视为已中断，使用相同幂等键的重试可以重新处理
+	idempotencyLockTimeout = time.Minute
+)
+
+func WithIdempotencyRepository(repo repository.IdempotencyRepository) Option {
+	return func(s *orderService) {
+		s.idempotencyRepo = repo
+	}
+}
+
+// idempotent 使用幂等键执行 handle。相同幂等键和相同请求内容的重试直接返回首次成功的响应，
+// 幂等键被不同的请求使用或首次请求仍在处理中时返回 rejected 生成的业务错误响应。
+// 只缓存成功的响应，失败的请求不会产生副作用，释放幂等键后可以使用相同的幂等键重试。
+// 幂等键按调用方隔离，不同的用户或服务使用相同的幂等键互不影响。
+// 未设置幂等键或未配置幂等键存储时直接执行 handle
+func idempotent[T interface {
+	proto.Message
+	GetSuccess() bool
+}](
+	ctx context.Context,
+	s *orderService,
+	scope, key string,
+	req proto.Message,
+	rejected func(errorMessage string) T,
+	handle func() (T, error),
+) (T, error) {
+	if key == "" || s.idempotencyRepo == nil {
+		return handle()
+	}
+	scope = scope + ":" + callerActor(ctx)
+
+	hash, err := requestHash(req)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	record, err := s.idempotencyRepo.Begin(ctx, scope, key, hash, idempotencyLockTimeout)
+	switch {
+	case errors.Is(err, repository.ErrIdempotencyKeyConflict):
+		return rejected("Idempotency key was used with a different request"), nil
+	case errors.Is(err, repository.ErrIdempotencyKeyInProgress):
+		return rejected("A request with the same idempotency key is in progress"), nil
+	case err != nil:
+		var zero T
+		return zero, fmt.Errorf("failed to begin idempotent request: %w", err)
+	}
+
+	if record != nil {
+		var zero T
+		resp := zero.ProtoReflect().New().Interface().(T)
+		if err := proto.Unmarshal(record.Response, resp); err != nil {
+			return zero, fmt.Errorf("failed to decode cached response: %w", err)
+		}
+		return resp, nil
+	}
+
+	resp, err := handle()
+	if err != nil || !resp.GetSuccess() {
+		if releaseErr := s.idempotencyRepo.Release(ctx, scope, key); releaseErr != nil {
+			log.Printf("Failed to release idempotency key %s/%s: %v", scope, key, releaseErr)
+		}
+		return resp, err
+	}
+
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		var zero T
+		return zero, fmt.Errorf("failed to encode response: %w", err)
+	}
+	if err := s.idempotencyRepo.Complete(ctx, scope, key, data); err != nil {
+		// 请求已处理成功，保存响应失败时仍返回结果；幂等键在超时后可被重新占用
+		log.Printf("Failed to save response for idempotency key %s/%s: %v", scope, key, err)
+	}
+
+	return resp, nil
+}
+
+// requestHash 计算除幂等键以外的请求内容的摘要
+func requestHash(req proto.Message) (string, error) {
+	m := proto.Clone(req).ProtoReflect()
+	if fd := m.Descriptor().Fields().ByName("idempotency_key"); fd != nil {
+		m.Clear(fd)
+	}
+
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(m.Interface())
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,112 @@
+package order
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryIdempotencyRepository 是测试使用的内存幂等键存储
+type memoryIdempotencyRepository struct {
+	records map[string]*repository.IdempotencyRecord
+}
+
+func (r *memoryIdempotencyRepository) Begin(ctx context.Context, scope, key, requestHash string, lockTimeout time.Duration) (*repository.IdempotencyRecord, error) {
+	record, ok := r.records[scope+"/"+key]
+	if !ok {
+		r.records[scope+"/"+key] = &repository.IdempotencyRecord{Scope: scope, Key: key, RequestHash: requestHash}
+		return nil, nil
+	}
+	if record.RequestHash != requestHash {
+		return nil, repository.ErrIdempotencyKeyConflict
+	}
+	if record.CompletedAt == nil {
+		return nil, repository.ErrIdempotencyKeyInProgress
+	}
+	return record, nil
+}
+
+func (r *memoryIdempotencyRepository) Complete(ctx context.Context, scope, key string, response []byte) error {
+	now := time.Now()
+	record := r.records[scope+"/"+key]
+	record.Response = response
+	record.CompletedAt = &now
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	delete(r.records, scope+"/"+key)
+	return nil
+}
+
+func TestIdempotent(t *testing.T) {
+	ctx := context.Background()
+	service := &orderService{idempotencyRepo: &memoryIdempotencyRepository{records: map[string]*repository.IdempotencyRecord{}}}
+	rejected := func(errorMessage string) *orderapi.CreateOrderResponse {
+		return &orderapi.CreateOrderResponse{Success: false, ErrorMessage: errorMessage}
+	}
+
+	calls := 0
+	create := func(req *orderapi.CreateOrderRequest, success bool) (*orderapi.CreateOrderResponse, error) {
+		return idempotent(ctx, service, idempotencyScopeCreateOrder, req.IdempotencyKey, req, rejected,
+			func() (*orderapi.CreateOrderResponse, error) {
+				calls++
+				return &orderapi.CreateOrderResponse{Success: success, OrderId: int32(calls)}, nil
+			},
+		)
+	}
+
+	req := &orderapi.CreateOrderRequest{UserId: 1, Address: "Beijing", IdempotencyKey: "key-1"}
+
+	t.Run("replays the first successful response", func(t *testing.T) {
+		first, err := create(req, true)
+		require.NoError(t, err)
+		require.True(t, first.Success)
+
+		replay, err := create(req, true)
+		require.NoError(t, err)
+		assert.True(t, replay.Success)
+		assert.Equal(t, first.OrderId, replay.OrderId)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("rejects reuse with a different request", func(t *testing.T) {
+		resp, err := create(&orderapi.CreateOrderRequest{UserId: 1, Address: "Shanghai", IdempotencyKey: "key-1"}, true)
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Equal(t, "Idempotency key was used with a different request", resp.ErrorMessage)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("failed requests can be retried with the same key", func(t *testing.T) {
+		retryReq := &orderapi.CreateOrderRequest{UserId: 2, IdempotencyKey: "key-2"}
+
+		resp, err := create(retryReq, false)
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+
+		resp, err = create(retryReq, true)
+		require.NoError(t, err)
+		assert.True(t, resp.Success)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("keys are scoped to the caller", func(t *testing.T) {
+		otherCtx := ContextWithCaller(ctx, Caller{UserID: 2})
+		resp, err := idempotent(otherCtx, service, idempotencyScopeCreateOrder, req.IdempotencyKey, req, rejected,
+			func() (*orderapi.CreateOrderResponse, error) {
+				calls++
+				return &orderapi.CreateOrderResponse{Success: true, OrderId: int32(calls)}, nil
+			},
+		)
+		require.NoError(t, err)
+		assert.True(t, resp.Success)
+		assert.Equal(t, int32(4), resp.OrderId)
+		assert.Equal(t, 4, calls)
+	})
+}
@@ -3,38 +3,64 @@ package order
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"time"
 
+	authpb "github.com/bytedance-youthcamp/demo/api/auth"
 	cartpb "github.com/bytedance-youthcamp/demo/api/cart"
 	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	paymentpb "github.com/bytedance-youthcamp/demo/api/payment"
 	productpb "github.com/bytedance-youthcamp/demo/api/product"
+	promotionpb "github.com/bytedance-youthcamp/demo/api/promotion"
 	userpb "github.com/bytedance-youthcamp/demo/api/user"
+	"github.com/bytedance-youthcamp/demo/internal/money"
 	"github.com/bytedance-youthcamp/demo/internal/repository"
 	// 添加日志导入
 )
 
+// orderPaymentTimeout 订单创建后默认的等待支付时间，超时未支付的订单会被自动取消
+const orderPaymentTimeout = 30 * time.Minute
+
 type orderService struct {
 	orderapi.UnimplementedOrderServiceServer
 	productClient productpb.ProductServiceClient
 	cartClient    cartpb.CartServiceClient
 	userClient    userpb.UserServiceClient
+	// 支付服务客户端，用于取消已支付订单的商品时退款
+	paymentClient paymentpb.PaymentServiceClient
+	// 促销服务客户端，用于下单时使用优惠券
+	promotionClient promotionpb.PromotionServiceClient
+	authClient      authpb.AuthServiceClient
+	// 内部服务的服务令牌，键为服务名
+	serviceTokens map[string]string
 	orderRepo     repository.OrderRepository
-	db            *sql.DB
+	shipmentRepo  repository.ShipmentRepository
+	// 幂等键存储，为 nil 时忽略请求中的幂等键
+	idempotencyRepo repository.IdempotencyRepository
+	db              *sql.DB
+	// 订单金额保留的小数位数
+	pricePrecision int
+	// 订单创建后等待支付的时间
+	autoCancelTimeout time.Duration
+	// 定价流水线，在商品小计和优惠券之后计算税费和运费
+	priceCalculators []PriceCalculator
 }
 
 func NewOrderService(opts ...Option) (*orderService, error) {
-	service := &orderService{}
+	service := &orderService{
+		pricePrecision:    defaultPricePrecision,
+		autoCancelTimeout: orderPaymentTimeout,
+	}
 
 	for _, opt := range opts {
 		opt(service)
 	}
 
-	// 启动自动取消订单的定时任务
-	// 每5分钟检查一次，取消超过30分钟未支付的订单
-	service.StartOrderCancellationTask(5*time.Minute, 30*time.Minute)
+	// 启动自动取消订单的定时任务，取消已到自动取消时间仍未支付的订单
+	service.StartOrderCancellationTask(autoCancelCheckInterval)
 
 	return service, nil
 }
@@ -71,7 +97,24 @@ func WithTestDatabase(db *sql.DB) Option {
 	}
 }
 
+// CreateOrder 创建订单，设置幂等键时重试返回首次成功创建的订单，不会重复创建
 func (s *orderService) CreateOrder(ctx context.Context, req *orderapi.CreateOrderRequest) (*orderapi.CreateOrderResponse, error) {
+	// 用户只能为自己下单
+	if err := s.authorizeOwner(ctx, ownerOf(req.UserId), permissionWrite); err != nil {
+		return nil, err
+	}
+
+	return idempotent(ctx, s, idempotencyScopeCreateOrder, req.IdempotencyKey, req,
+		func(errorMessage string) *orderapi.CreateOrderResponse {
+			return &orderapi.CreateOrderResponse{Success: false, ErrorMessage: errorMessage}
+		},
+		func() (*orderapi.CreateOrderResponse, error) {
+			return s.createOrder(ctx, req)
+		},
+	)
+}
+
+func (s *orderService) createOrder(ctx context.Context, req *orderapi.CreateOrderRequest) (*orderapi.CreateOrderResponse, error) {
 	// 验证用户信息，在测试环境中允许跳过
 	if s.userClient != nil {
 		_, err := s.userClient.GetUserInfo(ctx, &userpb.GetUserInfoRequest{UserId: req.UserId})
@@ -83,29 +126,80 @@ func (s *orderService) CreateOrder(ctx context.Context, req *orderapi.CreateOrde
 		}
 	}
 
-	// 准备订单项目
-	orderItems := make([]*repository.OrderItem, len(req.Items))
-	for i, item := range req.Items {
-		orderItems[i] = &repository.OrderItem{
-			ProductID:   strconv.Itoa(int(item.ProductId)),
-			ProductName: item.ProductName,
-			Quantity:    int32(item.Quantity),
-			Price:       item.Price,
+	// 以商品服务的名称和价格为准生成订单项目并重新计算总价
+	orderItems, totalAmount, errorMessage, err := s.priceOrderItems(ctx, req)
+	if err != nil {
+		var mismatch *PriceMismatchError
+		if errors.As(err, &mismatch) {
+			return &orderapi.CreateOrderResponse{
+				Success:       false,
+				ErrorMessage:  "Order total does not match current prices",
+				PriceMismatch: mismatch.toAPI(),
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to price order: %w", err)
+	}
+	if errorMessage != "" {
+		return &orderapi.CreateOrderResponse{
+			Success:      false,
+			ErrorMessage: errorMessage,
+		}, nil
+	}
+
+	// 使用优惠券，客户端提交的总价为优惠前的原价合计
+	discount := money.Zero(totalAmount.Currency)
+	var redemptionID string
+	if req.CouponCode != "" {
+		redemptionID, discount, errorMessage, err = s.applyCoupon(ctx, req.UserId, req.CouponCode, orderItems)
+		if err != nil {
+			return nil, err
+		}
+		if errorMessage != "" {
+			return &orderapi.CreateOrderResponse{
+				Success:      false,
+				ErrorMessage: errorMessage,
+			}, nil
 		}
 	}
 
 	// 创建订单
+	now := time.Now()
+	autoCancelAt := now.Add(s.autoCancelTimeout)
 	order := &repository.Order{
-		UserID:      strconv.Itoa(int(req.UserId)),
-		Status:      repository.OrderStatusPending,
-		Items:       orderItems,
-		TotalAmount: req.TotalPrice,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		UserID:             strconv.Itoa(int(req.UserId)),
+		Status:             repository.OrderStatusPending,
+		Items:              orderItems,
+		ShippingAddress:    req.Address,
+		ContactName:        req.ContactName,
+		ContactPhone:       req.ContactPhone,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+		AutoCancelAt:       &autoCancelAt,
+		CouponCode:         req.CouponCode,
+		CouponRedemptionID: redemptionID,
+		DiscountAmount:     discount,
 	}
 
+	// 计算税费和运费
+	quote := newPriceQuote(req.Region, orderItems, totalAmount, discount)
+	if err := s.calculatePrice(ctx, quote); err != nil {
+		// 订单未创建，归还占用的优惠券
+		s.releaseCoupon(ctx, order)
+		var pricingErr *PricingError
+		if errors.As(err, &pricingErr) {
+			return &orderapi.CreateOrderResponse{
+				Success:      false,
+				ErrorMessage: pricingErr.Message,
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to calculate order price: %w", err)
+	}
+	setOrderPrice(order, quote)
+
 	// 保存订单
 	if err := s.orderRepo.Create(ctx, order); err != nil {
+		// 订单未创建，归还占用的优惠券
+		s.releaseCoupon(ctx, order)
 		return &orderapi.CreateOrderResponse{
 			Success:       false,
 			ErrorMessage: "Failed to create order",
@@ -116,12 +210,34 @@ func (s *orderService) CreateOrder(ctx context.Context, req *orderapi.CreateOrde
 	orderID, _ := strconv.Atoi(order.ID)
 
 	return &orderapi.CreateOrderResponse{
-		Success:  true,
-		OrderId: int32(orderID),
+		Success:        true,
+		OrderId:        int32(orderID),
+		AutoCancelTime: autoCancelAt.UnixMilli(),
+		TotalMoney:     toAPIMoney(order.TotalAmount),
+		DiscountMoney:  toAPIMoney(discount),
+		PriceBreakdown: toAPIPriceBreakdown(quote, order.CouponCode),
 	}, nil
 }
 
+// SettleOrder 结算订单，设置幂等键时超时后的重试返回首次成功结算的结果，
+// 处理中的请求会拒绝相同幂等键的并发重试，避免重复扣减库存
 func (s *orderService) SettleOrder(ctx context.Context, req *orderapi.SettleOrderRequest) (*orderapi.SettleOrderResponse, error) {
+	// 订单由支付服务在确认支付后结算，用户不能直接结算
+	if err := s.authorizeAction(ctx, permissionWrite); err != nil {
+		return nil, err
+	}
+
+	return idempotent(ctx, s, idempotencyScopeSettleOrder, req.IdempotencyKey, req,
+		func(errorMessage string) *orderapi.SettleOrderResponse {
+			return &orderapi.SettleOrderResponse{Success: false, ErrorMessage: errorMessage}
+		},
+		func() (*orderapi.SettleOrderResponse, error) {
+			return s.settleOrder(ctx, req)
+		},
+	)
+}
+
+func (s *orderService) settleOrder(ctx context.Context, req *orderapi.SettleOrderRequest) (*orderapi.SettleOrderResponse, error) {
 	// 获取订单
 	order, err := s.orderRepo.Get(ctx, fmt.Sprint(req.OrderId))
 	if err != nil {
@@ -131,94 +247,74 @@ func (s *orderService) SettleOrder(ctx context.Context, req *orderapi.SettleOrde
 		}, nil
 	}
 
-	// 检查订单状态
-	if order.Status != repository.OrderStatusPending {
+	// 检查订单状态，支付失败的订单可以重新支付
+	if !canTransition(order.Status, repository.OrderStatusPaid) {
 		return &orderapi.SettleOrderResponse{
 			Success:       false,
 			ErrorMessage: "Order cannot be settled",
 		}, nil
 	}
 
+	// 支付金额必须等于订单当前的应付金额，订单修改商品前创建的支付不能结算
+	if req.AmountMoney == nil || money.New(req.AmountMoney.Amount, req.AmountMoney.Currency) != order.TotalAmount {
+		return &orderapi.SettleOrderResponse{
+			Success:      false,
+			ErrorMessage: "Payment amount does not match order total",
+		}, nil
+	}
+
 	// 在测试环境中，我们可能没有产品服务客户端，但有直接的数据库连接
 	if s.productClient != nil {
-		// 使用产品服务客户端减少库存
-		for _, item := range order.Items {
+		// 使用产品服务按订单原子扣减库存，订单ID保证重试不会重复扣减
+		items := make([]*productpb.StockItem, len(order.Items))
+		for i, item := range order.Items {
 			productID, _ := strconv.Atoi(item.ProductID)
-			
-			// 先获取产品信息
-			getResp, err := s.productClient.GetProduct(ctx, &productpb.GetProductRequest{
+			items[i] = &productpb.StockItem{
 				ProductId: int32(productID),
-			})
-			if err != nil || !getResp.Success {
-				return &orderapi.SettleOrderResponse{
-					Success:      false,
-					ErrorMessage: "Failed to get product information",
-				}, nil
+				Quantity:  item.Quantity,
 			}
-			
-			// 计算新的库存
-			newStock := getResp.Product.Stock - int32(item.Quantity)
-			if newStock < 0 {
-				return &orderapi.SettleOrderResponse{
-					Success:      false,
-					ErrorMessage: "Insufficient stock",
-				}, nil
-			}
-			
-			// 更新产品库存
-			_, err = s.productClient.UpdateProduct(ctx, &productpb.UpdateProductRequest{
-				ProductId: int32(productID),
-				Name:      getResp.Product.Name,
-				Stock:     newStock,
-				Price:     getResp.Product.Price,
-				Category:  getResp.Product.Category,
-				ImageUrl:  getResp.Product.ImageUrl,
-			})
-			if err != nil {
-				return &orderapi.SettleOrderResponse{
-					Success:      false,
-					ErrorMessage: "Failed to reduce stock",
-				}, nil
+		}
+
+		reduceResp, err := s.productClient.ReduceStock(ctx, &productpb.ReduceStockRequest{
+			OrderId: req.OrderId,
+			Items:   items,
+		})
+		if err != nil {
+			return &orderapi.SettleOrderResponse{
+				Success:      false,
+				ErrorMessage: "Failed to reduce stock",
+			}, nil
+		}
+		if !reduceResp.Success {
+			errorMessage := "Failed to reduce stock"
+			if reduceResp.FailedProductId != 0 {
+				errorMessage = "Insufficient stock"
 			}
+			return &orderapi.SettleOrderResponse{
+				Success:      false,
+				ErrorMessage: errorMessage,
+			}, nil
 		}
 	} else if s.db != nil {
-		// 在测试环境中直接使用数据库连接更新库存
-		for _, item := range order.Items {
-			productID, _ := strconv.Atoi(item.ProductID)
-			
-			// 先获取当前库存
-			var currentStock int
-			err := s.db.QueryRow("SELECT stock FROM products WHERE id = ?", productID).Scan(&currentStock)
-			if err != nil {
-				return &orderapi.SettleOrderResponse{
-					Success:      false,
-					ErrorMessage: "Failed to get product information",
-				}, nil
-			}
-			
-			// 计算新的库存
-			newStock := currentStock - int(item.Quantity)
-			if newStock < 0 {
-				return &orderapi.SettleOrderResponse{
-					Success:      false,
-					ErrorMessage: "Insufficient stock",
-				}, nil
-			}
-			
-			// 更新产品库存
-			_, err = s.db.Exec("UPDATE products SET stock = ? WHERE id = ?", newStock, productID)
-			if err != nil {
-				return &orderapi.SettleOrderResponse{
-					Success:      false,
-					ErrorMessage: "Failed to reduce stock",
-				}, nil
-			}
+		// 在测试环境中直接使用数据库连接条件扣减库存
+		errorMessage, err := s.reduceStockInDB(ctx, order.Items)
+		if err != nil {
+			errorMessage = "Failed to reduce stock"
+		}
+		if errorMessage != "" {
+			return &orderapi.SettleOrderResponse{
+				Success:      false,
+				ErrorMessage: errorMessage,
+			}, nil
 		}
 	}
 
 	// 更新订单状态
-	order.Status = repository.OrderStatusPaid
-	if err := s.orderRepo.Update(ctx, order); err != nil {
+	actor := actorPayment
+	if req.UserId > 0 {
+		actor = userActor(req.UserId)
+	}
+	if err := s.transition(ctx, order, repository.OrderStatusPaid, actor, "paid via "+req.PaymentMethod); err != nil {
 		return &orderapi.SettleOrderResponse{
 			Success:      false,
 			ErrorMessage: "Failed to update order status",
@@ -232,6 +328,46 @@ func (s *orderService) SettleOrder(ctx context.Context, req *orderapi.SettleOrde
 	}, nil
 }
 
+// reduceStockInDB 在一个事务中对订单的每个商品做条件扣减，任一商品库存不足则整体回滚
+func (s *orderService) reduceStockInDB(ctx context.Context, items []*repository.OrderItem) (string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, item := range items {
+		productID, _ := strconv.Atoi(item.ProductID)
+
+		result, err := tx.ExecContext(ctx,
+			"UPDATE products SET stock = stock - ? WHERE id = ? AND stock >= ?",
+			item.Quantity, productID, item.Quantity)
+		if err != nil {
+			return "", fmt.Errorf("failed to reduce stock: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return "", fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if affected == 0 {
+			var exists bool
+			if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM products WHERE id = ?)", productID).Scan(&exists); err != nil {
+				return "", fmt.Errorf("failed to check product existence: %w", err)
+			}
+			if !exists {
+				return "Failed to get product information", nil
+			}
+			return "Insufficient stock", nil
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return "", nil
+}
+
 func (s *orderService) GetOrderDetails(ctx context.Context, req *orderapi.GetOrderDetailsRequest) (*orderapi.GetOrderDetailsResponse, error) {
 	// 获取订单
 	order, err := s.orderRepo.Get(ctx, fmt.Sprint(req.OrderId))
@@ -243,40 +379,13 @@ func (s *orderService) GetOrderDetails(ctx context.Context, req *orderapi.GetOrd
 	}
 
 	// 检查用户权限
-	if order.UserID != strconv.Itoa(int(req.UserId)) {
-		return &orderapi.GetOrderDetailsResponse{
-			Success:      false,
-			ErrorMessage: "Unauthorized access",
-		}, nil
+	if err := s.authorizeOwner(ctx, order.UserID, permissionRead); err != nil {
+		return nil, err
 	}
 
-	// 转换订单项目
-	pbItems := make([]*orderapi.OrderItem, len(order.Items))
-	for i, item := range order.Items {
-		productID, _ := strconv.Atoi(item.ProductID)
-		pbItems[i] = &orderapi.OrderItem{
-			ProductId:   int32(productID),
-			ProductName: item.ProductName,
-			Quantity:    int32(item.Quantity),
-			Price:       item.Price,
-		}
-	}
-
-	// 转换订单ID
-	orderID, _ := strconv.Atoi(order.ID)
-	userID, _ := strconv.Atoi(order.UserID)
-
 	return &orderapi.GetOrderDetailsResponse{
 		Success: true,
-		Order: &orderapi.Order{
-			Id:          int32(orderID),
-			UserId:      int32(userID),
-			Status:      orderapi.OrderStatus(order.Status),
-			TotalAmount: order.TotalAmount,
-			Items:       pbItems,
-			CreatedAt:   order.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:   order.UpdatedAt.Format(time.RFC3339),
-		},
+		Order:   toAPIOrder(order),
 	}, nil
 }
 
@@ -289,55 +398,24 @@ func (s *orderService) GetOrder(ctx context.Context, req *orderapi.GetOrderReque
 			ErrorMessage: "Order not found",
 		}, nil
 	}
-
-	// 转换订单项目
-	pbItems := make([]*orderapi.OrderItem, len(order.Items))
-	for i, item := range order.Items {
-		productID, _ := strconv.Atoi(item.ProductID)
-		pbItems[i] = &orderapi.OrderItem{
-			ProductId:   int32(productID),
-			ProductName: item.ProductName,
-			Quantity:    int32(item.Quantity),
-			Price:       item.Price,
-		}
+	if err := s.authorizeOwner(ctx, order.UserID, permissionRead); err != nil {
+		return nil, err
 	}
 
-	// 转换订单ID
-	orderID, _ := strconv.Atoi(order.ID)
-	userID, _ := strconv.Atoi(order.UserID)
-
 	return &orderapi.GetOrderResponse{
-		Order: &orderapi.Order{
-			Id:          int32(orderID),
-			UserId:      int32(userID),
-			Status:      orderapi.OrderStatus(order.Status),
-			TotalAmount: order.TotalAmount,
-			Items:       pbItems,
-			CreatedAt:   order.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:   order.UpdatedAt.Format(time.RFC3339),
-		},
+		Order:   toAPIOrder(order),
 		Success: true,
 	}, nil
 }
 
 func (s *orderService) GetUserOrders(ctx context.Context, req *orderapi.GetUserOrdersRequest) (*orderapi.GetUserOrdersResponse, error) {
-	// 转换状态
-	var repoStatus repository.OrderStatus
-	switch req.Status {
-	case orderapi.OrderStatus_PENDING:
-		repoStatus = repository.OrderStatusPending
-	case orderapi.OrderStatus_PAID:
-		repoStatus = repository.OrderStatusPaid
-	case orderapi.OrderStatus_SHIPPING:
-		repoStatus = repository.OrderStatusShipped
-	case orderapi.OrderStatus_COMPLETED:
-		repoStatus = repository.OrderStatusDelivered
-	case orderapi.OrderStatus_CANCELLED:
-		repoStatus = repository.OrderStatusCancelled
-	default:
-		repoStatus = repository.OrderStatusPending
+	if err := s.authorizeOwner(ctx, ownerOf(req.UserId), permissionRead); err != nil {
+		return nil, err
 	}
 
+	// 转换状态
+	repoStatus := toRepoStatus(req.Status)
+
 	// 获取用户订单
 	orders, total, err := s.orderRepo.GetUserOrders(ctx, strconv.Itoa(int(req.UserId)), int(req.Page), int(req.PageSize), repoStatus)
 	if err != nil {
@@ -350,30 +428,7 @@ func (s *orderService) GetUserOrders(ctx context.Context, req *orderapi.GetUserO
 	// 转换订单
 	pbOrders := make([]*orderapi.Order, len(orders))
 	for i, order := range orders {
-		pbItems := make([]*orderapi.OrderItem, len(order.Items))
-		for j, item := range order.Items {
-			productID, _ := strconv.Atoi(item.ProductID)
-			pbItems[j] = &orderapi.OrderItem{
-				ProductId:   int32(productID),
-				ProductName: item.ProductName,
-				Quantity:    item.Quantity,
-				Price:       item.Price,
-			}
-		}
-
-		orderID, _ := strconv.Atoi(order.ID)
-		userID, _ := strconv.Atoi(order.UserID)
-
-		pbOrders[i] = &orderapi.Order{
-			Id:          int32(orderID),
-			UserId:      int32(userID),
-			Items:       pbItems,
-			TotalAmount: order.TotalAmount,
-			Status:      orderapi.OrderStatus(order.Status),
-			Address:     order.ShippingAddress,
-			CreatedAt:   order.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:   order.UpdatedAt.Format(time.RFC3339),
-		}
+		pbOrders[i] = toAPIOrder(order)
 	}
 
 	return &orderapi.GetUserOrdersResponse{
@@ -392,27 +447,52 @@ func (s *orderService) UpdateOrder(ctx context.Context, req *orderapi.UpdateOrde
 			ErrorMessage: "Order not found",
 		}, nil
 	}
+	if err := s.authorizeOwner(ctx, order.UserID, permissionWrite); err != nil {
+		return nil, err
+	}
 
-	// 更新订单信息
-	order.ShippingAddress = req.Address
+	// 更新订单信息，未填写的字段保持不变
+	if req.Address != "" {
+		order.ShippingAddress = req.Address
+	}
+	if req.ContactName != "" {
+		order.ContactName = req.ContactName
+	}
+	if req.ContactPhone != "" {
+		order.ContactPhone = req.ContactPhone
+	}
 	order.UpdatedAt = time.Now()
 
-	// 转换状态
-	switch req.Status {
-	case orderapi.OrderStatus_PENDING:
-		order.Status = repository.OrderStatusPending
-	case orderapi.OrderStatus_PAID:
-		order.Status = repository.OrderStatusPaid
-	case orderapi.OrderStatus_SHIPPING:
-		order.Status = repository.OrderStatusShipped
-	case orderapi.OrderStatus_COMPLETED:
-		order.Status = repository.OrderStatusDelivered
-	case orderapi.OrderStatus_CANCELLED:
-		order.Status = repository.OrderStatusCancelled
-	}
-
-	// 保存更新
-	if err := s.orderRepo.Update(ctx, order); err != nil {
+	// 状态变更必须经过状态机，只有运营人员可以直接修改订单状态
+	target := toRepoStatus(req.Status)
+	changeStatus := req.Status != orderapi.OrderStatus_PENDING && target != order.Status
+	if changeStatus {
+		if err := s.authorizeAction(ctx, permissionWrite); err != nil {
+			return nil, err
+		}
+	}
+
+	// 状态变更只保存状态，先单独保存订单信息
+	if err := s.orderRepo.UpdateContact(ctx, order); err != nil {
+		return &orderapi.UpdateOrderResponse{
+			Success:      false,
+			ErrorMessage: "Failed to update order",
+		}, nil
+	}
+	if !changeStatus {
+		return &orderapi.UpdateOrderResponse{
+			Success: true,
+		}, nil
+	}
+
+	if err := s.transition(ctx, order, target, actorOperator, "order updated"); err != nil {
+		var invalid *InvalidTransitionError
+		if errors.As(err, &invalid) {
+			return &orderapi.UpdateOrderResponse{
+				Success:      false,
+				ErrorMessage: invalid.Error(),
+			}, nil
+		}
 		return &orderapi.UpdateOrderResponse{
 			Success:      false,
 			ErrorMessage: "Failed to update order",
@@ -433,9 +513,12 @@ func (s *orderService) CancelOrder(ctx context.Context, req *orderapi.CancelOrde
 			ErrorMessage: "Order not found",
 		}, nil
 	}
+	if err := s.authorizeOwner(ctx, order.UserID, permissionWrite); err != nil {
+		return nil, err
+	}
 
 	// 检查订单状态是否允许取消
-	if order.Status != repository.OrderStatusPending && order.Status != repository.OrderStatusPaid {
+	if !canTransition(order.Status, repository.OrderStatusCancelled) {
 		return &orderapi.CancelOrderResponse{
 			Success:      false,
 			ErrorMessage: "Order cannot be cancelled",
@@ -443,11 +526,8 @@ func (s *orderService) CancelOrder(ctx context.Context, req *orderapi.CancelOrde
 	}
 
 	// 更新订单状态
-	order.Status = repository.OrderStatusCancelled
-	order.UpdatedAt = time.Now()
-
-	// 保存更新
-	if err := s.orderRepo.Update(ctx, order); err != nil {
+	order.CancelReason = req.CancelReason
+	if err := s.transition(ctx, order, repository.OrderStatusCancelled, callerActor(ctx), req.CancelReason); err != nil {
 		return &orderapi.CancelOrderResponse{
 			Success:      false,
 			ErrorMessage: "Failed to cancel order",
@@ -459,48 +539,102 @@ func (s *orderService) CancelOrder(ctx context.Context, req *orderapi.CancelOrde
 	}, nil
 }
 
-func (s *orderService) scheduleOrderCancellation(orderID string, minutes int) {
-	time.Sleep(time.Duration(minutes) * time.Minute)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// MarkOrderPaymentFailed 记录支付失败，订单保持可支付状态，重复通知不会产生副作用
+func (s *orderService) MarkOrderPaymentFailed(ctx context.Context, req *orderapi.MarkOrderPaymentFailedRequest) (*orderapi.MarkOrderPaymentFailedResponse, error) {
+	if err := s.authorizeAction(ctx, permissionWrite); err != nil {
+		return nil, err
+	}
 
-	order, err := s.orderRepo.Get(ctx, orderID)
+	// 获取订单
+	order, err := s.orderRepo.Get(ctx, fmt.Sprint(req.OrderId))
 	if err != nil {
-		log.Printf("Error retrieving order %s: %v", orderID, err)
-		return
+		return &orderapi.MarkOrderPaymentFailedResponse{
+			Success:      false,
+			ErrorMessage: "Order not found",
+		}, nil
 	}
 
-	if order.Status == repository.OrderStatusPending {
-		order.Status = repository.OrderStatusCancelled
-		order.UpdatedAt = time.Now()
+	// 重复通知直接返回成功
+	if order.Status == repository.OrderStatusPaymentFailed {
+		return &orderapi.MarkOrderPaymentFailedResponse{
+			Success: true,
+		}, nil
+	}
 
-		if err := s.orderRepo.Update(ctx, order); err != nil {
-			log.Printf("Error cancelling order %s: %v", orderID, err)
-		}
+	// 只有待支付的订单可以标记为支付失败
+	if !canTransition(order.Status, repository.OrderStatusPaymentFailed) {
+		return &orderapi.MarkOrderPaymentFailedResponse{
+			Success:      false,
+			ErrorMessage: "Order cannot be marked as payment failed",
+		}, nil
 	}
-}
 
-func (s *orderService) StartOrderCancellationTask(interval, timeout time.Duration) {
-	ticker := time.NewTicker(interval)
-	go func() {
-		for range ticker.C {
-			ctx := context.Background()
-			orders, err := s.orderRepo.ListPendingOrdersOlderThan(ctx, timeout)
-			if err != nil {
-				log.Printf("Error fetching pending orders: %v", err)
-				continue
-			}
+	if err := s.transition(ctx, order, repository.OrderStatusPaymentFailed, actorPayment, req.Reason); err != nil {
+		return &orderapi.MarkOrderPaymentFailedResponse{
+			Success:      false,
+			ErrorMessage: "Failed to update order status",
+		}, nil
+	}
 
-			for _, order := range orders {
-				order.Status = repository.OrderStatusCancelled
-				order.UpdatedAt = time.Now()
+	log.Printf("Order %d payment %s failed: %s", req.OrderId, req.PaymentId, req.Reason)
 
-				if err := s.orderRepo.Update(ctx, order); err != nil {
-					log.Printf("Error cancelling order %s: %v", order.ID, err)
-				} else {
-					log.Printf("Auto-cancelled order %s due to timeout", order.ID)
-				}
-			}
+	return &orderapi.MarkOrderPaymentFailedResponse{
+		Success: true,
+	}, nil
+}
+
+// toAPIOrder 将仓储层的订单转换为 API 订单
+func toAPIOrder(order *repository.Order) *orderapi.Order {
+	pbItems := make([]*orderapi.OrderItem, len(order.Items))
+	for i, item := range order.Items {
+		productID, _ := strconv.Atoi(item.ProductID)
+		pbItems[i] = &orderapi.OrderItem{
+			ProductId:         int32(productID),
+			ProductName:       item.ProductName,
+			Quantity:          item.Quantity,
+			Price:             item.Price.Float64(),
+			PriceMoney:        toAPIMoney(item.Price),
+			Category:          item.Category,
+			CancelledQuantity: item.CancelledQuantity,
+			DiscountMoney:     toAPIMoney(item.Discount),
 		}
-	}()
+	}
+
+	orderID, _ := strconv.Atoi(order.ID)
+	userID, _ := strconv.Atoi(order.UserID)
+
+	pbOrder := &orderapi.Order{
+		Id:           int32(orderID),
+		UserId:       int32(userID),
+		Items:        pbItems,
+		TotalAmount:  order.TotalAmount.Float64(),
+		TotalMoney:   toAPIMoney(order.TotalAmount),
+		Status:       toAPIStatus(order.Status),
+		Address:      order.ShippingAddress,
+		ContactName:  order.ContactName,
+		ContactPhone: order.ContactPhone,
+		CreatedAt:    order.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    order.UpdatedAt.Format(time.RFC3339),
+		PaidAt:       formatTime(order.PaidAt),
+		CancelledAt:  formatTime(order.CancelledAt),
+		CompletedAt:  formatTime(order.CompletedAt),
+		CancelReason: order.CancelReason,
+		CouponCode:   order.CouponCode,
+		// 旧订单没有优惠金额，币种与订单相同
+		DiscountMoney:  toAPIMoney(money.New(order.DiscountAmount.Amount, order.TotalAmount.Currency)),
+		Region:         order.Region,
+		PriceBreakdown: toAPIPriceBreakdown(orderPriceQuote(order), order.CouponCode),
+	}
+	if order.AutoCancelAt != nil {
+		pbOrder.AutoCancelTime = order.AutoCancelAt.UnixMilli()
+	}
+
+	return pbOrder
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
 }
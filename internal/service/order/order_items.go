@@ -0,0 +1,481 @@
+package order
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	paymentpb "github.com/bytedance-youthcamp/demo/api/payment"
+	productpb "github.com/bytedance-youthcamp/demo/api/product"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+)
+
+// itemChangeError 表示订单项变更请求不合法，错误信息直接返回给调用方
+type itemChangeError struct {
+	message string
+}
+
+func (e *itemChangeError) Error() string {
+	return e.message
+}
+
+// WithPaymentClient 注入支付服务客户端，取消已支付订单的商品时通过支付服务退款，未注入时不能取消已支付订单的商品
+func WithPaymentClient(client paymentpb.PaymentServiceClient) Option {
+	return func(s *orderService) {
+		s.paymentClient = client
+	}
+}
+
+// UpdateOrderItems 修改待支付订单的商品数量，数量为 0 时删除商品，只能修改订单中已有的商品。
+// 所有商品按当前价格重新计价，订单有预留库存时同步调整预留数量
+func (s *orderService) UpdateOrderItems(ctx context.Context, req *orderapi.UpdateOrderItemsRequest) (*orderapi.UpdateOrderItemsResponse, error) {
+	// 获取订单
+	order, err := s.orderRepo.Get(ctx, fmt.Sprint(req.OrderId))
+	if err != nil {
+		return &orderapi.UpdateOrderItemsResponse{
+			Success:      false,
+			ErrorMessage: "Order not found",
+		}, nil
+	}
+	if err := s.authorizeOwner(ctx, order.UserID, permissionWrite); err != nil {
+		return nil, err
+	}
+
+	quantities, errorMessage := itemQuantities(order, req.Items, true)
+	if errorMessage == "" && !modifiable(order.Status) {
+		errorMessage = "Order items cannot be modified"
+	}
+	if errorMessage == "" && order.CouponCode != "" {
+		// 优惠按下单时的商品分摊，修改商品后无法保持一致
+		errorMessage = "Items of an order with a coupon cannot be modified"
+	}
+	if errorMessage != "" {
+		return &orderapi.UpdateOrderItemsResponse{
+			Success:      false,
+			ErrorMessage: errorMessage,
+		}, nil
+	}
+
+	// 按当前价格重新计价
+	productIDs := make([]int32, 0, len(order.Items))
+	for _, item := range order.Items {
+		if quantity, ok := quantities[item.ProductID]; !ok || quantity > 0 {
+			id, _ := strconv.Atoi(item.ProductID)
+			productIDs = append(productIDs, int32(id))
+		}
+	}
+	var products map[int32]productSnapshot
+	if len(productIDs) > 0 {
+		if products, err = s.loadProducts(ctx, productIDs); err != nil {
+			return nil, fmt.Errorf("failed to price order items: %w", err)
+		}
+	}
+
+	now := time.Now()
+	items, changes, total, errorMessage := changeItems(order, quantities, products, callerActor(ctx), req.Reason, now)
+	if errorMessage != "" {
+		return &orderapi.UpdateOrderItemsResponse{
+			Success:      false,
+			ErrorMessage: errorMessage,
+		}, nil
+	}
+
+	// 按修改后的商品重新计算税费和运费，使用优惠券的订单不能修改商品
+	quote := newPriceQuote(order.Region, items, total, money.Zero(total.Currency))
+	if err := s.calculatePrice(ctx, quote); err != nil {
+		var pricingErr *PricingError
+		if errors.As(err, &pricingErr) {
+			return &orderapi.UpdateOrderItemsResponse{
+				Success:      false,
+				ErrorMessage: pricingErr.Message,
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to calculate order price: %w", err)
+	}
+
+	// 关闭按修改前金额创建的支付，订单已支付时不能修改商品
+	if len(changes) > 0 {
+		errorMessage, err := s.cancelOrderPayment(ctx, req.OrderId)
+		if err != nil {
+			return nil, err
+		}
+		if errorMessage != "" {
+			return &orderapi.UpdateOrderItemsResponse{
+				Success:      false,
+				ErrorMessage: errorMessage,
+			}, nil
+		}
+	}
+
+	// 在锁定订单前调整预留库存，库存不足时不修改订单；订单保存失败时恢复为修改前的数量
+	oldItems := order.Items
+	restoreItems := oldItems
+	adjusted := false
+	if len(changes) > 0 {
+		errorMessage, err := s.adjustReservation(ctx, order.ID, items)
+		if err != nil {
+			return nil, err
+		}
+		if errorMessage != "" {
+			return &orderapi.UpdateOrderItemsResponse{
+				Success:      false,
+				ErrorMessage: errorMessage,
+			}, nil
+		}
+		adjusted = true
+	}
+
+	updated, err := s.orderRepo.UpdateItems(ctx, order.ID, func(ctx context.Context, locked *repository.Order) ([]*repository.OrderItemChange, error) {
+		// 加锁后重新检查订单，订单可能已被支付、取消或修改了商品，此时预留库存恢复为订单当前的商品
+		restoreItems = locked.Items
+		if !modifiable(locked.Status) {
+			return nil, &itemChangeError{"Order items cannot be modified"}
+		}
+		if !sameItems(locked.Items, oldItems) {
+			return nil, &itemChangeError{"Order items have been modified, please retry"}
+		}
+
+		locked.Items = items
+		setOrderPrice(locked, quote)
+		locked.UpdatedAt = now
+		return changes, nil
+	})
+	if err != nil {
+		if adjusted {
+			s.restoreReservation(ctx, order.ID, restoreItems)
+		}
+
+		var changeErr *itemChangeError
+		if errors.As(err, &changeErr) {
+			return &orderapi.UpdateOrderItemsResponse{
+				Success:      false,
+				ErrorMessage: changeErr.message,
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to update order items: %w", err)
+	}
+
+	return &orderapi.UpdateOrderItemsResponse{
+		Success: true,
+		Order:   toAPIOrder(updated),
+	}, nil
+}
+
+// changeItems 按修改后的数量和当前价格生成订单的新商品列表和变更记录，返回新的商品金额合计。
+// 返回的错误信息非空表示修改不合法
+func changeItems(order *repository.Order, quantities map[string]int32, products map[int32]productSnapshot, actor, reason string, now time.Time) ([]*repository.OrderItem, []*repository.OrderItemChange, money.Money, string) {
+	currency := order.TotalAmount.Currency
+	items := make([]*repository.OrderItem, 0, len(order.Items))
+	var changes []*repository.OrderItemChange
+	total := money.Zero(currency)
+	for _, item := range order.Items {
+		quantity, requested := quantities[item.ProductID]
+		if !requested {
+			quantity = item.Quantity
+		}
+
+		price := money.Zero(currency)
+		if quantity > 0 {
+			id, _ := strconv.Atoi(item.ProductID)
+			product, ok := products[int32(id)]
+			if !ok {
+				return nil, nil, total, fmt.Sprintf("Product %d not found", id)
+			}
+			if product.Price.Currency != currency {
+				return nil, nil, total, "Products must use the same currency"
+			}
+			price = product.Price
+			items = append(items, &repository.OrderItem{
+				ProductID:   item.ProductID,
+				ProductName: product.Name,
+				Category:    product.Category,
+				Quantity:    quantity,
+				Price:       price,
+			})
+			total, _ = total.Add(price.Mul(int64(quantity)))
+		}
+
+		if requested && quantity != item.Quantity {
+			changeType := repository.OrderItemChangeQuantity
+			if quantity == 0 {
+				changeType = repository.OrderItemChangeRemoved
+			}
+			amount, _ := price.Mul(int64(quantity)).Sub(item.Price.Mul(int64(item.Quantity)))
+			changes = append(changes, &repository.OrderItemChange{
+				ProductID:   item.ProductID,
+				Type:        changeType,
+				OldQuantity: item.Quantity,
+				NewQuantity: quantity,
+				Amount:      amount,
+				Actor:       actor,
+				Reason:      reason,
+				CreatedAt:   now,
+			})
+		}
+	}
+	if len(items) == 0 {
+		return nil, nil, total, "Order must contain at least one item"
+	}
+	return items, changes, total, ""
+}
+
+// sameItems 判断两个商品列表的商品和数量是否相同
+func sameItems(a, b []*repository.OrderItem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].ProductID != b[i].ProductID || a[i].Quantity != b[i].Quantity {
+			return false
+		}
+	}
+	return true
+}
+
+// cancelOrderPayment 通过支付服务关闭订单待支付的支付，未注入支付服务时视为成功。
+// 返回的错误信息非空表示支付已完成，不能关闭
+func (s *orderService) cancelOrderPayment(ctx context.Context, orderID int32) (string, error) {
+	if s.paymentClient == nil {
+		return "", nil
+	}
+
+	resp, err := s.paymentClient.CancelOrderPayment(ctx, &paymentpb.CancelOrderPaymentRequest{OrderId: orderID})
+	if err != nil {
+		return "", fmt.Errorf("failed to cancel order payment: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Sprintf("Order payment cannot be cancelled: %s", resp.ErrorMessage), nil
+	}
+	return "", nil
+}
+
+// cancellationRefundID 根据订单和取消前各商品的已取消数量生成取消商品的退款单号，
+// 同一次取消重试时单号相同，已取消数量变化后单号不同
+func cancellationRefundID(order *repository.Order, quantities map[string]int32) string {
+	var parts []string
+	for _, item := range order.Items {
+		if quantity, ok := quantities[item.ProductID]; ok {
+			parts = append(parts, fmt.Sprintf("%s:%d:%d", item.ProductID, item.CancelledQuantity, quantity))
+		}
+	}
+	sort.Strings(parts)
+
+	sum := sha256.Sum256([]byte("cancel:" + order.ID + ":" + strings.Join(parts, ",")))
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// restoreReservation 将订单的预留库存恢复为 items 中的数量，恢复失败时只记录日志
+func (s *orderService) restoreReservation(ctx context.Context, orderID string, items []*repository.OrderItem) {
+	errorMessage, err := s.adjustReservation(ctx, orderID, items)
+	if err == nil && errorMessage != "" {
+		err = errors.New(errorMessage)
+	}
+	if err != nil {
+		log.Printf("Failed to restore stock reservation of order %s: %v", orderID, err)
+	}
+}
+
+// errCancellationRecorded 表示相同退款单号的取消记录已经保存
+var errCancellationRecorded = errors.New("cancellation was already recorded")
+
+// CancelOrderItems 取消已支付订单中的部分商品：先通过支付服务退还取消商品的金额，再在锁定订单后检查并记录取消的数量。
+// 退款单号由订单和取消前的商品数量决定，记录失败后重试会得到同一笔退款而不会重复退款。
+// 退款成功后由 CompleteRefund 归还库存，取消全部商品时订单随退款完成变为已退款
+func (s *orderService) CancelOrderItems(ctx context.Context, req *orderapi.CancelOrderItemsRequest) (*orderapi.CancelOrderItemsResponse, error) {
+	// 获取订单
+	order, err := s.orderRepo.Get(ctx, fmt.Sprint(req.OrderId))
+	if err != nil {
+		return &orderapi.CancelOrderItemsResponse{
+			Success:      false,
+			ErrorMessage: "Order not found",
+		}, nil
+	}
+	if err := s.authorizeOwner(ctx, order.UserID, permissionWrite); err != nil {
+		return nil, err
+	}
+
+	quantities, errorMessage := itemQuantities(order, req.Items, false)
+	if errorMessage == "" && order.Status != repository.OrderStatusPaid {
+		// 已发货的商品不能取消，订单进入配送中后只能在送达后退款
+		errorMessage = "Order items cannot be cancelled"
+	}
+	if errorMessage == "" && s.paymentClient == nil {
+		errorMessage = "Refund is not available"
+	}
+	if errorMessage != "" {
+		return &orderapi.CancelOrderItemsResponse{
+			Success:      false,
+			ErrorMessage: errorMessage,
+		}, nil
+	}
+
+	// 退款时订单会进入退款中，必须在锁定订单之前请求退款
+	refundItems := make([]*paymentpb.RefundItem, len(req.Items))
+	for i, item := range req.Items {
+		refundItems[i] = &paymentpb.RefundItem{
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+		}
+	}
+	refundResp, err := s.paymentClient.RequestRefund(ctx, &paymentpb.RequestRefundRequest{
+		OrderId:  req.OrderId,
+		Items:    refundItems,
+		Reason:   req.Reason,
+		RefundId: cancellationRefundID(order, quantities),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to request refund: %w", err)
+	}
+	if !refundResp.Success {
+		return &orderapi.CancelOrderItemsResponse{
+			Success:      false,
+			ErrorMessage: fmt.Sprintf("Failed to request refund: %s", refundResp.ErrorMessage),
+		}, nil
+	}
+
+	actor := callerActor(ctx)
+	updated, err := s.orderRepo.UpdateItems(ctx, order.ID, func(ctx context.Context, order *repository.Order) ([]*repository.OrderItemChange, error) {
+		// 重试时退款可能已经记录过
+		recorded, err := s.orderRepo.ListItemChanges(ctx, order.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range recorded {
+			if change.RefundID == refundResp.RefundId {
+				return nil, errCancellationRecorded
+			}
+		}
+
+		// 加锁后重新检查订单，并发的取消可能已经取消了同样的商品
+		switch order.Status {
+		case repository.OrderStatusPaid, repository.OrderStatusRefunding, repository.OrderStatusRefunded:
+		default:
+			return nil, fmt.Errorf("order status changed to %s", order.Status)
+		}
+		for _, item := range order.Items {
+			if quantity, ok := quantities[item.ProductID]; ok && quantity > item.Quantity-item.CancelledQuantity {
+				return nil, fmt.Errorf("product %s has only %d items left to cancel", item.ProductID, item.Quantity-item.CancelledQuantity)
+			}
+		}
+
+		now := time.Now()
+		var changes []*repository.OrderItemChange
+		for _, item := range order.Items {
+			quantity, ok := quantities[item.ProductID]
+			if !ok {
+				continue
+			}
+			remaining := item.Quantity - item.CancelledQuantity
+			// 退款金额扣除取消的商品分摊到的优惠
+			amount, _ := itemDiscountShare(item, item.CancelledQuantity, quantity).Sub(item.Price.Mul(int64(quantity)))
+			item.CancelledQuantity += quantity
+			changes = append(changes, &repository.OrderItemChange{
+				ProductID:   item.ProductID,
+				Type:        repository.OrderItemChangeCancelled,
+				OldQuantity: remaining,
+				NewQuantity: remaining - quantity,
+				Amount:      amount,
+				RefundID:    refundResp.RefundId,
+				Actor:       actor,
+				Reason:      req.Reason,
+				CreatedAt:   now,
+			})
+		}
+		order.UpdatedAt = now
+		return changes, nil
+	})
+	if errors.Is(err, errCancellationRecorded) {
+		updated, err = s.orderRepo.Get(ctx, order.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record cancelled items for refund %s: %w", refundResp.RefundId, err)
+	}
+
+	return &orderapi.CancelOrderItemsResponse{
+		Success:  true,
+		Order:    toAPIOrder(updated),
+		RefundId: refundResp.RefundId,
+	}, nil
+}
+
+// modifiable 判断订单是否处于可以修改商品的待支付状态
+func modifiable(status repository.OrderStatus) bool {
+	return status == repository.OrderStatusPending || status == repository.OrderStatusPaymentFailed
+}
+
+// itemQuantities 校验请求中的商品并返回按商品 ID 索引的数量，商品必须在订单中且不能重复。
+// allowZero 为 true 时数量为修改后的数量，否则为要取消的数量，不能超过尚未取消的数量
+func itemQuantities(order *repository.Order, requested []*orderapi.OrderItemQuantity, allowZero bool) (map[string]int32, string) {
+	if len(requested) == 0 {
+		return nil, "No items specified"
+	}
+
+	items := make(map[string]*repository.OrderItem, len(order.Items))
+	for _, item := range order.Items {
+		items[item.ProductID] = item
+	}
+
+	quantities := make(map[string]int32, len(requested))
+	for _, req := range requested {
+		productID := strconv.Itoa(int(req.ProductId))
+		item, ok := items[productID]
+		if !ok {
+			return nil, fmt.Sprintf("Product %d is not in the order", req.ProductId)
+		}
+		if _, ok := quantities[productID]; ok {
+			return nil, fmt.Sprintf("Duplicate product %d", req.ProductId)
+		}
+		if req.Quantity < 0 || (!allowZero && req.Quantity == 0) {
+			return nil, "Invalid item quantity"
+		}
+		if !allowZero && req.Quantity > item.Quantity-item.CancelledQuantity {
+			return nil, fmt.Sprintf("Cancel quantity exceeds remaining quantity for product %d", req.ProductId)
+		}
+		quantities[productID] = req.Quantity
+	}
+	return quantities, ""
+}
+
+// adjustReservation 将订单的预留库存调整为 items 中的数量，订单没有预留库存时不做任何修改。
+// 返回的错误信息非空表示库存不足等业务错误
+func (s *orderService) adjustReservation(ctx context.Context, orderID string, items []*repository.OrderItem) (string, error) {
+	if s.productClient == nil {
+		return "", nil
+	}
+
+	id, err := strconv.Atoi(orderID)
+	if err != nil {
+		return "", fmt.Errorf("invalid order ID %s: %w", orderID, err)
+	}
+	stockItems := make([]*productpb.StockItem, len(items))
+	for i, item := range items {
+		productID, _ := strconv.Atoi(item.ProductID)
+		stockItems[i] = &productpb.StockItem{
+			ProductId: int32(productID),
+			Quantity:  item.Quantity,
+		}
+	}
+
+	resp, err := s.productClient.AdjustReservation(ctx, &productpb.AdjustReservationRequest{
+		OrderId: int32(id),
+		Items:   stockItems,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to adjust stock reservation: %w", err)
+	}
+	if !resp.Success {
+		if resp.FailedProductId != 0 {
+			return fmt.Sprintf("Insufficient stock for product %d", resp.FailedProductId), nil
+		}
+		return "Failed to adjust stock reservation", nil
+	}
+	return "", nil
+}
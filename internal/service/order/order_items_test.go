@@ -0,0 +1,406 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	paymentpb "github.com/bytedance-youthcamp/demo/api/payment"
+	productpb "github.com/bytedance-youthcamp/demo/api/product"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// itemsOrderRepository 在内存中保存一个订单，UpdateItems 在副本上修改，update 成功后才替换订单；
+// saveErr 非空时 update 成功后保存失败，concurrent 非空时在加锁前修改订单，模拟并发的请求
+type itemsOrderRepository struct {
+	repository.OrderRepository
+	order      *repository.Order
+	changes    []*repository.OrderItemChange
+	saveErr    error
+	concurrent func(order *repository.Order)
+}
+
+func (r *itemsOrderRepository) ListItemChanges(ctx context.Context, orderID string) ([]*repository.OrderItemChange, error) {
+	return r.changes, nil
+}
+
+func (r *itemsOrderRepository) Get(ctx context.Context, orderID string) (*repository.Order, error) {
+	return copyOrder(r.order), nil
+}
+
+func (r *itemsOrderRepository) UpdateItems(ctx context.Context, orderID string, update func(ctx context.Context, order *repository.Order) ([]*repository.OrderItemChange, error)) (*repository.Order, error) {
+	if r.concurrent != nil {
+		r.concurrent(r.order)
+	}
+	order := copyOrder(r.order)
+	changes, err := update(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	if r.saveErr != nil {
+		return nil, r.saveErr
+	}
+	r.order = order
+	r.changes = append(r.changes, changes...)
+	return copyOrder(order), nil
+}
+
+func copyOrder(order *repository.Order) *repository.Order {
+	copied := *order
+	copied.Items = make([]*repository.OrderItem, len(order.Items))
+	for i, item := range order.Items {
+		copiedItem := *item
+		copied.Items[i] = &copiedItem
+	}
+	return &copied
+}
+
+func newItemsTestOrder(status repository.OrderStatus) *repository.Order {
+	return &repository.Order{
+		ID:          "7",
+		UserID:      "1",
+		Status:      status,
+		TotalAmount: money.New(25000, "CNY"),
+		Items: []*repository.OrderItem{
+			{ProductID: "1", ProductName: "Keyboard", Quantity: 2, Price: money.New(10000, "CNY")},
+			{ProductID: "2", ProductName: "Mouse", Quantity: 1, Price: money.New(5000, "CNY")},
+		},
+	}
+}
+
+func TestUpdateOrderItems(t *testing.T) {
+	ctx := context.Background()
+	newService := func(status repository.OrderStatus) (*orderService, *itemsOrderRepository, *MockProductClient) {
+		productClient := new(MockProductClient)
+		productClient.On("GetProducts", mock.Anything, mock.Anything, mock.Anything).Return(&productpb.GetProductsResponse{
+			Success: true,
+			Products: []*productpb.Product{
+				{Id: 1, Name: "Keyboard", PriceMoney: &productpb.Money{Amount: 12000, Currency: "CNY"}},
+				{Id: 2, Name: "Mouse", PriceMoney: &productpb.Money{Amount: 5000, Currency: "CNY"}},
+			},
+		}, nil)
+		repo := &itemsOrderRepository{order: newItemsTestOrder(status)}
+		return &orderService{orderRepo: repo, productClient: productClient}, repo, productClient
+	}
+
+	t.Run("changes quantities at current prices", func(t *testing.T) {
+		service, repo, productClient := newService(repository.OrderStatusPending)
+		productClient.On("AdjustReservation", mock.Anything, mock.Anything, mock.Anything).Return(&productpb.AdjustReservationResponse{
+			Success:  true,
+			Reserved: true,
+		}, nil)
+
+		resp, err := service.UpdateOrderItems(ctx, &orderapi.UpdateOrderItemsRequest{
+			OrderId: 7,
+			Items:   []*orderapi.OrderItemQuantity{{ProductId: 1, Quantity: 1}, {ProductId: 2, Quantity: 0}},
+			Reason:  "changed my mind",
+		})
+		require.NoError(t, err)
+		require.True(t, resp.Success, resp.ErrorMessage)
+
+		require.Len(t, resp.Order.Items, 1)
+		assert.Equal(t, int32(1), resp.Order.Items[0].Quantity)
+		assert.Equal(t, int64(12000), resp.Order.TotalMoney.Amount)
+
+		req := productClient.Calls[len(productClient.Calls)-1].Arguments.Get(1).(*productpb.AdjustReservationRequest)
+		assert.Equal(t, int32(7), req.OrderId)
+		require.Len(t, req.Items, 1)
+		assert.Equal(t, int32(1), req.Items[0].ProductId)
+		assert.Equal(t, int32(1), req.Items[0].Quantity)
+
+		require.Len(t, repo.changes, 2)
+		assert.Equal(t, repository.OrderItemChangeQuantity, repo.changes[0].Type)
+		assert.Equal(t, int32(2), repo.changes[0].OldQuantity)
+		assert.Equal(t, int32(1), repo.changes[0].NewQuantity)
+		assert.Equal(t, money.New(-8000, "CNY"), repo.changes[0].Amount)
+		assert.Equal(t, repository.OrderItemChangeRemoved, repo.changes[1].Type)
+		assert.Equal(t, money.New(-5000, "CNY"), repo.changes[1].Amount)
+		assert.Equal(t, "changed my mind", repo.changes[1].Reason)
+	})
+
+	t.Run("keeps order when stock is insufficient", func(t *testing.T) {
+		service, repo, productClient := newService(repository.OrderStatusPending)
+		productClient.On("AdjustReservation", mock.Anything, mock.Anything, mock.Anything).Return(&productpb.AdjustReservationResponse{
+			Success:         false,
+			ErrorMessage:    "商品库存不足",
+			FailedProductId: 1,
+		}, nil)
+
+		resp, err := service.UpdateOrderItems(ctx, &orderapi.UpdateOrderItemsRequest{
+			OrderId: 7,
+			Items:   []*orderapi.OrderItemQuantity{{ProductId: 1, Quantity: 5}},
+		})
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Equal(t, "Insufficient stock for product 1", resp.ErrorMessage)
+		assert.Equal(t, int32(2), repo.order.Items[0].Quantity)
+		assert.Empty(t, repo.changes)
+	})
+
+	t.Run("closes the pending payment", func(t *testing.T) {
+		service, _, productClient := newService(repository.OrderStatusPending)
+		paymentClient := new(MockPaymentClient)
+		paymentClient.On("CancelOrderPayment", mock.Anything, &paymentpb.CancelOrderPaymentRequest{OrderId: 7}, mock.Anything).
+			Return(&paymentpb.CancelOrderPaymentResponse{Success: true}, nil)
+		service.paymentClient = paymentClient
+		productClient.On("AdjustReservation", mock.Anything, mock.Anything, mock.Anything).Return(&productpb.AdjustReservationResponse{
+			Success:  true,
+			Reserved: true,
+		}, nil)
+
+		resp, err := service.UpdateOrderItems(ctx, &orderapi.UpdateOrderItemsRequest{
+			OrderId: 7,
+			Items:   []*orderapi.OrderItemQuantity{{ProductId: 1, Quantity: 3}},
+		})
+		require.NoError(t, err)
+		require.True(t, resp.Success, resp.ErrorMessage)
+		paymentClient.AssertExpectations(t)
+	})
+
+	t.Run("keeps order when the payment has completed", func(t *testing.T) {
+		service, repo, productClient := newService(repository.OrderStatusPending)
+		paymentClient := new(MockPaymentClient)
+		paymentClient.On("CancelOrderPayment", mock.Anything, mock.Anything, mock.Anything).
+			Return(&paymentpb.CancelOrderPaymentResponse{Success: false, ErrorMessage: "order 7 has already been paid"}, nil)
+		service.paymentClient = paymentClient
+
+		resp, err := service.UpdateOrderItems(ctx, &orderapi.UpdateOrderItemsRequest{
+			OrderId: 7,
+			Items:   []*orderapi.OrderItemQuantity{{ProductId: 1, Quantity: 3}},
+		})
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Equal(t, int32(2), repo.order.Items[0].Quantity)
+		productClient.AssertNotCalled(t, "AdjustReservation", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("restores reservation when saving fails", func(t *testing.T) {
+		service, repo, productClient := newService(repository.OrderStatusPending)
+		repo.saveErr = errors.New("database is down")
+		productClient.On("AdjustReservation", mock.Anything, mock.Anything, mock.Anything).Return(&productpb.AdjustReservationResponse{
+			Success:  true,
+			Reserved: true,
+		}, nil)
+
+		_, err := service.UpdateOrderItems(ctx, &orderapi.UpdateOrderItemsRequest{
+			OrderId: 7,
+			Items:   []*orderapi.OrderItemQuantity{{ProductId: 1, Quantity: 1}},
+		})
+		require.Error(t, err)
+
+		// 第二次调整将预留库存恢复为修改前的数量
+		req := productClient.Calls[len(productClient.Calls)-1].Arguments.Get(1).(*productpb.AdjustReservationRequest)
+		require.Len(t, req.Items, 2)
+		assert.Equal(t, int32(2), req.Items[0].Quantity)
+		assert.Equal(t, int32(1), req.Items[1].Quantity)
+		assert.Empty(t, repo.changes)
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		for _, tc := range []struct {
+			status       repository.OrderStatus
+			items        []*orderapi.OrderItemQuantity
+			errorMessage string
+		}{
+			{repository.OrderStatusPaid, []*orderapi.OrderItemQuantity{{ProductId: 1, Quantity: 1}}, "Order items cannot be modified"},
+			{repository.OrderStatusPending, []*orderapi.OrderItemQuantity{{ProductId: 3, Quantity: 1}}, "Product 3 is not in the order"},
+			{repository.OrderStatusPending, []*orderapi.OrderItemQuantity{{ProductId: 1, Quantity: -1}}, "Invalid item quantity"},
+			{repository.OrderStatusPending, []*orderapi.OrderItemQuantity{{ProductId: 1}, {ProductId: 2}}, "Order must contain at least one item"},
+		} {
+			service, repo, _ := newService(tc.status)
+			resp, err := service.UpdateOrderItems(ctx, &orderapi.UpdateOrderItemsRequest{OrderId: 7, Items: tc.items})
+			require.NoError(t, err)
+			assert.False(t, resp.Success)
+			assert.Equal(t, tc.errorMessage, resp.ErrorMessage)
+			assert.Empty(t, repo.changes)
+		}
+	})
+}
+
+func TestSettleOrderRequiresCurrentTotal(t *testing.T) {
+	repo := &itemsOrderRepository{order: newItemsTestOrder(repository.OrderStatusPending)}
+	service := &orderService{orderRepo: repo}
+
+	// 订单修改商品前创建的支付金额与当前应付金额不一致
+	for _, amount := range []*orderapi.Money{nil, {Amount: 20000, Currency: "CNY"}} {
+		resp, err := service.settleOrder(context.Background(), &orderapi.SettleOrderRequest{OrderId: 7, AmountMoney: amount})
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Equal(t, "Payment amount does not match order total", resp.ErrorMessage)
+		assert.Equal(t, repository.OrderStatusPending, repo.order.Status)
+	}
+}
+
+func TestCancelOrderItems(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("refunds cancelled items", func(t *testing.T) {
+		paymentClient := new(MockPaymentClient)
+		paymentClient.On("RequestRefund", mock.Anything, mock.Anything, mock.Anything).Return(&paymentpb.RequestRefundResponse{
+			Success:  true,
+			RefundId: "refund-1",
+		}, nil)
+		repo := &itemsOrderRepository{order: newItemsTestOrder(repository.OrderStatusPaid)}
+		service := &orderService{orderRepo: repo, paymentClient: paymentClient}
+
+		resp, err := service.CancelOrderItems(ctx, &orderapi.CancelOrderItemsRequest{
+			OrderId: 7,
+			Items:   []*orderapi.OrderItemQuantity{{ProductId: 1, Quantity: 1}},
+			Reason:  "no longer needed",
+		})
+		require.NoError(t, err)
+		require.True(t, resp.Success, resp.ErrorMessage)
+		assert.Equal(t, "refund-1", resp.RefundId)
+
+		req := paymentClient.Calls[0].Arguments.Get(1).(*paymentpb.RequestRefundRequest)
+		assert.Equal(t, int32(7), req.OrderId)
+		require.Len(t, req.Items, 1)
+		assert.Equal(t, int32(1), req.Items[0].ProductId)
+		assert.Equal(t, int32(1), req.Items[0].Quantity)
+
+		assert.Equal(t, int32(1), resp.Order.Items[0].CancelledQuantity)
+		require.Len(t, repo.changes, 1)
+		assert.Equal(t, repository.OrderItemChangeCancelled, repo.changes[0].Type)
+		assert.Equal(t, int32(2), repo.changes[0].OldQuantity)
+		assert.Equal(t, int32(1), repo.changes[0].NewQuantity)
+		assert.Equal(t, money.New(-10000, "CNY"), repo.changes[0].Amount)
+		assert.Equal(t, "refund-1", repo.changes[0].RefundID)
+
+		// 已取消的商品不再发货
+		assert.Equal(t, int32(1), unshipped(repo.order, nil)["1"])
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		order := newItemsTestOrder(repository.OrderStatusPaid)
+		order.Items[0].CancelledQuantity = 1
+
+		for _, tc := range []struct {
+			order        *repository.Order
+			items        []*orderapi.OrderItemQuantity
+			errorMessage string
+		}{
+			{order, []*orderapi.OrderItemQuantity{{ProductId: 1, Quantity: 2}}, "Cancel quantity exceeds remaining quantity for product 1"},
+			{order, []*orderapi.OrderItemQuantity{{ProductId: 2, Quantity: 0}}, "Invalid item quantity"},
+			{order, []*orderapi.OrderItemQuantity{{ProductId: 2, Quantity: 1}, {ProductId: 2, Quantity: 1}}, "Duplicate product 2"},
+			{newItemsTestOrder(repository.OrderStatusPending), []*orderapi.OrderItemQuantity{{ProductId: 1, Quantity: 1}}, "Order items cannot be cancelled"},
+			{newItemsTestOrder(repository.OrderStatusShipped), []*orderapi.OrderItemQuantity{{ProductId: 1, Quantity: 1}}, "Order items cannot be cancelled"},
+		} {
+			paymentClient := new(MockPaymentClient)
+			service := &orderService{orderRepo: &itemsOrderRepository{order: tc.order}, paymentClient: paymentClient}
+			resp, err := service.CancelOrderItems(ctx, &orderapi.CancelOrderItemsRequest{OrderId: 7, Items: tc.items})
+			require.NoError(t, err)
+			assert.False(t, resp.Success)
+			assert.Equal(t, tc.errorMessage, resp.ErrorMessage)
+			paymentClient.AssertNotCalled(t, "RequestRefund", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("reports refund rejection", func(t *testing.T) {
+		paymentClient := new(MockPaymentClient)
+		paymentClient.On("RequestRefund", mock.Anything, mock.Anything, mock.Anything).Return(&paymentpb.RequestRefundResponse{
+			Success:      false,
+			ErrorMessage: "another refund is in progress",
+		}, nil)
+		repo := &itemsOrderRepository{order: newItemsTestOrder(repository.OrderStatusPaid)}
+		service := &orderService{orderRepo: repo, paymentClient: paymentClient}
+
+		resp, err := service.CancelOrderItems(ctx, &orderapi.CancelOrderItemsRequest{
+			OrderId: 7,
+			Items:   []*orderapi.OrderItemQuantity{{ProductId: 2, Quantity: 1}},
+		})
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Equal(t, "Failed to request refund: another refund is in progress", resp.ErrorMessage)
+		assert.Empty(t, repo.changes)
+		assert.Zero(t, repo.order.Items[1].CancelledQuantity)
+	})
+
+	t.Run("retries with the same refund id", func(t *testing.T) {
+		paymentClient := new(MockPaymentClient)
+		paymentClient.On("RequestRefund", mock.Anything, mock.Anything, mock.Anything).Return(&paymentpb.RequestRefundResponse{
+			Success:  true,
+			RefundId: "refund-1",
+		}, nil)
+		repo := &itemsOrderRepository{order: newItemsTestOrder(repository.OrderStatusPaid), saveErr: errors.New("connection lost")}
+		service := &orderService{orderRepo: repo, paymentClient: paymentClient}
+		req := &orderapi.CancelOrderItemsRequest{
+			OrderId: 7,
+			Items:   []*orderapi.OrderItemQuantity{{ProductId: 1, Quantity: 1}},
+		}
+
+		_, err := service.CancelOrderItems(ctx, req)
+		require.Error(t, err)
+		assert.Empty(t, repo.changes)
+
+		repo.saveErr = nil
+		resp, err := service.CancelOrderItems(ctx, req)
+		require.NoError(t, err)
+		require.True(t, resp.Success, resp.ErrorMessage)
+		require.Len(t, repo.changes, 1)
+
+		first := paymentClient.Calls[0].Arguments.Get(1).(*paymentpb.RequestRefundRequest)
+		second := paymentClient.Calls[1].Arguments.Get(1).(*paymentpb.RequestRefundRequest)
+		assert.NotEmpty(t, first.RefundId)
+		assert.Equal(t, first.RefundId, second.RefundId)
+
+		// 已取消的数量变化后是新的取消
+		_, err = service.CancelOrderItems(ctx, req)
+		require.NoError(t, err)
+		third := paymentClient.Calls[2].Arguments.Get(1).(*paymentpb.RequestRefundRequest)
+		assert.NotEqual(t, first.RefundId, third.RefundId)
+	})
+
+	t.Run("does not record the same refund twice", func(t *testing.T) {
+		paymentClient := new(MockPaymentClient)
+		paymentClient.On("RequestRefund", mock.Anything, mock.Anything, mock.Anything).Return(&paymentpb.RequestRefundResponse{
+			Success:  true,
+			RefundId: "refund-1",
+		}, nil)
+		repo := &itemsOrderRepository{order: newItemsTestOrder(repository.OrderStatusPaid)}
+		// 相同的请求在加锁前已经记录了取消
+		repo.concurrent = func(order *repository.Order) {
+			if len(repo.changes) == 0 {
+				order.Items[0].CancelledQuantity = 1
+				repo.changes = append(repo.changes, &repository.OrderItemChange{ProductID: "1", RefundID: "refund-1"})
+			}
+		}
+		service := &orderService{orderRepo: repo, paymentClient: paymentClient}
+
+		resp, err := service.CancelOrderItems(ctx, &orderapi.CancelOrderItemsRequest{
+			OrderId: 7,
+			Items:   []*orderapi.OrderItemQuantity{{ProductId: 1, Quantity: 1}},
+		})
+		require.NoError(t, err)
+		require.True(t, resp.Success, resp.ErrorMessage)
+		assert.Equal(t, "refund-1", resp.RefundId)
+		assert.Len(t, repo.changes, 1)
+		assert.Equal(t, int32(1), repo.order.Items[0].CancelledQuantity)
+	})
+
+	t.Run("rejects items cancelled concurrently", func(t *testing.T) {
+		paymentClient := new(MockPaymentClient)
+		paymentClient.On("RequestRefund", mock.Anything, mock.Anything, mock.Anything).Return(&paymentpb.RequestRefundResponse{
+			Success:  true,
+			RefundId: "refund-2",
+		}, nil)
+		repo := &itemsOrderRepository{order: newItemsTestOrder(repository.OrderStatusPaid)}
+		repo.concurrent = func(order *repository.Order) {
+			order.Items[0].CancelledQuantity = 2
+		}
+		service := &orderService{orderRepo: repo, paymentClient: paymentClient}
+
+		_, err := service.CancelOrderItems(ctx, &orderapi.CancelOrderItemsRequest{
+			OrderId: 7,
+			Items:   []*orderapi.OrderItemQuantity{{ProductId: 1, Quantity: 1}},
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "refund-2")
+		assert.Empty(t, repo.changes)
+		assert.Equal(t, int32(2), repo.order.Items[0].CancelledQuantity)
+	})
+}
@@ -0,0 +1,304 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+)
+
+// PriceQuote 是定价流水线的计算对象。商品小计和优惠在进入流水线前确定，
+// 计算器按顺序累加税费和运费，所有金额使用订单币种
+type PriceQuote struct {
+	Region      string
+	Items       []*repository.OrderItem
+	Subtotal    money.Money
+	Discount    money.Money
+	Tax         money.Money
+	ShippingFee money.Money
+}
+
+// newPriceQuote 创建价格明细，税费和运费从零开始累加
+func newPriceQuote(region string, items []*repository.OrderItem, subtotal, discount money.Money) *PriceQuote {
+	return &PriceQuote{
+		Region:      normalizeRegion(region),
+		Items:       items,
+		Subtotal:    subtotal,
+		Discount:    discount,
+		Tax:         money.Zero(subtotal.Currency),
+		ShippingFee: money.Zero(subtotal.Currency),
+	}
+}
+
+// Currency 返回价格明细的币种
+func (q *PriceQuote) Currency() string {
+	return q.Subtotal.Currency
+}
+
+// Merchandise 返回优惠后的商品金额，是计税和免运费门槛的基数
+func (q *PriceQuote) Merchandise() money.Money {
+	return money.New(q.Subtotal.Amount-q.Discount.Amount, q.Currency())
+}
+
+// Total 返回应付金额
+func (q *PriceQuote) Total() money.Money {
+	return money.New(q.Subtotal.Amount-q.Discount.Amount+q.Tax.Amount+q.ShippingFee.Amount, q.Currency())
+}
+
+// PriceCalculator 是定价流水线中的一个计算步骤，按注册顺序依次调用。
+// 返回 *PricingError 表示订单无法按当前条件计价，其他错误按内部错误处理
+type PriceCalculator interface {
+	Calculate(ctx context.Context, quote *PriceQuote) error
+}
+
+// PricingError 表示计算器拒绝为订单计价，如收货地区不支持配送
+type PricingError struct {
+	Message string
+}
+
+func (e *PricingError) Error() string {
+	return e.Message
+}
+
+// WithPriceCalculators 设置定价流水线，计算器在商品小计和优惠券之后按顺序执行，未设置时不收税费和运费
+func WithPriceCalculators(calculators ...PriceCalculator) Option {
+	return func(s *orderService) {
+		s.priceCalculators = calculators
+	}
+}
+
+// calculatePrice 依次执行定价流水线中的计算器
+func (s *orderService) calculatePrice(ctx context.Context, quote *PriceQuote) error {
+	for _, calculator := range s.priceCalculators {
+		if err := calculator.Calculate(ctx, quote); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeRegion 统一地区代码的格式，地区代码不区分大小写
+func normalizeRegion(region string) string {
+	return strings.ToUpper(strings.TrimSpace(region))
+}
+
+// TaxCalculator 按收货地区的税率对优惠后的商品金额计税，未配置的地区使用默认税率
+type TaxCalculator struct {
+	// 税率以百万分之一为单位保存，避免浮点误差
+	defaultRate int64
+	rates       map[string]int64
+}
+
+// NewTaxCalculator 创建税费计算器，税率为小数，如 0.06 表示 6%
+func NewTaxCalculator(defaultRate float64, rates map[string]float64) (*TaxCalculator, error) {
+	c := &TaxCalculator{rates: make(map[string]int64, len(rates))}
+
+	var err error
+	if c.defaultRate, err = taxRate(defaultRate); err != nil {
+		return nil, err
+	}
+	for region, rate := range rates {
+		if c.rates[normalizeRegion(region)], err = taxRate(rate); err != nil {
+			return nil, fmt.Errorf("invalid tax rate for region %s: %w", region, err)
+		}
+	}
+	return c, nil
+}
+
+func taxRate(rate float64) (int64, error) {
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("tax rate %v out of range [0, 1]", rate)
+	}
+	return int64(math.Round(rate * 1e6)), nil
+}
+
+// Calculate 按四舍五入计算税费
+func (c *TaxCalculator) Calculate(ctx context.Context, quote *PriceQuote) error {
+	rate, ok := c.rates[quote.Region]
+	if !ok {
+		rate = c.defaultRate
+	}
+
+	base := quote.Merchandise().Amount
+	if rate == 0 || base <= 0 {
+		return nil
+	}
+	tax := (base*rate + 500000) / 1000000
+	quote.Tax = money.New(quote.Tax.Amount+tax, quote.Currency())
+	return nil
+}
+
+// FlatShippingCalculator 每个订单收取固定运费，优惠后的商品金额达到免运费门槛时免收运费
+type FlatShippingCalculator struct {
+	fee           money.Money
+	freeThreshold money.Money
+}
+
+// NewFlatShippingCalculator 创建固定运费计算器，freeThreshold 为零时不免运费
+func NewFlatShippingCalculator(fee, freeThreshold money.Money) *FlatShippingCalculator {
+	return &FlatShippingCalculator{fee: fee, freeThreshold: freeThreshold}
+}
+
+func (c *FlatShippingCalculator) Calculate(ctx context.Context, quote *PriceQuote) error {
+	if err := checkShippingCurrency(c.fee, quote); err != nil {
+		return err
+	}
+	if freeShipping(c.freeThreshold, quote) {
+		return nil
+	}
+	quote.ShippingFee = money.New(quote.ShippingFee.Amount+c.fee.Amount, quote.Currency())
+	return nil
+}
+
+// WeightShippingRule 按重量计算运费的规则
+type WeightShippingRule struct {
+	// 每个订单的基础运费
+	BaseFee money.Money
+	// 每公斤的运费，不足一公斤按一公斤计算
+	PerKgFee money.Money
+	// 未配置分类重量的商品使用的单件重量（克）
+	DefaultItemGrams int64
+	// 按商品分类配置的单件重量（克），分类不区分大小写
+	CategoryGrams map[string]int64
+	// 优惠后的商品金额达到该金额时免运费，为零时不免运费
+	FreeThreshold money.Money
+}
+
+// WeightShippingCalculator 按订单商品的总重量计算运费
+type WeightShippingCalculator struct {
+	rule          WeightShippingRule
+	categoryGrams map[string]int64
+}
+
+// NewWeightShippingCalculator 创建按重量计算运费的计算器
+func NewWeightShippingCalculator(rule WeightShippingRule) (*WeightShippingCalculator, error) {
+	if rule.BaseFee.Currency != rule.PerKgFee.Currency {
+		return nil, fmt.Errorf("shipping fees must use the same currency")
+	}
+	if rule.DefaultItemGrams < 0 {
+		return nil, fmt.Errorf("invalid default item weight %d", rule.DefaultItemGrams)
+	}
+
+	categoryGrams := make(map[string]int64, len(rule.CategoryGrams))
+	for category, grams := range rule.CategoryGrams {
+		if grams < 0 {
+			return nil, fmt.Errorf("invalid weight %d for category %s", grams, category)
+		}
+		categoryGrams[strings.ToLower(category)] = grams
+	}
+	return &WeightShippingCalculator{rule: rule, categoryGrams: categoryGrams}, nil
+}
+
+func (c *WeightShippingCalculator) Calculate(ctx context.Context, quote *PriceQuote) error {
+	if err := checkShippingCurrency(c.rule.BaseFee, quote); err != nil {
+		return err
+	}
+	if freeShipping(c.rule.FreeThreshold, quote) {
+		return nil
+	}
+
+	var grams int64
+	for _, item := range quote.Items {
+		weight, ok := c.categoryGrams[strings.ToLower(item.Category)]
+		if !ok {
+			weight = c.rule.DefaultItemGrams
+		}
+		grams += weight * int64(item.Quantity-item.CancelledQuantity)
+	}
+	kilograms := (grams + 999) / 1000
+
+	fee := c.rule.BaseFee.Amount + c.rule.PerKgFee.Amount*kilograms
+	quote.ShippingFee = money.New(quote.ShippingFee.Amount+fee, quote.Currency())
+	return nil
+}
+
+// checkShippingCurrency 检查运费币种与订单币种是否一致，运费不做汇率换算
+func checkShippingCurrency(fee money.Money, quote *PriceQuote) error {
+	if fee.Currency != quote.Currency() {
+		return &PricingError{Message: fmt.Sprintf("Shipping is not available for currency %s", quote.Currency())}
+	}
+	return nil
+}
+
+func freeShipping(threshold money.Money, quote *PriceQuote) bool {
+	return threshold.Amount > 0 && quote.Merchandise().Amount >= threshold.Amount
+}
+
+// setOrderPrice 将价格明细保存到订单，订单金额为明细的应付金额
+func setOrderPrice(order *repository.Order, quote *PriceQuote) {
+	order.Region = quote.Region
+	order.SubtotalAmount = quote.Subtotal
+	order.DiscountAmount = quote.Discount
+	order.TaxAmount = quote.Tax
+	order.ShippingFee = quote.ShippingFee
+	order.TotalAmount = quote.Total()
+}
+
+// orderPriceQuote 返回订单保存的价格明细，引入价格明细之前创建的订单只有商品小计和优惠
+func orderPriceQuote(order *repository.Order) *PriceQuote {
+	currency := order.TotalAmount.Currency
+	quote := &PriceQuote{
+		Region:      order.Region,
+		Items:       order.Items,
+		Subtotal:    money.New(order.SubtotalAmount.Amount, currency),
+		Discount:    money.New(order.DiscountAmount.Amount, currency),
+		Tax:         money.New(order.TaxAmount.Amount, currency),
+		ShippingFee: money.New(order.ShippingFee.Amount, currency),
+	}
+	if quote.Subtotal.IsZero() && quote.Tax.IsZero() && quote.ShippingFee.IsZero() {
+		quote.Subtotal = money.New(order.TotalAmount.Amount+order.DiscountAmount.Amount, currency)
+	}
+	return quote
+}
+
+// toAPIPriceBreakdown 将价格明细转换为 API 价格明细，明细行只包含金额不为零的项目
+func toAPIPriceBreakdown(quote *PriceQuote, couponCode string) *orderapi.PriceBreakdown {
+	breakdown := &orderapi.PriceBreakdown{
+		Subtotal:    toAPIMoney(quote.Subtotal),
+		Discount:    toAPIMoney(quote.Discount),
+		Tax:         toAPIMoney(quote.Tax),
+		ShippingFee: toAPIMoney(quote.ShippingFee),
+		Total:       toAPIMoney(quote.Total()),
+		Lines: []*orderapi.PriceLine{{
+			Type:        orderapi.PriceLineType_PRICE_LINE_SUBTOTAL,
+			Description: "Subtotal",
+			Amount:      toAPIMoney(quote.Subtotal),
+		}},
+	}
+
+	if !quote.Discount.IsZero() {
+		description := "Discount"
+		if couponCode != "" {
+			description = fmt.Sprintf("Discount (%s)", couponCode)
+		}
+		breakdown.Lines = append(breakdown.Lines, &orderapi.PriceLine{
+			Type:        orderapi.PriceLineType_PRICE_LINE_DISCOUNT,
+			Description: description,
+			Amount:      toAPIMoney(money.New(-quote.Discount.Amount, quote.Currency())),
+		})
+	}
+	if !quote.Tax.IsZero() {
+		description := "Tax"
+		if quote.Region != "" {
+			description = fmt.Sprintf("Tax (%s)", quote.Region)
+		}
+		breakdown.Lines = append(breakdown.Lines, &orderapi.PriceLine{
+			Type:        orderapi.PriceLineType_PRICE_LINE_TAX,
+			Description: description,
+			Amount:      toAPIMoney(quote.Tax),
+		})
+	}
+	if !quote.ShippingFee.IsZero() {
+		breakdown.Lines = append(breakdown.Lines, &orderapi.PriceLine{
+			Type:        orderapi.PriceLineType_PRICE_LINE_SHIPPING,
+			Description: "Shipping",
+			Amount:      toAPIMoney(quote.ShippingFee),
+		})
+	}
+
+	return breakdown
+}
@@ -0,0 +1,182 @@
+package order
+
+import (
+	"context"
+	"testing"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	promotionpb "github.com/bytedance-youthcamp/demo/api/promotion"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func pipelineTestQuote(region string, subtotal, discount int64) *PriceQuote {
+	items := []*repository.OrderItem{
+		{ProductID: "1", Category: "Electronics", Quantity: 2, Price: money.New(10000, "CNY")},
+		{ProductID: "2", Category: "books", Quantity: 3, Price: money.New(5000, "CNY")},
+	}
+	return newPriceQuote(region, items, money.New(subtotal, "CNY"), money.New(discount, "CNY"))
+}
+
+func TestTaxCalculator(t *testing.T) {
+	ctx := context.Background()
+	calculator, err := NewTaxCalculator(0.06, map[string]float64{"us-ca": 0.0725, "HK": 0})
+	require.NoError(t, err)
+
+	quote := pipelineTestQuote(" US-CA ", 12345, 2345)
+	require.NoError(t, calculator.Calculate(ctx, quote))
+	// 按优惠后的金额计税并四舍五入：10000 * 7.25% = 725
+	assert.Equal(t, "US-CA", quote.Region)
+	assert.Equal(t, int64(725), quote.Tax.Amount)
+
+	quote = pipelineTestQuote("cn", 1009, 0)
+	require.NoError(t, calculator.Calculate(ctx, quote))
+	assert.Equal(t, int64(61), quote.Tax.Amount)
+
+	quote = pipelineTestQuote("hk", 1000, 0)
+	require.NoError(t, calculator.Calculate(ctx, quote))
+	assert.True(t, quote.Tax.IsZero())
+
+	_, err = NewTaxCalculator(1.5, nil)
+	assert.Error(t, err)
+}
+
+func TestShippingCalculators(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("flat fee with free threshold", func(t *testing.T) {
+		calculator := NewFlatShippingCalculator(money.New(1000, "CNY"), money.New(9900, "CNY"))
+
+		quote := pipelineTestQuote("", 9000, 0)
+		require.NoError(t, calculator.Calculate(ctx, quote))
+		assert.Equal(t, int64(1000), quote.ShippingFee.Amount)
+
+		// 门槛按优惠后的商品金额判断
+		quote = pipelineTestQuote("", 10000, 200)
+		require.NoError(t, calculator.Calculate(ctx, quote))
+		assert.Equal(t, int64(1000), quote.ShippingFee.Amount)
+
+		quote = pipelineTestQuote("", 9900, 0)
+		require.NoError(t, calculator.Calculate(ctx, quote))
+		assert.True(t, quote.ShippingFee.IsZero())
+	})
+
+	t.Run("weight based fee", func(t *testing.T) {
+		calculator, err := NewWeightShippingCalculator(WeightShippingRule{
+			BaseFee:          money.New(800, "CNY"),
+			PerKgFee:         money.New(200, "CNY"),
+			DefaultItemGrams: 500,
+			CategoryGrams:    map[string]int64{"electronics": 1200},
+		})
+		require.NoError(t, err)
+
+		// 2 * 1200g + 3 * 500g = 3.9kg，按 4kg 计算
+		quote := pipelineTestQuote("", 35000, 0)
+		require.NoError(t, calculator.Calculate(ctx, quote))
+		assert.Equal(t, int64(800+4*200), quote.ShippingFee.Amount)
+
+		// 已取消的商品不计重量
+		quote.Items[0].CancelledQuantity = 2
+		quote.ShippingFee = money.Zero("CNY")
+		require.NoError(t, calculator.Calculate(ctx, quote))
+		assert.Equal(t, int64(800+2*200), quote.ShippingFee.Amount)
+	})
+
+	t.Run("rejects other currencies", func(t *testing.T) {
+		calculator := NewFlatShippingCalculator(money.New(1000, "CNY"), money.Money{})
+		quote := newPriceQuote("", nil, money.New(1000, "USD"), money.Zero("USD"))
+
+		err := calculator.Calculate(ctx, quote)
+		var pricingErr *PricingError
+		require.ErrorAs(t, err, &pricingErr)
+		assert.Equal(t, "Shipping is not available for currency USD", pricingErr.Message)
+	})
+}
+
+func TestCreateOrderWithPricePipeline(t *testing.T) {
+	ctx := context.Background()
+	tax, err := NewTaxCalculator(0, map[string]float64{"CN-SH": 0.06})
+	require.NoError(t, err)
+
+	repo := &couponOrderRepository{}
+	service, promotionClient := newCouponTestService(repo)
+	service.priceCalculators = []PriceCalculator{tax, NewFlatShippingCalculator(money.New(1000, "CNY"), money.Money{})}
+	promotionClient.On("ApplyCoupon", mock.Anything, mock.Anything, mock.Anything).Return(&promotionpb.ApplyCouponResponse{
+		Success:      true,
+		RedemptionId: "redemption-1",
+		Breakdown:    &promotionpb.PriceBreakdown{Discount: &promotionpb.Money{Amount: 5000, Currency: "CNY"}},
+	}, nil)
+
+	req := couponOrderRequest("SAVE50")
+	req.Region = "cn-sh"
+	resp, err := service.createOrder(ctx, req)
+	require.NoError(t, err)
+	require.True(t, resp.Success, resp.ErrorMessage)
+
+	// (25000 - 5000) * 6% = 1200，运费 1000
+	assert.Equal(t, int64(22200), resp.TotalMoney.Amount)
+	breakdown := resp.PriceBreakdown
+	assert.Equal(t, int64(25000), breakdown.Subtotal.Amount)
+	assert.Equal(t, int64(5000), breakdown.Discount.Amount)
+	assert.Equal(t, int64(1200), breakdown.Tax.Amount)
+	assert.Equal(t, int64(1000), breakdown.ShippingFee.Amount)
+	assert.Equal(t, int64(22200), breakdown.Total.Amount)
+	require.Len(t, breakdown.Lines, 4)
+	assert.Equal(t, orderapi.PriceLineType_PRICE_LINE_DISCOUNT, breakdown.Lines[1].Type)
+	assert.Equal(t, "Discount (SAVE50)", breakdown.Lines[1].Description)
+	assert.Equal(t, int64(-5000), breakdown.Lines[1].Amount.Amount)
+	assert.Equal(t, "Tax (CN-SH)", breakdown.Lines[2].Description)
+
+	// 价格明细保存在订单上，查询订单时返回相同的明细
+	assert.Equal(t, "CN-SH", repo.order.Region)
+	assert.Equal(t, money.New(25000, "CNY"), repo.order.SubtotalAmount)
+	assert.Equal(t, money.New(1200, "CNY"), repo.order.TaxAmount)
+	assert.Equal(t, money.New(1000, "CNY"), repo.order.ShippingFee)
+	assert.Equal(t, money.New(22200, "CNY"), repo.order.TotalAmount)
+
+	apiOrder := toAPIOrder(repo.order)
+	assert.Equal(t, "CN-SH", apiOrder.Region)
+	assert.Equal(t, int64(1200), apiOrder.PriceBreakdown.Tax.Amount)
+	assert.Equal(t, int64(22200), apiOrder.PriceBreakdown.Total.Amount)
+}
+
+func TestQuoteOrder(t *testing.T) {
+	ctx := context.Background()
+	service, promotionClient := newCouponTestService(&couponOrderRepository{})
+	service.priceCalculators = []PriceCalculator{NewFlatShippingCalculator(money.New(1000, "CNY"), money.New(30000, "CNY"))}
+	promotionClient.On("PriceCart", mock.Anything, mock.Anything, mock.Anything).Return(&promotionpb.PriceCartResponse{
+		Success:   true,
+		Breakdown: &promotionpb.PriceBreakdown{Discount: &promotionpb.Money{Amount: 2000, Currency: "CNY"}},
+	}, nil)
+
+	// 计价使用商品服务的当前价格，客户端未提交单价
+	resp, err := service.QuoteOrder(ctx, &orderapi.QuoteOrderRequest{
+		UserId:     1,
+		Items:      []*orderapi.OrderItem{{ProductId: 1, Quantity: 2}, {ProductId: 2, Quantity: 1}},
+		CouponCode: "SAVE20",
+	})
+	require.NoError(t, err)
+	require.True(t, resp.Success, resp.ErrorMessage)
+	assert.Equal(t, int64(25000), resp.PriceBreakdown.Subtotal.Amount)
+	assert.Equal(t, int64(2000), resp.PriceBreakdown.Discount.Amount)
+	assert.Equal(t, int64(1000), resp.PriceBreakdown.ShippingFee.Amount)
+	assert.Equal(t, int64(24000), resp.PriceBreakdown.Total.Amount)
+
+	// 试算不占用优惠券
+	promotionClient.AssertNotCalled(t, "ApplyCoupon", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOrderPriceQuoteForLegacyOrder(t *testing.T) {
+	order := &repository.Order{
+		TotalAmount:    money.New(9000, "CNY"),
+		DiscountAmount: money.New(1000, "CNY"),
+	}
+
+	quote := orderPriceQuote(order)
+	assert.Equal(t, int64(10000), quote.Subtotal.Amount)
+	assert.True(t, quote.Tax.IsZero())
+	assert.Equal(t, order.TotalAmount, quote.Total())
+}
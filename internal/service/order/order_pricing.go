@@ -0,0 +1,242 @@
+package order
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	productpb "github.com/bytedance-youthcamp/demo/api/product"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+)
+
+const defaultPricePrecision = 2
+
+// productSnapshot 下单时商品的名称、分类和价格快照
+type productSnapshot struct {
+	Name     string
+	Category string
+	Price    money.Money
+}
+
+// ItemPriceMismatch 表示客户端提交的商品单价与服务端价格不一致
+type ItemPriceMismatch struct {
+	ProductID   int32
+	ClientPrice money.Money
+	ServerPrice money.Money
+}
+
+// PriceMismatchError 表示客户端提交的订单总价与服务端计算结果不一致
+type PriceMismatchError struct {
+	ClientTotal money.Money
+	ServerTotal money.Money
+	Items       []ItemPriceMismatch
+}
+
+func (e *PriceMismatchError) Error() string {
+	return fmt.Sprintf("order total mismatch: client %s, server %s", e.ClientTotal, e.ServerTotal)
+}
+
+func (e *PriceMismatchError) toAPI() *orderapi.PriceMismatch {
+	items := make([]*orderapi.ItemPriceMismatch, len(e.Items))
+	for i, item := range e.Items {
+		items[i] = &orderapi.ItemPriceMismatch{
+			ProductId:        item.ProductID,
+			ClientPrice:      item.ClientPrice.Float64(),
+			ServerPrice:      item.ServerPrice.Float64(),
+			ClientPriceMoney: toAPIMoney(item.ClientPrice),
+			ServerPriceMoney: toAPIMoney(item.ServerPrice),
+		}
+	}
+	return &orderapi.PriceMismatch{
+		ClientTotal:      e.ClientTotal.Float64(),
+		ServerTotal:      e.ServerTotal.Float64(),
+		Items:            items,
+		ClientTotalMoney: toAPIMoney(e.ClientTotal),
+		ServerTotalMoney: toAPIMoney(e.ServerTotal),
+	}
+}
+
+// WithPricePrecision 设置订单金额保留的小数位数
+func WithPricePrecision(precision int) Option {
+	return func(s *orderService) {
+		if precision >= 0 {
+			s.pricePrecision = precision
+		}
+	}
+}
+
+// roundPrice 按配置的精度四舍五入金额
+func (s *orderService) roundPrice(amount float64) float64 {
+	factor := math.Pow10(s.pricePrecision)
+	return math.Round(amount*factor) / factor
+}
+
+// moneyFromAPI 读取接口中的金额，未提供精确金额时按配置精度转换浮点金额
+func (s *orderService) moneyFromAPI(exact *orderapi.Money, amount float64, currency string) money.Money {
+	if exact != nil {
+		return money.New(exact.Amount, exact.Currency)
+	}
+	return money.FromFloat(s.roundPrice(amount), currency)
+}
+
+// priceOrderItems 使用商品服务的名称和价格生成订单项并计算总价，
+// 返回的错误信息非空表示请求不合法，总价不一致时返回 *PriceMismatchError
+func (s *orderService) priceOrderItems(ctx context.Context, req *orderapi.CreateOrderRequest) ([]*repository.OrderItem, money.Money, string, error) {
+	items, total, mismatch, errorMessage, err := s.buildOrderItems(ctx, req.Items)
+	if err != nil || errorMessage != "" {
+		return nil, money.Money{}, errorMessage, err
+	}
+
+	clientTotal := s.moneyFromAPI(req.TotalMoney, req.TotalPrice, total.Currency)
+	if !clientTotal.Equal(total) {
+		mismatch.ClientTotal = clientTotal
+		mismatch.ServerTotal = total
+		return nil, money.Money{}, "", mismatch
+	}
+
+	return items, total, "", nil
+}
+
+// buildOrderItems 按商品服务的当前名称和价格生成订单项并计算总价，
+// 客户端提交的单价与当前价格不一致的商品记录在返回的 PriceMismatchError 中
+func (s *orderService) buildOrderItems(ctx context.Context, reqItems []*orderapi.OrderItem) ([]*repository.OrderItem, money.Money, *PriceMismatchError, string, error) {
+	if len(reqItems) == 0 {
+		return nil, money.Money{}, nil, "Order must contain at least one item", nil
+	}
+
+	productIDs := make([]int32, 0, len(reqItems))
+	seen := make(map[int32]bool, len(reqItems))
+	for _, item := range reqItems {
+		if item.Quantity <= 0 {
+			return nil, money.Money{}, nil, "Invalid item quantity", nil
+		}
+		if !seen[item.ProductId] {
+			seen[item.ProductId] = true
+			productIDs = append(productIDs, item.ProductId)
+		}
+	}
+
+	products, err := s.loadProducts(ctx, productIDs)
+	if err != nil {
+		return nil, money.Money{}, nil, "", err
+	}
+
+	// 订单币种以第一个商品为准，一个订单中的商品必须使用相同币种
+	first, ok := products[reqItems[0].ProductId]
+	if !ok {
+		return nil, money.Money{}, nil, fmt.Sprintf("Product %d not found", reqItems[0].ProductId), nil
+	}
+	currency := first.Price.Currency
+
+	mismatch := &PriceMismatchError{}
+	items := make([]*repository.OrderItem, len(reqItems))
+	total := money.Zero(currency)
+	for i, item := range reqItems {
+		product, ok := products[item.ProductId]
+		if !ok {
+			return nil, money.Money{}, nil, fmt.Sprintf("Product %d not found", item.ProductId), nil
+		}
+
+		total, err = total.Add(product.Price.Mul(int64(item.Quantity)))
+		if errors.Is(err, money.ErrCurrencyMismatch) {
+			return nil, money.Money{}, nil, "Products must use the same currency", nil
+		}
+
+		clientPrice := s.moneyFromAPI(item.PriceMoney, item.Price, currency)
+		if !clientPrice.Equal(product.Price) {
+			mismatch.Items = append(mismatch.Items, ItemPriceMismatch{
+				ProductID:   item.ProductId,
+				ClientPrice: clientPrice,
+				ServerPrice: product.Price,
+			})
+		}
+
+		items[i] = &repository.OrderItem{
+			ProductID:   strconv.Itoa(int(item.ProductId)),
+			ProductName: product.Name,
+			Category:    product.Category,
+			Quantity:    item.Quantity,
+			Price:       product.Price,
+		}
+	}
+
+	return items, total, mismatch, "", nil
+}
+
+// loadProducts 查询商品的当前名称和价格
+func (s *orderService) loadProducts(ctx context.Context, productIDs []int32) (map[int32]productSnapshot, error) {
+	products := make(map[int32]productSnapshot, len(productIDs))
+
+	if s.productClient != nil {
+		// 商品服务按分页返回，逐页查询直到取回所有商品
+		for page := int32(1); len(products) < len(productIDs); page++ {
+			resp, err := s.productClient.GetProducts(ctx, &productpb.GetProductsRequest{
+				ProductIds: productIDs,
+				Page:       page,
+				PageSize:   int32(len(productIDs)),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get products: %w", err)
+			}
+			if !resp.Success && resp.ErrorMessage != "" {
+				return nil, fmt.Errorf("failed to get products: %s", resp.ErrorMessage)
+			}
+			if len(resp.Products) == 0 {
+				break
+			}
+			for _, product := range resp.Products {
+				products[product.Id] = productSnapshot{
+					Name:     product.Name,
+					Category: product.Category,
+					Price:    productPrice(product, s.roundPrice),
+				}
+			}
+		}
+		return products, nil
+	}
+
+	if s.db == nil {
+		return nil, fmt.Errorf("no product source configured")
+	}
+
+	// 在测试环境中直接查询数据库
+	for _, id := range productIDs {
+		var name string
+		var category sql.NullString
+		var price float64
+		err := s.db.QueryRowContext(ctx, "SELECT name, category, price FROM products WHERE id = ?", id).Scan(&name, &category, &price)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get product %d: %w", id, err)
+		}
+		products[id] = productSnapshot{
+			Name:     name,
+			Category: category.String,
+			Price:    money.FromFloat(s.roundPrice(price), money.DefaultCurrency),
+		}
+	}
+
+	return products, nil
+}
+
+// productPrice 返回商品的精确价格，旧版本商品服务未返回精确价格时转换浮点价格
+func productPrice(product *productpb.Product, round func(float64) float64) money.Money {
+	if product.PriceMoney != nil {
+		return money.New(product.PriceMoney.Amount, product.PriceMoney.Currency)
+	}
+	return money.FromFloat(round(product.Price), money.DefaultCurrency)
+}
+
+func toAPIMoney(m money.Money) *orderapi.Money {
+	return &orderapi.Money{
+		Amount:   m.Amount,
+		Currency: m.Currency,
+	}
+}
@@ -0,0 +1,70 @@
+package order
+
+import (
+	"context"
+	"testing"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	productpb "github.com/bytedance-youthcamp/demo/api/product"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceOrderItems(t *testing.T) {
+	productClient := new(MockProductClient)
+	productClient.On("GetProducts", mock.Anything, mock.Anything, mock.Anything).Return(&productpb.GetProductsResponse{
+		Success: true,
+		Products: []*productpb.Product{
+			{Id: 1, Name: "Keyboard", Price: 99.99},
+			{Id: 2, Name: "Mouse", Price: 19.5, PriceMoney: &productpb.Money{Amount: 1950, Currency: "CNY"}},
+		},
+	}, nil)
+
+	service := &orderService{productClient: productClient, pricePrecision: defaultPricePrecision}
+	ctx := context.Background()
+
+	t.Run("snapshots server prices", func(t *testing.T) {
+		items, total, errorMessage, err := service.priceOrderItems(ctx, &orderapi.CreateOrderRequest{
+			Items: []*orderapi.OrderItem{
+				{ProductId: 1, ProductName: "Cheap Keyboard", Quantity: 2, Price: 99.99},
+				{ProductId: 2, Quantity: 1, Price: 19.5},
+			},
+			TotalPrice: 219.48,
+		})
+		require.NoError(t, err)
+		assert.Empty(t, errorMessage)
+		assert.Equal(t, money.New(21948, "CNY"), total)
+		require.Len(t, items, 2)
+		assert.Equal(t, "Keyboard", items[0].ProductName)
+		assert.Equal(t, "Mouse", items[1].ProductName)
+	})
+
+	t.Run("rejects tampered total", func(t *testing.T) {
+		_, _, _, err := service.priceOrderItems(ctx, &orderapi.CreateOrderRequest{
+			Items: []*orderapi.OrderItem{
+				{ProductId: 1, Quantity: 2, Price: 0.01},
+			},
+			TotalMoney: &orderapi.Money{Amount: 2, Currency: "CNY"},
+		})
+		var mismatch *PriceMismatchError
+		require.ErrorAs(t, err, &mismatch)
+		assert.Equal(t, money.New(2, "CNY"), mismatch.ClientTotal)
+		assert.Equal(t, money.New(19998, "CNY"), mismatch.ServerTotal)
+		require.Len(t, mismatch.Items, 1)
+		assert.Equal(t, int32(1), mismatch.Items[0].ProductID)
+		assert.Equal(t, money.New(9999, "CNY"), mismatch.Items[0].ServerPrice)
+	})
+
+	t.Run("rejects unknown product", func(t *testing.T) {
+		_, _, errorMessage, err := service.priceOrderItems(ctx, &orderapi.CreateOrderRequest{
+			Items: []*orderapi.OrderItem{
+				{ProductId: 3, Quantity: 1, Price: 10},
+			},
+			TotalPrice: 10,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Product 3 not found", errorMessage)
+	})
+}
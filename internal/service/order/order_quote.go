@@ -0,0 +1,78 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+// QuoteOrder 按商品服务的当前价格对商品或购物车计价，依次计算优惠、税费和运费。
+// 只试算优惠券，不占用使用次数；下单时的价格以 CreateOrder 的计算结果为准
+func (s *orderService) QuoteOrder(ctx context.Context, req *orderapi.QuoteOrderRequest) (*orderapi.QuoteOrderResponse, error) {
+	if err := s.authorizeOwner(ctx, ownerOf(req.UserId), permissionRead); err != nil {
+		return nil, err
+	}
+
+	reqItems := req.Items
+	if req.CartId != 0 {
+		cartItems, errorMessage, err := s.loadCartItems(ctx, req.CartId, req.UserId, req.CartItemIds)
+		if err != nil {
+			return nil, err
+		}
+		if errorMessage == "" {
+			reqItems, _, errorMessage = cartOrderItems(cartItems)
+		}
+		if errorMessage != "" {
+			return &orderapi.QuoteOrderResponse{
+				Success:      false,
+				ErrorMessage: errorMessage,
+			}, nil
+		}
+	}
+
+	// 计价以商品服务的当前价格为准，忽略客户端提交的单价
+	items, subtotal, _, errorMessage, err := s.buildOrderItems(ctx, reqItems)
+	if err != nil {
+		return nil, fmt.Errorf("failed to price order items: %w", err)
+	}
+	if errorMessage != "" {
+		return &orderapi.QuoteOrderResponse{
+			Success:      false,
+			ErrorMessage: errorMessage,
+		}, nil
+	}
+
+	discount := money.Zero(subtotal.Currency)
+	if req.CouponCode != "" {
+		discount, errorMessage, err = s.previewCoupon(ctx, req.UserId, req.CouponCode, items)
+		if err != nil {
+			return nil, err
+		}
+		if errorMessage != "" {
+			return &orderapi.QuoteOrderResponse{
+				Success:      false,
+				ErrorMessage: errorMessage,
+			}, nil
+		}
+	}
+
+	quote := newPriceQuote(req.Region, items, subtotal, discount)
+	if err := s.calculatePrice(ctx, quote); err != nil {
+		var pricingErr *PricingError
+		if errors.As(err, &pricingErr) {
+			return &orderapi.QuoteOrderResponse{
+				Success:      false,
+				ErrorMessage: pricingErr.Message,
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to calculate order price: %w", err)
+	}
+
+	return &orderapi.QuoteOrderResponse{
+		Success:        true,
+		PriceBreakdown: toAPIPriceBreakdown(quote, req.CouponCode),
+	}, nil
+}
@@ -0,0 +1,170 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	productpb "github.com/bytedance-youthcamp/demo/api/product"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+)
+
+// StartRefund 将已支付或已完成的订单标记为退款中，重复请求不会产生副作用
+func (s *orderService) StartRefund(ctx context.Context, req *orderapi.StartRefundRequest) (*orderapi.StartRefundResponse, error) {
+	if err := s.authorizeAction(ctx, permissionWrite); err != nil {
+		return nil, err
+	}
+
+	// 获取订单
+	order, err := s.orderRepo.Get(ctx, fmt.Sprint(req.OrderId))
+	if err != nil {
+		return &orderapi.StartRefundResponse{
+			Success:      false,
+			ErrorMessage: "Order not found",
+		}, nil
+	}
+
+	// 重复请求直接返回成功
+	if order.Status == repository.OrderStatusRefunding {
+		return &orderapi.StartRefundResponse{
+			Success: true,
+		}, nil
+	}
+
+	if !canTransition(order.Status, repository.OrderStatusRefunding) {
+		return &orderapi.StartRefundResponse{
+			Success:      false,
+			ErrorMessage: "Order cannot be refunded",
+		}, nil
+	}
+
+	if err := s.transition(ctx, order, repository.OrderStatusRefunding, actorPayment, "refund "+req.RefundId); err != nil {
+		return &orderapi.StartRefundResponse{
+			Success:      false,
+			ErrorMessage: "Failed to update order status",
+		}, nil
+	}
+
+	return &orderapi.StartRefundResponse{
+		Success: true,
+	}, nil
+}
+
+// CompleteRefund 根据退款结果推进订单状态：退款成功时恢复退款商品的库存，
+// 全额退款的订单变为已退款，部分退款或退款失败的订单回到发起退款前的状态
+func (s *orderService) CompleteRefund(ctx context.Context, req *orderapi.CompleteRefundRequest) (*orderapi.CompleteRefundResponse, error) {
+	if err := s.authorizeAction(ctx, permissionWrite); err != nil {
+		return nil, err
+	}
+
+	// 获取订单
+	order, err := s.orderRepo.Get(ctx, fmt.Sprint(req.OrderId))
+	if err != nil {
+		return &orderapi.CompleteRefundResponse{
+			Success:      false,
+			ErrorMessage: "Order not found",
+		}, nil
+	}
+
+	// 订单已经不在退款中，说明是重复通知
+	if order.Status != repository.OrderStatusRefunding {
+		if order.Status == repository.OrderStatusRefunded || (!req.FullyRefunded && canTransition(repository.OrderStatusRefunding, order.Status)) {
+			return &orderapi.CompleteRefundResponse{
+				Success: true,
+			}, nil
+		}
+		return &orderapi.CompleteRefundResponse{
+			Success:      false,
+			ErrorMessage: "Order is not refunding",
+		}, nil
+	}
+
+	target := repository.OrderStatusRefunded
+	if !req.Success || !req.FullyRefunded {
+		target, err = s.previousStatus(ctx, order, repository.OrderStatusPaid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get order history: %w", err)
+		}
+	}
+
+	// 先恢复库存再更新订单状态，保证状态变化时库存已经归还
+	if req.Success && len(req.Items) > 0 {
+		errorMessage, err := s.restoreStock(ctx, req.RefundId, req.Items)
+		if err != nil {
+			log.Printf("Error restoring stock for refund %s: %v", req.RefundId, err)
+			errorMessage = "Failed to restore stock"
+		}
+		if errorMessage != "" {
+			return &orderapi.CompleteRefundResponse{
+				Success:      false,
+				ErrorMessage: errorMessage,
+			}, nil
+		}
+	}
+
+	reason := "refund " + req.RefundId + " succeeded"
+	if !req.Success {
+		reason = "refund " + req.RefundId + " failed"
+	}
+	if err := s.transition(ctx, order, target, actorPayment, reason); err != nil {
+		return &orderapi.CompleteRefundResponse{
+			Success:      false,
+			ErrorMessage: "Failed to update order status",
+		}, nil
+	}
+
+	return &orderapi.CompleteRefundResponse{
+		Success: true,
+	}, nil
+}
+
+// restoreStock 归还退款商品的库存，退款单号保证重试不会重复归还
+func (s *orderService) restoreStock(ctx context.Context, refundID string, items []*orderapi.OrderItem) (string, error) {
+	if s.productClient != nil {
+		stockItems := make([]*productpb.StockItem, len(items))
+		for i, item := range items {
+			stockItems[i] = &productpb.StockItem{
+				ProductId: item.ProductId,
+				Quantity:  item.Quantity,
+			}
+		}
+
+		restoreResp, err := s.productClient.RestoreStock(ctx, &productpb.RestoreStockRequest{
+			RequestId: "refund:" + refundID,
+			Items:     stockItems,
+		})
+		if err != nil {
+			return "", err
+		}
+		if !restoreResp.Success {
+			return "Failed to restore stock", nil
+		}
+		return "", nil
+	}
+
+	if s.db == nil {
+		return "", nil
+	}
+
+	// 在测试环境中直接使用数据库连接归还库存
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, item := range items {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE products SET stock = stock + ? WHERE id = ?",
+			item.Quantity, item.ProductId); err != nil {
+			return "", fmt.Errorf("failed to restore stock: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return "", nil
+}
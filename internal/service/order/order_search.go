@@ -0,0 +1,191 @@
+package order
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+)
+
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 100
+)
+
+// searchCursor 是 SearchOrders 返回给客户端的游标内容，客户端只透传 base64 编码后的字符串。
+// 游标记录了生成它时的排序方式，排序方式变化后旧游标失效
+type searchCursor struct {
+	SortBy      orderapi.OrderSortField `json:"s"`
+	Ascending   bool                    `json:"a,omitempty"`
+	CreatedAt   int64                   `json:"c,omitempty"` // 纳秒时间戳
+	TotalAmount int64                   `json:"t,omitempty"`
+	ID          int64                   `json:"i"`
+}
+
+// SearchOrders 按条件搜索订单，user_id 为 0 时搜索所有用户的订单。
+// 使用基于排序字段和订单 ID 的游标分页，翻页期间插入的新订单不会导致重复或遗漏
+func (s *orderService) SearchOrders(ctx context.Context, req *orderapi.SearchOrdersRequest) (*orderapi.SearchOrdersResponse, error) {
+	// 用户只能搜索自己的订单，搜索其他用户或所有用户的订单需要订单查看权限
+	if err := s.authorizeOwner(ctx, ownerOf(req.UserId), permissionRead); err != nil {
+		return nil, err
+	}
+
+	filter, errorMessage := searchFilter(req)
+	if errorMessage != "" {
+		return &orderapi.SearchOrdersResponse{
+			Success:      false,
+			ErrorMessage: errorMessage,
+		}, nil
+	}
+
+	// 多查询一个订单用于判断是否还有下一页
+	pageSize := filter.Limit
+	filter.Limit++
+	orders, err := s.orderRepo.SearchOrders(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search orders: %w", err)
+	}
+
+	var nextCursor string
+	if len(orders) > pageSize {
+		orders = orders[:pageSize]
+		nextCursor, err = encodeSearchCursor(req, orders[pageSize-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pbOrders := make([]*orderapi.Order, len(orders))
+	for i, order := range orders {
+		pbOrders[i] = toAPIOrder(order)
+	}
+
+	return &orderapi.SearchOrdersResponse{
+		Success:    true,
+		Orders:     pbOrders,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// searchFilter 将请求转换为仓储层的搜索条件，请求无效时返回错误信息
+func searchFilter(req *orderapi.SearchOrdersRequest) (repository.OrderSearchFilter, string) {
+	filter := repository.OrderSearchFilter{
+		Ascending: req.Ascending,
+		Limit:     int(req.PageSize),
+	}
+
+	switch {
+	case req.PageSize < 0:
+		return filter, "Invalid page size"
+	case req.PageSize == 0:
+		filter.Limit = defaultSearchPageSize
+	case req.PageSize > maxSearchPageSize:
+		filter.Limit = maxSearchPageSize
+	}
+
+	switch req.SortBy {
+	case orderapi.OrderSortField_SORT_BY_CREATED_AT:
+		filter.SortBy = repository.OrderSortByCreatedAt
+	case orderapi.OrderSortField_SORT_BY_TOTAL_AMOUNT:
+		filter.SortBy = repository.OrderSortByTotalAmount
+	default:
+		return filter, "Invalid sort field"
+	}
+
+	if req.UserId != 0 {
+		filter.UserID = strconv.Itoa(int(req.UserId))
+	}
+	if req.ProductId != 0 {
+		filter.ProductID = strconv.Itoa(int(req.ProductId))
+	}
+
+	for _, status := range req.Statuses {
+		if _, ok := orderapi.OrderStatus_name[int32(status)]; !ok {
+			return filter, "Invalid order status"
+		}
+		filter.Statuses = append(filter.Statuses, toRepoStatus(status))
+	}
+
+	if req.CreatedFrom != 0 {
+		from := time.UnixMilli(req.CreatedFrom)
+		filter.CreatedFrom = &from
+	}
+	if req.CreatedTo != 0 {
+		to := time.UnixMilli(req.CreatedTo)
+		filter.CreatedTo = &to
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return filter, "Invalid date range"
+	}
+
+	if req.MinAmount != nil {
+		min := money.New(req.MinAmount.Amount, req.MinAmount.Currency)
+		filter.MinAmount = &min
+	}
+	if req.MaxAmount != nil {
+		max := money.New(req.MaxAmount.Amount, req.MaxAmount.Currency)
+		filter.MaxAmount = &max
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil {
+		if cmp, err := filter.MinAmount.Cmp(*filter.MaxAmount); err != nil || cmp > 0 {
+			return filter, "Invalid amount range"
+		}
+	}
+
+	if req.Cursor != "" {
+		after, ok := decodeSearchCursor(req)
+		if !ok {
+			return filter, "Invalid cursor"
+		}
+		filter.After = after
+	}
+
+	return filter, ""
+}
+
+func encodeSearchCursor(req *orderapi.SearchOrdersRequest, last *repository.Order) (string, error) {
+	id, err := strconv.ParseInt(last.ID, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid order ID %q: %w", last.ID, err)
+	}
+
+	data, err := json.Marshal(searchCursor{
+		SortBy:      req.SortBy,
+		Ascending:   req.Ascending,
+		CreatedAt:   last.CreatedAt.UnixNano(),
+		TotalAmount: last.TotalAmount.Amount,
+		ID:          id,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeSearchCursor 解析请求中的游标，游标格式错误或与请求的排序方式不一致时返回 false
+func decodeSearchCursor(req *orderapi.SearchOrdersRequest) (*repository.OrderCursor, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(req.Cursor)
+	if err != nil {
+		return nil, false
+	}
+
+	var cursor searchCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, false
+	}
+	if cursor.SortBy != req.SortBy || cursor.Ascending != req.Ascending || cursor.ID <= 0 {
+		return nil, false
+	}
+
+	return &repository.OrderCursor{
+		CreatedAt:   time.Unix(0, cursor.CreatedAt),
+		TotalAmount: cursor.TotalAmount,
+		ID:          cursor.ID,
+	}, true
+}
@@ -0,0 +1,87 @@
+package order
+
+import (
+	"testing"
+	"time"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchFilter(t *testing.T) {
+	t.Run("defaults to all users and statuses", func(t *testing.T) {
+		filter, errorMessage := searchFilter(&orderapi.SearchOrdersRequest{})
+		require.Empty(t, errorMessage)
+		assert.Empty(t, filter.UserID)
+		assert.Empty(t, filter.Statuses)
+		assert.Equal(t, defaultSearchPageSize, filter.Limit)
+		assert.Equal(t, repository.OrderSortByCreatedAt, filter.SortBy)
+		assert.Nil(t, filter.After)
+	})
+
+	t.Run("converts filters", func(t *testing.T) {
+		filter, errorMessage := searchFilter(&orderapi.SearchOrdersRequest{
+			UserId:      7,
+			Statuses:    []orderapi.OrderStatus{orderapi.OrderStatus_PENDING, orderapi.OrderStatus_COMPLETED},
+			CreatedFrom: 1000,
+			CreatedTo:   2000,
+			MinAmount:   &orderapi.Money{Amount: 100, Currency: "CNY"},
+			ProductId:   3,
+			SortBy:      orderapi.OrderSortField_SORT_BY_TOTAL_AMOUNT,
+			PageSize:    500,
+		})
+		require.Empty(t, errorMessage)
+		assert.Equal(t, "7", filter.UserID)
+		assert.Equal(t, []repository.OrderStatus{repository.OrderStatusPending, repository.OrderStatusDelivered}, filter.Statuses)
+		assert.Equal(t, time.UnixMilli(1000), *filter.CreatedFrom)
+		assert.Equal(t, time.UnixMilli(2000), *filter.CreatedTo)
+		assert.Equal(t, money.New(100, "CNY"), *filter.MinAmount)
+		assert.Nil(t, filter.MaxAmount)
+		assert.Equal(t, "3", filter.ProductID)
+		assert.Equal(t, repository.OrderSortByTotalAmount, filter.SortBy)
+		assert.Equal(t, maxSearchPageSize, filter.Limit)
+	})
+
+	t.Run("rejects invalid ranges", func(t *testing.T) {
+		_, errorMessage := searchFilter(&orderapi.SearchOrdersRequest{CreatedFrom: 2000, CreatedTo: 1000})
+		assert.Equal(t, "Invalid date range", errorMessage)
+
+		_, errorMessage = searchFilter(&orderapi.SearchOrdersRequest{
+			MinAmount: &orderapi.Money{Amount: 200, Currency: "CNY"},
+			MaxAmount: &orderapi.Money{Amount: 100, Currency: "CNY"},
+		})
+		assert.Equal(t, "Invalid amount range", errorMessage)
+
+		_, errorMessage = searchFilter(&orderapi.SearchOrdersRequest{Statuses: []orderapi.OrderStatus{42}})
+		assert.Equal(t, "Invalid order status", errorMessage)
+	})
+}
+
+func TestSearchCursor(t *testing.T) {
+	req := &orderapi.SearchOrdersRequest{SortBy: orderapi.OrderSortField_SORT_BY_TOTAL_AMOUNT, Ascending: true}
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cursor, err := encodeSearchCursor(req, &repository.Order{
+		ID:          "42",
+		TotalAmount: money.New(1999, "CNY"),
+		CreatedAt:   createdAt,
+	})
+	require.NoError(t, err)
+
+	req.Cursor = cursor
+	filter, errorMessage := searchFilter(req)
+	require.Empty(t, errorMessage)
+	require.NotNil(t, filter.After)
+	assert.Equal(t, int64(42), filter.After.ID)
+	assert.Equal(t, int64(1999), filter.After.TotalAmount)
+	assert.True(t, createdAt.Equal(filter.After.CreatedAt))
+
+	// 排序方式变化后旧游标失效
+	_, errorMessage = searchFilter(&orderapi.SearchOrdersRequest{Cursor: cursor})
+	assert.Equal(t, "Invalid cursor", errorMessage)
+
+	_, errorMessage = searchFilter(&orderapi.SearchOrdersRequest{Cursor: "not-a-cursor"})
+	assert.Equal(t, "Invalid cursor", errorMessage)
+}
@@ -14,4 +14,8 @@ type OrderService interface {
 	GetUserOrders(ctx context.Context, req *orderapi.GetUserOrdersRequest) (*orderapi.GetUserOrdersResponse, error)
 	UpdateOrder(ctx context.Context, req *orderapi.UpdateOrderRequest) (*orderapi.UpdateOrderResponse, error)
 	CancelOrder(ctx context.Context, req *orderapi.CancelOrderRequest) (*orderapi.CancelOrderResponse, error)
+	MarkOrderPaymentFailed(ctx context.Context, req *orderapi.MarkOrderPaymentFailedRequest) (*orderapi.MarkOrderPaymentFailedResponse, error)
+	StartRefund(ctx context.Context, req *orderapi.StartRefundRequest) (*orderapi.StartRefundResponse, error)
+	CompleteRefund(ctx context.Context, req *orderapi.CompleteRefundRequest) (*orderapi.CompleteRefundResponse, error)
+	GetOrderHistory(ctx context.Context, req *orderapi.GetOrderHistoryRequest) (*orderapi.GetOrderHistoryResponse, error)
 }
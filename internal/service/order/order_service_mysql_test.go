@@ -9,6 +9,7 @@ import (
 	"time"
 
 	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	"github.com/bytedance-youthcamp/demo/internal/money"
 	"github.com/bytedance-youthcamp/demo/internal/repository"
 	"github.com/stretchr/testify/suite"
 
@@ -20,6 +21,7 @@ type OrderServiceMySQLTestSuite struct {
 	db           *sql.DB
 	orderService *orderService
 	orderRepo    repository.OrderRepository
+	shipmentRepo repository.ShipmentRepository
 }
 
 func (s *OrderServiceMySQLTestSuite) SetupSuite() {
@@ -41,10 +43,12 @@ func (s *OrderServiceMySQLTestSuite) SetupSuite() {
 
 	// Create repository
 	s.orderRepo = repository.NewMySQLOrderRepository(db)
+	s.shipmentRepo = repository.NewMySQLShipmentRepository(db)
 
 	// Create service
 	s.orderService, err = NewOrderService(
 		WithOrderRepository(s.orderRepo),
+		WithShipmentRepository(s.shipmentRepo),
 		WithTestDatabase(db),
 	)
 	s.Require().NoError(err)
@@ -69,10 +73,24 @@ func (s *OrderServiceMySQLTestSuite) setupTestDatabase() {
 		CREATE TABLE orders (
 			id INT AUTO_INCREMENT PRIMARY KEY,
 			user_id VARCHAR(50) NOT NULL,
-			total_amount DECIMAL(10, 2) NOT NULL,
+			total_amount_minor BIGINT NOT NULL,
+			currency CHAR(3) NOT NULL DEFAULT 'CNY',
 			status INT NOT NULL,
 			shipping_address TEXT,
-			items JSON,
+			contact_name VARCHAR(100),
+			contact_phone VARCHAR(20),
+			cancel_reason VARCHAR(255),
+			paid_at TIMESTAMP NULL,
+			cancelled_at TIMESTAMP NULL,
+			completed_at TIMESTAMP NULL,
+			auto_cancel_at TIMESTAMP NULL,
+			coupon_code VARCHAR(64) NOT NULL DEFAULT '',
+			coupon_redemption_id VARCHAR(64) NOT NULL DEFAULT '',
+			discount_amount_minor BIGINT NOT NULL DEFAULT 0,
+			region VARCHAR(32) NOT NULL DEFAULT '',
+			subtotal_amount_minor BIGINT NOT NULL DEFAULT 0,
+			tax_amount_minor BIGINT NOT NULL DEFAULT 0,
+			shipping_fee_minor BIGINT NOT NULL DEFAULT 0,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 		)
@@ -86,13 +104,135 @@ func (s *OrderServiceMySQLTestSuite) setupTestDatabase() {
 			order_id INT NOT NULL,
 			product_id VARCHAR(50) NOT NULL,
 			product_name VARCHAR(255) NOT NULL,
+			category VARCHAR(100) NOT NULL DEFAULT '',
 			quantity INT NOT NULL,
-			price DECIMAL(10, 2) NOT NULL,
+			cancelled_quantity INT NOT NULL DEFAULT 0,
+			price_minor BIGINT NOT NULL,
+			discount_minor BIGINT NOT NULL DEFAULT 0,
+			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+		)
+	`)
+	s.Require().NoError(err)
+
+	// Create order_status_history table
+	_, err = s.db.Exec(`
+		CREATE TABLE order_status_history (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			order_id INT NOT NULL,
+			from_status INT NOT NULL,
+			to_status INT NOT NULL,
+			actor VARCHAR(64) NOT NULL,
+			reason VARCHAR(255),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_order_status_history_order_id (order_id),
+			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+		)
+	`)
+	s.Require().NoError(err)
+
+	// Create outbox_events table
+	_, err = s.db.Exec(`
+		CREATE TABLE outbox_events (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			event_id VARCHAR(36) NOT NULL UNIQUE,
+			event_type VARCHAR(64) NOT NULL,
+			aggregate_type VARCHAR(32) NOT NULL,
+			aggregate_id VARCHAR(64) NOT NULL,
+			payload TEXT NOT NULL,
+			occurred_at TIMESTAMP NOT NULL,
+			published_at TIMESTAMP NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			last_error VARCHAR(255) NOT NULL DEFAULT ''
+		)
+	`)
+	s.Require().NoError(err)
+
+	// Create user_order_stats table
+	_, err = s.db.Exec(`
+		CREATE TABLE user_order_stats (
+			user_id VARCHAR(50) NOT NULL,
+			status INT NOT NULL,
+			currency CHAR(3) NOT NULL,
+			order_count INT NOT NULL DEFAULT 0,
+			amount_minor BIGINT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (user_id, status, currency)
+		)
+	`)
+	s.Require().NoError(err)
+
+	// Create user_order_summaries table
+	_, err = s.db.Exec(`
+		CREATE TABLE user_order_summaries (
+			user_id VARCHAR(50) NOT NULL PRIMARY KEY,
+			last_order_id BIGINT NOT NULL,
+			last_order_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)
+	`)
+	s.Require().NoError(err)
+
+	// Create order_item_changes table
+	_, err = s.db.Exec(`
+		CREATE TABLE order_item_changes (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			order_id INT NOT NULL,
+			product_id VARCHAR(50) NOT NULL,
+			change_type INT NOT NULL,
+			old_quantity INT NOT NULL,
+			new_quantity INT NOT NULL,
+			amount_minor BIGINT NOT NULL,
+			refund_id VARCHAR(64),
+			actor VARCHAR(64) NOT NULL,
+			reason VARCHAR(255),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_order_item_changes_order_id (order_id),
+			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+		)
+	`)
+	s.Require().NoError(err)
+
+	// Create shipment tables
+	_, err = s.db.Exec(`
+		CREATE TABLE shipments (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			order_id INT NOT NULL,
+			carrier VARCHAR(64) NOT NULL,
+			tracking_number VARCHAR(64),
+			status INT NOT NULL,
+			shipped_at TIMESTAMP NULL,
+			delivered_at TIMESTAMP NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
 		)
 	`)
 	s.Require().NoError(err)
 
+	_, err = s.db.Exec(`
+		CREATE TABLE shipment_items (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			shipment_id BIGINT NOT NULL,
+			product_id VARCHAR(50) NOT NULL,
+			quantity INT NOT NULL,
+			FOREIGN KEY (shipment_id) REFERENCES shipments(id) ON DELETE CASCADE
+		)
+	`)
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec(`
+		CREATE TABLE shipment_events (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			shipment_id BIGINT NOT NULL,
+			status INT NOT NULL,
+			location VARCHAR(255),
+			description VARCHAR(255),
+			event_time TIMESTAMP NOT NULL,
+			FOREIGN KEY (shipment_id) REFERENCES shipments(id) ON DELETE CASCADE
+		)
+	`)
+	s.Require().NoError(err)
+
 	// Create products table for testing
 	_, err = s.db.Exec(`
 		CREATE TABLE IF NOT EXISTS products (
@@ -138,7 +278,31 @@ func (s *OrderServiceMySQLTestSuite) setupTestDatabase() {
 
 func (s *OrderServiceMySQLTestSuite) dropTestTables() {
 	// Drop tables in reverse order to avoid foreign key constraints
-	_, err := s.db.Exec("DROP TABLE IF EXISTS order_items")
+	_, err := s.db.Exec("DROP TABLE IF EXISTS shipment_events")
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS shipment_items")
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS shipments")
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS order_item_changes")
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS order_items")
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS order_status_history")
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS outbox_events")
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS user_order_stats")
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS user_order_summaries")
 	s.Require().NoError(err)
 
 	// First check if payments table exists and drop it
@@ -194,13 +358,13 @@ func (s *OrderServiceMySQLTestSuite) TestCreateOrder() {
 	order, err := s.orderRepo.Get(ctx, strconv.Itoa(int(resp.OrderId)))
 	s.Require().NoError(err)
 	s.Equal("1", order.UserID)
-	s.Equal(199.98, order.TotalAmount)
+	s.Equal(199.98, order.TotalAmount.Float64())
 	s.Equal(repository.OrderStatusPending, order.Status)
 	s.Len(order.Items, 1)
 	s.Equal("1", order.Items[0].ProductID)
 	s.Equal("Test Product", order.Items[0].ProductName)
 	s.Equal(int32(2), order.Items[0].Quantity)
-	s.Equal(99.99, order.Items[0].Price)
+	s.Equal(99.99, order.Items[0].Price.Float64())
 }
 
 func (s *OrderServiceMySQLTestSuite) TestUpdateOrder() {
@@ -210,17 +374,17 @@ func (s *OrderServiceMySQLTestSuite) TestUpdateOrder() {
 
 	ctx := context.Background()
 
-	// First create an order
+	// First create a paid order
 	order := &repository.Order{
 		UserID:      "1",
-		TotalAmount: 199.98,
-		Status:      repository.OrderStatusPending,
+		TotalAmount: money.FromFloat(199.98, money.DefaultCurrency),
+		Status:      repository.OrderStatusPaid,
 		Items: []*repository.OrderItem{
 			{
 				ProductID:   "1",
 				ProductName: "Test Product",
 				Quantity:    2,
-				Price:       99.99,
+				Price:       money.FromFloat(99.99, money.DefaultCurrency),
 			},
 		},
 		CreatedAt: time.Now(),
@@ -259,14 +423,14 @@ func (s *OrderServiceMySQLTestSuite) TestCancelOrder() {
 	// First create an order
 	order := &repository.Order{
 		UserID:      "1",
-		TotalAmount: 199.98,
+		TotalAmount: money.FromFloat(199.98, money.DefaultCurrency),
 		Status:      repository.OrderStatusPending,
 		Items: []*repository.OrderItem{
 			{
 				ProductID:   "1",
 				ProductName: "Test Product",
 				Quantity:    2,
-				Price:       99.99,
+				Price:       money.FromFloat(99.99, money.DefaultCurrency),
 			},
 		},
 		CreatedAt: time.Now(),
@@ -293,6 +457,56 @@ func (s *OrderServiceMySQLTestSuite) TestCancelOrder() {
 	s.Equal(repository.OrderStatusCancelled, cancelledOrder.Status)
 }
 
+func (s *OrderServiceMySQLTestSuite) TestUpdateStatusKeepsConcurrentChanges() {
+	if s.db == nil {
+		s.T().Skip("Skipping test due to no database connection")
+	}
+
+	ctx := context.Background()
+
+	order := &repository.Order{
+		UserID:      "1",
+		TotalAmount: money.FromFloat(199.98, money.DefaultCurrency),
+		Status:      repository.OrderStatusPending,
+		Items: []*repository.OrderItem{
+			{
+				ProductID:   "1",
+				ProductName: "Test Product",
+				Quantity:    2,
+				Price:       money.FromFloat(99.99, money.DefaultCurrency),
+			},
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	s.Require().NoError(s.orderRepo.Create(ctx, order))
+
+	// Load the order, then change its items and contact before the status change is saved
+	stale, err := s.orderRepo.Get(ctx, order.ID)
+	s.Require().NoError(err)
+
+	_, err = s.orderRepo.UpdateItems(ctx, order.ID, func(ctx context.Context, order *repository.Order) ([]*repository.OrderItemChange, error) {
+		order.Items[0].Quantity = 1
+		order.TotalAmount = money.FromFloat(99.99, money.DefaultCurrency)
+		return nil, nil
+	})
+	s.Require().NoError(err)
+	edited := *stale
+	edited.ContactName = "Alice"
+	edited.UpdatedAt = time.Now()
+	s.Require().NoError(s.orderRepo.UpdateContact(ctx, &edited))
+
+	s.Require().NoError(s.orderService.transition(ctx, stale, repository.OrderStatusCancelled, actorSystem, "test"))
+
+	saved, err := s.orderRepo.Get(ctx, order.ID)
+	s.Require().NoError(err)
+	s.Equal(repository.OrderStatusCancelled, saved.Status)
+	s.Equal(money.FromFloat(99.99, money.DefaultCurrency), saved.TotalAmount)
+	s.Equal(int32(1), saved.Items[0].Quantity)
+	s.Equal("Alice", saved.ContactName)
+	s.Equal(saved.TotalAmount, stale.TotalAmount)
+}
+
 func (s *OrderServiceMySQLTestSuite) TestAutoCancelOrder() {
 	if s.db == nil {
 		s.T().Skip("Skipping test due to no database connection")
@@ -300,57 +514,398 @@ func (s *OrderServiceMySQLTestSuite) TestAutoCancelOrder() {
 
 	ctx := context.Background()
 
-	// Create an order with a timestamp in the past
+	// Create an order whose auto-cancel time has passed and one that is not due yet
 	pastTime := time.Now().Add(-35 * time.Minute) // 35 minutes ago
+	expiredAt := time.Now().Add(-5 * time.Minute)
+	notDueAt := time.Now().Add(25 * time.Minute)
+	newOrder := func(autoCancelAt time.Time) *repository.Order {
+		return &repository.Order{
+			UserID:      "1",
+			TotalAmount: money.FromFloat(199.98, money.DefaultCurrency),
+			Status:      repository.OrderStatusPending,
+			Items: []*repository.OrderItem{
+				{
+					ProductID:   "1",
+					ProductName: "Test Product",
+					Quantity:    2,
+					Price:       money.FromFloat(99.99, money.DefaultCurrency),
+				},
+			},
+			CreatedAt:    pastTime,
+			UpdatedAt:    pastTime,
+			AutoCancelAt: &autoCancelAt,
+		}
+	}
+
+	expired := newOrder(expiredAt)
+	s.Require().NoError(s.orderRepo.Create(ctx, expired))
+	notDue := newOrder(notDueAt)
+	s.Require().NoError(s.orderRepo.Create(ctx, notDue))
+
+	cancelled, err := s.orderService.cancelExpiredOrders(ctx, time.Now())
+	s.Require().NoError(err)
+	s.GreaterOrEqual(cancelled, 1)
+
+	// Verify only the expired order was cancelled
+	cancelledOrder, err := s.orderRepo.Get(ctx, expired.ID)
+	s.Require().NoError(err)
+	s.Equal(repository.OrderStatusCancelled, cancelledOrder.Status)
+	s.Equal("payment timeout", cancelledOrder.CancelReason)
+	s.NotNil(cancelledOrder.CancelledAt)
+	// Released after the cancelling transaction committed
+	s.Nil(cancelledOrder.AutoCancelAt)
+
+	history, err := s.orderRepo.ListStatusHistory(ctx, expired.ID)
+	s.Require().NoError(err)
+	s.Require().Len(history, 1)
+	s.Equal("system", history[0].Actor)
+
+	pendingOrder, err := s.orderRepo.Get(ctx, notDue.ID)
+	s.Require().NoError(err)
+	s.Equal(repository.OrderStatusPending, pendingOrder.Status)
+
+	// Running again is a no-op for already cancelled orders
+	_, err = s.orderService.cancelExpiredOrders(ctx, time.Now())
+	s.Require().NoError(err)
+	history, err = s.orderRepo.ListStatusHistory(ctx, expired.ID)
+	s.Require().NoError(err)
+	s.Len(history, 1)
+}
+
+func (s *OrderServiceMySQLTestSuite) TestSearchOrders() {
+	if s.db == nil {
+		s.T().Skip("Skipping test due to no database connection")
+	}
+
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	// Orders of two users with different statuses, amounts and products
+	fixtures := []struct {
+		userID    string
+		status    repository.OrderStatus
+		productID string
+		amount    int64
+	}{
+		{"1", repository.OrderStatusPending, "1", 1000},
+		{"1", repository.OrderStatusPaid, "2", 3000},
+		{"1", repository.OrderStatusDelivered, "1", 2000},
+		{"2", repository.OrderStatusPending, "1", 5000},
+	}
+	for i, f := range fixtures {
+		createdAt := base.Add(time.Duration(i) * time.Minute)
+		order := &repository.Order{
+			UserID:      f.userID,
+			TotalAmount: money.New(f.amount, money.DefaultCurrency),
+			Status:      f.status,
+			Items: []*repository.OrderItem{
+				{ProductID: f.productID, ProductName: "Test Product", Quantity: 1, Price: money.New(f.amount, money.DefaultCurrency)},
+			},
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		}
+		s.Require().NoError(s.orderRepo.Create(ctx, order))
+	}
+
+	// All statuses of one user, newest first, paged by cursor
+	resp, err := s.orderService.SearchOrders(ctx, &orderapi.SearchOrdersRequest{UserId: 1, PageSize: 2})
+	s.Require().NoError(err)
+	s.Require().True(resp.Success, resp.ErrorMessage)
+	s.Require().Len(resp.Orders, 2)
+	s.Equal(orderapi.OrderStatus_COMPLETED, resp.Orders[0].Status)
+	s.Equal(orderapi.OrderStatus_PAID, resp.Orders[1].Status)
+	s.Require().NotEmpty(resp.NextCursor)
+
+	resp, err = s.orderService.SearchOrders(ctx, &orderapi.SearchOrdersRequest{UserId: 1, PageSize: 2, Cursor: resp.NextCursor})
+	s.Require().NoError(err)
+	s.Require().True(resp.Success, resp.ErrorMessage)
+	s.Require().Len(resp.Orders, 1)
+	s.Equal(orderapi.OrderStatus_PENDING, resp.Orders[0].Status)
+	s.Len(resp.Orders[0].Items, 1)
+	s.Empty(resp.NextCursor)
+
+	// Across users, filtered by status and product, sorted by amount
+	resp, err = s.orderService.SearchOrders(ctx, &orderapi.SearchOrdersRequest{
+		Statuses:  []orderapi.OrderStatus{orderapi.OrderStatus_PENDING, orderapi.OrderStatus_COMPLETED},
+		ProductId: 1,
+		MinAmount: &orderapi.Money{Amount: 1500, Currency: money.DefaultCurrency},
+		SortBy:    orderapi.OrderSortField_SORT_BY_TOTAL_AMOUNT,
+		Ascending: true,
+	})
+	s.Require().NoError(err)
+	s.Require().True(resp.Success, resp.ErrorMessage)
+	s.Require().Len(resp.Orders, 2)
+	s.Equal(int64(2000), resp.Orders[0].TotalMoney.Amount)
+	s.Equal(int64(5000), resp.Orders[1].TotalMoney.Amount)
+
+	// Date range
+	resp, err = s.orderService.SearchOrders(ctx, &orderapi.SearchOrdersRequest{
+		CreatedFrom: base.Add(time.Minute).UnixMilli(),
+		CreatedTo:   base.Add(3 * time.Minute).UnixMilli(),
+	})
+	s.Require().NoError(err)
+	s.Require().True(resp.Success, resp.ErrorMessage)
+	s.Len(resp.Orders, 2)
+}
+
+func (s *OrderServiceMySQLTestSuite) TestShipmentLifecycle() {
+	if s.db == nil {
+		s.T().Skip("Skipping test due to no database connection")
+	}
+
+	ctx := context.Background()
+
 	order := &repository.Order{
 		UserID:      "1",
-		TotalAmount: 199.98,
-		Status:      repository.OrderStatusPending,
+		TotalAmount: money.FromFloat(199.98, money.DefaultCurrency),
+		Status:      repository.OrderStatusPaid,
 		Items: []*repository.OrderItem{
 			{
 				ProductID:   "1",
 				ProductName: "Test Product",
 				Quantity:    2,
-				Price:       99.99,
+				Price:       money.FromFloat(99.99, money.DefaultCurrency),
 			},
 		},
-		CreatedAt: pastTime,
-		UpdatedAt: pastTime,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	s.Require().NoError(s.orderRepo.Create(ctx, order))
+	orderID, _ := strconv.Atoi(order.ID)
+
+	// Ship one of the two items
+	first, err := s.orderService.CreateShipment(ctx, &orderapi.CreateShipmentRequest{
+		OrderId:        int32(orderID),
+		Carrier:        "SF Express",
+		TrackingNumber: "SF1001",
+		Items:          []*orderapi.ShipmentItem{{ProductId: 1, Quantity: 1}},
+	})
+	s.Require().NoError(err)
+	s.Require().True(first.Success, first.ErrorMessage)
+	s.Equal(orderapi.OrderStatus_SHIPPING, first.OrderStatus)
+
+	// Cannot ship more than was ordered
+	exceeded, err := s.orderService.CreateShipment(ctx, &orderapi.CreateShipmentRequest{
+		OrderId: int32(orderID),
+		Carrier: "SF Express",
+		Items:   []*orderapi.ShipmentItem{{ProductId: 1, Quantity: 2}},
+	})
+	s.Require().NoError(err)
+	s.False(exceeded.Success)
+
+	// Ship the rest
+	second, err := s.orderService.CreateShipment(ctx, &orderapi.CreateShipmentRequest{
+		OrderId: int32(orderID),
+		Carrier: "YTO",
+	})
+	s.Require().NoError(err)
+	s.Require().True(second.Success, second.ErrorMessage)
+
+	trackResp, err := s.orderService.UpdateShipmentTracking(ctx, &orderapi.UpdateShipmentTrackingRequest{
+		ShipmentId:  first.ShipmentId,
+		Status:      orderapi.ShipmentStatus_SHIPMENT_IN_TRANSIT,
+		Location:    "Shenzhen",
+		Description: "picked up",
+	})
+	s.Require().NoError(err)
+	s.True(trackResp.Success)
+
+	// The order completes only after every shipment is delivered
+	confirmResp, err := s.orderService.ConfirmDelivery(ctx, &orderapi.ConfirmDeliveryRequest{ShipmentId: first.ShipmentId})
+	s.Require().NoError(err)
+	s.True(confirmResp.Success)
+	s.Equal(orderapi.OrderStatus_SHIPPING, confirmResp.OrderStatus)
+
+	confirmResp, err = s.orderService.ConfirmDelivery(ctx, &orderapi.ConfirmDeliveryRequest{ShipmentId: second.ShipmentId})
+	s.Require().NoError(err)
+	s.True(confirmResp.Success)
+	s.Equal(orderapi.OrderStatus_COMPLETED, confirmResp.OrderStatus)
+
+	// Delivered shipments cannot go back in transit
+	trackResp, err = s.orderService.UpdateShipmentTracking(ctx, &orderapi.UpdateShipmentTrackingRequest{
+		ShipmentId: second.ShipmentId,
+		Status:     orderapi.ShipmentStatus_SHIPMENT_IN_TRANSIT,
+	})
+	s.Require().NoError(err)
+	s.False(trackResp.Success)
+
+	shipmentsResp, err := s.orderService.GetOrderShipments(ctx, &orderapi.GetOrderShipmentsRequest{OrderId: int32(orderID)})
+	s.Require().NoError(err)
+	s.Require().Len(shipmentsResp.Shipments, 2)
+	s.Equal("SF1001", shipmentsResp.Shipments[0].TrackingNumber)
+	s.Len(shipmentsResp.Shipments[0].Events, 3)
+	s.NotEmpty(shipmentsResp.Shipments[0].ShippedAt)
+	s.Equal(int32(1), shipmentsResp.Shipments[1].Items[0].Quantity)
+}
+
+func (s *OrderServiceMySQLTestSuite) TestOrderStatusHistory() {
+	if s.db == nil {
+		s.T().Skip("Skipping test due to no database connection")
+	}
+
+	ctx := context.Background()
+
+	order := &repository.Order{
+		UserID:      "1",
+		TotalAmount: money.FromFloat(99.99, money.DefaultCurrency),
+		Status:      repository.OrderStatusPending,
+		Items: []*repository.OrderItem{
+			{
+				ProductID:   "1",
+				ProductName: "Test Product",
+				Quantity:    1,
+				Price:       money.FromFloat(99.99, money.DefaultCurrency),
+			},
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
 	}
 
 	err := s.orderRepo.Create(ctx, order)
 	s.Require().NoError(err)
+	orderID := int32(s.parseOrderID(order.ID))
 
-	// Get pending orders older than 30 minutes
-	oldOrders, err := s.orderRepo.ListPendingOrdersOlderThan(ctx, 30*time.Minute)
+	// Pending orders cannot be shipped directly
+	updateResp, err := s.orderService.UpdateOrder(ctx, &orderapi.UpdateOrderRequest{
+		OrderId: orderID,
+		Status:  orderapi.OrderStatus_SHIPPING,
+	})
+	s.Require().NoError(err)
+	s.False(updateResp.Success)
+
+	err = s.orderService.transition(ctx, order, repository.OrderStatusShipped, actorOperator, "")
+	var invalid *InvalidTransitionError
+	s.Require().ErrorAs(err, &invalid)
+	s.Equal(repository.OrderStatusPending, invalid.From)
+	s.Equal(repository.OrderStatusShipped, invalid.To)
+
+	cancelResp, err := s.orderService.CancelOrder(ctx, &orderapi.CancelOrderRequest{
+		OrderId:      orderID,
+		CancelReason: "Changed my mind",
+	})
 	s.Require().NoError(err)
-	s.NotEmpty(oldOrders)
+	s.True(cancelResp.Success)
+
+	historyResp, err := s.orderService.GetOrderHistory(ctx, &orderapi.GetOrderHistoryRequest{OrderId: orderID})
+	s.Require().NoError(err)
+	s.True(historyResp.Success)
+	s.Require().Len(historyResp.History, 1)
+	s.Equal(orderapi.OrderStatus_PENDING, historyResp.History[0].FromStatus)
+	s.Equal(orderapi.OrderStatus_CANCELLED, historyResp.History[0].ToStatus)
+	s.Equal(actorUser, historyResp.History[0].Actor)
+	s.Equal("Changed my mind", historyResp.History[0].Reason)
+}
+
+func (s *OrderServiceMySQLTestSuite) TestGetOrderDetailsReturnsFullOrder() {
+	if s.db == nil {
+		s.T().Skip("Skipping test due to no database connection")
+	}
 
-	// Verify our order is in the list
-	found := false
-	for _, oldOrder := range oldOrders {
-		if oldOrder.ID == order.ID {
-			found = true
-			break
+	ctx := context.Background()
+
+	createResp, err := s.orderService.CreateOrder(ctx, &orderapi.CreateOrderRequest{
+		UserId: 1,
+		Items: []*orderapi.OrderItem{
+			{ProductId: 1, ProductName: "Test Product", Quantity: 2, Price: 99.99},
+		},
+		TotalPrice:   199.98,
+		Address:      "Test Address",
+		ContactName:  "Tester",
+		ContactPhone: "1234567890",
+	})
+	s.Require().NoError(err)
+	s.Require().True(createResp.Success)
+
+	cancelResp, err := s.orderService.CancelOrder(ctx, &orderapi.CancelOrderRequest{
+		OrderId:      createResp.OrderId,
+		CancelReason: "Test cancellation",
+	})
+	s.Require().NoError(err)
+	s.Require().True(cancelResp.Success)
+
+	detailsResp, err := s.orderService.GetOrderDetails(ctx, &orderapi.GetOrderDetailsRequest{
+		OrderId: createResp.OrderId,
+		UserId:  1,
+	})
+	s.Require().NoError(err)
+	s.Require().True(detailsResp.Success)
+
+	order := detailsResp.Order
+	s.Equal("Test Address", order.Address)
+	s.Equal("Tester", order.ContactName)
+	s.Equal("1234567890", order.ContactPhone)
+	s.Equal("Test cancellation", order.CancelReason)
+	s.Equal(orderapi.OrderStatus_CANCELLED, order.Status)
+	s.NotEmpty(order.CancelledAt)
+	s.Empty(order.PaidAt)
+	s.NotZero(order.AutoCancelTime)
+	s.Require().Len(order.Items, 1)
+	s.Equal(int32(2), order.Items[0].Quantity)
+}
+
+// Helper function to parse order ID from string to int
+func (s *OrderServiceMySQLTestSuite) TestUserOrderSummary() {
+	if s.db == nil {
+		s.T().Skip("Skipping test due to no database connection")
+	}
+
+	ctx := context.Background()
+
+	// Use a user that no other test creates orders for
+	var orderIDs []int32
+	for i := 0; i < 2; i++ {
+		order := &repository.Order{
+			UserID:      "77",
+			TotalAmount: money.FromFloat(50, money.DefaultCurrency),
+			Status:      repository.OrderStatusPending,
+			Items: []*repository.OrderItem{
+				{
+					ProductID:   "1",
+					ProductName: "Test Product",
+					Quantity:    1,
+					Price:       money.FromFloat(50, money.DefaultCurrency),
+				},
+			},
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
 		}
+		s.Require().NoError(s.orderRepo.Create(ctx, order))
+		orderIDs = append(orderIDs, int32(s.parseOrderID(order.ID)))
 	}
-	s.True(found)
 
-	// Cancel the order
-	for _, oldOrder := range oldOrders {
-		oldOrder.Status = repository.OrderStatusCancelled
-		oldOrder.UpdatedAt = time.Now()
-		err = s.orderRepo.Update(ctx, oldOrder)
+	cancelResp, err := s.orderService.CancelOrder(ctx, &orderapi.CancelOrderRequest{
+		OrderId:      orderIDs[0],
+		CancelReason: "Changed my mind",
+	})
+	s.Require().NoError(err)
+	s.True(cancelResp.Success)
+
+	assertSummary := func() {
+		resp, err := s.orderService.GetUserOrderSummary(ctx, &orderapi.GetUserOrderSummaryRequest{UserId: 77})
 		s.Require().NoError(err)
+		s.True(resp.Success)
+		s.Equal(int32(2), resp.TotalOrders)
+		s.Equal(orderIDs[1], resp.LastOrderId)
+		s.Empty(resp.LifetimeSpend)
+
+		s.Require().Len(resp.StatusCounts, 2)
+		s.Equal(orderapi.OrderStatus_PENDING, resp.StatusCounts[0].Status)
+		s.Equal(int32(1), resp.StatusCounts[0].Count)
+		s.Equal(orderapi.OrderStatus_CANCELLED, resp.StatusCounts[1].Status)
+		s.Equal(int32(1), resp.StatusCounts[1].Count)
+		s.Equal(int64(5000), resp.StatusCounts[1].Amounts[0].Amount)
 	}
+	assertSummary()
 
-	// Verify the order was cancelled
-	cancelledOrder, err := s.orderRepo.Get(ctx, order.ID)
+	// Rebuilding from the orders table gives the same aggregates
+	_, err = s.db.Exec("DELETE FROM user_order_stats WHERE user_id = ?", "77")
 	s.Require().NoError(err)
-	s.Equal(repository.OrderStatusCancelled, cancelledOrder.Status)
+	users, err := s.orderRepo.(*repository.MySQLOrderRepository).RebuildUserOrderSummaries(ctx, "77")
+	s.Require().NoError(err)
+	s.Equal(1, users)
+	assertSummary()
 }
 
-// Helper function to parse order ID from string to int
 func (s *OrderServiceMySQLTestSuite) parseOrderID(id string) int {
 	var orderID int
 	_, err := fmt.Sscanf(id, "%d", &orderID)
@@ -9,6 +9,7 @@ import (
 
 	orderapi "github.com/bytedance-youthcamp/demo/api/order"
 	// productpb "github.com/bytedance-youthcamp/demo/api/product"
+	"github.com/bytedance-youthcamp/demo/internal/money"
 	"github.com/bytedance-youthcamp/demo/internal/repository"
 	"github.com/stretchr/testify/suite"
 
@@ -45,6 +46,7 @@ func (s *OrderServiceSettleTestSuite) SetupSuite() {
 	// Create service
 	s.orderService, err = NewOrderService(
 		WithOrderRepository(s.orderRepo),
+		WithIdempotencyRepository(repository.NewMySQLIdempotencyRepository(db)),
 		WithTestDatabase(db),
 	)
 	s.Require().NoError(err)
@@ -69,10 +71,24 @@ func (s *OrderServiceSettleTestSuite) setupTestDatabase() {
 		CREATE TABLE orders (
 			id INT AUTO_INCREMENT PRIMARY KEY,
 			user_id VARCHAR(50) NOT NULL,
-			total_amount DECIMAL(10, 2) NOT NULL,
+			total_amount_minor BIGINT NOT NULL,
+			currency CHAR(3) NOT NULL DEFAULT 'CNY',
 			status INT NOT NULL,
 			shipping_address TEXT,
-			items JSON,
+			contact_name VARCHAR(100),
+			contact_phone VARCHAR(20),
+			cancel_reason VARCHAR(255),
+			paid_at TIMESTAMP NULL,
+			cancelled_at TIMESTAMP NULL,
+			completed_at TIMESTAMP NULL,
+			auto_cancel_at TIMESTAMP NULL,
+			coupon_code VARCHAR(64) NOT NULL DEFAULT '',
+			coupon_redemption_id VARCHAR(64) NOT NULL DEFAULT '',
+			discount_amount_minor BIGINT NOT NULL DEFAULT 0,
+			region VARCHAR(32) NOT NULL DEFAULT '',
+			subtotal_amount_minor BIGINT NOT NULL DEFAULT 0,
+			tax_amount_minor BIGINT NOT NULL DEFAULT 0,
+			shipping_fee_minor BIGINT NOT NULL DEFAULT 0,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 		)
@@ -86,13 +102,74 @@ func (s *OrderServiceSettleTestSuite) setupTestDatabase() {
 			order_id INT NOT NULL,
 			product_id VARCHAR(50) NOT NULL,
 			product_name VARCHAR(255) NOT NULL,
+			category VARCHAR(100) NOT NULL DEFAULT '',
 			quantity INT NOT NULL,
-			price DECIMAL(10, 2) NOT NULL,
+			cancelled_quantity INT NOT NULL DEFAULT 0,
+			price_minor BIGINT NOT NULL,
+			discount_minor BIGINT NOT NULL DEFAULT 0,
+			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+		)
+	`)
+	s.Require().NoError(err)
+
+	// Create order_status_history table
+	_, err = s.db.Exec(`
+		CREATE TABLE order_status_history (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			order_id INT NOT NULL,
+			from_status INT NOT NULL,
+			to_status INT NOT NULL,
+			actor VARCHAR(64) NOT NULL,
+			reason VARCHAR(255),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_order_status_history_order_id (order_id),
 			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
 		)
 	`)
 	s.Require().NoError(err)
 
+	// Create outbox_events table
+	_, err = s.db.Exec(`
+		CREATE TABLE outbox_events (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			event_id VARCHAR(36) NOT NULL UNIQUE,
+			event_type VARCHAR(64) NOT NULL,
+			aggregate_type VARCHAR(32) NOT NULL,
+			aggregate_id VARCHAR(64) NOT NULL,
+			payload TEXT NOT NULL,
+			occurred_at TIMESTAMP NOT NULL,
+			published_at TIMESTAMP NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			last_error VARCHAR(255) NOT NULL DEFAULT ''
+		)
+	`)
+	s.Require().NoError(err)
+
+	// Create user_order_stats table
+	_, err = s.db.Exec(`
+		CREATE TABLE user_order_stats (
+			user_id VARCHAR(50) NOT NULL,
+			status INT NOT NULL,
+			currency CHAR(3) NOT NULL,
+			order_count INT NOT NULL DEFAULT 0,
+			amount_minor BIGINT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (user_id, status, currency)
+		)
+	`)
+	s.Require().NoError(err)
+
+	// Create user_order_summaries table
+	_, err = s.db.Exec(`
+		CREATE TABLE user_order_summaries (
+			user_id VARCHAR(50) NOT NULL PRIMARY KEY,
+			last_order_id BIGINT NOT NULL,
+			last_order_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)
+	`)
+	s.Require().NoError(err)
+
 	// Create products table for testing
 	_, err = s.db.Exec(`
 		CREATE TABLE products (
@@ -115,6 +192,20 @@ func (s *OrderServiceSettleTestSuite) setupTestDatabase() {
 		VALUES (1, 'Test Product', 'Test Description', 99.99, 10, 'Test Category', 'http://example.com/image.jpg')
 	`)
 	s.Require().NoError(err)
+
+	// Create idempotency_keys table
+	_, err = s.db.Exec(`
+		CREATE TABLE idempotency_keys (
+			scope VARCHAR(96) NOT NULL,
+			idempotency_key VARCHAR(128) NOT NULL,
+			request_hash CHAR(64) NOT NULL,
+			response BLOB NULL,
+			created_at TIMESTAMP NOT NULL,
+			completed_at TIMESTAMP NULL,
+			PRIMARY KEY (scope, idempotency_key)
+		)
+	`)
+	s.Require().NoError(err)
 }
 
 func (s *OrderServiceSettleTestSuite) dropTestTables() {
@@ -122,6 +213,18 @@ func (s *OrderServiceSettleTestSuite) dropTestTables() {
 	_, err := s.db.Exec("DROP TABLE IF EXISTS order_items")
 	s.Require().NoError(err)
 
+	_, err = s.db.Exec("DROP TABLE IF EXISTS order_status_history")
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS outbox_events")
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS user_order_stats")
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS user_order_summaries")
+	s.Require().NoError(err)
+
 	// First check if payments table exists and drop it
 	_, err = s.db.Exec("DROP TABLE IF EXISTS payments")
 	s.Require().NoError(err)
@@ -131,6 +234,9 @@ func (s *OrderServiceSettleTestSuite) dropTestTables() {
 
 	_, err = s.db.Exec("DROP TABLE IF EXISTS products")
 	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS idempotency_keys")
+	s.Require().NoError(err)
 }
 
 func (s *OrderServiceSettleTestSuite) TestSettleOrder() {
@@ -143,14 +249,14 @@ func (s *OrderServiceSettleTestSuite) TestSettleOrder() {
 	// First create an order
 	order := &repository.Order{
 		UserID:      "1",
-		TotalAmount: 199.98,
+		TotalAmount: money.FromFloat(199.98, money.DefaultCurrency),
 		Status:      repository.OrderStatusPending,
 		Items: []*repository.OrderItem{
 			{
 				ProductID:   "1",
 				ProductName: "Test Product",
 				Quantity:    2,
-				Price:       99.99,
+				Price:       money.FromFloat(99.99, money.DefaultCurrency),
 			},
 		},
 		CreatedAt: time.Now(),
@@ -170,6 +276,7 @@ func (s *OrderServiceSettleTestSuite) TestSettleOrder() {
 		OrderId:       int32(s.parseOrderID(order.ID)),
 		UserId:        1,
 		PaymentMethod: "credit_card",
+		AmountMoney:   toAPIMoney(order.TotalAmount),
 	}
 
 	settleResp, err := s.orderService.SettleOrder(ctx, settleReq)
@@ -189,6 +296,69 @@ func (s *OrderServiceSettleTestSuite) TestSettleOrder() {
 	s.Equal(8, newStock) // 10 - 2 = 8
 }
 
+func (s *OrderServiceSettleTestSuite) TestSettleOrderIdempotencyKey() {
+	if s.db == nil {
+		s.T().Skip("Skipping test due to no database connection")
+	}
+
+	ctx := context.Background()
+
+	order := &repository.Order{
+		UserID:      "1",
+		TotalAmount: money.FromFloat(199.98, money.DefaultCurrency),
+		Status:      repository.OrderStatusPending,
+		Items: []*repository.OrderItem{
+			{
+				ProductID:   "1",
+				ProductName: "Test Product",
+				Quantity:    2,
+				Price:       money.FromFloat(99.99, money.DefaultCurrency),
+			},
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	s.Require().NoError(s.orderRepo.Create(ctx, order))
+
+	_, err := s.db.Exec("UPDATE products SET stock = 10 WHERE id = 1")
+	s.Require().NoError(err)
+
+	settleReq := &orderapi.SettleOrderRequest{
+		OrderId:        int32(s.parseOrderID(order.ID)),
+		UserId:         1,
+		PaymentMethod:  "credit_card",
+		IdempotencyKey: "settle-" + order.ID,
+		AmountMoney:    toAPIMoney(order.TotalAmount),
+	}
+
+	settleResp, err := s.orderService.SettleOrder(ctx, settleReq)
+	s.Require().NoError(err)
+	s.Require().True(settleResp.Success, settleResp.ErrorMessage)
+
+	// A retry with the same key returns the original result without reducing stock again
+	retryResp, err := s.orderService.SettleOrder(ctx, settleReq)
+	s.Require().NoError(err)
+	s.True(retryResp.Success)
+	s.Equal(orderapi.OrderStatus_PAID, retryResp.Status)
+
+	var stock int
+	err = s.db.QueryRow("SELECT stock FROM products WHERE id = 1").Scan(&stock)
+	s.Require().NoError(err)
+	s.Equal(8, stock)
+
+	// Reusing the key for a different request is rejected
+	conflictResp, err := s.orderService.SettleOrder(ctx, &orderapi.SettleOrderRequest{
+		OrderId:        settleReq.OrderId,
+		UserId:         1,
+		PaymentMethod:  "alipay",
+		IdempotencyKey: settleReq.IdempotencyKey,
+		AmountMoney:    settleReq.AmountMoney,
+	})
+	s.Require().NoError(err)
+	s.False(conflictResp.Success)
+	s.Equal("Idempotency key was used with a different request", conflictResp.ErrorMessage)
+}
+
 func (s *OrderServiceSettleTestSuite) TestSettleOrderInsufficientStock() {
 	if s.db == nil {
 		s.T().Skip("Skipping test due to no database connection")
@@ -199,14 +369,14 @@ func (s *OrderServiceSettleTestSuite) TestSettleOrderInsufficientStock() {
 	// First create an order
 	order := &repository.Order{
 		UserID:      "1",
-		TotalAmount: 999.90,
+		TotalAmount: money.FromFloat(999.90, money.DefaultCurrency),
 		Status:      repository.OrderStatusPending,
 		Items: []*repository.OrderItem{
 			{
 				ProductID:   "1",
 				ProductName: "Test Product",
 				Quantity:    10, // Order for 10 items
-				Price:       99.99,
+				Price:       money.FromFloat(99.99, money.DefaultCurrency),
 			},
 		},
 		CreatedAt: time.Now(),
@@ -226,6 +396,7 @@ func (s *OrderServiceSettleTestSuite) TestSettleOrderInsufficientStock() {
 		OrderId:       int32(s.parseOrderID(order.ID)),
 		UserId:        1,
 		PaymentMethod: "credit_card",
+		AmountMoney:   toAPIMoney(order.TotalAmount),
 	}
 
 	settleResp, err := s.orderService.SettleOrder(ctx, settleReq)
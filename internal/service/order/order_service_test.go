@@ -11,8 +11,11 @@ import (
 	"github.com/stretchr/testify/suite"
 	// "gopkg.in/yaml.v2"
 
+	cartpb "github.com/bytedance-youthcamp/demo/api/cart"
 	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	"github.com/bytedance-youthcamp/demo/internal/money"
 	"github.com/bytedance-youthcamp/demo/internal/repository"
+	"github.com/stretchr/testify/mock"
 	_ "github.com/go-sql-driver/mysql"
 )
 
@@ -115,10 +118,24 @@ func (s *OrderServiceIntegrationTestSuite) setupTestDatabase() {
 		CREATE TABLE orders (
 			id INT AUTO_INCREMENT PRIMARY KEY,
 			user_id VARCHAR(50) NOT NULL,
-			total_amount DECIMAL(10, 2) NOT NULL,
+			total_amount_minor BIGINT NOT NULL,
+			currency CHAR(3) NOT NULL DEFAULT 'CNY',
 			status INT NOT NULL,
 			shipping_address TEXT,
-			items JSON,
+			contact_name VARCHAR(100),
+			contact_phone VARCHAR(20),
+			cancel_reason VARCHAR(255),
+			paid_at TIMESTAMP NULL,
+			cancelled_at TIMESTAMP NULL,
+			completed_at TIMESTAMP NULL,
+			auto_cancel_at TIMESTAMP NULL,
+			coupon_code VARCHAR(64) NOT NULL DEFAULT '',
+			coupon_redemption_id VARCHAR(64) NOT NULL DEFAULT '',
+			discount_amount_minor BIGINT NOT NULL DEFAULT 0,
+			region VARCHAR(32) NOT NULL DEFAULT '',
+			subtotal_amount_minor BIGINT NOT NULL DEFAULT 0,
+			tax_amount_minor BIGINT NOT NULL DEFAULT 0,
+			shipping_fee_minor BIGINT NOT NULL DEFAULT 0,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 		)
@@ -132,13 +149,74 @@ func (s *OrderServiceIntegrationTestSuite) setupTestDatabase() {
 			order_id INT NOT NULL,
 			product_id VARCHAR(50) NOT NULL,
 			product_name VARCHAR(255) NOT NULL,
+			category VARCHAR(100) NOT NULL DEFAULT '',
 			quantity INT NOT NULL,
-			price DECIMAL(10, 2) NOT NULL,
+			cancelled_quantity INT NOT NULL DEFAULT 0,
+			price_minor BIGINT NOT NULL,
+			discount_minor BIGINT NOT NULL DEFAULT 0,
+			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+		)
+	`)
+	s.Require().NoError(err)
+
+	// 创建 order_status_history 表
+	_, err = s.db.Exec(`
+		CREATE TABLE order_status_history (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			order_id INT NOT NULL,
+			from_status INT NOT NULL,
+			to_status INT NOT NULL,
+			actor VARCHAR(64) NOT NULL,
+			reason VARCHAR(255),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_order_status_history_order_id (order_id),
 			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
 		)
 	`)
 	s.Require().NoError(err)
 
+	// 创建 outbox_events 表
+	_, err = s.db.Exec(`
+		CREATE TABLE outbox_events (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			event_id VARCHAR(36) NOT NULL UNIQUE,
+			event_type VARCHAR(64) NOT NULL,
+			aggregate_type VARCHAR(32) NOT NULL,
+			aggregate_id VARCHAR(64) NOT NULL,
+			payload TEXT NOT NULL,
+			occurred_at TIMESTAMP NOT NULL,
+			published_at TIMESTAMP NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			last_error VARCHAR(255) NOT NULL DEFAULT ''
+		)
+	`)
+	s.Require().NoError(err)
+
+	// 创建 user_order_stats 表
+	_, err = s.db.Exec(`
+		CREATE TABLE user_order_stats (
+			user_id VARCHAR(50) NOT NULL,
+			status INT NOT NULL,
+			currency CHAR(3) NOT NULL,
+			order_count INT NOT NULL DEFAULT 0,
+			amount_minor BIGINT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (user_id, status, currency)
+		)
+	`)
+	s.Require().NoError(err)
+
+	// 创建 user_order_summaries 表
+	_, err = s.db.Exec(`
+		CREATE TABLE user_order_summaries (
+			user_id VARCHAR(50) NOT NULL PRIMARY KEY,
+			last_order_id BIGINT NOT NULL,
+			last_order_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)
+	`)
+	s.Require().NoError(err)
+
 	// 创建测试产品数据
 	_, err = s.db.Exec(`
 		CREATE TABLE IF NOT EXISTS products (
@@ -187,6 +265,18 @@ func (s *OrderServiceIntegrationTestSuite) dropTestTables() {
 	_, err := s.db.Exec("DROP TABLE IF EXISTS order_items")
 	s.Require().NoError(err)
 
+	_, err = s.db.Exec("DROP TABLE IF EXISTS order_status_history")
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS outbox_events")
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS user_order_stats")
+	s.Require().NoError(err)
+
+	_, err = s.db.Exec("DROP TABLE IF EXISTS user_order_summaries")
+	s.Require().NoError(err)
+
 	_, err = s.db.Exec("DROP TABLE IF EXISTS orders")
 	s.Require().NoError(err)
 
@@ -219,10 +309,10 @@ func (s *OrderServiceIntegrationTestSuite) TestCreateOrder() {
 				ProductId:   1,
 				ProductName: "测试商品",
 				Quantity:    2,
-				Price:       100.0,
+				Price:       99.99,
 			},
 		},
-		TotalPrice: 200.0,
+		TotalPrice: 199.98,
 	}
 
 	resp, err := s.orderService.CreateOrder(ctx, req)
@@ -234,7 +324,7 @@ func (s *OrderServiceIntegrationTestSuite) TestCreateOrder() {
 	order, err := s.orderRepo.Get(ctx, fmt.Sprint(resp.OrderId))
 	s.NoError(err)
 	s.Equal("1", order.UserID)
-	s.Equal(200.0, order.TotalAmount)
+	s.Equal(199.98, order.TotalAmount.Float64())
 	s.Equal(repository.OrderStatusPending, order.Status)
 }
 
@@ -253,10 +343,10 @@ func (s *OrderServiceIntegrationTestSuite) TestSettleOrder() {
 				ProductId:   1,
 				ProductName: "测试商品",
 				Quantity:    2,
-				Price:       100.0,
+				Price:       99.99,
 			},
 		},
-		TotalPrice: 200.0,
+		TotalPrice: 199.98,
 	}
 
 	createResp, err := s.orderService.CreateOrder(ctx, createReq)
@@ -267,6 +357,7 @@ func (s *OrderServiceIntegrationTestSuite) TestSettleOrder() {
 		OrderId:        createResp.OrderId,
 		UserId:         1,
 		PaymentMethod:  "credit_card",
+		AmountMoney:    &orderapi.Money{Amount: 19998, Currency: "CNY"},
 	}
 
 	settleResp, err := s.orderService.SettleOrder(ctx, settleReq)
@@ -295,10 +386,10 @@ func (s *OrderServiceIntegrationTestSuite) TestCancelOrder() {
 				ProductId:   1,
 				ProductName: "测试商品",
 				Quantity:    2,
-				Price:       100.0,
+				Price:       99.99,
 			},
 		},
-		TotalPrice: 200.0,
+		TotalPrice: 199.98,
 	}
 
 	createResp, err := s.orderService.CreateOrder(ctx, createReq)
@@ -320,6 +411,105 @@ func (s *OrderServiceIntegrationTestSuite) TestCancelOrder() {
 	s.Equal(repository.OrderStatusCancelled, order.Status)
 }
 
+func (s *OrderServiceIntegrationTestSuite) TestCreateOrderFromCart() {
+	if s.db == nil {
+		s.T().Skip("Skipping test due to no database connection")
+	}
+
+	ctx := context.Background()
+
+	cartClient := new(MockCartClient)
+	s.orderService.cartClient = cartClient
+	defer func() { s.orderService.cartClient = nil }()
+
+	cartClient.On("GetCart", mock.Anything, &cartpb.GetCartRequest{CartId: 7}, mock.Anything).Return(&cartpb.GetCartResponse{
+		Success: true,
+		Cart: &cartpb.Cart{
+			Id:     7,
+			UserId: 1,
+			Items: []*cartpb.CartItem{
+				{Id: 11, ProductId: 1, ProductName: "测试商品", Quantity: 2, Price: 99.99, PriceMoney: &cartpb.Money{Amount: 9999, Currency: "CNY"}},
+				{Id: 12, ProductId: 1, ProductName: "测试商品", Quantity: 1, Price: 99.99, PriceMoney: &cartpb.Money{Amount: 9999, Currency: "CNY"}},
+			},
+		},
+	}, nil)
+	cartClient.On("RemoveFromCart", mock.Anything, &cartpb.RemoveFromCartRequest{CartId: 7, CartItemId: 11}, mock.Anything).
+		Return(&cartpb.RemoveFromCartResponse{Success: true}, nil)
+
+	// 购物车不属于该用户
+	resp, err := s.orderService.CreateOrderFromCart(ctx, &orderapi.CreateOrderFromCartRequest{CartId: 7, UserId: 2})
+	s.NoError(err)
+	s.False(resp.Success)
+	s.Equal("Cart does not belong to user", resp.ErrorMessage)
+
+	// 只结算选中的商品
+	resp, err = s.orderService.CreateOrderFromCart(ctx, &orderapi.CreateOrderFromCartRequest{
+		CartId:       7,
+		UserId:       1,
+		Address:      "测试地址",
+		ContactName:  "张三",
+		ContactPhone: "13800000000",
+		CartItemIds:  []int32{11},
+	})
+	s.NoError(err)
+	s.True(resp.Success)
+	cartClient.AssertNumberOfCalls(s.T(), "RemoveFromCart", 1)
+
+	order, err := s.orderRepo.Get(ctx, fmt.Sprint(resp.OrderId))
+	s.NoError(err)
+	s.Equal(money.New(19998, "CNY"), order.TotalAmount)
+	s.Equal("测试地址", order.ShippingAddress)
+	s.Len(order.Items, 1)
+}
+
+func (s *OrderServiceIntegrationTestSuite) TestCreateOrderFromCartRevertsOnCartFailure() {
+	if s.db == nil {
+		s.T().Skip("Skipping test due to no database connection")
+	}
+
+	ctx := context.Background()
+
+	cartClient := new(MockCartClient)
+	s.orderService.cartClient = cartClient
+	defer func() { s.orderService.cartClient = nil }()
+
+	cartClient.On("GetCart", mock.Anything, mock.Anything, mock.Anything).Return(&cartpb.GetCartResponse{
+		Success: true,
+		Cart: &cartpb.Cart{
+			Id:     8,
+			UserId: 1,
+			Items: []*cartpb.CartItem{
+				{Id: 21, ProductId: 1, ProductName: "测试商品", Quantity: 1, Price: 99.99},
+				{Id: 22, ProductId: 1, ProductName: "测试商品", Quantity: 1, Price: 99.99},
+			},
+		},
+	}, nil)
+	cartClient.On("RemoveFromCart", mock.Anything, &cartpb.RemoveFromCartRequest{CartId: 8, CartItemId: 21}, mock.Anything).
+		Return(&cartpb.RemoveFromCartResponse{Success: true}, nil)
+	cartClient.On("RemoveFromCart", mock.Anything, &cartpb.RemoveFromCartRequest{CartId: 8, CartItemId: 22}, mock.Anything).
+		Return(&cartpb.RemoveFromCartResponse{Success: false, ErrorMessage: "商品项不存在于购物车中"}, nil)
+	cartClient.On("AddToCart", mock.Anything, &cartpb.AddToCartRequest{CartId: 8, ProductId: 1, Quantity: 1}, mock.Anything).
+		Return(&cartpb.AddToCartResponse{Success: true}, nil)
+
+	resp, err := s.orderService.CreateOrderFromCart(ctx, &orderapi.CreateOrderFromCartRequest{CartId: 8, UserId: 1})
+	s.NoError(err)
+	s.False(resp.Success)
+
+	// 已移出的商品被放回购物车，订单被取消
+	cartClient.AssertCalled(s.T(), "AddToCart", mock.Anything, &cartpb.AddToCartRequest{CartId: 8, ProductId: 1, Quantity: 1}, mock.Anything)
+
+	cancelled := repository.OrderStatusCancelled
+	orders, err := s.orderRepo.List(ctx, "1", &cancelled)
+	s.NoError(err)
+	reverted := false
+	for _, o := range orders {
+		if o.CancelReason == "cart checkout failed" {
+			reverted = true
+		}
+	}
+	s.True(reverted)
+}
+
 func TestOrderServiceIntegration(t *testing.T) {
 	// 如果是短测试模式，跳过集成测试
 	if testing.Short() {
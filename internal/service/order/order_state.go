@@ -0,0 +1,247 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+)
+
+// 订单状态变更的操作者
+const (
+	actorUser        = "user"
+	actorOperator    = "operator"
+	actorPayment     = "payment"
+	actorSystem      = "system"
+	actorFulfillment = "fulfillment"
+)
+
+// orderTransitions 定义订单状态机中所有合法的状态变更
+var orderTransitions = map[repository.OrderStatus][]repository.OrderStatus{
+	repository.OrderStatusPending: {
+		repository.OrderStatusPaid,
+		repository.OrderStatusPaymentFailed,
+		repository.OrderStatusCancelled,
+	},
+	repository.OrderStatusPaymentFailed: {
+		repository.OrderStatusPaid,
+		repository.OrderStatusCancelled,
+	},
+	// 已支付的订单不能直接取消，需要通过退款流程退回款项和库存
+	repository.OrderStatusPaid: {
+		repository.OrderStatusShipped,
+		repository.OrderStatusRefunding,
+	},
+	repository.OrderStatusShipped: {
+		repository.OrderStatusDelivered,
+	},
+	repository.OrderStatusDelivered: {
+		repository.OrderStatusRefunding,
+	},
+	// 部分退款或退款失败后回到发起退款前的状态
+	repository.OrderStatusRefunding: {
+		repository.OrderStatusPaid,
+		repository.OrderStatusDelivered,
+		repository.OrderStatusRefunded,
+	},
+}
+
+// InvalidTransitionError 表示请求的订单状态变更不被状态机允许
+type InvalidTransitionError struct {
+	From repository.OrderStatus
+	To   repository.OrderStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("invalid order status transition from %s to %s", e.From, e.To)
+}
+
+// userActor 返回用户操作者标识
+func userActor(userID int32) string {
+	return fmt.Sprintf("%s:%d", actorUser, userID)
+}
+
+// callerActor 返回 context 中调用方的操作者标识，未启用鉴权时视为用户
+func callerActor(ctx context.Context) string {
+	caller, ok := CallerFromContext(ctx)
+	switch {
+	case !ok:
+		return actorUser
+	case caller.Service != "":
+		return caller.Service
+	default:
+		return userActor(caller.UserID)
+	}
+}
+
+// canTransition 判断订单能否从 from 变更为 to
+func canTransition(from, to repository.OrderStatus) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transition 按状态机变更订单状态并记录变更历史，非法变更返回 *InvalidTransitionError，
+// 订单状态已被其他请求修改时返回 repository.ErrStatusConflict
+func (s *orderService) transition(ctx context.Context, order *repository.Order, to repository.OrderStatus, actor, reason string) error {
+	from := order.Status
+	if !canTransition(from, to) {
+		return &InvalidTransitionError{From: from, To: to}
+	}
+
+	now := time.Now()
+	order.Status = to
+	order.UpdatedAt = now
+
+	// 记录进入关键状态的时间
+	switch to {
+	case repository.OrderStatusPaid:
+		if order.PaidAt == nil {
+			order.PaidAt = &now
+		}
+	case repository.OrderStatusCancelled:
+		if order.CancelledAt == nil {
+			order.CancelledAt = &now
+		}
+	case repository.OrderStatusDelivered:
+		if order.CompletedAt == nil {
+			order.CompletedAt = &now
+		}
+	}
+
+	if err := s.orderRepo.UpdateStatus(ctx, order, from, actor, reason); err != nil {
+		order.Status = from
+		return err
+	}
+
+	// 只有待支付的订单可以取消，取消后释放预留的库存并归还占用的优惠券。
+	// 释放失败不影响取消，订单由超时取消任务重试释放
+	if to == repository.OrderStatusCancelled {
+		if err := s.releaseCancelledOrder(ctx, order); err != nil {
+			log.Printf("Failed to release cancelled order %s, will retry: %v", order.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// GetOrderHistory 按时间顺序返回订单的状态变更记录
+func (s *orderService) GetOrderHistory(ctx context.Context, req *orderapi.GetOrderHistoryRequest) (*orderapi.GetOrderHistoryResponse, error) {
+	order, err := s.orderRepo.Get(ctx, fmt.Sprint(req.OrderId))
+	if err != nil {
+		return &orderapi.GetOrderHistoryResponse{
+			Success:      false,
+			ErrorMessage: "Order not found",
+		}, nil
+	}
+	if err := s.authorizeOwner(ctx, order.UserID, permissionRead); err != nil {
+		return nil, err
+	}
+
+	history, err := s.orderRepo.ListStatusHistory(ctx, fmt.Sprint(req.OrderId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order history: %w", err)
+	}
+
+	changes := make([]*orderapi.OrderStatusChange, len(history))
+	for i, h := range history {
+		changes[i] = &orderapi.OrderStatusChange{
+			FromStatus: toAPIStatus(h.FromStatus),
+			ToStatus:   toAPIStatus(h.ToStatus),
+			Actor:      h.Actor,
+			Reason:     h.Reason,
+			CreatedAt:  h.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	itemChanges, err := s.orderRepo.ListItemChanges(ctx, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order item changes: %w", err)
+	}
+
+	apiItemChanges := make([]*orderapi.OrderItemChange, len(itemChanges))
+	for i, c := range itemChanges {
+		productID, _ := strconv.Atoi(c.ProductID)
+		apiItemChanges[i] = &orderapi.OrderItemChange{
+			ProductId:   int32(productID),
+			Type:        orderapi.OrderItemChangeType(c.Type),
+			OldQuantity: c.OldQuantity,
+			NewQuantity: c.NewQuantity,
+			Amount:      toAPIMoney(c.Amount),
+			RefundId:    c.RefundID,
+			Actor:       c.Actor,
+			Reason:      c.Reason,
+			CreatedAt:   c.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	return &orderapi.GetOrderHistoryResponse{
+		Success:     true,
+		History:     changes,
+		ItemChanges: apiItemChanges,
+	}, nil
+}
+
+// previousStatus 查询订单进入当前状态之前的状态，没有记录时返回 fallback
+func (s *orderService) previousStatus(ctx context.Context, order *repository.Order, fallback repository.OrderStatus) (repository.OrderStatus, error) {
+	history, err := s.orderRepo.ListStatusHistory(ctx, order.ID)
+	if err != nil {
+		return fallback, err
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].ToStatus == order.Status {
+			return history[i].FromStatus, nil
+		}
+	}
+	return fallback, nil
+}
+
+func toRepoStatus(status orderapi.OrderStatus) repository.OrderStatus {
+	switch status {
+	case orderapi.OrderStatus_PAID:
+		return repository.OrderStatusPaid
+	case orderapi.OrderStatus_SHIPPING:
+		return repository.OrderStatusShipped
+	case orderapi.OrderStatus_COMPLETED:
+		return repository.OrderStatusDelivered
+	case orderapi.OrderStatus_CANCELLED:
+		return repository.OrderStatusCancelled
+	case orderapi.OrderStatus_REFUNDING:
+		return repository.OrderStatusRefunding
+	case orderapi.OrderStatus_REFUNDED:
+		return repository.OrderStatusRefunded
+	case orderapi.OrderStatus_PAYMENT_FAILED:
+		return repository.OrderStatusPaymentFailed
+	default:
+		return repository.OrderStatusPending
+	}
+}
+
+func toAPIStatus(status repository.OrderStatus) orderapi.OrderStatus {
+	switch status {
+	case repository.OrderStatusPaid:
+		return orderapi.OrderStatus_PAID
+	case repository.OrderStatusShipped:
+		return orderapi.OrderStatus_SHIPPING
+	case repository.OrderStatusDelivered:
+		return orderapi.OrderStatus_COMPLETED
+	case repository.OrderStatusCancelled:
+		return orderapi.OrderStatus_CANCELLED
+	case repository.OrderStatusRefunding:
+		return orderapi.OrderStatus_REFUNDING
+	case repository.OrderStatusRefunded:
+		return orderapi.OrderStatus_REFUNDED
+	case repository.OrderStatusPaymentFailed:
+		return orderapi.OrderStatus_PAYMENT_FAILED
+	default:
+		return orderapi.OrderStatus_PENDING
+	}
+}
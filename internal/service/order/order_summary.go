@@ -0,0 +1,79 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+)
+
+// spentStatuses 计入累计消费的订单状态，退款中的订单在退款完成前仍计入
+var spentStatuses = map[repository.OrderStatus]bool{
+	repository.OrderStatusPaid:      true,
+	repository.OrderStatusShipped:   true,
+	repository.OrderStatusDelivered: true,
+	repository.OrderStatusRefunding: true,
+}
+
+// GetUserOrderSummary 返回用户各状态的订单数、累计消费和最近一笔订单。
+// 统计由订单仓库随订单变更增量维护，不需要查询用户的所有订单
+func (s *orderService) GetUserOrderSummary(ctx context.Context, req *orderapi.GetUserOrderSummaryRequest) (*orderapi.GetUserOrderSummaryResponse, error) {
+	if err := s.authorizeOwner(ctx, ownerOf(req.UserId), permissionRead); err != nil {
+		return nil, err
+	}
+
+	summary, err := s.orderRepo.GetUserOrderSummary(ctx, strconv.Itoa(int(req.UserId)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user order summary: %w", err)
+	}
+
+	return toAPIOrderSummary(summary), nil
+}
+
+func toAPIOrderSummary(summary *repository.UserOrderSummary) *orderapi.GetUserOrderSummaryResponse {
+	resp := &orderapi.GetUserOrderSummaryResponse{Success: true}
+
+	var current *orderapi.OrderStatusCount
+	spent := make(map[string]int64)
+	for _, stat := range summary.Stats {
+		if stat.Count == 0 {
+			continue
+		}
+		status := toAPIStatus(stat.Status)
+		if current == nil || current.Status != status {
+			current = &orderapi.OrderStatusCount{Status: status}
+			resp.StatusCounts = append(resp.StatusCounts, current)
+		}
+		current.Count += int32(stat.Count)
+		current.Amounts = append(current.Amounts, toAPIMoney(stat.Amount))
+		resp.TotalOrders += int32(stat.Count)
+
+		if spentStatuses[stat.Status] {
+			spent[stat.Amount.Currency] += stat.Amount.Amount
+		}
+	}
+
+	currencies := make([]string, 0, len(spent))
+	for currency := range spent {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		resp.LifetimeSpend = append(resp.LifetimeSpend, toAPIMoney(money.New(spent[currency], currency)))
+	}
+
+	if summary.LastOrderID != "" {
+		id, _ := strconv.Atoi(summary.LastOrderID)
+		resp.LastOrderId = int32(id)
+	}
+	if summary.LastOrderAt != nil {
+		resp.LastOrderAt = summary.LastOrderAt.Format(time.RFC3339)
+	}
+
+	return resp
+}
@@ -0,0 +1,84 @@
+package order
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	orderapi "github.com/bytedance-youthcamp/demo/api/order"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// summaryOrderRepository 返回固定的用户订单统计
+type summaryOrderRepository struct {
+	repository.OrderRepository
+	summaries map[string]*repository.UserOrderSummary
+}
+
+func (r *summaryOrderRepository) GetUserOrderSummary(ctx context.Context, userID string) (*repository.UserOrderSummary, error) {
+	if summary, ok := r.summaries[userID]; ok {
+		return summary, nil
+	}
+	return &repository.UserOrderSummary{UserID: userID}, nil
+}
+
+func TestGetUserOrderSummary(t *testing.T) {
+	lastOrderAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	repo := &summaryOrderRepository{summaries: map[string]*repository.UserOrderSummary{
+		"1": {
+			UserID: "1",
+			Stats: []repository.UserOrderStat{
+				{Status: repository.OrderStatusPending, Count: 1, Amount: money.New(500, "CNY")},
+				{Status: repository.OrderStatusPaid, Count: 2, Amount: money.New(3000, "CNY")},
+				{Status: repository.OrderStatusPaid, Count: 1, Amount: money.New(1000, "USD")},
+				{Status: repository.OrderStatusDelivered, Count: 3, Amount: money.New(4500, "CNY")},
+				{Status: repository.OrderStatusCancelled, Count: 0, Amount: money.Zero("CNY")},
+				{Status: repository.OrderStatusRefunded, Count: 1, Amount: money.New(800, "CNY")},
+			},
+			LastOrderID: "42",
+			LastOrderAt: &lastOrderAt,
+		},
+	}}
+	service := &orderService{orderRepo: repo}
+
+	t.Run("summarizes counts and spend", func(t *testing.T) {
+		resp, err := service.GetUserOrderSummary(context.Background(), &orderapi.GetUserOrderSummaryRequest{UserId: 1})
+		require.NoError(t, err)
+		require.True(t, resp.Success)
+
+		assert.Equal(t, int32(8), resp.TotalOrders)
+		assert.Equal(t, int32(42), resp.LastOrderId)
+		assert.Equal(t, "2024-06-01T12:00:00Z", resp.LastOrderAt)
+
+		counts := make(map[orderapi.OrderStatus]int32)
+		for _, count := range resp.StatusCounts {
+			counts[count.Status] = count.Count
+		}
+		assert.Equal(t, map[orderapi.OrderStatus]int32{
+			orderapi.OrderStatus_PENDING:   1,
+			orderapi.OrderStatus_PAID:      3,
+			orderapi.OrderStatus_COMPLETED: 3,
+			orderapi.OrderStatus_REFUNDED:  1,
+		}, counts)
+
+		// 待支付和已退款的订单不计入累计消费
+		require.Len(t, resp.LifetimeSpend, 2)
+		assert.Equal(t, "CNY", resp.LifetimeSpend[0].Currency)
+		assert.Equal(t, int64(7500), resp.LifetimeSpend[0].Amount)
+		assert.Equal(t, "USD", resp.LifetimeSpend[1].Currency)
+		assert.Equal(t, int64(1000), resp.LifetimeSpend[1].Amount)
+	})
+
+	t.Run("user without orders", func(t *testing.T) {
+		resp, err := service.GetUserOrderSummary(context.Background(), &orderapi.GetUserOrderSummaryRequest{UserId: 2})
+		require.NoError(t, err)
+		assert.True(t, resp.Success)
+		assert.Zero(t, resp.TotalOrders)
+		assert.Empty(t, resp.StatusCounts)
+		assert.Empty(t, resp.LifetimeSpend)
+		assert.Zero(t, resp.LastOrderId)
+	})
+}
@@ -39,11 +39,31 @@ func createOrderTables(db *sql.DB) error {
 		CREATE TABLE IF NOT EXISTS orders (
 			id INT AUTO_INCREMENT PRIMARY KEY,
 			user_id VARCHAR(50) NOT NULL,
-			total_amount DECIMAL(10, 2) NOT NULL,
+			total_amount_minor BIGINT NOT NULL,
+			currency CHAR(3) NOT NULL DEFAULT 'CNY',
 			status INT NOT NULL,
 			shipping_address TEXT,
+			contact_name VARCHAR(100),
+			contact_phone VARCHAR(20),
+			cancel_reason VARCHAR(255),
+			paid_at TIMESTAMP NULL,
+			cancelled_at TIMESTAMP NULL,
+			completed_at TIMESTAMP NULL,
+			auto_cancel_at TIMESTAMP NULL,
+			coupon_code VARCHAR(64) NOT NULL DEFAULT '',
+			coupon_redemption_id VARCHAR(64) NOT NULL DEFAULT '',
+			discount_amount_minor BIGINT NOT NULL DEFAULT 0,
+			region VARCHAR(32) NOT NULL DEFAULT '',
+			subtotal_amount_minor BIGINT NOT NULL DEFAULT 0,
+			tax_amount_minor BIGINT NOT NULL DEFAULT 0,
+			shipping_fee_minor BIGINT NOT NULL DEFAULT 0,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			INDEX idx_orders_user_id_created_at (user_id, created_at, id),
+			INDEX idx_orders_status_created_at (status, created_at),
+			INDEX idx_orders_status_auto_cancel_at (status, auto_cancel_at),
+			INDEX idx_orders_created_at (created_at, id),
+			INDEX idx_orders_paid_at (paid_at)
 		)
 	`)
 	if err != nil {
@@ -57,8 +77,13 @@ func createOrderTables(db *sql.DB) error {
 			order_id INT NOT NULL,
 			product_id VARCHAR(50) NOT NULL,
 			product_name VARCHAR(255) NOT NULL,
+			category VARCHAR(100) NOT NULL DEFAULT '',
 			quantity INT NOT NULL,
-			price DECIMAL(10, 2) NOT NULL,
+			cancelled_quantity INT NOT NULL DEFAULT 0,
+			price_minor BIGINT NOT NULL,
+			discount_minor BIGINT NOT NULL DEFAULT 0,
+			INDEX idx_order_items_order_id (order_id),
+			INDEX idx_order_items_product_id (product_id),
 			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
 		)
 	`)
@@ -66,5 +91,161 @@ func createOrderTables(db *sql.DB) error {
 		return fmt.Errorf("failed to create order_items table: %w", err)
 	}
 
+	// Create order_status_history table
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS order_status_history (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			order_id INT NOT NULL,
+			from_status INT NOT NULL,
+			to_status INT NOT NULL,
+			actor VARCHAR(64) NOT NULL,
+			reason VARCHAR(255),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_order_status_history_order_id (order_id),
+			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create order_status_history table: %w", err)
+	}
+
+	// Create outbox_events table
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS outbox_events (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			event_id VARCHAR(36) NOT NULL UNIQUE,
+			event_type VARCHAR(64) NOT NULL,
+			aggregate_type VARCHAR(32) NOT NULL,
+			aggregate_id VARCHAR(64) NOT NULL,
+			payload TEXT NOT NULL,
+			occurred_at TIMESTAMP NOT NULL,
+			published_at TIMESTAMP NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			last_error VARCHAR(255) NOT NULL DEFAULT ''
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create outbox_events table: %w", err)
+	}
+
+	// Create user_order_stats table
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_order_stats (
+			user_id VARCHAR(50) NOT NULL,
+			status INT NOT NULL,
+			currency CHAR(3) NOT NULL,
+			order_count INT NOT NULL DEFAULT 0,
+			amount_minor BIGINT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (user_id, status, currency)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create user_order_stats table: %w", err)
+	}
+
+	// Create user_order_summaries table
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_order_summaries (
+			user_id VARCHAR(50) NOT NULL PRIMARY KEY,
+			last_order_id BIGINT NOT NULL,
+			last_order_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create user_order_summaries table: %w", err)
+	}
+
+	// Create order_item_changes table
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS order_item_changes (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			order_id INT NOT NULL,
+			product_id VARCHAR(50) NOT NULL,
+			change_type INT NOT NULL,
+			old_quantity INT NOT NULL,
+			new_quantity INT NOT NULL,
+			amount_minor BIGINT NOT NULL,
+			refund_id VARCHAR(64),
+			actor VARCHAR(64) NOT NULL,
+			reason VARCHAR(255),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_order_item_changes_order_id (order_id),
+			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create order_item_changes table: %w", err)
+	}
+
+	// Create shipments table
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS shipments (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			order_id INT NOT NULL,
+			carrier VARCHAR(64) NOT NULL,
+			tracking_number VARCHAR(64),
+			status INT NOT NULL,
+			shipped_at TIMESTAMP NULL,
+			delivered_at TIMESTAMP NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			INDEX idx_shipments_order_id (order_id),
+			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create shipments table: %w", err)
+	}
+
+	// Create shipment_items table
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS shipment_items (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			shipment_id BIGINT NOT NULL,
+			product_id VARCHAR(50) NOT NULL,
+			quantity INT NOT NULL,
+			INDEX idx_shipment_items_shipment_id (shipment_id),
+			FOREIGN KEY (shipment_id) REFERENCES shipments(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create shipment_items table: %w", err)
+	}
+
+	// Create shipment_events table
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS shipment_events (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			shipment_id BIGINT NOT NULL,
+			status INT NOT NULL,
+			location VARCHAR(255),
+			description VARCHAR(255),
+			event_time TIMESTAMP NOT NULL,
+			INDEX idx_shipment_events_shipment_id (shipment_id),
+			FOREIGN KEY (shipment_id) REFERENCES shipments(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create shipment_events table: %w", err)
+	}
+
+	// Create idempotency_keys table
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS idempotency_keys (
+			scope VARCHAR(96) NOT NULL,
+			idempotency_key VARCHAR(128) NOT NULL,
+			request_hash CHAR(64) NOT NULL,
+			response BLOB NULL,
+			created_at TIMESTAMP NOT NULL,
+			completed_at TIMESTAMP NULL,
+			PRIMARY KEY (scope, idempotency_key)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create idempotency_keys table: %w", err)
+	}
+
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,189 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	orderpb "github.com/bytedance-youthcamp/demo/api/order"
+	pb "github.com/bytedance-youthcamp/demo/api/payment"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+// defaultPaymentStaleAfter 待支付的支付超过该时间后，再次发起支付时会被新的支付取代
+const defaultPaymentStaleAfter = 15 * time.Minute
+
+// WithPaymentStaleAfter 设置待支付的支付可以被重复使用的时间，超过后再次发起支付会取代旧的支付
+func WithPaymentStaleAfter(d time.Duration) Option {
+	return func(s *PaymentService) {
+		if d > 0 {
+			s.paymentStaleAfter = d
+		}
+	}
+}
+
+// findIdempotentPayment 查找幂等键对应的支付，键已用于其他订单、金额或支付方式时返回错误信息
+func (s *PaymentService) findIdempotentPayment(req *pb.CreatePaymentRequest, amount money.Money) (*Payment, string, error) {
+	var payment Payment
+	err := s.db.Where("idempotency_key = ?", req.IdempotencyKey).First(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find payment by idempotency key: %w", err)
+	}
+
+	if payment.OrderID != req.OrderId || payment.Money() != amount || payment.Method != req.Method {
+		return nil, "idempotency key was already used for a different payment", nil
+	}
+	return &payment, "", nil
+}
+
+// verifyOrderAmount 检查订单等待支付且应付金额与支付金额一致，未配置订单服务时不检查
+func (s *PaymentService) verifyOrderAmount(ctx context.Context, orderID int32, amount money.Money) (string, error) {
+	if s.orderClient == nil {
+		return "", nil
+	}
+
+	orderResp, err := s.orderClient.GetOrder(ctx, &orderpb.GetOrderRequest{OrderId: orderID})
+	if err != nil {
+		return "", fmt.Errorf("failed to get order: %w", err)
+	}
+	if !orderResp.Success {
+		return fmt.Sprintf("failed to get order: %s", orderResp.ErrorMessage), nil
+	}
+
+	switch orderResp.Order.Status {
+	case orderpb.OrderStatus_PENDING, orderpb.OrderStatus_PAYMENT_FAILED:
+	default:
+		return fmt.Sprintf("order %d is not awaiting payment", orderID), nil
+	}
+
+	if total := orderTotal(orderResp.Order); total != amount {
+		return fmt.Sprintf("payment amount %s does not match order total %s", amount, total), nil
+	}
+	return "", nil
+}
+
+// activePayment 返回订单待支付的支付，没有时返回 nil
+func (s *PaymentService) activePayment(orderID int32) (*Payment, error) {
+	var payment Payment
+	err := s.db.Where("active_order_id = ?", orderID).First(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find active payment: %w", err)
+	}
+	return &payment, nil
+}
+
+// reusable 判断待支付的支付能否作为本次请求的结果返回
+func (s *PaymentService) reusable(payment *Payment, amount money.Money, method pb.PaymentMethod) bool {
+	return payment.Money() == amount &&
+		payment.Method == method &&
+		time.Since(payment.CreatedAt) < s.paymentStaleAfter
+}
+
+// supersedePayment 在渠道关闭收款后将待支付的支付标记为已取代，用户无法再通过旧的支付链接付款。
+// 渠道已收款时按回调处理支付结果并返回 ErrChargeCompleted
+func (s *PaymentService) supersedePayment(ctx context.Context, payment *Payment) error {
+	if err := s.closeCharge(ctx, payment); err != nil {
+		return err
+	}
+
+	// 支付被取代的事件与状态在同一事务中写入
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Payment{}).
+			Where("id = ? AND status = ?", payment.ID, pb.PaymentStatus_PAYMENT_STATUS_PENDING).
+			Updates(map[string]interface{}{
+				"status":          pb.PaymentStatus_PAYMENT_STATUS_SUPERSEDED,
+				"active_order_id": nil,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to supersede payment: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("payment %s changed concurrently", payment.PaymentID)
+		}
+		payment.Status = pb.PaymentStatus_PAYMENT_STATUS_SUPERSEDED
+		payment.ActiveOrderID = nil
+		return recordPaymentEvent(ctx, tx, payment)
+	})
+}
+
+// CancelOrderPayment 关闭订单待支付的支付，订单修改商品或金额后由订单服务调用，旧的支付链接不能再付款。
+// 订单没有待支付的支付时视为成功，渠道已收款时返回错误信息
+func (s *PaymentService) CancelOrderPayment(ctx context.Context, req *pb.CancelOrderPaymentRequest) (*pb.CancelOrderPaymentResponse, error) {
+	active, err := s.activePayment(req.OrderId)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		if err := s.supersedePayment(ctx, active); err != nil {
+			if errors.Is(err, ErrChargeCompleted) {
+				return &pb.CancelOrderPaymentResponse{Success: false, ErrorMessage: fmt.Sprintf("order %d has already been paid", req.OrderId)}, nil
+			}
+			return nil, err
+		}
+	}
+	return &pb.CancelOrderPaymentResponse{Success: true}, nil
+}
+
+// closeCharge 在渠道关闭待支付的收款，渠道中不存在的收款无法再支付，视为关闭成功。
+// 渠道已收款时按回调处理支付结果并返回 ErrChargeCompleted
+func (s *PaymentService) closeCharge(ctx context.Context, payment *Payment) error {
+	provider, err := s.paymentProvider(payment)
+	if err != nil {
+		return err
+	}
+
+	err = provider.CancelCharge(ctx, payment.PaymentID)
+	switch {
+	case err == nil, errors.Is(err, ErrUnknownCharge):
+		return nil
+	case errors.Is(err, ErrChargeCompleted):
+		if refreshErr := s.refreshPayment(ctx, payment); refreshErr != nil {
+			return fmt.Errorf("failed to settle paid charge %s: %w", payment.PaymentID, refreshErr)
+		}
+		return err
+	default:
+		return fmt.Errorf("failed to cancel charge %s with provider %s: %w", payment.PaymentID, provider.Name(), err)
+	}
+}
+
+// concurrentPayment 在创建支付违反唯一索引时，返回并发请求创建的相同支付
+func (s *PaymentService) concurrentPayment(req *pb.CreatePaymentRequest) *Payment {
+	amount := requestAmount(req.AmountMoney, req.Amount)
+	if req.IdempotencyKey != "" {
+		payment, _, err := s.findIdempotentPayment(req, amount)
+		if err == nil && payment != nil {
+			return payment
+		}
+	}
+
+	payment, err := s.activePayment(req.OrderId)
+	if err != nil || payment == nil || payment.Money() != amount || payment.Method != req.Method {
+		return nil
+	}
+	return payment
+}
+
+func createPaymentResponse(payment *Payment) *pb.CreatePaymentResponse {
+	return &pb.CreatePaymentResponse{
+		PaymentId:  payment.PaymentID,
+		PaymentUrl: payment.PaymentURL,
+		Success:    true,
+	}
+}
+
+// orderTotal 返回订单的应付金额，订单服务未返回精确金额时转换浮点金额
+func orderTotal(order *orderpb.Order) money.Money {
+	if order.TotalMoney != nil {
+		return money.New(order.TotalMoney.Amount, order.TotalMoney.Currency)
+	}
+	return money.FromFloat(order.TotalAmount, money.DefaultCurrency)
+}
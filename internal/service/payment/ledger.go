@@ -0,0 +1,337 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+// ErrLedgerAppendOnly 账本只能追加，分录和过账不能修改或删除，更正需要记一笔反向分录
+var ErrLedgerAppendOnly = errors.New("ledger is append-only")
+
+// LedgerAccountType 账户类型，决定余额的方向
+type LedgerAccountType string
+
+const (
+	// AccountAsset 资产账户，借方余额：渠道代收的资金
+	AccountAsset LedgerAccountType = "asset"
+	// AccountLiability 负债账户，贷方余额：订单已收的款项，退款时退回
+	AccountLiability LedgerAccountType = "liability"
+)
+
+// JournalEntryKind 分录对应的业务
+type JournalEntryKind string
+
+const (
+	// EntryPayment 支付成功，渠道收款
+	EntryPayment JournalEntryKind = "payment"
+	// EntryRefund 退款成功，渠道退款
+	EntryRefund JournalEntryKind = "refund"
+)
+
+// unknownProvider 未记录渠道的旧支付使用的渠道账户
+const unknownProvider = "unknown"
+
+// LedgerAccount 账本账户，同一账户的每种币种是一个账户
+type LedgerAccount struct {
+	ID        uint              `gorm:"primarykey"`
+	Code      string            `gorm:"size:64;not null;uniqueIndex:idx_ledger_account,priority:1"`
+	Currency  string            `gorm:"size:3;not null;uniqueIndex:idx_ledger_account,priority:2"`
+	Type      LedgerAccountType `gorm:"size:16;not null"`
+	CreatedAt time.Time
+}
+
+func (LedgerAccount) TableName() string {
+	return "ledger_accounts"
+}
+
+// JournalEntry 一笔分录，同一业务只会记一笔分录
+type JournalEntry struct {
+	ID          uint             `gorm:"primarykey"`
+	EntryID     string           `gorm:"size:36;not null;uniqueIndex"`
+	Kind        JournalEntryKind `gorm:"size:16;not null;uniqueIndex:idx_journal_entry_reference,priority:1"`
+	Reference   string           `gorm:"size:36;not null;uniqueIndex:idx_journal_entry_reference,priority:2"`
+	OrderID     int32            `gorm:"not null;index"`
+	Description string           `gorm:"size:255;not null;default:''"`
+	CreatedAt   time.Time
+}
+
+func (JournalEntry) TableName() string {
+	return "journal_entries"
+}
+
+func (JournalEntry) BeforeUpdate(*gorm.DB) error { return ErrLedgerAppendOnly }
+func (JournalEntry) BeforeDelete(*gorm.DB) error { return ErrLedgerAppendOnly }
+
+// Posting 分录中的一条过账，借方金额为正，贷方金额为负，同一分录的过账合计为零
+type Posting struct {
+	ID             uint   `gorm:"primarykey"`
+	JournalEntryID uint   `gorm:"not null;index"`
+	AccountID      uint   `gorm:"not null;index"`
+	AmountMinor    int64  `gorm:"not null"`
+	Currency       string `gorm:"size:3;not null"`
+	CreatedAt      time.Time
+}
+
+func (Posting) TableName() string {
+	return "ledger_postings"
+}
+
+func (Posting) BeforeUpdate(*gorm.DB) error { return ErrLedgerAppendOnly }
+func (Posting) BeforeDelete(*gorm.DB) error { return ErrLedgerAppendOnly }
+
+// ProviderAccount 返回渠道代收资金的资产账户
+func ProviderAccount(provider string) string {
+	if provider == "" {
+		provider = unknownProvider
+	}
+	return "provider:" + provider
+}
+
+// OrderAccount 返回订单已收款项的负债账户
+func OrderAccount(orderID int32) string {
+	return fmt.Sprintf("order:%d", orderID)
+}
+
+// ledgerLeg 分录中的一条过账，金额为正时记借方，为负时记贷方
+type ledgerLeg struct {
+	account     string
+	accountType LedgerAccountType
+	amount      int64
+}
+
+// postPayment 在支付事务中记支付成功的分录：借记渠道账户，贷记订单账户
+func postPayment(ctx context.Context, tx *gorm.DB, payment *Payment) error {
+	return postEntry(ctx, tx, JournalEntry{
+		Kind:        EntryPayment,
+		Reference:   payment.PaymentID,
+		OrderID:     payment.OrderID,
+		Description: fmt.Sprintf("payment %s via %s", payment.PaymentID, payment.Method),
+	}, payment.Currency,
+		ledgerLeg{ProviderAccount(payment.Provider), AccountAsset, payment.AmountMinor},
+		ledgerLeg{OrderAccount(payment.OrderID), AccountLiability, -payment.AmountMinor},
+	)
+}
+
+// postRefund 在退款事务中记退款成功的分录：借记订单账户，贷记渠道账户
+func postRefund(ctx context.Context, tx *gorm.DB, refund *Refund, payment *Payment) error {
+	return postEntry(ctx, tx, JournalEntry{
+		Kind:        EntryRefund,
+		Reference:   refund.RefundID,
+		OrderID:     refund.OrderID,
+		Description: fmt.Sprintf("refund %s of payment %s", refund.RefundID, refund.PaymentID),
+	}, refund.Currency,
+		ledgerLeg{OrderAccount(refund.OrderID), AccountLiability, refund.AmountMinor},
+		ledgerLeg{ProviderAccount(payment.Provider), AccountAsset, -refund.AmountMinor},
+	)
+}
+
+// postEntry 写入一笔分录及其过账，过账合计不为零时拒绝写入
+func postEntry(ctx context.Context, tx *gorm.DB, entry JournalEntry, currency string, legs ...ledgerLeg) error {
+	var sum int64
+	for _, leg := range legs {
+		sum += leg.amount
+	}
+	if sum != 0 {
+		return fmt.Errorf("unbalanced journal entry %s %s: postings sum to %d", entry.Kind, entry.Reference, sum)
+	}
+
+	tx = tx.WithContext(ctx)
+	entry.EntryID = uuid.New().String()
+	if err := tx.Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to create journal entry: %w", err)
+	}
+
+	postings := make([]Posting, len(legs))
+	for i, leg := range legs {
+		account, err := ledgerAccount(tx, leg.account, currency, leg.accountType)
+		if err != nil {
+			return err
+		}
+		postings[i] = Posting{
+			JournalEntryID: entry.ID,
+			AccountID:      account.ID,
+			AmountMinor:    leg.amount,
+			Currency:       currency,
+		}
+	}
+	if err := tx.Create(&postings).Error; err != nil {
+		return fmt.Errorf("failed to create postings: %w", err)
+	}
+	return nil
+}
+
+// ledgerAccount 返回账户，不存在时创建；并发创建同一账户时使用已创建的账户
+func ledgerAccount(tx *gorm.DB, code, currency string, accountType LedgerAccountType) (*LedgerAccount, error) {
+	account := LedgerAccount{Code: code, Currency: currency, Type: accountType}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&account)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to create ledger account: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return &account, nil
+	}
+	account = LedgerAccount{}
+	if err := tx.Where("code = ? AND currency = ?", code, currency).First(&account).Error; err != nil {
+		return nil, fmt.Errorf("failed to find ledger account: %w", err)
+	}
+	return &account, nil
+}
+
+// LedgerBalance 账户余额，资产账户为借方余额，负债账户为贷方余额
+type LedgerBalance struct {
+	Account string
+	Type    LedgerAccountType
+	Balance money.Money
+}
+
+// AccountBalance 查询账户在某币种下的余额，账户不存在时余额为零
+func (s *PaymentService) AccountBalance(ctx context.Context, code, currency string) (LedgerBalance, error) {
+	balances, err := s.ledgerBalances(ctx, code, currency)
+	if err != nil {
+		return LedgerBalance{}, err
+	}
+	if len(balances) == 0 {
+		return LedgerBalance{Account: code, Balance: money.New(0, currency)}, nil
+	}
+	return balances[0], nil
+}
+
+// LedgerBalances 查询所有账户的余额
+func (s *PaymentService) LedgerBalances(ctx context.Context) ([]LedgerBalance, error) {
+	return s.ledgerBalances(ctx, "", "")
+}
+
+// ledgerBalances 查询账户余额，code 为空时查询所有账户
+func (s *PaymentService) ledgerBalances(ctx context.Context, code, currency string) ([]LedgerBalance, error) {
+	var rows []struct {
+		Code     string
+		Currency string
+		Type     LedgerAccountType
+		Total    int64
+	}
+	query := s.db.WithContext(ctx).Model(&LedgerAccount{}).
+		Select("ledger_accounts.code, ledger_accounts.currency, ledger_accounts.type, COALESCE(SUM(ledger_postings.amount_minor), 0) AS total").
+		Joins("LEFT JOIN ledger_postings ON ledger_postings.account_id = ledger_accounts.id")
+	if code != "" {
+		query = query.Where("ledger_accounts.code = ? AND ledger_accounts.currency = ?", code, currency)
+	}
+	err := query.
+		Group("ledger_accounts.id, ledger_accounts.code, ledger_accounts.currency, ledger_accounts.type").
+		Order("ledger_accounts.code, ledger_accounts.currency").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ledger balances: %w", err)
+	}
+
+	balances := make([]LedgerBalance, len(rows))
+	for i, row := range rows {
+		total := row.Total
+		if row.Type == AccountLiability {
+			total = -total
+		}
+		balances[i] = LedgerBalance{
+			Account: row.Code,
+			Type:    row.Type,
+			Balance: money.New(total, row.Currency),
+		}
+	}
+	return balances, nil
+}
+
+// LedgerCheck 账本校验结果
+type LedgerCheck struct {
+	Entries    int64
+	Postings   int64
+	Violations []string
+}
+
+// CheckLedger 校验账本不变量：每笔分录至少有两条过账且按币种合计为零，过账币种与账户一致，
+// 所有过账按币种合计为零
+func (s *PaymentService) CheckLedger(ctx context.Context) (*LedgerCheck, error) {
+	db := s.db.WithContext(ctx)
+	result := &LedgerCheck{}
+	if err := db.Model(&JournalEntry{}).Count(&result.Entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to count journal entries: %w", err)
+	}
+	if err := db.Model(&Posting{}).Count(&result.Postings).Error; err != nil {
+		return nil, fmt.Errorf("failed to count postings: %w", err)
+	}
+
+	var sparse []struct {
+		EntryID string
+		Count   int64
+	}
+	if err := db.Model(&JournalEntry{}).
+		Select("journal_entries.entry_id, COUNT(ledger_postings.id) AS count").
+		Joins("LEFT JOIN ledger_postings ON ledger_postings.journal_entry_id = journal_entries.id").
+		Group("journal_entries.id, journal_entries.entry_id").
+		Having("COUNT(ledger_postings.id) < 2").
+		Scan(&sparse).Error; err != nil {
+		return nil, fmt.Errorf("failed to check journal entries: %w", err)
+	}
+	for _, row := range sparse {
+		result.Violations = append(result.Violations, fmt.Sprintf("journal entry %s has %d postings", row.EntryID, row.Count))
+	}
+
+	var unbalanced []struct {
+		EntryID  string
+		Currency string
+		Total    int64
+	}
+	if err := db.Model(&Posting{}).
+		Select("journal_entries.entry_id, ledger_postings.currency, SUM(ledger_postings.amount_minor) AS total").
+		Joins("JOIN journal_entries ON journal_entries.id = ledger_postings.journal_entry_id").
+		Group("journal_entries.id, journal_entries.entry_id, ledger_postings.currency").
+		Having("SUM(ledger_postings.amount_minor) <> 0").
+		Scan(&unbalanced).Error; err != nil {
+		return nil, fmt.Errorf("failed to check journal entry balances: %w", err)
+	}
+	for _, row := range unbalanced {
+		result.Violations = append(result.Violations, fmt.Sprintf("journal entry %s postings sum to %s", row.EntryID, money.New(row.Total, row.Currency)))
+	}
+
+	var mismatched []struct {
+		ID              uint
+		Currency        string
+		AccountCurrency string
+	}
+	if err := db.Model(&Posting{}).
+		Select("ledger_postings.id, ledger_postings.currency, COALESCE(ledger_accounts.currency, '') AS account_currency").
+		Joins("LEFT JOIN ledger_accounts ON ledger_accounts.id = ledger_postings.account_id").
+		Where("ledger_accounts.id IS NULL OR ledger_accounts.currency <> ledger_postings.currency").
+		Scan(&mismatched).Error; err != nil {
+		return nil, fmt.Errorf("failed to check posting accounts: %w", err)
+	}
+	for _, row := range mismatched {
+		if row.AccountCurrency == "" {
+			result.Violations = append(result.Violations, fmt.Sprintf("posting %d references a missing account", row.ID))
+			continue
+		}
+		result.Violations = append(result.Violations, fmt.Sprintf("posting %d in %s is posted to a %s account", row.ID, row.Currency, row.AccountCurrency))
+	}
+
+	var totals []struct {
+		Currency string
+		Total    int64
+	}
+	if err := db.Model(&Posting{}).
+		Select("currency, SUM(amount_minor) AS total").
+		Group("currency").
+		Having("SUM(amount_minor) <> 0").
+		Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to check ledger totals: %w", err)
+	}
+	for _, row := range totals {
+		result.Violations = append(result.Violations, fmt.Sprintf("ledger postings in %s sum to %s", row.Currency, money.New(row.Total, row.Currency)))
+	}
+
+	return result, nil
+}
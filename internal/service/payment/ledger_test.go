@@ -0,0 +1,70 @@
+package payment_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/bytedance-youthcamp/demo/api/payment"
+	"github.com/bytedance-youthcamp/demo/internal/service/payment"
+)
+
+func TestLedger(t *testing.T) {
+	paymentService := setupTestPaymentService(t)
+	ctx := context.Background()
+
+	balance := func(account string) int64 {
+		b, err := paymentService.AccountBalance(ctx, account, "CNY")
+		require.NoError(t, err)
+		return b.Balance.Amount
+	}
+
+	orderID := int32(time.Now().UnixNano()%1000000) + 4000000
+	providerBefore := balance(payment.ProviderAccount("sandbox"))
+
+	createResp, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId:     orderID,
+		AmountMoney: &pb.Money{Amount: 5000, Currency: "CNY"},
+		Method:      pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+	})
+	require.NoError(t, err)
+
+	// 待支付的支付不记账
+	assert.Equal(t, int64(0), balance(payment.OrderAccount(orderID)))
+
+	notification := &pb.PaymentNotificationRequest{
+		PaymentId:     createResp.PaymentId,
+		Status:        pb.PaymentStatus_PAYMENT_STATUS_SUCCESS,
+		TransactionId: "trans_ledger",
+	}
+	_, err = paymentService.ProcessPaymentNotification(ctx, notification)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5000), balance(payment.OrderAccount(orderID)))
+	assert.Equal(t, providerBefore+5000, balance(payment.ProviderAccount("sandbox")))
+
+	// 重复回调不会重复记账
+	_, err = paymentService.ProcessPaymentNotification(ctx, notification)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5000), balance(payment.OrderAccount(orderID)))
+
+	refundResp, err := paymentService.RequestRefund(ctx, &pb.RequestRefundRequest{PaymentId: createResp.PaymentId})
+	require.NoError(t, err)
+	require.True(t, refundResp.Success)
+	assert.Equal(t, int64(5000), balance(payment.OrderAccount(orderID)))
+
+	_, err = paymentService.ProcessRefundNotification(ctx, &pb.RefundNotificationRequest{
+		RefundId: refundResp.RefundId,
+		Status:   pb.RefundStatus_REFUND_STATUS_SUCCESS,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), balance(payment.OrderAccount(orderID)))
+	assert.Equal(t, providerBefore, balance(payment.ProviderAccount("sandbox")))
+
+	result, err := paymentService.CheckLedger(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, result.Violations)
+	assert.GreaterOrEqual(t, result.Postings, 2*result.Entries)
+}
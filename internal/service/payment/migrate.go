@@ -0,0 +1,60 @@
+package payment
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// legacyAmountColumn 旧版本以浮点数存储金额的字段
+const legacyAmountColumn = "amount"
+
+// outboxEvent 发件箱表结构，与 migrations/015_create_outbox_events.up.sql 一致
+type outboxEvent struct {
+	ID            int64      `gorm:"primaryKey;autoIncrement;index:idx_outbox_events_pending,priority:3"`
+	EventID       string     `gorm:"size:36;not null;uniqueIndex"`
+	EventType     string     `gorm:"size:64;not null"`
+	AggregateType string     `gorm:"size:32;not null;index:idx_outbox_events_pending,priority:2"`
+	AggregateID   string     `gorm:"size:64;not null"`
+	Payload       string     `gorm:"type:text;not null"`
+	OccurredAt    time.Time  `gorm:"not null"`
+	PublishedAt   *time.Time `gorm:"index:idx_outbox_events_pending,priority:1"`
+	Attempts      int        `gorm:"not null;default:0"`
+	LastError     string     `gorm:"size:255;not null;default:''"`
+}
+
+func (outboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// Migrate 迁移支付相关的数据表，并将旧版本的浮点金额转换为最小货币单位
+func Migrate(db *gorm.DB) error {
+	models := []interface{}{&Payment{}, &Refund{}, &RefundItem{}}
+	if err := db.AutoMigrate(models...); err != nil {
+		return fmt.Errorf("failed to migrate payment tables: %w", err)
+	}
+	if err := db.AutoMigrate(&outboxEvent{}); err != nil {
+		return fmt.Errorf("failed to migrate outbox table: %w", err)
+	}
+	if err := db.AutoMigrate(&LedgerAccount{}, &JournalEntry{}, &Posting{}); err != nil {
+		return fmt.Errorf("failed to migrate ledger tables: %w", err)
+	}
+
+	for _, model := range models {
+		if !db.Migrator().HasColumn(model, legacyAmountColumn) {
+			continue
+		}
+		// 旧数据均为人民币，按两位小数换算为分
+		if err := db.Unscoped().Model(model).
+			Where("amount_minor = 0").
+			Update("amount_minor", gorm.Expr("ROUND(amount * 100)")).Error; err != nil {
+			return fmt.Errorf("failed to convert legacy amounts: %w", err)
+		}
+		if err := db.Migrator().DropColumn(model, legacyAmountColumn); err != nil {
+			return fmt.Errorf("failed to drop legacy amount column: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package payment
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/bytedance-youthcamp/demo/api/payment"
+)
+
+// serviceTokenHeader 携带内部服务的服务令牌，与订单服务使用相同的元数据键
+const serviceTokenHeader = "x-service-token"
+
+// internalMethods 直接修改支付或退款结果、发起退款和关闭订单支付的 RPC，只允许持有服务令牌的内部服务调用。
+// 用户通过订单服务取消商品发起退款，订单服务负责检查订单所有者。
+// 外部的支付结果只能通过 HandleProviderCallback 验证签名、时间和金额后进入
+var internalMethods = map[string]bool{
+	pb.PaymentService_ProcessPaymentNotification_FullMethodName: true,
+	pb.PaymentService_ProcessRefundNotification_FullMethodName:  true,
+	pb.PaymentService_SimulatePaymentCallback_FullMethodName:    true,
+	pb.PaymentService_CancelOrderPayment_FullMethodName:         true,
+	pb.PaymentService_RequestRefund_FullMethodName:              true,
+}
+
+// WithServiceTokens 设置可以调用内部 RPC 的服务令牌，键为服务名。未设置时内部 RPC 拒绝所有调用
+func WithServiceTokens(tokens map[string]string) Option {
+	return func(s *PaymentService) {
+		s.serviceTokens = tokens
+	}
+}
+
+// UnaryAuthInterceptor 拒绝没有有效服务令牌的内部 RPC 调用，其他 RPC 不受影响
+func (s *PaymentService) UnaryAuthInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if internalMethods[info.FullMethod] && !s.isInternalCaller(ctx) {
+			return nil, status.Error(codes.PermissionDenied, "method is only available to internal services")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (s *PaymentService) isInternalCaller(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(serviceTokenHeader)
+	if len(values) == 0 {
+		return false
+	}
+	for _, token := range s.serviceTokens {
+		if token != "" && subtle.ConstantTimeCompare([]byte(values[0]), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/bytedance-youthcamp/demo/api/payment"
+)
+
+func TestUnaryAuthInterceptor(t *testing.T) {
+	service := NewPaymentService(nil, WithServiceTokens(map[string]string{"ops": "ops-token"}))
+	interceptor := service.UnaryAuthInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	call := func(method, token string) codes.Code {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(serviceTokenHeader, token))
+		}
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return status.Code(err)
+	}
+
+	testCases := []struct {
+		name   string
+		method string
+		token  string
+		code   codes.Code
+	}{
+		{"public method without token", pb.PaymentService_CreatePayment_FullMethodName, "", codes.OK},
+		{"notification without token", pb.PaymentService_ProcessPaymentNotification_FullMethodName, "", codes.PermissionDenied},
+		{"notification with wrong token", pb.PaymentService_ProcessPaymentNotification_FullMethodName, "wrong", codes.PermissionDenied},
+		{"notification with service token", pb.PaymentService_ProcessPaymentNotification_FullMethodName, "ops-token", codes.OK},
+		{"simulated callback without token", pb.PaymentService_SimulatePaymentCallback_FullMethodName, "", codes.PermissionDenied},
+		{"refund notification without token", pb.PaymentService_ProcessRefundNotification_FullMethodName, "", codes.PermissionDenied},
+		{"cancel order payment without token", pb.PaymentService_CancelOrderPayment_FullMethodName, "", codes.PermissionDenied},
+		{"request refund without token", pb.PaymentService_RequestRefund_FullMethodName, "", codes.PermissionDenied},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.code, call(tc.method, tc.token))
+		})
+	}
+
+	t.Run("no service tokens configured", func(t *testing.T) {
+		closed := NewPaymentService(nil).UnaryAuthInterceptor()
+		_, err := closed(context.Background(), nil,
+			&grpc.UnaryServerInfo{FullMethod: pb.PaymentService_ProcessPaymentNotification_FullMethodName}, handler)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
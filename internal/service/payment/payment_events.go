@@ -0,0 +1,65 @@
+package payment
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	pb "github.com/bytedance-youthcamp/demo/api/payment"
+	"github.com/bytedance-youthcamp/demo/internal/outbox"
+)
+
+// PaymentEvent 支付领域事件的内容
+type PaymentEvent struct {
+	PaymentID     string `json:"payment_id"`
+	OrderID       int32  `json:"order_id"`
+	AmountMinor   int64  `json:"amount_minor"`
+	Currency      string `json:"currency"`
+	Method        string `json:"method"`
+	TransactionID string `json:"transaction_id,omitempty"`
+}
+
+// RefundEvent 退款领域事件的内容
+type RefundEvent struct {
+	RefundID      string `json:"refund_id"`
+	PaymentID     string `json:"payment_id"`
+	OrderID       int32  `json:"order_id"`
+	AmountMinor   int64  `json:"amount_minor"`
+	Currency      string `json:"currency"`
+	FullyRefunded bool   `json:"fully_refunded"`
+	TransactionID string `json:"transaction_id,omitempty"`
+}
+
+// recordPaymentEvent 在支付事务中写入支付结果事件，支付成功、失败、过期和被取代分别对应不同的事件
+func recordPaymentEvent(ctx context.Context, tx *gorm.DB, payment *Payment) error {
+	eventType := outbox.PaymentSucceeded
+	switch payment.Status {
+	case pb.PaymentStatus_PAYMENT_STATUS_FAILED:
+		eventType = outbox.PaymentFailed
+	case pb.PaymentStatus_PAYMENT_STATUS_EXPIRED:
+		eventType = outbox.PaymentExpired
+	case pb.PaymentStatus_PAYMENT_STATUS_SUPERSEDED:
+		eventType = outbox.PaymentSuperseded
+	}
+	return outbox.Record(ctx, tx.Statement.ConnPool, outbox.AggregatePayment, payment.PaymentID, eventType, PaymentEvent{
+		PaymentID:     payment.PaymentID,
+		OrderID:       payment.OrderID,
+		AmountMinor:   payment.AmountMinor,
+		Currency:      payment.Currency,
+		Method:        payment.Method.String(),
+		TransactionID: payment.TransactionID,
+	})
+}
+
+// recordRefundEvent 在退款事务中写入退款成功事件
+func recordRefundEvent(ctx context.Context, tx *gorm.DB, refund *Refund) error {
+	return outbox.Record(ctx, tx.Statement.ConnPool, outbox.AggregateRefund, refund.RefundID, outbox.RefundSucceeded, RefundEvent{
+		RefundID:      refund.RefundID,
+		PaymentID:     refund.PaymentID,
+		OrderID:       refund.OrderID,
+		AmountMinor:   refund.AmountMinor,
+		Currency:      refund.Currency,
+		FullyRefunded: refund.FullyRefunded,
+		TransactionID: refund.TransactionID,
+	})
+}
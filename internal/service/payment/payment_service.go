@@ -2,65 +2,221 @@ package payment
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	orderpb "github.com/bytedance-youthcamp/demo/api/order"
 	pb "github.com/bytedance-youthcamp/demo/api/payment"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+const (
+	defaultOrderSyncAttempts = 3
+	defaultOrderSyncBackoff  = 200 * time.Millisecond
+	// 后台任务对同一笔支付最多重试的次数，超过后需要人工处理
+	maxOrderSyncAttempts = 20
 )
 
 type PaymentService struct {
 	pb.UnimplementedPaymentServiceServer
-	db *gorm.DB
+	db               *gorm.DB
+	orderClient      orderpb.OrderServiceClient
+	orderSyncRetries int
+	orderSyncBackoff time.Duration
+	// 每种支付方式使用的渠道，以及按名称索引的全部渠道
+	providers       map[pb.PaymentMethod]PaymentProvider
+	providersByName map[string]PaymentProvider
+	// 渠道回调时间与当前时间允许的最大偏差
+	callbackReplayWindow time.Duration
+	// 待支付的支付可以被重复使用的时间
+	paymentStaleAfter time.Duration
+	// 支付的有效期，以及待支付多久后向渠道查询结果
+	paymentTTL     time.Duration
+	reconcileAfter time.Duration
+	// 对账按支付 ID 分批处理，reconcileCursor 为上一批最后一笔支付的 ID，处理到末尾后从头开始
+	reconcileMu     sync.Mutex
+	reconcileCursor uint
+	// 可以调用内部 RPC 的服务令牌
+	serviceTokens map[string]string
 }
 
 type Payment struct {
 	gorm.Model
-	PaymentID     string             `gorm:"uniqueIndex:idx_payment_id,length:36"`
-	OrderID       int32              `gorm:"not null"`
-	Amount        float64            `gorm:"not null"`
-	Status        pb.PaymentStatus   `gorm:"not null"`
-	Method        pb.PaymentMethod   `gorm:"not null"`
-	TransactionID string             `gorm:"default:null"`
+	PaymentID     string           `gorm:"uniqueIndex:idx_payment_id,length:36"`
+	OrderID       int32            `gorm:"not null"`
+	AmountMinor   int64            `gorm:"not null;default:0"`
+	Currency      string           `gorm:"size:3;not null;default:CNY"`
+	Status        pb.PaymentStatus `gorm:"not null"`
+	Method        pb.PaymentMethod `gorm:"not null"`
+	TransactionID string           `gorm:"default:null"`
+	Provider      string           `gorm:"size:32;not null;default:''"`
+	PaymentURL    string           `gorm:"size:512;not null;default:''"`
+	Refunds       []Refund         `gorm:"foreignKey:PaymentID;references:PaymentID"`
+	// 幂等键，相同的键只会创建一笔支付
+	IdempotencyKey string `gorm:"size:64;default:null;uniqueIndex:idx_payment_idempotency_key"`
+	// 待支付时为订单ID，支付结束或被取代后置空，唯一索引保证每个订单只有一笔待支付的支付
+	ActiveOrderID *int32 `gorm:"uniqueIndex:idx_payment_active_order"`
+	// 订单服务是否已确认支付结果
+	OrderSynced       bool `gorm:"not null;default:false"`
+	OrderSyncAttempts int  `gorm:"not null;default:0"`
+}
+
+// Money 返回支付的精确金额
+func (p *Payment) Money() money.Money {
+	return money.New(p.AmountMinor, p.Currency)
+}
+
+type Option func(*PaymentService)
+
+// WithOrderClient 注入订单服务客户端，支付结果会同步到订单状态
+func WithOrderClient(client orderpb.OrderServiceClient) Option {
+	return func(s *PaymentService) {
+		s.orderClient = client
+	}
+}
+
+// WithOrderSyncRetry 设置同步订单状态的重试次数和初始退避时间
+func WithOrderSyncRetry(attempts int, backoff time.Duration) Option {
+	return func(s *PaymentService) {
+		if attempts > 0 {
+			s.orderSyncRetries = attempts
+		}
+		if backoff > 0 {
+			s.orderSyncBackoff = backoff
+		}
+	}
 }
 
-func NewPaymentService(db *gorm.DB) *PaymentService {
-	return &PaymentService{
-		db: db,
+func NewPaymentService(db *gorm.DB, opts ...Option) *PaymentService {
+	service := &PaymentService{
+		db:                   db,
+		orderSyncRetries:     defaultOrderSyncAttempts,
+		orderSyncBackoff:     defaultOrderSyncBackoff,
+		providers:            make(map[pb.PaymentMethod]PaymentProvider),
+		providersByName:      make(map[string]PaymentProvider),
+		callbackReplayWindow: defaultCallbackReplayWindow,
+		paymentStaleAfter:    defaultPaymentStaleAfter,
+		paymentTTL:           defaultPaymentTTL,
+		reconcileAfter:       defaultReconcileAfter,
 	}
+
+	for _, opt := range opts {
+		opt(service)
+	}
+	service.registerProviders()
+
+	return service
 }
 
+// CreatePayment 为订单发起支付。同一订单同时只有一笔待支付的支付：重复请求返回已有的支付，
+// 金额或支付方式变化、或已有支付超过有效期时，在渠道关闭旧的收款并将旧支付标记为已取代后创建新的支付
 func (s *PaymentService) CreatePayment(ctx context.Context, req *pb.CreatePaymentRequest) (*pb.CreatePaymentResponse, error) {
 	// 验证支付金额
-	if req.Amount <= 0 {
+	amount := requestAmount(req.AmountMoney, req.Amount)
+	if !amount.IsPositive() {
 		return nil, fmt.Errorf("invalid payment amount: must be greater than zero")
 	}
 
+	provider, ok := s.providers[req.Method]
+	if !ok {
+		return nil, fmt.Errorf("unsupported payment method: %s", req.Method)
+	}
+
+	// 相同幂等键的重复请求返回同一笔支付
+	if req.IdempotencyKey != "" {
+		existing, errorMessage, err := s.findIdempotentPayment(req, amount)
+		if err != nil {
+			return nil, err
+		}
+		if errorMessage != "" {
+			return &pb.CreatePaymentResponse{Success: false, ErrorMessage: errorMessage}, nil
+		}
+		if existing != nil {
+			return createPaymentResponse(existing), nil
+		}
+	}
+
+	// 支付金额必须等于订单应付金额
+	if errorMessage, err := s.verifyOrderAmount(ctx, req.OrderId, amount); err != nil {
+		return nil, err
+	} else if errorMessage != "" {
+		return &pb.CreatePaymentResponse{Success: false, ErrorMessage: errorMessage}, nil
+	}
+
+	// 订单已有相同的待支付支付时直接返回，否则取代已有的支付
+	active, err := s.activePayment(req.OrderId)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		if s.reusable(active, amount, req.Method) {
+			return createPaymentResponse(active), nil
+		}
+		if err := s.supersedePayment(ctx, active); err != nil {
+			if errors.Is(err, ErrChargeCompleted) {
+				return &pb.CreatePaymentResponse{Success: false, ErrorMessage: fmt.Sprintf("order %d has already been paid", req.OrderId)}, nil
+			}
+			return nil, err
+		}
+	}
+
 	// 生成唯一的支付ID
 	paymentID := uuid.New().String()
+	orderID := req.OrderId
 
 	// 创建支付记录
 	payment := &Payment{
-		PaymentID: paymentID,
-		OrderID:   req.OrderId,
-		Amount:    req.Amount,
-		Status:    pb.PaymentStatus_PAYMENT_STATUS_PENDING,
-		Method:    req.Method,
+		PaymentID:      paymentID,
+		OrderID:        req.OrderId,
+		AmountMinor:    amount.Amount,
+		Currency:       amount.Currency,
+		Status:         pb.PaymentStatus_PAYMENT_STATUS_PENDING,
+		Method:         req.Method,
+		Provider:       provider.Name(),
+		IdempotencyKey: req.IdempotencyKey,
+		ActiveOrderID:  &orderID,
 	}
 
-	// 保存到数据库
+	// 先保存支付记录再向渠道发起收款，保证渠道的回调总能找到支付记录。
+	// 并发请求由唯一索引保证只有一个能创建成功，其他请求返回创建成功的支付
 	if err := s.db.Create(payment).Error; err != nil {
+		if existing := s.concurrentPayment(req); existing != nil {
+			return createPaymentResponse(existing), nil
+		}
 		return nil, fmt.Errorf("failed to create payment: %w", err)
 	}
 
-	// 模拟生成支付URL（实际应该对接第三方支付）
-	paymentURL := fmt.Sprintf("https://payment.example.com/pay?id=%s", paymentID)
+	charge, err := provider.CreateCharge(ctx, ChargeRequest{
+		PaymentID: paymentID,
+		OrderID:   req.OrderId,
+		Amount:    amount,
+		Method:    req.Method,
+	})
+	if err != nil {
+		// 渠道未受理收款时将支付标记为失败，订单可以重新发起支付
+		if _, failErr := s.ProcessPaymentNotification(ctx, &pb.PaymentNotificationRequest{
+			PaymentId: paymentID,
+			OrderId:   req.OrderId,
+			Status:    pb.PaymentStatus_PAYMENT_STATUS_FAILED,
+		}); failErr != nil {
+			log.Printf("Failed to mark payment %s as failed: %v", paymentID, failErr)
+		}
+		return nil, fmt.Errorf("failed to create charge with provider %s: %w", provider.Name(), err)
+	}
+
+	// 保存支付链接，重复请求时返回同一个链接
+	payment.PaymentURL = charge.PaymentURL
+	if err := s.db.Model(payment).Update("payment_url", charge.PaymentURL).Error; err != nil {
+		return nil, fmt.Errorf("failed to save payment url: %w", err)
+	}
 
-	return &pb.CreatePaymentResponse{
-		PaymentId:  paymentID,
-		PaymentUrl: paymentURL,
-	}, nil
+	return createPaymentResponse(payment), nil
 }
 
 func (s *PaymentService) QueryPayment(ctx context.Context, req *pb.QueryPaymentRequest) (*pb.QueryPaymentResponse, error) {
@@ -69,10 +225,18 @@ func (s *PaymentService) QueryPayment(ctx context.Context, req *pb.QueryPaymentR
 		return nil, fmt.Errorf("payment not found: %w", err)
 	}
 
+	// 待支付时向渠道查询，弥补丢失或延迟的回调
+	if payment.Status == pb.PaymentStatus_PAYMENT_STATUS_PENDING {
+		if err := s.refreshPayment(ctx, &payment); err != nil {
+			log.Printf("Failed to query payment %s from provider: %v", payment.PaymentID, err)
+		}
+	}
+
 	return &pb.QueryPaymentResponse{
 		PaymentId:     payment.PaymentID,
 		OrderId:       payment.OrderID,
-		Amount:        payment.Amount,
+		Amount:        payment.Money().Float64(),
+		AmountMoney:   toAPIMoney(payment.Money()),
 		Status:        payment.Status,
 		Method:        payment.Method,
 		TransactionId: payment.TransactionID,
@@ -86,12 +250,20 @@ func (s *PaymentService) ProcessPaymentNotification(ctx context.Context, req *pb
 		return nil, fmt.Errorf("payment not found: %w", err)
 	}
 
+	// 重复回调：状态已经是通知的终态，只需确保订单状态已同步
+	if payment.Status == req.Status && isFinalStatus(req.Status) {
+		if err := s.syncOrderStatus(ctx, &payment); err != nil {
+			return nil, err
+		}
+		return &pb.PaymentNotificationResponse{Success: true}, nil
+	}
+
 	// 验证状态转换规则
 	switch payment.Status {
 	case pb.PaymentStatus_PAYMENT_STATUS_PENDING:
 		// 从 PENDING 只能转换到 SUCCESS 或 FAILED
 		if req.Status != pb.PaymentStatus_PAYMENT_STATUS_SUCCESS &&
-		   req.Status != pb.PaymentStatus_PAYMENT_STATUS_FAILED {
+			req.Status != pb.PaymentStatus_PAYMENT_STATUS_FAILED {
 			return nil, fmt.Errorf("invalid status transition from PENDING")
 		}
 	case pb.PaymentStatus_PAYMENT_STATUS_SUCCESS:
@@ -100,34 +272,280 @@ func (s *PaymentService) ProcessPaymentNotification(ctx context.Context, req *pb
 	case pb.PaymentStatus_PAYMENT_STATUS_FAILED:
 		// 失败状态不能再转换
 		return nil, fmt.Errorf("cannot change status from FAILED")
+	case pb.PaymentStatus_PAYMENT_STATUS_SUPERSEDED:
+		// 已被同一订单的新支付取代
+		return nil, fmt.Errorf("payment has been superseded")
+	case pb.PaymentStatus_PAYMENT_STATUS_EXPIRED:
+		// 已过期，订单需要重新发起支付
+		return nil, fmt.Errorf("payment has expired")
 	default:
 		// 未知状态
 		return nil, fmt.Errorf("invalid current payment status")
 	}
 
-	// 更新支付状态
-	payment.Status = req.Status
-	payment.TransactionID = req.TransactionId
+	// 条件更新支付状态，并发的重复回调只有一个能更新成功；支付结果事件和支付成功的账本分录与状态在同一事务中写入
+	var updated bool
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Payment{}).
+			Where("id = ? AND status = ?", payment.ID, pb.PaymentStatus_PAYMENT_STATUS_PENDING).
+			Updates(map[string]interface{}{
+				"status":          req.Status,
+				"transaction_id":  req.TransactionId,
+				"active_order_id": nil,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update payment: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
 
-	if err := s.db.Save(&payment).Error; err != nil {
-		return nil, fmt.Errorf("failed to update payment: %w", err)
+		updated = true
+		payment.Status = req.Status
+		payment.TransactionID = req.TransactionId
+		payment.ActiveOrderID = nil
+		if payment.Status == pb.PaymentStatus_PAYMENT_STATUS_SUCCESS {
+			if err := postPayment(ctx, tx, &payment); err != nil {
+				return err
+			}
+		}
+		return recordPaymentEvent(ctx, tx, &payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		// 支付状态已被并发请求修改，重新读取后按重复回调处理
+		if err := s.db.First(&payment, payment.ID).Error; err != nil {
+			return nil, fmt.Errorf("failed to reload payment: %w", err)
+		}
+		if payment.Status != req.Status {
+			return nil, fmt.Errorf("payment status changed concurrently to %s", payment.Status)
+		}
 	}
 
-	// 如果支付成功，记录日志
-	if req.Status == pb.PaymentStatus_PAYMENT_STATUS_SUCCESS {
-		fmt.Printf("Payment successful for order %d\n", req.OrderId)
+	// 将支付结果同步到订单
+	if err := s.syncOrderStatus(ctx, &payment); err != nil {
+		return nil, err
 	}
 
-	return &pb.PaymentNotificationResponse{}, nil
+	return &pb.PaymentNotificationResponse{Success: true}, nil
 }
 
-// 模拟支付回调（实际应该由第三方支付系统调用）
+// syncOrderStatus 将支付结果同步到订单服务，失败时按指数退避重试；
+// 订单已确认后不会重复通知
+func (s *PaymentService) syncOrderStatus(ctx context.Context, payment *Payment) error {
+	if s.orderClient == nil || payment.OrderSynced {
+		return nil
+	}
+
+	err := s.retry(ctx, func() error {
+		return s.notifyOrder(ctx, payment)
+	}, "sync payment %s to order %d", payment.PaymentID, payment.OrderID)
+
+	// 记录同步结果，未同步的支付由后台任务继续重试
+	updates := map[string]interface{}{
+		"order_sync_attempts": gorm.Expr("order_sync_attempts + ?", 1),
+	}
+	if err == nil {
+		updates["order_synced"] = true
+	}
+	if dbErr := s.db.Model(&Payment{}).Where("id = ?", payment.ID).Updates(updates).Error; dbErr != nil {
+		return fmt.Errorf("failed to record order sync result: %w", dbErr)
+	}
+	payment.OrderSyncAttempts++
+
+	if err != nil {
+		return fmt.Errorf("failed to sync order status: %w", err)
+	}
+	payment.OrderSynced = true
+	return nil
+}
+
+// retry 按指数退避重试 fn，直到成功、达到重试次数或 ctx 结束，返回最后一次的错误
+func (s *PaymentService) retry(ctx context.Context, fn func() error, format string, args ...interface{}) error {
+	backoff := s.orderSyncBackoff
+	var err error
+	for attempt := 1; attempt <= s.orderSyncRetries; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		log.Printf("Failed to %s (attempt %d/%d): %v",
+			fmt.Sprintf(format, args...), attempt, s.orderSyncRetries, err)
+
+		if attempt < s.orderSyncRetries {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(backoff):
+				backoff *= 2
+			}
+		}
+	}
+	return err
+}
+
+// notifyOrder 根据支付结果推进订单状态，订单已处于目标状态时视为成功
+func (s *PaymentService) notifyOrder(ctx context.Context, payment *Payment) error {
+	switch payment.Status {
+	case pb.PaymentStatus_PAYMENT_STATUS_SUCCESS:
+		settleResp, err := s.orderClient.SettleOrder(ctx, &orderpb.SettleOrderRequest{
+			OrderId:       payment.OrderID,
+			PaymentMethod: payment.Method.String(),
+			AmountMoney:   &orderpb.Money{Amount: payment.AmountMinor, Currency: payment.Currency},
+		})
+		if err != nil {
+			return err
+		}
+		if settleResp.Success {
+			return nil
+		}
+		return s.checkOrderStatus(ctx, payment.OrderID, settleResp.ErrorMessage,
+			orderpb.OrderStatus_PAID, orderpb.OrderStatus_SHIPPING, orderpb.OrderStatus_COMPLETED)
+
+	case pb.PaymentStatus_PAYMENT_STATUS_FAILED:
+		failedResp, err := s.orderClient.MarkOrderPaymentFailed(ctx, &orderpb.MarkOrderPaymentFailedRequest{
+			OrderId:   payment.OrderID,
+			PaymentId: payment.PaymentID,
+			Reason:    "payment failed",
+		})
+		if err != nil {
+			return err
+		}
+		if failedResp.Success {
+			return nil
+		}
+		// 订单已支付或已取消时无需再标记失败
+		return s.checkOrderStatus(ctx, payment.OrderID, failedResp.ErrorMessage,
+			orderpb.OrderStatus_PAID, orderpb.OrderStatus_SHIPPING, orderpb.OrderStatus_COMPLETED, orderpb.OrderStatus_CANCELLED)
+	}
+
+	return nil
+}
+
+// checkOrderStatus 在订单服务拒绝请求时检查订单是否已经处于期望的状态
+func (s *PaymentService) checkOrderStatus(ctx context.Context, orderID int32, errorMessage string, expected ...orderpb.OrderStatus) error {
+	orderResp, err := s.orderClient.GetOrder(ctx, &orderpb.GetOrderRequest{OrderId: orderID})
+	if err != nil {
+		return err
+	}
+	if orderResp.Success {
+		for _, status := range expected {
+			if orderResp.Order.Status == status {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("order service rejected update: %s", errorMessage)
+}
+
+// RetryOrderSync 重新同步已有结果但订单尚未确认的支付和退款，返回同步成功的数量
+func (s *PaymentService) RetryOrderSync(ctx context.Context) (int, error) {
+	if s.orderClient == nil {
+		return 0, nil
+	}
+
+	var payments []Payment
+	err := s.db.Where("status IN ? AND order_synced = ? AND order_sync_attempts < ?",
+		[]pb.PaymentStatus{pb.PaymentStatus_PAYMENT_STATUS_SUCCESS, pb.PaymentStatus_PAYMENT_STATUS_FAILED},
+		false, maxOrderSyncAttempts).
+		Limit(100).
+		Find(&payments).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to list unsynced payments: %w", err)
+	}
+
+	synced := 0
+	for i := range payments {
+		if err := s.syncOrderStatus(ctx, &payments[i]); err != nil {
+			log.Printf("Error syncing payment %s: %v", payments[i].PaymentID, err)
+			continue
+		}
+		synced++
+	}
+
+	var refunds []Refund
+	err = s.db.Preload("Items").
+		Where("status IN ? AND order_synced = ? AND order_sync_attempts < ?",
+			[]pb.RefundStatus{pb.RefundStatus_REFUND_STATUS_SUCCESS, pb.RefundStatus_REFUND_STATUS_FAILED},
+			false, maxOrderSyncAttempts).
+		Limit(100).
+		Find(&refunds).Error
+	if err != nil {
+		return synced, fmt.Errorf("failed to list unsynced refunds: %w", err)
+	}
+
+	for i := range refunds {
+		if err := s.syncRefund(ctx, &refunds[i]); err != nil {
+			log.Printf("Error syncing refund %s: %v", refunds[i].RefundID, err)
+			continue
+		}
+		synced++
+	}
+
+	return synced, nil
+}
+
+// StartOrderSyncTask 定期重试未同步到订单的支付结果
+func (s *PaymentService) StartOrderSyncTask(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if _, err := s.RetryOrderSync(context.Background()); err != nil {
+				log.Printf("Error retrying order sync: %v", err)
+			}
+		}
+	}()
+}
+
+func isFinalStatus(status pb.PaymentStatus) bool {
+	return status == pb.PaymentStatus_PAYMENT_STATUS_SUCCESS || status == pb.PaymentStatus_PAYMENT_STATUS_FAILED
+}
+
+// refreshPayment 向渠道查询待支付的支付，渠道已有结果时按回调处理
+func (s *PaymentService) refreshPayment(ctx context.Context, payment *Payment) error {
+	provider, err := s.paymentProvider(payment)
+	if err != nil {
+		return err
+	}
+	status, err := provider.QueryCharge(ctx, payment.PaymentID)
+	if err != nil {
+		return err
+	}
+	if !isFinalStatus(status.Status) {
+		return nil
+	}
+
+	if _, err := s.ProcessPaymentNotification(ctx, &pb.PaymentNotificationRequest{
+		PaymentId:     payment.PaymentID,
+		OrderId:       payment.OrderID,
+		Status:        status.Status,
+		TransactionId: status.TransactionID,
+	}); err != nil {
+		return err
+	}
+	return s.db.First(payment, payment.ID).Error
+}
+
+// 模拟支付回调（实际应该由第三方支付系统调用）。沙箱渠道创建的支付由沙箱渠道完成并发出签名回调
 func (s *PaymentService) SimulatePaymentCallback(ctx context.Context, req *pb.SimulatePaymentCallbackRequest) (*pb.SimulatePaymentCallbackResponse, error) {
 	var payment Payment
 	if err := s.db.Where("payment_id = ?", req.PaymentId).First(&payment).Error; err != nil {
 		return nil, fmt.Errorf("payment not found: %w", err)
 	}
 
+	if provider, err := s.paymentProvider(&payment); err == nil {
+		if sandbox, ok := provider.(*SandboxProvider); ok {
+			err := sandbox.Complete(ctx, payment.PaymentID, req.Status)
+			if err == nil {
+				return &pb.SimulatePaymentCallbackResponse{Success: true}, nil
+			}
+			if !errors.Is(err, ErrUnknownCharge) {
+				return nil, err
+			}
+		}
+	}
+
 	transactionID := fmt.Sprintf("simulated_callback_%s", uuid.New().String())
 
 	_, err := s.ProcessPaymentNotification(ctx, &pb.PaymentNotificationRequest{
@@ -144,3 +562,18 @@ func (s *PaymentService) SimulatePaymentCallback(ctx context.Context, req *pb.Si
 		Success: true,
 	}, nil
 }
+
+// requestAmount 读取请求中的支付金额，优先使用精确金额
+func requestAmount(exact *pb.Money, amount float64) money.Money {
+	if exact != nil {
+		return money.New(exact.Amount, exact.Currency)
+	}
+	return money.FromFloat(amount, money.DefaultCurrency)
+}
+
+func toAPIMoney(m money.Money) *pb.Money {
+	return &pb.Money{
+		Amount:   m.Amount,
+		Currency: m.Currency,
+	}
+}
@@ -1,20 +1,29 @@
 package payment_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 
+	orderpb "github.com/bytedance-youthcamp/demo/api/order"
 	pb "github.com/bytedance-youthcamp/demo/api/payment"
 	"github.com/bytedance-youthcamp/demo/internal/service/payment"
 )
 
-func setupTestPaymentService(t *testing.T) *payment.PaymentService {
+func setupTestPaymentService(t *testing.T, opts ...payment.Option) *payment.PaymentService {
 	// 根据环境变量选择数据库主机
 	host := os.Getenv("DB_HOST")
 	if host == "" {
@@ -27,10 +36,10 @@ func setupTestPaymentService(t *testing.T) *payment.PaymentService {
 	assert.NoError(t, err)
 
 	// 自动迁移数据库模型
-	err = db.AutoMigrate(&payment.Payment{})
+	err = payment.Migrate(db)
 	assert.NoError(t, err)
 
-	paymentService := payment.NewPaymentService(db)
+	paymentService := payment.NewPaymentService(db, opts...)
 	return paymentService
 }
 
@@ -383,3 +392,397 @@ func TestPaymentAmountValidation(t *testing.T) {
 		})
 	}
 }
+
+type mockOrderClient struct {
+	mock.Mock
+	orderpb.OrderServiceClient
+}
+
+func (m *mockOrderClient) SettleOrder(ctx context.Context, in *orderpb.SettleOrderRequest, opts ...grpc.CallOption) (*orderpb.SettleOrderResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*orderpb.SettleOrderResponse), args.Error(1)
+}
+
+func (m *mockOrderClient) MarkOrderPaymentFailed(ctx context.Context, in *orderpb.MarkOrderPaymentFailedRequest, opts ...grpc.CallOption) (*orderpb.MarkOrderPaymentFailedResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*orderpb.MarkOrderPaymentFailedResponse), args.Error(1)
+}
+
+func TestPaymentNotificationSyncsOrder(t *testing.T) {
+	orderClient := new(mockOrderClient)
+	paymentService := setupTestPaymentService(t,
+		payment.WithOrderClient(orderClient),
+		payment.WithOrderSyncRetry(3, time.Millisecond),
+	)
+	ctx := context.Background()
+
+	// 支付金额需要与订单应付金额一致
+	orderClient.On("GetOrder", mock.Anything, mock.MatchedBy(func(req *orderpb.GetOrderRequest) bool {
+		return req.OrderId == 3
+	})).Return(&orderpb.GetOrderResponse{Success: true, Order: &orderpb.Order{Id: 3, TotalAmount: 200.0}}, nil)
+	orderClient.On("GetOrder", mock.Anything, mock.MatchedBy(func(req *orderpb.GetOrderRequest) bool {
+		return req.OrderId == 4
+	})).Return(&orderpb.GetOrderResponse{Success: true, Order: &orderpb.Order{Id: 4, TotalAmount: 100.0}}, nil)
+
+	createResp, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId: 3,
+		Amount:  200.0,
+		Method:  pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+	})
+	assert.NoError(t, err)
+
+	// 第一次调用订单服务失败，重试后成功
+	orderClient.On("SettleOrder", mock.Anything, mock.MatchedBy(func(req *orderpb.SettleOrderRequest) bool {
+		return req.OrderId == 3
+	})).Return((*orderpb.SettleOrderResponse)(nil), errors.New("connection refused")).Once()
+	orderClient.On("SettleOrder", mock.Anything, mock.Anything).
+		Return(&orderpb.SettleOrderResponse{Success: true, OrderId: 3, Status: orderpb.OrderStatus_PAID}, nil).Once()
+
+	notification := &pb.PaymentNotificationRequest{
+		PaymentId:     createResp.PaymentId,
+		OrderId:       3,
+		Status:        pb.PaymentStatus_PAYMENT_STATUS_SUCCESS,
+		TransactionId: "trans_789",
+	}
+	resp, err := paymentService.ProcessPaymentNotification(ctx, notification)
+	assert.NoError(t, err)
+	assert.True(t, resp.Success)
+
+	// 重复回调不会再次通知订单服务
+	resp, err = paymentService.ProcessPaymentNotification(ctx, notification)
+	assert.NoError(t, err)
+	assert.True(t, resp.Success)
+	orderClient.AssertNumberOfCalls(t, "SettleOrder", 2)
+
+	// 支付失败通知会标记订单支付失败
+	failedResp, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId: 4,
+		Amount:  100.0,
+		Method:  pb.PaymentMethod_PAYMENT_METHOD_WECHAT,
+	})
+	assert.NoError(t, err)
+
+	orderClient.On("MarkOrderPaymentFailed", mock.Anything, mock.MatchedBy(func(req *orderpb.MarkOrderPaymentFailedRequest) bool {
+		return req.OrderId == 4 && req.PaymentId == failedResp.PaymentId
+	})).Return(&orderpb.MarkOrderPaymentFailedResponse{Success: true}, nil).Once()
+
+	resp, err = paymentService.ProcessPaymentNotification(ctx, &pb.PaymentNotificationRequest{
+		PaymentId: failedResp.PaymentId,
+		OrderId:   4,
+		Status:    pb.PaymentStatus_PAYMENT_STATUS_FAILED,
+	})
+	assert.NoError(t, err)
+	assert.True(t, resp.Success)
+	orderClient.AssertExpectations(t)
+}
+
+func (m *mockOrderClient) GetOrder(ctx context.Context, in *orderpb.GetOrderRequest, opts ...grpc.CallOption) (*orderpb.GetOrderResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*orderpb.GetOrderResponse), args.Error(1)
+}
+
+func (m *mockOrderClient) StartRefund(ctx context.Context, in *orderpb.StartRefundRequest, opts ...grpc.CallOption) (*orderpb.StartRefundResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*orderpb.StartRefundResponse), args.Error(1)
+}
+
+func (m *mockOrderClient) CompleteRefund(ctx context.Context, in *orderpb.CompleteRefundRequest, opts ...grpc.CallOption) (*orderpb.CompleteRefundResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*orderpb.CompleteRefundResponse), args.Error(1)
+}
+
+func TestRefundWorkflow(t *testing.T) {
+	orderClient := new(mockOrderClient)
+	paymentService := setupTestPaymentService(t, payment.WithOrderClient(orderClient))
+	ctx := context.Background()
+
+	orderClient.On("SettleOrder", mock.Anything, mock.Anything).
+		Return(&orderpb.SettleOrderResponse{Success: true}, nil)
+	orderClient.On("GetOrder", mock.Anything, mock.Anything).Return(&orderpb.GetOrderResponse{
+		Success: true,
+		Order: &orderpb.Order{
+			Id:          5,
+			TotalAmount: 200.0,
+			Items: []*orderpb.OrderItem{
+				{ProductId: 1, Quantity: 2, Price: 50.0},
+				{ProductId: 2, Quantity: 1, Price: 100.0},
+			},
+		},
+	}, nil)
+	orderClient.On("StartRefund", mock.Anything, mock.Anything).
+		Return(&orderpb.StartRefundResponse{Success: true}, nil)
+
+	createResp, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId: 5,
+		Amount:  200.0,
+		Method:  pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+	})
+	assert.NoError(t, err)
+	_, err = paymentService.ProcessPaymentNotification(ctx, &pb.PaymentNotificationRequest{
+		PaymentId:     createResp.PaymentId,
+		OrderId:       5,
+		Status:        pb.PaymentStatus_PAYMENT_STATUS_SUCCESS,
+		TransactionId: "trans_refund",
+	})
+	assert.NoError(t, err)
+
+	// 部分退款一件商品
+	refundResp, err := paymentService.RequestRefund(ctx, &pb.RequestRefundRequest{
+		PaymentId: createResp.PaymentId,
+		Items:     []*pb.RefundItem{{ProductId: 1, Quantity: 1}},
+		RefundId:  "5a1b2c3d-0000-4000-8000-000000000001",
+	})
+	assert.NoError(t, err)
+	assert.True(t, refundResp.Success)
+	assert.Equal(t, 50.0, refundResp.Amount)
+	assert.Equal(t, "5a1b2c3d-0000-4000-8000-000000000001", refundResp.RefundId)
+
+	// 相同退款单号的重复请求返回已有的退款
+	repeatResp, err := paymentService.RequestRefund(ctx, &pb.RequestRefundRequest{
+		PaymentId: createResp.PaymentId,
+		Items:     []*pb.RefundItem{{ProductId: 1, Quantity: 1}},
+		RefundId:  refundResp.RefundId,
+	})
+	assert.NoError(t, err)
+	assert.True(t, repeatResp.Success)
+	assert.Equal(t, refundResp.RefundId, repeatResp.RefundId)
+	assert.Equal(t, 50.0, repeatResp.Amount)
+
+	// 处理中的退款未完成前不能再次退款
+	pendingResp, err := paymentService.RequestRefund(ctx, &pb.RequestRefundRequest{PaymentId: createResp.PaymentId})
+	assert.NoError(t, err)
+	assert.False(t, pendingResp.Success)
+
+	orderClient.On("CompleteRefund", mock.Anything, mock.MatchedBy(func(req *orderpb.CompleteRefundRequest) bool {
+		return req.RefundId == refundResp.RefundId && req.Success && !req.FullyRefunded && len(req.Items) == 1
+	})).Return(&orderpb.CompleteRefundResponse{Success: true}, nil).Once()
+
+	_, err = paymentService.ProcessRefundNotification(ctx, &pb.RefundNotificationRequest{
+		RefundId: refundResp.RefundId,
+		Status:   pb.RefundStatus_REFUND_STATUS_SUCCESS,
+	})
+	assert.NoError(t, err)
+
+	// 退还剩余金额后支付变为已退款
+	fullResp, err := paymentService.RequestRefund(ctx, &pb.RequestRefundRequest{PaymentId: createResp.PaymentId})
+	assert.NoError(t, err)
+	assert.True(t, fullResp.Success)
+	assert.Equal(t, 150.0, fullResp.Amount)
+
+	orderClient.On("CompleteRefund", mock.Anything, mock.MatchedBy(func(req *orderpb.CompleteRefundRequest) bool {
+		return req.RefundId == fullResp.RefundId && req.Success && req.FullyRefunded && len(req.Items) == 2
+	})).Return(&orderpb.CompleteRefundResponse{Success: true}, nil).Once()
+
+	_, err = paymentService.ProcessRefundNotification(ctx, &pb.RefundNotificationRequest{
+		RefundId: fullResp.RefundId,
+		Status:   pb.RefundStatus_REFUND_STATUS_SUCCESS,
+	})
+	assert.NoError(t, err)
+
+	queryResp, err := paymentService.QueryPayment(ctx, &pb.QueryPaymentRequest{PaymentId: createResp.PaymentId})
+	assert.NoError(t, err)
+	assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_REFUNDED, queryResp.Status)
+	orderClient.AssertExpectations(t)
+}
+
+func TestWebhookCallback(t *testing.T) {
+	sandbox := payment.NewSandboxProvider(payment.WithSandboxSecret("webhook-secret"))
+	paymentService := setupTestPaymentService(t, payment.WithProvider(pb.PaymentMethod_PAYMENT_METHOD_ALIPAY, sandbox))
+	handler := paymentService.WebhookHandler()
+	ctx := context.Background()
+
+	createResp, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId:     6,
+		AmountMoney: &pb.Money{Amount: 12000, Currency: "CNY"},
+		Method:      pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+	})
+	assert.NoError(t, err)
+
+	post := func(orderID int32, amount int64) int {
+		body := []byte(fmt.Sprintf(
+			`{"type":"payment","id":%q,"order_id":%d,"status":"PAYMENT_STATUS_SUCCESS","transaction_id":"webhook_trans","amount":%d,"currency":"CNY","timestamp":%d}`,
+			createResp.PaymentId, orderID, amount, time.Now().Unix()))
+		signature, err := sandbox.Sign(body)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/sandbox", bytes.NewReader(body))
+		req.Header.Set(payment.SignatureHeader, signature)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// 金额或订单与支付记录不一致的回调被拒绝，支付保持待支付
+	assert.Equal(t, http.StatusBadRequest, post(6, 100))
+	assert.Equal(t, http.StatusBadRequest, post(7, 12000))
+	queryResp, err := paymentService.QueryPayment(ctx, &pb.QueryPaymentRequest{PaymentId: createResp.PaymentId})
+	assert.NoError(t, err)
+	assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_PENDING, queryResp.Status)
+
+	// 有效回调更新支付状态，重复回调同样返回成功
+	assert.Equal(t, http.StatusOK, post(6, 12000))
+	assert.Equal(t, http.StatusOK, post(6, 12000))
+	queryResp, err = paymentService.QueryPayment(ctx, &pb.QueryPaymentRequest{PaymentId: createResp.PaymentId})
+	assert.NoError(t, err)
+	assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_SUCCESS, queryResp.Status)
+	assert.Equal(t, "webhook_trans", queryResp.TransactionId)
+}
+
+func TestCreatePaymentIdempotency(t *testing.T) {
+	paymentService := setupTestPaymentService(t)
+	ctx := context.Background()
+
+	// 测试数据库在多次运行之间保留，使用不重复的订单号和幂等键
+	orderID := int32(time.Now().UnixNano()%1000000) + 1000
+	key := uuid.New().String()
+	request := &pb.CreatePaymentRequest{
+		OrderId:        orderID,
+		AmountMoney:    &pb.Money{Amount: 5000, Currency: "CNY"},
+		Method:         pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+		IdempotencyKey: key,
+	}
+
+	first, err := paymentService.CreatePayment(ctx, request)
+	assert.NoError(t, err)
+	assert.True(t, first.Success)
+
+	// 相同幂等键返回同一笔支付
+	again, err := paymentService.CreatePayment(ctx, request)
+	assert.NoError(t, err)
+	assert.Equal(t, first.PaymentId, again.PaymentId)
+	assert.Equal(t, first.PaymentUrl, again.PaymentUrl)
+
+	// 幂等键不能用于不同的金额
+	conflict, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId:        orderID,
+		AmountMoney:    &pb.Money{Amount: 6000, Currency: "CNY"},
+		Method:         pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+		IdempotencyKey: key,
+	})
+	assert.NoError(t, err)
+	assert.False(t, conflict.Success)
+
+	// 同一订单没有幂等键的相同请求返回待支付的支付
+	same, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId:     orderID,
+		AmountMoney: &pb.Money{Amount: 5000, Currency: "CNY"},
+		Method:      pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, first.PaymentId, same.PaymentId)
+
+	// 更换支付方式时取代旧的支付，旧支付的回调被拒绝
+	replaced, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId:     orderID,
+		AmountMoney: &pb.Money{Amount: 5000, Currency: "CNY"},
+		Method:      pb.PaymentMethod_PAYMENT_METHOD_WECHAT,
+	})
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.PaymentId, replaced.PaymentId)
+
+	queryResp, err := paymentService.QueryPayment(ctx, &pb.QueryPaymentRequest{PaymentId: first.PaymentId})
+	assert.NoError(t, err)
+	assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_SUPERSEDED, queryResp.Status)
+
+	_, err = paymentService.ProcessPaymentNotification(ctx, &pb.PaymentNotificationRequest{
+		PaymentId: first.PaymentId,
+		Status:    pb.PaymentStatus_PAYMENT_STATUS_SUCCESS,
+	})
+	assert.Error(t, err)
+
+	// 支付结束后可以为订单发起新的支付
+	_, err = paymentService.ProcessPaymentNotification(ctx, &pb.PaymentNotificationRequest{
+		PaymentId: replaced.PaymentId,
+		Status:    pb.PaymentStatus_PAYMENT_STATUS_FAILED,
+	})
+	assert.NoError(t, err)
+	retried, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId:     orderID,
+		AmountMoney: &pb.Money{Amount: 5000, Currency: "CNY"},
+		Method:      pb.PaymentMethod_PAYMENT_METHOD_WECHAT,
+	})
+	assert.NoError(t, err)
+	assert.NotEqual(t, replaced.PaymentId, retried.PaymentId)
+}
+
+func TestCancelOrderPayment(t *testing.T) {
+	paymentService := setupTestPaymentService(t)
+	ctx := context.Background()
+
+	orderID := int32(time.Now().UnixNano()%1000000) + 2000000
+	created, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId:     orderID,
+		AmountMoney: &pb.Money{Amount: 5000, Currency: "CNY"},
+		Method:      pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+	})
+	assert.NoError(t, err)
+	assert.True(t, created.Success)
+
+	// 订单修改商品后关闭待支付的支付，旧支付的回调被拒绝
+	resp, err := paymentService.CancelOrderPayment(ctx, &pb.CancelOrderPaymentRequest{OrderId: orderID})
+	assert.NoError(t, err)
+	assert.True(t, resp.Success)
+
+	queryResp, err := paymentService.QueryPayment(ctx, &pb.QueryPaymentRequest{PaymentId: created.PaymentId})
+	assert.NoError(t, err)
+	assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_SUPERSEDED, queryResp.Status)
+
+	_, err = paymentService.ProcessPaymentNotification(ctx, &pb.PaymentNotificationRequest{
+		PaymentId: created.PaymentId,
+		Status:    pb.PaymentStatus_PAYMENT_STATUS_SUCCESS,
+	})
+	assert.Error(t, err)
+
+	// 没有待支付的支付时视为成功
+	resp, err = paymentService.CancelOrderPayment(ctx, &pb.CancelOrderPaymentRequest{OrderId: orderID})
+	assert.NoError(t, err)
+	assert.True(t, resp.Success)
+}
+
+func TestCreatePaymentVerifiesOrder(t *testing.T) {
+	orderClient := new(mockOrderClient)
+	paymentService := setupTestPaymentService(t, payment.WithOrderClient(orderClient))
+	ctx := context.Background()
+
+	orderClient.On("GetOrder", mock.Anything, mock.MatchedBy(func(req *orderpb.GetOrderRequest) bool {
+		return req.OrderId == 8
+	})).Return(&orderpb.GetOrderResponse{Success: true, Order: &orderpb.Order{
+		Id:         8,
+		Status:     orderpb.OrderStatus_PENDING,
+		TotalMoney: &orderpb.Money{Amount: 9900, Currency: "CNY"},
+	}}, nil)
+	orderClient.On("GetOrder", mock.Anything, mock.MatchedBy(func(req *orderpb.GetOrderRequest) bool {
+		return req.OrderId == 9
+	})).Return(&orderpb.GetOrderResponse{Success: true, Order: &orderpb.Order{
+		Id:         9,
+		Status:     orderpb.OrderStatus_CANCELLED,
+		TotalMoney: &orderpb.Money{Amount: 9900, Currency: "CNY"},
+	}}, nil)
+
+	// 金额与订单应付金额不一致
+	resp, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId:     8,
+		AmountMoney: &pb.Money{Amount: 100, Currency: "CNY"},
+		Method:      pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+	})
+	assert.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.Contains(t, resp.ErrorMessage, "does not match order total")
+
+	// 订单已取消
+	resp, err = paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId:     9,
+		AmountMoney: &pb.Money{Amount: 9900, Currency: "CNY"},
+		Method:      pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+	})
+	assert.NoError(t, err)
+	assert.False(t, resp.Success)
+
+	resp, err = paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId:     8,
+		AmountMoney: &pb.Money{Amount: 9900, Currency: "CNY"},
+		Method:      pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+	})
+	assert.NoError(t, err)
+	assert.True(t, resp.Success)
+}
@@ -0,0 +1,247 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/bytedance-youthcamp/demo/api/payment"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+var (
+	// ErrInvalidCallback 表示支付渠道回调的签名或内容无效
+	ErrInvalidCallback = errors.New("invalid provider callback")
+	// ErrCallbackExpired 表示回调时间超出了允许的时间窗口，可能是重放的旧回调
+	ErrCallbackExpired = errors.New("provider callback expired")
+	// ErrCallbackMismatch 表示回调的金额或订单与支付记录不一致
+	ErrCallbackMismatch = errors.New("provider callback does not match payment")
+	// ErrUnknownProvider 表示回调来自未配置的支付渠道
+	ErrUnknownProvider = errors.New("unknown payment provider")
+	// ErrChargeCompleted 表示收款在渠道已支付成功，不能再关闭
+	ErrChargeCompleted = errors.New("charge has already been paid")
+)
+
+// defaultCallbackReplayWindow 回调时间与当前时间允许的最大偏差
+const defaultCallbackReplayWindow = 5 * time.Minute
+
+// ChargeRequest 向支付渠道发起的收款
+type ChargeRequest struct {
+	PaymentID string
+	OrderID   int32
+	Amount    money.Money
+	Method    pb.PaymentMethod
+}
+
+// Charge 支付渠道创建的收款，用户通过 PaymentURL 完成支付
+type Charge struct {
+	PaymentURL    string
+	TransactionID string // 渠道交易号，部分渠道在支付完成后才返回
+}
+
+// ChargeStatus 支付渠道查询到的收款状态
+type ChargeStatus struct {
+	Status        pb.PaymentStatus
+	TransactionID string
+	Amount        money.Money
+}
+
+// RefundRequest 向支付渠道发起的退款
+type RefundRequest struct {
+	RefundID      string
+	PaymentID     string
+	TransactionID string // 原支付的渠道交易号
+	Amount        money.Money
+}
+
+// ProviderRefund 支付渠道受理的退款，退款结果通常通过回调异步通知
+type ProviderRefund struct {
+	Status        pb.RefundStatus
+	TransactionID string
+}
+
+// CallbackType 支付渠道回调的类型
+type CallbackType string
+
+const (
+	CallbackPayment CallbackType = "payment"
+	CallbackRefund  CallbackType = "refund"
+)
+
+// Callback 验证通过的支付渠道回调。支付回调时 ID 为支付单号，退款回调时为退款单号
+type Callback struct {
+	Type          CallbackType
+	ID            string
+	OrderID       int32 // 渠道未返回订单号时为 0
+	PaymentStatus pb.PaymentStatus
+	RefundStatus  pb.RefundStatus
+	TransactionID string
+	Amount        money.Money
+	Timestamp     time.Time // 渠道发出回调的时间，用于拒绝重放的旧回调
+}
+
+// PaymentProvider 支付渠道适配器，每种支付方式由一个渠道处理
+type PaymentProvider interface {
+	// Name 返回渠道名称，保存在支付记录上，用于把回调和退款路由到创建支付的渠道
+	Name() string
+	CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error)
+	QueryCharge(ctx context.Context, paymentID string) (*ChargeStatus, error)
+	// CancelCharge 关闭待支付的收款，之后用户无法再通过支付链接完成支付；
+	// 收款已支付成功时返回 ErrChargeCompleted，已关闭或已失败的收款视为关闭成功
+	CancelCharge(ctx context.Context, paymentID string) error
+	Refund(ctx context.Context, req RefundRequest) (*ProviderRefund, error)
+	// VerifyCallback 验证回调签名并解析回调内容，签名无效时返回 ErrInvalidCallback
+	VerifyCallback(ctx context.Context, payload []byte, signature string) (*Callback, error)
+}
+
+// CallbackFunc 处理支付渠道发出的回调
+type CallbackFunc func(ctx context.Context, payload []byte, signature string) error
+
+// callbackSource 由在进程内发出回调的渠道实现，如沙箱渠道
+type callbackSource interface {
+	OnCallback(fn CallbackFunc)
+}
+
+// WithProvider 指定处理某种支付方式的渠道。指定渠道后只支持指定了渠道的支付方式，
+// 未指定任何渠道时所有支付方式使用默认的沙箱渠道
+func WithProvider(method pb.PaymentMethod, provider PaymentProvider) Option {
+	return func(s *PaymentService) {
+		s.providers[method] = provider
+	}
+}
+
+// WithCallbackReplayWindow 设置回调时间与当前时间允许的最大偏差，超出的回调会被拒绝
+func WithCallbackReplayWindow(window time.Duration) Option {
+	return func(s *PaymentService) {
+		if window > 0 {
+			s.callbackReplayWindow = window
+		}
+	}
+}
+
+// registerProviders 未指定任何渠道时为所有支付方式使用同一个沙箱渠道，并接收进程内渠道的回调
+func (s *PaymentService) registerProviders() {
+	if len(s.providers) == 0 {
+		sandbox := NewSandboxProvider()
+		for method := range pb.PaymentMethod_name {
+			s.providers[pb.PaymentMethod(method)] = sandbox
+		}
+	}
+
+	for _, provider := range s.providers {
+		if _, ok := s.providersByName[provider.Name()]; ok {
+			continue
+		}
+		s.providersByName[provider.Name()] = provider
+
+		if source, ok := provider.(callbackSource); ok {
+			name := provider.Name()
+			source.OnCallback(func(ctx context.Context, payload []byte, signature string) error {
+				return s.HandleProviderCallback(ctx, name, payload, signature)
+			})
+		}
+	}
+}
+
+// paymentProvider 返回处理支付的渠道，优先使用创建支付的渠道
+func (s *PaymentService) paymentProvider(payment *Payment) (PaymentProvider, error) {
+	if provider, ok := s.providersByName[payment.Provider]; ok {
+		return provider, nil
+	}
+	if provider, ok := s.providers[payment.Method]; ok {
+		return provider, nil
+	}
+	return nil, fmt.Errorf("no payment provider for method %s", payment.Method)
+}
+
+// HandleProviderCallback 验证支付渠道回调的签名、时间窗口以及金额和订单是否与支付记录一致，
+// 然后更新支付或退款状态。时间窗口内的重放回调按重复回调处理，不会产生副作用
+func (s *PaymentService) HandleProviderCallback(ctx context.Context, providerName string, payload []byte, signature string) error {
+	provider, ok := s.providersByName[providerName]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownProvider, providerName)
+	}
+
+	callback, err := provider.VerifyCallback(ctx, payload, signature)
+	if err != nil {
+		return err
+	}
+
+	if callback.Timestamp.IsZero() {
+		return fmt.Errorf("%w: missing timestamp", ErrInvalidCallback)
+	}
+	if skew := time.Since(callback.Timestamp); skew > s.callbackReplayWindow || skew < -s.callbackReplayWindow {
+		return fmt.Errorf("%w: sent at %s", ErrCallbackExpired, callback.Timestamp.Format(time.RFC3339))
+	}
+
+	switch callback.Type {
+	case CallbackPayment:
+		var payment Payment
+		if err := s.db.Where("payment_id = ?", callback.ID).First(&payment).Error; err != nil {
+			return fmt.Errorf("payment not found: %w", err)
+		}
+		if err := checkCallbackProvider(&payment, providerName); err != nil {
+			return err
+		}
+		if err := checkCallback(callback, payment.OrderID, payment.Money()); err != nil {
+			return err
+		}
+
+		_, err = s.ProcessPaymentNotification(ctx, &pb.PaymentNotificationRequest{
+			PaymentId:     callback.ID,
+			OrderId:       payment.OrderID,
+			Status:        callback.PaymentStatus,
+			TransactionId: callback.TransactionID,
+		})
+	case CallbackRefund:
+		var refund Refund
+		if err := s.db.Where("refund_id = ?", callback.ID).First(&refund).Error; err != nil {
+			return fmt.Errorf("refund not found: %w", err)
+		}
+		var payment Payment
+		if err := s.db.Where("payment_id = ?", refund.PaymentID).First(&payment).Error; err != nil {
+			return fmt.Errorf("payment not found: %w", err)
+		}
+		if err := checkCallbackProvider(&payment, providerName); err != nil {
+			return err
+		}
+		if err := checkCallback(callback, refund.OrderID, refund.Money()); err != nil {
+			return err
+		}
+
+		_, err = s.ProcessRefundNotification(ctx, &pb.RefundNotificationRequest{
+			RefundId:      callback.ID,
+			Status:        callback.RefundStatus,
+			TransactionId: callback.TransactionID,
+		})
+	default:
+		return fmt.Errorf("%w: unknown callback type %q", ErrInvalidCallback, callback.Type)
+	}
+	return err
+}
+
+// checkCallbackProvider 检查回调来自创建支付的渠道。未记录渠道的旧支付不接受任何渠道的回调，
+// 只能由内部服务通过 ProcessPaymentNotification 处理
+func checkCallbackProvider(payment *Payment, providerName string) error {
+	if payment.Provider == "" {
+		return fmt.Errorf("%w: payment %s has no provider", ErrCallbackMismatch, payment.PaymentID)
+	}
+	if payment.Provider != providerName {
+		return fmt.Errorf("%w: payment %s was created with provider %s", ErrCallbackMismatch, payment.PaymentID, payment.Provider)
+	}
+	return nil
+}
+
+// checkCallback 检查回调的订单和金额与本地记录一致，渠道未返回订单号时只检查金额
+func checkCallback(callback *Callback, orderID int32, amount money.Money) error {
+	if callback.OrderID != 0 && callback.OrderID != orderID {
+		return fmt.Errorf("%w: %s %s belongs to order %d, callback says %d",
+			ErrCallbackMismatch, callback.Type, callback.ID, orderID, callback.OrderID)
+	}
+	if callback.Amount != amount {
+		return fmt.Errorf("%w: %s %s amount is %s, callback says %s",
+			ErrCallbackMismatch, callback.Type, callback.ID, amount, callback.Amount)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	pb "github.com/bytedance-youthcamp/demo/api/payment"
+)
+
+const (
+	// defaultPaymentTTL 待支付的支付超过该时间仍未支付时过期
+	defaultPaymentTTL = 30 * time.Minute
+	// defaultReconcileAfter 待支付超过该时间仍未收到回调的支付会向渠道查询
+	defaultReconcileAfter = time.Minute
+	// reconcileBatchSize 每轮对账处理的最大支付数
+	reconcileBatchSize = 100
+)
+
+// WithPaymentTTL 设置支付的有效期，超过有效期仍未支付的支付会过期
+func WithPaymentTTL(ttl time.Duration) Option {
+	return func(s *PaymentService) {
+		if ttl > 0 {
+			s.paymentTTL = ttl
+		}
+	}
+}
+
+// WithReconcileAfter 设置待支付多久后开始向渠道查询支付结果
+func WithReconcileAfter(d time.Duration) Option {
+	return func(s *PaymentService) {
+		if d > 0 {
+			s.reconcileAfter = d
+		}
+	}
+}
+
+// ReconcileResult 一轮对账的结果
+type ReconcileResult struct {
+	Checked int // 向渠道查询的支付数
+	Settled int // 渠道已有结果、按回调处理的支付数
+	Expired int // 过期的支付数
+}
+
+// ReconcilePendingPayments 处理长时间未收到回调的待支付支付：向渠道查询结果，渠道已有结果时按回调处理，
+// 渠道仍未完成且超过有效期时在渠道关闭收款并将支付标记为过期。单笔支付失败不影响其他支付，只记录日志。
+// 每轮从上一轮处理到的支付之后继续，一直无法处理的支付不会阻塞后面的支付
+func (s *PaymentService) ReconcilePendingPayments(ctx context.Context) (ReconcileResult, error) {
+	var result ReconcileResult
+
+	s.reconcileMu.Lock()
+	defer s.reconcileMu.Unlock()
+
+	var payments []Payment
+	err := s.db.Where("status = ? AND created_at < ? AND id > ?", pb.PaymentStatus_PAYMENT_STATUS_PENDING, time.Now().Add(-s.reconcileAfter), s.reconcileCursor).
+		Order("id").
+		Limit(reconcileBatchSize).
+		Find(&payments).Error
+	if err != nil {
+		return result, fmt.Errorf("failed to list pending payments: %w", err)
+	}
+	if len(payments) < reconcileBatchSize {
+		s.reconcileCursor = 0
+	} else {
+		s.reconcileCursor = payments[len(payments)-1].ID
+	}
+
+	for i := range payments {
+		payment := &payments[i]
+		result.Checked++
+
+		// 渠道查询失败时仍然按有效期处理，渠道中不存在的支付也会过期；渠道无法关闭收款时留到下一轮
+		if err := s.refreshPayment(ctx, payment); err != nil {
+			log.Printf("Failed to query payment %s from provider: %v", payment.PaymentID, err)
+		}
+		if payment.Status != pb.PaymentStatus_PAYMENT_STATUS_PENDING {
+			result.Settled++
+			continue
+		}
+
+		if time.Since(payment.CreatedAt) < s.paymentTTL {
+			continue
+		}
+		expired, err := s.expirePayment(ctx, payment)
+		if err != nil {
+			log.Printf("Failed to expire payment %s: %v", payment.PaymentID, err)
+			continue
+		}
+		if expired {
+			result.Expired++
+		}
+	}
+
+	return result, nil
+}
+
+// expirePayment 在渠道关闭收款后将待支付的支付标记为过期，用户无法再通过支付链接付款。
+// 渠道已收款时按回调处理支付结果，与支付状态已被并发修改时一样返回 false
+func (s *PaymentService) expirePayment(ctx context.Context, payment *Payment) (bool, error) {
+	if err := s.closeCharge(ctx, payment); err != nil {
+		if errors.Is(err, ErrChargeCompleted) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var expired bool
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Payment{}).
+			Where("id = ? AND status = ?", payment.ID, pb.PaymentStatus_PAYMENT_STATUS_PENDING).
+			Updates(map[string]interface{}{
+				"status":          pb.PaymentStatus_PAYMENT_STATUS_EXPIRED,
+				"active_order_id": nil,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update payment: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		expired = true
+		payment.Status = pb.PaymentStatus_PAYMENT_STATUS_EXPIRED
+		payment.ActiveOrderID = nil
+		return recordPaymentEvent(ctx, tx, payment)
+	})
+	return expired, err
+}
+
+// StartReconcileTask 定期对长时间未收到回调的支付对账
+func (s *PaymentService) StartReconcileTask(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			result, err := s.ReconcilePendingPayments(context.Background())
+			if err != nil {
+				log.Printf("Error reconciling payments: %v", err)
+				continue
+			}
+			if result.Settled > 0 || result.Expired > 0 {
+				log.Printf("Reconciled %d pending payments: %d settled, %d expired", result.Checked, result.Settled, result.Expired)
+			}
+		}
+	}()
+}
@@ -0,0 +1,180 @@
+package payment_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	orderpb "github.com/bytedance-youthcamp/demo/api/order"
+	pb "github.com/bytedance-youthcamp/demo/api/payment"
+	"github.com/bytedance-youthcamp/demo/internal/report"
+	"github.com/bytedance-youthcamp/demo/internal/service/payment"
+)
+
+func (m *mockOrderClient) SearchOrders(ctx context.Context, in *orderpb.SearchOrdersRequest, opts ...grpc.CallOption) (*orderpb.SearchOrdersResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*orderpb.SearchOrdersResponse), args.Error(1)
+}
+
+// reconcileAll 处理所有待对账的支付，测试数据库中可能留有其他测试的待支付支付
+func reconcileAll(t *testing.T, paymentService *payment.PaymentService) {
+	for {
+		result, err := paymentService.ReconcilePendingPayments(context.Background())
+		require.NoError(t, err)
+		if result.Checked < 100 {
+			return
+		}
+	}
+}
+
+func TestReconcilePendingPayments(t *testing.T) {
+	sandbox := payment.NewSandboxProvider()
+	paymentService := setupTestPaymentService(t,
+		payment.WithProvider(pb.PaymentMethod_PAYMENT_METHOD_ALIPAY, sandbox),
+		payment.WithReconcileAfter(time.Millisecond),
+		payment.WithPaymentTTL(200*time.Millisecond),
+	)
+	ctx := context.Background()
+
+	// 模拟丢失的回调：渠道已完成支付，但回调没有送达
+	sandbox.OnCallback(func(ctx context.Context, payload []byte, signature string) error {
+		return errors.New("callback lost")
+	})
+
+	baseOrderID := int32(time.Now().UnixNano()%1000000) + 2000000
+	paid, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId:     baseOrderID,
+		AmountMoney: &pb.Money{Amount: 3000, Currency: "CNY"},
+		Method:      pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+	})
+	require.NoError(t, err)
+	unpaid, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId:     baseOrderID + 1,
+		AmountMoney: &pb.Money{Amount: 3000, Currency: "CNY"},
+		Method:      pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+	})
+	require.NoError(t, err)
+
+	assert.Error(t, sandbox.Complete(ctx, paid.PaymentId, pb.PaymentStatus_PAYMENT_STATUS_SUCCESS))
+
+	// 未超过有效期时只处理渠道已有结果的支付
+	time.Sleep(10 * time.Millisecond)
+	reconcileAll(t, paymentService)
+
+	status := func(paymentID string) pb.PaymentStatus {
+		resp, err := paymentService.QueryPayment(ctx, &pb.QueryPaymentRequest{PaymentId: paymentID})
+		require.NoError(t, err)
+		return resp.Status
+	}
+	assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_SUCCESS, status(paid.PaymentId))
+	assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_PENDING, status(unpaid.PaymentId))
+
+	// 超过有效期后支付过期，之后到达的回调被拒绝，订单可以重新发起支付
+	time.Sleep(250 * time.Millisecond)
+	reconcileAll(t, paymentService)
+	assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_EXPIRED, status(unpaid.PaymentId))
+	// 渠道中的收款已关闭，用户无法再通过旧的支付链接付款
+	assert.Error(t, sandbox.Complete(ctx, unpaid.PaymentId, pb.PaymentStatus_PAYMENT_STATUS_SUCCESS))
+
+	_, err = paymentService.ProcessPaymentNotification(ctx, &pb.PaymentNotificationRequest{
+		PaymentId: unpaid.PaymentId,
+		Status:    pb.PaymentStatus_PAYMENT_STATUS_SUCCESS,
+	})
+	assert.Error(t, err)
+
+	retried, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+		OrderId:     baseOrderID + 1,
+		AmountMoney: &pb.Money{Amount: 3000, Currency: "CNY"},
+		Method:      pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, unpaid.PaymentId, retried.PaymentId)
+}
+
+func TestReconciliationReport(t *testing.T) {
+	orderClient := new(mockOrderClient)
+	paymentService := setupTestPaymentService(t, payment.WithOrderClient(orderClient))
+	ctx := context.Background()
+
+	baseOrderID := int32(time.Now().UnixNano()%1000000) + 3000000
+	cancelledOrder, modifiedOrder, unpaidOrder := baseOrderID, baseOrderID+1, baseOrderID+2
+
+	order := func(id int32, status orderpb.OrderStatus, amount int64) *orderpb.GetOrderResponse {
+		return &orderpb.GetOrderResponse{Success: true, Order: &orderpb.Order{
+			Id:         id,
+			Status:     status,
+			TotalMoney: &orderpb.Money{Amount: amount, Currency: "CNY"},
+		}}
+	}
+	orderID := func(id int32) interface{} {
+		return mock.MatchedBy(func(req *orderpb.GetOrderRequest) bool { return req.OrderId == id })
+	}
+
+	// 创建支付时订单待支付，对账时一个订单已取消，另一个订单的金额已修改
+	orderClient.On("GetOrder", mock.Anything, orderID(cancelledOrder)).Return(order(cancelledOrder, orderpb.OrderStatus_PENDING, 1000), nil).Once()
+	orderClient.On("GetOrder", mock.Anything, orderID(modifiedOrder)).Return(order(modifiedOrder, orderpb.OrderStatus_PENDING, 1000), nil).Once()
+	orderClient.On("GetOrder", mock.Anything, orderID(cancelledOrder)).Return(order(cancelledOrder, orderpb.OrderStatus_CANCELLED, 1000), nil)
+	orderClient.On("GetOrder", mock.Anything, orderID(modifiedOrder)).Return(order(modifiedOrder, orderpb.OrderStatus_PAID, 1200), nil)
+	// 测试数据库中其他测试的支付
+	orderClient.On("GetOrder", mock.Anything, mock.Anything).Return(&orderpb.GetOrderResponse{Success: false, ErrorMessage: "order not found"}, nil)
+	orderClient.On("SettleOrder", mock.Anything, mock.Anything).Return(&orderpb.SettleOrderResponse{Success: true}, nil)
+	orderClient.On("SearchOrders", mock.Anything, mock.Anything).Return(&orderpb.SearchOrdersResponse{
+		Success: true,
+		Orders: []*orderpb.Order{
+			order(modifiedOrder, orderpb.OrderStatus_PAID, 1200).Order,
+			order(unpaidOrder, orderpb.OrderStatus_PAID, 800).Order,
+		},
+	}, nil)
+
+	payments := make(map[int32]string)
+	for _, id := range []int32{cancelledOrder, modifiedOrder} {
+		createResp, err := paymentService.CreatePayment(ctx, &pb.CreatePaymentRequest{
+			OrderId:     id,
+			AmountMoney: &pb.Money{Amount: 1000, Currency: "CNY"},
+			Method:      pb.PaymentMethod_PAYMENT_METHOD_ALIPAY,
+		})
+		require.NoError(t, err)
+		require.True(t, createResp.Success)
+		_, err = paymentService.ProcessPaymentNotification(ctx, &pb.PaymentNotificationRequest{
+			PaymentId: createResp.PaymentId,
+			Status:    pb.PaymentStatus_PAYMENT_STATUS_SUCCESS,
+		})
+		require.NoError(t, err)
+		payments[id] = createResp.PaymentId
+	}
+
+	now := time.Now()
+	result, err := paymentService.Reconcile(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+
+	issues := make(map[int32]payment.ReconciliationIssueKind)
+	for _, issue := range result.Issues {
+		if issue.OrderID >= baseOrderID && issue.OrderID <= unpaidOrder {
+			issues[issue.OrderID] = issue.Kind
+		}
+	}
+	assert.Equal(t, map[int32]payment.ReconciliationIssueKind{
+		cancelledOrder: payment.IssuePaidOrderCancelled,
+		modifiedOrder:  payment.IssueAmountMismatch,
+		unpaidOrder:    payment.IssueOrderPaidWithoutPayment,
+	}, issues)
+
+	// 报表写入按日期命名的文件
+	dir := t.TempDir()
+	path, _, err := paymentService.WriteReconciliationReport(ctx, now, dir, report.FormatCSV)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "reconciliation-"+now.Format("2006-01-02")+".csv"), path)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), payments[cancelledOrder])
+	assert.Contains(t, string(content), strconv.Itoa(int(unpaidOrder)))
+}
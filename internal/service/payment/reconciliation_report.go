@@ -0,0 +1,287 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	orderpb "github.com/bytedance-youthcamp/demo/api/order"
+	pb "github.com/bytedance-youthcamp/demo/api/payment"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/bytedance-youthcamp/demo/internal/report"
+)
+
+// ReconciliationIssueKind 对账差异的类型
+type ReconciliationIssueKind string
+
+const (
+	// IssuePaidOrderCancelled 支付成功但订单已取消，需要退款
+	IssuePaidOrderCancelled ReconciliationIssueKind = "paid_but_order_cancelled"
+	// IssueOrderPaidWithoutPayment 订单已支付但没有支付成功的支付
+	IssueOrderPaidWithoutPayment ReconciliationIssueKind = "order_paid_without_payment"
+	// IssueAmountMismatch 支付金额与订单应付金额不一致
+	IssueAmountMismatch ReconciliationIssueKind = "amount_mismatch"
+	// IssueDuplicatePayment 同一订单有多笔支付成功的支付
+	IssueDuplicatePayment ReconciliationIssueKind = "duplicate_payment"
+	// IssueOrderNotFound 支付对应的订单不存在
+	IssueOrderNotFound ReconciliationIssueKind = "order_not_found"
+)
+
+// paidOrderStatuses 已收款的订单状态
+var paidOrderStatuses = []orderpb.OrderStatus{
+	orderpb.OrderStatus_PAID,
+	orderpb.OrderStatus_SHIPPING,
+	orderpb.OrderStatus_COMPLETED,
+	orderpb.OrderStatus_REFUNDING,
+	orderpb.OrderStatus_REFUNDED,
+}
+
+// paidPaymentStatuses 已收款的支付状态，包括之后退款的支付
+var paidPaymentStatuses = []pb.PaymentStatus{
+	pb.PaymentStatus_PAYMENT_STATUS_SUCCESS,
+	pb.PaymentStatus_PAYMENT_STATUS_REFUNDED,
+}
+
+// ReconciliationIssue 对账报表中的一条差异
+type ReconciliationIssue struct {
+	Kind          ReconciliationIssueKind
+	OrderID       int32
+	PaymentID     string
+	OrderStatus   string
+	PaymentStatus string
+	OrderAmount   money.Money
+	PaymentAmount money.Money
+}
+
+func (i ReconciliationIssue) Fields() []report.Field {
+	currency := i.PaymentAmount.Currency
+	if currency == "" {
+		currency = i.OrderAmount.Currency
+	}
+	return []report.Field{
+		{Name: "kind", Value: string(i.Kind)},
+		{Name: "order_id", Value: i.OrderID},
+		{Name: "payment_id", Value: i.PaymentID},
+		{Name: "order_status", Value: i.OrderStatus},
+		{Name: "payment_status", Value: i.PaymentStatus},
+		{Name: "currency", Value: currency},
+		{Name: "order_amount", Value: formatAmount(i.OrderAmount)},
+		{Name: "payment_amount", Value: formatAmount(i.PaymentAmount)},
+	}
+}
+
+// ReconciliationReport 一段时间内支付与订单的对账结果
+type ReconciliationReport struct {
+	From     time.Time
+	To       time.Time
+	Payments int // 核对的已收款支付数
+	Orders   int // 核对的已支付订单数
+	Issues   []ReconciliationIssue
+}
+
+// Reconcile 核对 [from, to) 内创建的已收款支付和已支付订单：
+// 支付成功但订单已取消、订单已支付但没有支付成功的支付、金额不一致、同一订单重复收款
+func (s *PaymentService) Reconcile(ctx context.Context, from, to time.Time) (*ReconciliationReport, error) {
+	if s.orderClient == nil {
+		return nil, fmt.Errorf("order client is not configured")
+	}
+
+	result := &ReconciliationReport{From: from, To: to}
+
+	var payments []Payment
+	if err := s.db.Where("status IN ? AND created_at >= ? AND created_at < ?", paidPaymentStatuses, from, to).
+		Order("id").
+		Find(&payments).Error; err != nil {
+		return nil, fmt.Errorf("failed to list payments: %w", err)
+	}
+	result.Payments = len(payments)
+
+	// 按订单核对支付，同一订单的第一笔之后的支付为重复收款
+	checked := make(map[int32]bool)
+	for i := range payments {
+		payment := &payments[i]
+		issue := ReconciliationIssue{
+			OrderID:       payment.OrderID,
+			PaymentID:     payment.PaymentID,
+			PaymentStatus: payment.Status.String(),
+			PaymentAmount: payment.Money(),
+		}
+		if checked[payment.OrderID] {
+			issue.Kind = IssueDuplicatePayment
+			result.Issues = append(result.Issues, issue)
+			continue
+		}
+		checked[payment.OrderID] = true
+
+		orderResp, err := s.orderClient.GetOrder(ctx, &orderpb.GetOrderRequest{OrderId: payment.OrderID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get order %d: %w", payment.OrderID, err)
+		}
+		if !orderResp.Success {
+			issue.Kind = IssueOrderNotFound
+			result.Issues = append(result.Issues, issue)
+			continue
+		}
+
+		issue.OrderStatus = orderResp.Order.Status.String()
+		issue.OrderAmount = orderTotal(orderResp.Order)
+		if orderResp.Order.Status == orderpb.OrderStatus_CANCELLED {
+			issue.Kind = IssuePaidOrderCancelled
+			result.Issues = append(result.Issues, issue)
+		}
+		if issue.OrderAmount != issue.PaymentAmount {
+			issue.Kind = IssueAmountMismatch
+			result.Issues = append(result.Issues, issue)
+		}
+	}
+
+	// 核对同一时间范围内创建的已支付订单，支付可能在时间范围之外创建，因此按订单号查询所有支付
+	orders, err := s.paidOrders(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	result.Orders = len(orders)
+
+	var unmatched []int32
+	for _, order := range orders {
+		if !checked[order.Id] {
+			unmatched = append(unmatched, order.Id)
+		}
+	}
+	paid := make(map[int32]bool)
+	if len(unmatched) > 0 {
+		var orderIDs []int32
+		if err := s.db.Model(&Payment{}).
+			Where("order_id IN ? AND status IN ?", unmatched, paidPaymentStatuses).
+			Distinct().Pluck("order_id", &orderIDs).Error; err != nil {
+			return nil, fmt.Errorf("failed to find payments for orders: %w", err)
+		}
+		for _, id := range orderIDs {
+			paid[id] = true
+		}
+	}
+	for _, order := range orders {
+		if checked[order.Id] || paid[order.Id] {
+			continue
+		}
+		result.Issues = append(result.Issues, ReconciliationIssue{
+			Kind:        IssueOrderPaidWithoutPayment,
+			OrderID:     order.Id,
+			OrderStatus: order.Status.String(),
+			OrderAmount: orderTotal(order),
+		})
+	}
+
+	sort.SliceStable(result.Issues, func(i, j int) bool {
+		if result.Issues[i].Kind != result.Issues[j].Kind {
+			return result.Issues[i].Kind < result.Issues[j].Kind
+		}
+		return result.Issues[i].OrderID < result.Issues[j].OrderID
+	})
+	return result, nil
+}
+
+// paidOrders 分页查询 [from, to) 内创建的已支付订单
+func (s *PaymentService) paidOrders(ctx context.Context, from, to time.Time) ([]*orderpb.Order, error) {
+	var orders []*orderpb.Order
+	cursor := ""
+	for {
+		resp, err := s.orderClient.SearchOrders(ctx, &orderpb.SearchOrdersRequest{
+			Statuses:    paidOrderStatuses,
+			CreatedFrom: from.UnixMilli(),
+			CreatedTo:   to.UnixMilli(),
+			Ascending:   true,
+			PageSize:    100,
+			Cursor:      cursor,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search orders: %w", err)
+		}
+		if !resp.Success {
+			return nil, fmt.Errorf("failed to search orders: %s", resp.ErrorMessage)
+		}
+		orders = append(orders, resp.Orders...)
+		if resp.NextCursor == "" {
+			return orders, nil
+		}
+		cursor = resp.NextCursor
+	}
+}
+
+// WriteReconciliationReport 生成 day 所在自然日的对账报表，写入 dir 下的 reconciliation-YYYY-MM-DD 文件，返回文件路径
+func (s *PaymentService) WriteReconciliationReport(ctx context.Context, day time.Time, dir string, format report.Format) (string, *ReconciliationReport, error) {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	result, err := s.Reconcile(ctx, from, from.AddDate(0, 0, 1))
+	if err != nil {
+		return "", nil, err
+	}
+
+	path := reconciliationReportPath(dir, from, format)
+	// 先写入临时文件，避免中断时留下不完整的报表
+	tmp, err := os.CreateTemp(dir, ".reconciliation-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w := report.NewWriter(tmp, format)
+	for _, issue := range result.Issues {
+		if err := w.Write(issue); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return "", nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", nil, fmt.Errorf("failed to write report file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", nil, fmt.Errorf("failed to save report file: %w", err)
+	}
+	return path, result, nil
+}
+
+// StartDailyReconciliationTask 每小时检查前一天的对账报表是否已生成，未生成时生成报表
+func (s *PaymentService) StartDailyReconciliationTask(dir string, format report.Format) {
+	run := func() {
+		now := time.Now()
+		yesterday := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, now.Location())
+		if _, err := os.Stat(reconciliationReportPath(dir, yesterday, format)); err == nil {
+			return
+		}
+
+		path, result, err := s.WriteReconciliationReport(context.Background(), yesterday, dir, format)
+		if err != nil {
+			log.Printf("Error generating reconciliation report for %s: %v", yesterday.Format("2006-01-02"), err)
+			return
+		}
+		log.Printf("Reconciliation report %s: %d payments, %d orders, %d issues",
+			path, result.Payments, result.Orders, len(result.Issues))
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	go func() {
+		run()
+		for range ticker.C {
+			run()
+		}
+	}()
+}
+
+func reconciliationReportPath(dir string, day time.Time, format report.Format) string {
+	return filepath.Join(dir, fmt.Sprintf("reconciliation-%s.%s", day.Format("2006-01-02"), format))
+}
+
+// formatAmount 返回金额的十进制表示，没有对应的订单或支付时返回空字符串
+func formatAmount(m money.Money) string {
+	if m.Currency == "" {
+		return ""
+	}
+	return m.Decimal()
+}
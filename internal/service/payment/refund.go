@@ -0,0 +1,527 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	orderpb "github.com/bytedance-youthcamp/demo/api/order"
+	pb "github.com/bytedance-youthcamp/demo/api/payment"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+// errRefundUpdatedConcurrently 表示退款状态已被并发的回调修改
+var errRefundUpdatedConcurrently = errors.New("refund was updated concurrently")
+
+type Refund struct {
+	gorm.Model
+	RefundID      string          `gorm:"uniqueIndex:idx_refund_id,length:36"`
+	PaymentID     string          `gorm:"index;not null"`
+	OrderID       int32           `gorm:"not null"`
+	AmountMinor   int64           `gorm:"not null;default:0"`
+	Currency      string          `gorm:"size:3;not null;default:CNY"`
+	Status        pb.RefundStatus `gorm:"not null"`
+	Reason        string
+	TransactionID string       `gorm:"default:null"`
+	FullyRefunded bool         `gorm:"not null;default:false"`
+	Items         []RefundItem `gorm:"foreignKey:RefundID;references:RefundID"`
+	// 订单服务是否已确认退款结果
+	OrderSynced       bool `gorm:"not null;default:false"`
+	OrderSyncAttempts int  `gorm:"not null;default:0"`
+}
+
+// Money 返回退款的精确金额
+func (r *Refund) Money() money.Money {
+	return money.New(r.AmountMinor, r.Currency)
+}
+
+type RefundItem struct {
+	ID          uint   `gorm:"primarykey"`
+	RefundID    string `gorm:"index;not null"`
+	ProductID   int32  `gorm:"not null"`
+	Quantity    int32  `gorm:"not null"`
+	AmountMinor int64  `gorm:"not null;default:0"`
+}
+
+// RequestRefund 为已支付的订单发起全额或按商品的部分退款，
+// 同一笔支付同时只能有一个处理中的退款；指定的退款单号已存在时返回已有的退款。
+// 检查和创建退款时锁定支付单，锁定期间会请求订单服务将订单标记为退款中
+func (s *PaymentService) RequestRefund(ctx context.Context, req *pb.RequestRefundRequest) (*pb.RequestRefundResponse, error) {
+	if req.RefundId != "" {
+		if resp, err := existingRefund(s.db, req); resp != nil || err != nil {
+			return resp, err
+		}
+	}
+
+	// 查找支付记录，未指定支付单时按订单查找支付成功的支付单
+	var payment Payment
+	query := s.db.Where("payment_id = ?", req.PaymentId)
+	if req.PaymentId == "" {
+		query = s.db.Where("order_id = ? AND status IN ?", req.OrderId, []pb.PaymentStatus{
+			pb.PaymentStatus_PAYMENT_STATUS_SUCCESS,
+			pb.PaymentStatus_PAYMENT_STATUS_REFUNDED,
+		}).Order("id DESC")
+	}
+	if err := query.First(&payment).Error; err != nil {
+		return nil, fmt.Errorf("payment not found: %w", err)
+	}
+
+	// 锁定支付单后检查并创建退款，同一笔支付的退款请求依次执行，不会同时创建两个处理中的退款
+	var resp *pb.RequestRefundResponse
+	var refund *Refund
+	started := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, payment.ID).Error; err != nil {
+			return fmt.Errorf("failed to lock payment: %w", err)
+		}
+
+		// 相同单号的并发请求可能已经创建了退款
+		if req.RefundId != "" {
+			var err error
+			if resp, err = existingRefund(tx, req); resp != nil || err != nil {
+				return err
+			}
+		}
+
+		switch payment.Status {
+		case pb.PaymentStatus_PAYMENT_STATUS_SUCCESS:
+		case pb.PaymentStatus_PAYMENT_STATUS_REFUNDED:
+			resp = &pb.RequestRefundResponse{
+				Success:      false,
+				ErrorMessage: "payment has been fully refunded",
+			}
+			return nil
+		default:
+			resp = &pb.RequestRefundResponse{
+				Success:      false,
+				ErrorMessage: "payment is not refundable",
+			}
+			return nil
+		}
+
+		// 检查是否有处理中的退款
+		inProgress := tx.Model(&Refund{}).Where("payment_id = ? AND status = ?", payment.PaymentID, pb.RefundStatus_REFUND_STATUS_PENDING)
+		if s.orderClient != nil {
+			inProgress = inProgress.Or("payment_id = ? AND order_synced = ?", payment.PaymentID, false)
+		}
+		var count int64
+		if err := inProgress.Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check refunds: %w", err)
+		}
+		if count > 0 {
+			resp = &pb.RequestRefundResponse{
+				Success:      false,
+				ErrorMessage: "another refund is in progress",
+			}
+			return nil
+		}
+
+		var errorMessage string
+		var err error
+		refund, errorMessage, err = s.buildRefund(ctx, tx, &payment, req)
+		if err != nil {
+			return err
+		}
+		if errorMessage != "" {
+			resp = &pb.RequestRefundResponse{
+				Success:      false,
+				ErrorMessage: errorMessage,
+			}
+			return nil
+		}
+
+		// 通知订单进入退款中
+		if s.orderClient != nil {
+			var startResp *orderpb.StartRefundResponse
+			err := s.retry(ctx, func() error {
+				var err error
+				startResp, err = s.orderClient.StartRefund(ctx, &orderpb.StartRefundRequest{
+					OrderId:  payment.OrderID,
+					RefundId: refund.RefundID,
+				})
+				return err
+			}, "start refund %s for order %d", refund.RefundID, payment.OrderID)
+			if err != nil {
+				return fmt.Errorf("failed to start order refund: %w", err)
+			}
+			if !startResp.Success {
+				resp = &pb.RequestRefundResponse{
+					Success:      false,
+					ErrorMessage: fmt.Sprintf("order cannot be refunded: %s", startResp.ErrorMessage),
+				}
+				return nil
+			}
+			started = true
+		}
+
+		// 保存退款记录
+		if err := tx.Create(refund).Error; err != nil {
+			return fmt.Errorf("failed to create refund: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// 退款记录保存失败时将订单恢复为已支付
+		if started {
+			if _, rollbackErr := s.orderClient.CompleteRefund(ctx, &orderpb.CompleteRefundRequest{
+				OrderId:  payment.OrderID,
+				RefundId: refund.RefundID,
+				Success:  false,
+			}); rollbackErr != nil {
+				log.Printf("Failed to revert order %d after refund creation failure: %v", payment.OrderID, rollbackErr)
+			}
+		}
+		return nil, err
+	}
+	if resp != nil {
+		return resp, nil
+	}
+
+	// 向渠道发起退款，退款结果通过回调通知；渠道未受理时退款失败，订单恢复为已支付
+	provider, err := s.paymentProvider(&payment)
+	if err == nil {
+		_, err = provider.Refund(ctx, RefundRequest{
+			RefundID:      refund.RefundID,
+			PaymentID:     payment.PaymentID,
+			TransactionID: payment.TransactionID,
+			Amount:        refund.Money(),
+		})
+	}
+	if err != nil {
+		if _, failErr := s.ProcessRefundNotification(ctx, &pb.RefundNotificationRequest{
+			RefundId: refund.RefundID,
+			Status:   pb.RefundStatus_REFUND_STATUS_FAILED,
+		}); failErr != nil {
+			log.Printf("Failed to mark refund %s as failed: %v", refund.RefundID, failErr)
+		}
+		return &pb.RequestRefundResponse{
+			Success:      false,
+			ErrorMessage: fmt.Sprintf("payment provider rejected refund: %v", err),
+		}, nil
+	}
+
+	return &pb.RequestRefundResponse{
+		RefundId:    refund.RefundID,
+		Amount:      refund.Money().Float64(),
+		AmountMoney: toAPIMoney(refund.Money()),
+		Status:      refund.Status,
+		Success:     true,
+	}, nil
+}
+
+// existingRefund 返回请求指定的退款单号已有的退款，退款不存在时返回 nil；
+// 单号已用于其他订单或支付时返回错误信息
+func existingRefund(db *gorm.DB, req *pb.RequestRefundRequest) (*pb.RequestRefundResponse, error) {
+	var refund Refund
+	err := db.Where("refund_id = ?", req.RefundId).First(&refund).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find refund: %w", err)
+	}
+
+	if (req.PaymentId != "" && refund.PaymentID != req.PaymentId) || (req.PaymentId == "" && refund.OrderID != req.OrderId) {
+		return &pb.RequestRefundResponse{
+			Success:      false,
+			ErrorMessage: "refund id was already used for a different payment",
+		}, nil
+	}
+	return &pb.RequestRefundResponse{
+		RefundId:    refund.RefundID,
+		Amount:      refund.Money().Float64(),
+		AmountMoney: toAPIMoney(refund.Money()),
+		Status:      refund.Status,
+		Success:     true,
+	}, nil
+}
+
+// buildRefund 计算退款金额和退款商品，校验不超过剩余可退数量和金额
+func (s *PaymentService) buildRefund(ctx context.Context, db *gorm.DB, payment *Payment, req *pb.RequestRefundRequest) (*Refund, string, error) {
+	// 统计已成功退款的金额和商品数量
+	var refunds []Refund
+	if err := db.Preload("Items").
+		Where("payment_id = ? AND status = ?", payment.PaymentID, pb.RefundStatus_REFUND_STATUS_SUCCESS).
+		Find(&refunds).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to list refunds: %w", err)
+	}
+
+	var refundedAmount int64
+	refundedQuantities := make(map[int32]int32)
+	for _, r := range refunds {
+		refundedAmount += r.AmountMinor
+		for _, item := range r.Items {
+			refundedQuantities[item.ProductID] += item.Quantity
+		}
+	}
+	remaining := payment.AmountMinor - refundedAmount
+	if remaining <= 0 {
+		return nil, "payment has been fully refunded", nil
+	}
+
+	// 获取订单商品用于计算退款金额
+	var orderItems []*orderpb.OrderItem
+	if s.orderClient != nil {
+		orderResp, err := s.orderClient.GetOrder(ctx, &orderpb.GetOrderRequest{OrderId: payment.OrderID})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get order: %w", err)
+		}
+		if !orderResp.Success {
+			return nil, fmt.Sprintf("failed to get order: %s", orderResp.ErrorMessage), nil
+		}
+		orderItems = orderResp.Order.Items
+	}
+
+	refundID := req.RefundId
+	if refundID == "" {
+		refundID = uuid.New().String()
+	}
+	refund := &Refund{
+		RefundID:  refundID,
+		PaymentID: payment.PaymentID,
+		OrderID:   payment.OrderID,
+		Currency:  payment.Money().Currency,
+		Status:    pb.RefundStatus_REFUND_STATUS_PENDING,
+		Reason:    req.Reason,
+	}
+
+	// 全额退款：退还剩余金额和全部未退商品
+	if len(req.Items) == 0 {
+		refund.AmountMinor = remaining
+		for _, item := range orderItems {
+			quantity := item.Quantity - refundedQuantities[item.ProductId]
+			if quantity > 0 {
+				refund.Items = append(refund.Items, RefundItem{
+					RefundID:    refund.RefundID,
+					ProductID:   item.ProductId,
+					Quantity:    quantity,
+					AmountMinor: orderItemPrice(item, refund.Currency).Mul(int64(quantity)).Amount - orderItemDiscount(item, refundedQuantities[item.ProductId], quantity),
+				})
+			}
+		}
+		return refund, "", nil
+	}
+
+	// 部分退款：按订单中的商品单价计算
+	if orderItems == nil {
+		return nil, "partial refund requires order information", nil
+	}
+	ordered := make(map[int32]*orderpb.OrderItem)
+	for _, item := range orderItems {
+		ordered[item.ProductId] = item
+	}
+
+	requested := make(map[int32]int32)
+	var amount int64
+	for _, item := range req.Items {
+		orderItem, ok := ordered[item.ProductId]
+		if !ok {
+			return nil, fmt.Sprintf("product %d is not in the order", item.ProductId), nil
+		}
+		if item.Quantity <= 0 {
+			return nil, fmt.Sprintf("invalid refund quantity for product %d", item.ProductId), nil
+		}
+		previous := refundedQuantities[item.ProductId] + requested[item.ProductId]
+		requested[item.ProductId] += item.Quantity
+		if requested[item.ProductId]+refundedQuantities[item.ProductId] > orderItem.Quantity {
+			return nil, fmt.Sprintf("refund quantity exceeds purchased quantity for product %d", item.ProductId), nil
+		}
+
+		price := orderItemPrice(orderItem, refund.Currency)
+		if price.Currency != refund.Currency {
+			return nil, fmt.Sprintf("product %d currency does not match payment", item.ProductId), nil
+		}
+		// 退款金额扣除退款商品分摊到的优惠
+		itemAmount := price.Mul(int64(item.Quantity)).Amount - orderItemDiscount(orderItem, previous, item.Quantity)
+		amount += itemAmount
+		refund.Items = append(refund.Items, RefundItem{
+			RefundID:    refund.RefundID,
+			ProductID:   item.ProductId,
+			Quantity:    item.Quantity,
+			AmountMinor: itemAmount,
+		})
+	}
+
+	refund.AmountMinor = min(amount, remaining)
+	return refund, "", nil
+}
+
+// ProcessRefundNotification 处理退款结果回调，重复回调不会产生副作用
+func (s *PaymentService) ProcessRefundNotification(ctx context.Context, req *pb.RefundNotificationRequest) (*pb.RefundNotificationResponse, error) {
+	// 查找退款记录
+	var refund Refund
+	if err := s.db.Preload("Items").Where("refund_id = ?", req.RefundId).First(&refund).Error; err != nil {
+		return nil, fmt.Errorf("refund not found: %w", err)
+	}
+
+	// 重复回调：只需确保订单状态已同步
+	if refund.Status == req.Status && req.Status != pb.RefundStatus_REFUND_STATUS_PENDING {
+		if err := s.syncRefund(ctx, &refund); err != nil {
+			return nil, err
+		}
+		return &pb.RefundNotificationResponse{Success: true}, nil
+	}
+
+	// 验证状态转换规则
+	switch refund.Status {
+	case pb.RefundStatus_REFUND_STATUS_PENDING:
+		if req.Status != pb.RefundStatus_REFUND_STATUS_SUCCESS &&
+			req.Status != pb.RefundStatus_REFUND_STATUS_FAILED {
+			return nil, fmt.Errorf("invalid refund status transition from PENDING")
+		}
+	case pb.RefundStatus_REFUND_STATUS_SUCCESS:
+		return nil, fmt.Errorf("cannot change refund status from SUCCESS")
+	case pb.RefundStatus_REFUND_STATUS_FAILED:
+		return nil, fmt.Errorf("cannot change refund status from FAILED")
+	default:
+		return nil, fmt.Errorf("invalid current refund status")
+	}
+
+	// 在一个事务中更新退款状态并记退款分录，退款成功且累计金额覆盖支付金额时将支付标记为已退款
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"status":         req.Status,
+			"transaction_id": req.TransactionId,
+		}
+
+		fullyRefunded := false
+		var payment Payment
+		if req.Status == pb.RefundStatus_REFUND_STATUS_SUCCESS {
+			if err := tx.Where("payment_id = ?", refund.PaymentID).First(&payment).Error; err != nil {
+				return fmt.Errorf("payment not found: %w", err)
+			}
+
+			var refundedAmount int64
+			if err := tx.Model(&Refund{}).
+				Where("payment_id = ? AND status = ?", refund.PaymentID, pb.RefundStatus_REFUND_STATUS_SUCCESS).
+				Select("COALESCE(SUM(amount_minor), 0)").Scan(&refundedAmount).Error; err != nil {
+				return fmt.Errorf("failed to sum refunds: %w", err)
+			}
+
+			if refundedAmount+refund.AmountMinor >= payment.AmountMinor {
+				fullyRefunded = true
+				updates["fully_refunded"] = true
+				if err := tx.Model(&Payment{}).
+					Where("id = ? AND status = ?", payment.ID, pb.PaymentStatus_PAYMENT_STATUS_SUCCESS).
+					Update("status", pb.PaymentStatus_PAYMENT_STATUS_REFUNDED).Error; err != nil {
+					return fmt.Errorf("failed to update payment: %w", err)
+				}
+			}
+		}
+
+		// 条件更新，并发的重复回调只有一个能更新成功
+		result := tx.Model(&Refund{}).
+			Where("id = ? AND status = ?", refund.ID, pb.RefundStatus_REFUND_STATUS_PENDING).
+			Updates(updates)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update refund: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errRefundUpdatedConcurrently
+		}
+
+		refund.Status = req.Status
+		refund.TransactionID = req.TransactionId
+		refund.FullyRefunded = fullyRefunded
+		if refund.Status == pb.RefundStatus_REFUND_STATUS_SUCCESS {
+			if err := postRefund(ctx, tx, &refund, &payment); err != nil {
+				return err
+			}
+			return recordRefundEvent(ctx, tx, &refund)
+		}
+		return nil
+	})
+	if errors.Is(err, errRefundUpdatedConcurrently) {
+		// 退款状态已被并发请求修改，重新读取后按重复回调处理
+		if err := s.db.Preload("Items").First(&refund, refund.ID).Error; err != nil {
+			return nil, fmt.Errorf("failed to reload refund: %w", err)
+		}
+		if refund.Status != req.Status {
+			return nil, fmt.Errorf("refund status changed concurrently to %s", refund.Status)
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	// 将退款结果同步到订单
+	if err := s.syncRefund(ctx, &refund); err != nil {
+		return nil, err
+	}
+
+	return &pb.RefundNotificationResponse{Success: true}, nil
+}
+
+// syncRefund 将退款结果同步到订单服务，退款成功时由订单服务恢复库存
+func (s *PaymentService) syncRefund(ctx context.Context, refund *Refund) error {
+	if s.orderClient == nil || refund.OrderSynced {
+		return nil
+	}
+
+	items := make([]*orderpb.OrderItem, len(refund.Items))
+	for i, item := range refund.Items {
+		items[i] = &orderpb.OrderItem{
+			ProductId: item.ProductID,
+			Quantity:  item.Quantity,
+		}
+	}
+
+	success := refund.Status == pb.RefundStatus_REFUND_STATUS_SUCCESS
+	err := s.retry(ctx, func() error {
+		completeResp, err := s.orderClient.CompleteRefund(ctx, &orderpb.CompleteRefundRequest{
+			OrderId:       refund.OrderID,
+			RefundId:      refund.RefundID,
+			Success:       success,
+			FullyRefunded: refund.FullyRefunded,
+			Items:         items,
+		})
+		if err != nil {
+			return err
+		}
+		if !completeResp.Success {
+			return fmt.Errorf("order service rejected refund result: %s", completeResp.ErrorMessage)
+		}
+		return nil
+	}, "sync refund %s to order %d", refund.RefundID, refund.OrderID)
+
+	// 记录同步结果，未同步的退款由后台任务继续重试
+	updates := map[string]interface{}{
+		"order_sync_attempts": gorm.Expr("order_sync_attempts + ?", 1),
+	}
+	if err == nil {
+		updates["order_synced"] = true
+	}
+	if dbErr := s.db.Model(&Refund{}).Where("id = ?", refund.ID).Updates(updates).Error; dbErr != nil {
+		return fmt.Errorf("failed to record order sync result: %w", dbErr)
+	}
+	refund.OrderSyncAttempts++
+
+	if err != nil {
+		return fmt.Errorf("failed to sync order status: %w", err)
+	}
+	refund.OrderSynced = true
+	return nil
+}
+
+// orderItemPrice 返回订单商品的精确单价，订单服务未返回精确金额时转换浮点单价
+func orderItemPrice(item *orderpb.OrderItem, currency string) money.Money {
+	if item.PriceMoney != nil {
+		return money.New(item.PriceMoney.Amount, item.PriceMoney.Currency)
+	}
+	return money.FromFloat(item.Price, currency)
+}
+
+// orderItemDiscount 返回已退款 refunded 件之后再退款 quantity 件商品应扣除的优惠金额。
+// 按累计退款数量计算，商品全部退款时扣除的优惠合计正好等于该商品分摊的优惠
+func orderItemDiscount(item *orderpb.OrderItem, refunded, quantity int32) int64 {
+	if item.DiscountMoney == nil || item.Quantity == 0 {
+		return 0
+	}
+	discount := item.DiscountMoney.Amount
+	total := int64(item.Quantity)
+	return discount*int64(refunded+quantity)/total - discount*int64(refunded)/total
+}
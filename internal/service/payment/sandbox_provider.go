@@ -0,0 +1,446 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	pb "github.com/bytedance-youthcamp/demo/api/payment"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+const (
+	defaultSandboxName   = "sandbox"
+	defaultSandboxPayURL = "https://payment.example.com/pay"
+	// MinSandboxSecretLength 沙箱回调密钥的最小长度，过短的密钥可以被猜测并用于伪造回调
+	MinSandboxSecretLength = 32
+)
+
+// ErrUnknownCharge 表示渠道中不存在该收款或退款
+var ErrUnknownCharge = errors.New("unknown charge")
+
+// SandboxOutcome 沙箱渠道中收款或退款的结果
+type SandboxOutcome int
+
+const (
+	SandboxSucceed SandboxOutcome = iota
+	SandboxFail
+	// SandboxTimeout 渠道不发出回调，收款或退款一直处于处理中
+	SandboxTimeout
+)
+
+// SandboxProvider 完全在本地运行的支付渠道，模拟真实渠道的异步流程：
+// 创建收款后处于待支付状态，结果通过签名的回调通知，也可以主动查询。
+// 开启自动完成后，收款和退款在延迟后按金额决定结果：金额的最后两位为 51 时失败，为 52 时超时不回调，
+// 其他金额成功；SetOutcome 可以为单笔收款或退款指定结果。未开启自动完成时由 Complete 和 CompleteRefund 手动完成
+type SandboxProvider struct {
+	name         string
+	signer       CallbackSigner
+	verifier     CallbackVerifier
+	payURL       string
+	autoComplete bool
+	delay        time.Duration
+
+	mu       sync.Mutex
+	charges  map[string]*sandboxCharge
+	refunds  map[string]*sandboxRefund
+	outcomes map[string]SandboxOutcome
+	callback CallbackFunc
+}
+
+type sandboxCharge struct {
+	orderID       int32
+	amount        money.Money
+	status        pb.PaymentStatus
+	transactionID string
+	refunded      int64
+}
+
+type sandboxRefund struct {
+	paymentID     string
+	amount        money.Money
+	status        pb.RefundStatus
+	transactionID string
+}
+
+// sandboxCallback 沙箱渠道回调的内容，Status 为支付或退款状态的枚举名，Timestamp 为发出回调的 Unix 时间
+type sandboxCallback struct {
+	Type          CallbackType `json:"type"`
+	ID            string       `json:"id"`
+	OrderID       int32        `json:"order_id"`
+	Status        string       `json:"status"`
+	TransactionID string       `json:"transaction_id"`
+	Amount        int64        `json:"amount"`
+	Currency      string       `json:"currency"`
+	Timestamp     int64        `json:"timestamp"`
+}
+
+type SandboxOption func(*SandboxProvider)
+
+// WithSandboxName 设置渠道名称，多个沙箱渠道同时使用时需要不同的名称
+func WithSandboxName(name string) SandboxOption {
+	return func(p *SandboxProvider) {
+		if name != "" {
+			p.name = name
+		}
+	}
+}
+
+// WithSandboxSecret 使用共享密钥的 HMAC-SHA256 为回调签名
+func WithSandboxSecret(secret string) SandboxOption {
+	return func(p *SandboxProvider) {
+		if secret != "" {
+			signature := NewHMACSignature(secret)
+			p.signer = signature
+			p.verifier = signature
+		}
+	}
+}
+
+// WithSandboxRSAKey 使用 RSA 私钥为回调签名，模拟使用非对称签名的渠道
+func WithSandboxRSAKey(key *rsa.PrivateKey) SandboxOption {
+	return func(p *SandboxProvider) {
+		if key != nil {
+			p.signer = NewRSASigner(key)
+			p.verifier = NewRSAVerifier(&key.PublicKey)
+		}
+	}
+}
+
+// WithSandboxPayURL 设置支付页面地址，支付单号作为 id 参数
+func WithSandboxPayURL(payURL string) SandboxOption {
+	return func(p *SandboxProvider) {
+		if payURL != "" {
+			p.payURL = payURL
+		}
+	}
+}
+
+// WithSandboxAutoComplete 在 delay 后按金额或指定的结果自动完成收款和退款
+func WithSandboxAutoComplete(delay time.Duration) SandboxOption {
+	return func(p *SandboxProvider) {
+		p.autoComplete = true
+		p.delay = delay
+	}
+}
+
+// NewSandboxProvider 创建沙箱支付渠道
+func NewSandboxProvider(opts ...SandboxOption) *SandboxProvider {
+	p := &SandboxProvider{
+		name:     defaultSandboxName,
+		payURL:   defaultSandboxPayURL,
+		charges:  make(map[string]*sandboxCharge),
+		refunds:  make(map[string]*sandboxRefund),
+		outcomes: make(map[string]SandboxOutcome),
+	}
+	// 未设置密钥时使用随机密钥，回调只能由沙箱自身发出，外部无法伪造
+	WithSandboxSecret(randomSandboxSecret())(p)
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func randomSandboxSecret() string {
+	secret := make([]byte, MinSandboxSecretLength/2)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("failed to generate sandbox secret: %v", err))
+	}
+	return hex.EncodeToString(secret)
+}
+
+func (p *SandboxProvider) Name() string {
+	return p.name
+}
+
+// OnCallback 设置接收回调的函数
+func (p *SandboxProvider) OnCallback(fn CallbackFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.callback = fn
+}
+
+// SetOutcome 指定收款或退款的结果，id 为支付单号或退款单号，需要在创建收款或退款之前设置
+func (p *SandboxProvider) SetOutcome(id string, outcome SandboxOutcome) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.outcomes[id] = outcome
+}
+
+func (p *SandboxProvider) CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error) {
+	p.mu.Lock()
+	if _, ok := p.charges[req.PaymentID]; ok {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("charge %s already exists", req.PaymentID)
+	}
+	charge := &sandboxCharge{
+		orderID:       req.OrderID,
+		amount:        req.Amount,
+		status:        pb.PaymentStatus_PAYMENT_STATUS_PENDING,
+		transactionID: "sandbox_" + uuid.New().String(),
+	}
+	p.charges[req.PaymentID] = charge
+	outcome := p.outcomeLocked(req.PaymentID, req.Amount)
+	p.mu.Unlock()
+
+	if p.autoComplete && outcome != SandboxTimeout {
+		status := pb.PaymentStatus_PAYMENT_STATUS_SUCCESS
+		if outcome == SandboxFail {
+			status = pb.PaymentStatus_PAYMENT_STATUS_FAILED
+		}
+		p.after(func(ctx context.Context) error {
+			return p.Complete(ctx, req.PaymentID, status)
+		})
+	}
+
+	return &Charge{
+		PaymentURL:    fmt.Sprintf("%s?id=%s", p.payURL, url.QueryEscape(req.PaymentID)),
+		TransactionID: charge.transactionID,
+	}, nil
+}
+
+func (p *SandboxProvider) QueryCharge(ctx context.Context, paymentID string) (*ChargeStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charge, ok := p.charges[paymentID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCharge, paymentID)
+	}
+	return &ChargeStatus{
+		Status:        charge.status,
+		TransactionID: charge.transactionID,
+		Amount:        charge.amount,
+	}, nil
+}
+
+// CancelCharge 关闭待支付的收款，关闭后的收款按支付失败处理，不能再完成支付
+func (p *SandboxProvider) CancelCharge(ctx context.Context, paymentID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charge, ok := p.charges[paymentID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownCharge, paymentID)
+	}
+	switch charge.status {
+	case pb.PaymentStatus_PAYMENT_STATUS_SUCCESS:
+		return fmt.Errorf("%w: %s", ErrChargeCompleted, paymentID)
+	case pb.PaymentStatus_PAYMENT_STATUS_PENDING:
+		charge.status = pb.PaymentStatus_PAYMENT_STATUS_FAILED
+	}
+	return nil
+}
+
+func (p *SandboxProvider) Refund(ctx context.Context, req RefundRequest) (*ProviderRefund, error) {
+	p.mu.Lock()
+	charge, ok := p.charges[req.PaymentID]
+	if !ok {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCharge, req.PaymentID)
+	}
+	// 支付结果可能经由其他途径通知，只拒绝沙箱中已失败的收款
+	if charge.status == pb.PaymentStatus_PAYMENT_STATUS_FAILED {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("charge %s has failed", req.PaymentID)
+	}
+	if charge.refunded+req.Amount.Amount > charge.amount.Amount {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("refund amount exceeds charge %s", req.PaymentID)
+	}
+	if _, ok := p.refunds[req.RefundID]; ok {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("refund %s already exists", req.RefundID)
+	}
+
+	refund := &sandboxRefund{
+		paymentID:     req.PaymentID,
+		amount:        req.Amount,
+		status:        pb.RefundStatus_REFUND_STATUS_PENDING,
+		transactionID: "sandbox_refund_" + uuid.New().String(),
+	}
+	p.refunds[req.RefundID] = refund
+	// 退款受理后即占用可退金额，失败时归还
+	charge.refunded += req.Amount.Amount
+	outcome := p.outcomeLocked(req.RefundID, req.Amount)
+	p.mu.Unlock()
+
+	if p.autoComplete && outcome != SandboxTimeout {
+		status := pb.RefundStatus_REFUND_STATUS_SUCCESS
+		if outcome == SandboxFail {
+			status = pb.RefundStatus_REFUND_STATUS_FAILED
+		}
+		p.after(func(ctx context.Context) error {
+			return p.CompleteRefund(ctx, req.RefundID, status)
+		})
+	}
+
+	return &ProviderRefund{
+		Status:        pb.RefundStatus_REFUND_STATUS_PENDING,
+		TransactionID: refund.transactionID,
+	}, nil
+}
+
+// Complete 完成待支付的收款并发出回调；收款已经是该状态时重复发出回调，模拟渠道的重复通知
+func (p *SandboxProvider) Complete(ctx context.Context, paymentID string, status pb.PaymentStatus) error {
+	if status != pb.PaymentStatus_PAYMENT_STATUS_SUCCESS && status != pb.PaymentStatus_PAYMENT_STATUS_FAILED {
+		return fmt.Errorf("invalid sandbox payment status %s", status)
+	}
+
+	p.mu.Lock()
+	charge, ok := p.charges[paymentID]
+	if !ok {
+		p.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrUnknownCharge, paymentID)
+	}
+	if charge.status != pb.PaymentStatus_PAYMENT_STATUS_PENDING && charge.status != status {
+		p.mu.Unlock()
+		return fmt.Errorf("charge %s is already %s", paymentID, charge.status)
+	}
+	charge.status = status
+	callback := sandboxCallback{
+		Type:          CallbackPayment,
+		ID:            paymentID,
+		OrderID:       charge.orderID,
+		Status:        status.String(),
+		TransactionID: charge.transactionID,
+		Amount:        charge.amount.Amount,
+		Currency:      charge.amount.Currency,
+		Timestamp:     time.Now().Unix(),
+	}
+	p.mu.Unlock()
+
+	return p.deliver(ctx, callback)
+}
+
+// CompleteRefund 完成处理中的退款并发出回调
+func (p *SandboxProvider) CompleteRefund(ctx context.Context, refundID string, status pb.RefundStatus) error {
+	if status != pb.RefundStatus_REFUND_STATUS_SUCCESS && status != pb.RefundStatus_REFUND_STATUS_FAILED {
+		return fmt.Errorf("invalid sandbox refund status %s", status)
+	}
+
+	p.mu.Lock()
+	refund, ok := p.refunds[refundID]
+	if !ok {
+		p.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrUnknownCharge, refundID)
+	}
+	if refund.status != pb.RefundStatus_REFUND_STATUS_PENDING && refund.status != status {
+		p.mu.Unlock()
+		return fmt.Errorf("refund %s is already %s", refundID, refund.status)
+	}
+	if refund.status == pb.RefundStatus_REFUND_STATUS_PENDING && status == pb.RefundStatus_REFUND_STATUS_FAILED {
+		p.charges[refund.paymentID].refunded -= refund.amount.Amount
+	}
+	refund.status = status
+	callback := sandboxCallback{
+		Type:          CallbackRefund,
+		ID:            refundID,
+		OrderID:       p.charges[refund.paymentID].orderID,
+		Status:        status.String(),
+		TransactionID: refund.transactionID,
+		Amount:        refund.amount.Amount,
+		Currency:      refund.amount.Currency,
+		Timestamp:     time.Now().Unix(),
+	}
+	p.mu.Unlock()
+
+	return p.deliver(ctx, callback)
+}
+
+func (p *SandboxProvider) VerifyCallback(ctx context.Context, payload []byte, signature string) (*Callback, error) {
+	if err := p.verifier.Verify(payload, signature); err != nil {
+		return nil, err
+	}
+
+	var data sandboxCallback
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCallback, err)
+	}
+
+	callback := &Callback{
+		Type:          data.Type,
+		ID:            data.ID,
+		OrderID:       data.OrderID,
+		TransactionID: data.TransactionID,
+		Amount:        money.New(data.Amount, data.Currency),
+	}
+	if data.Timestamp > 0 {
+		callback.Timestamp = time.Unix(data.Timestamp, 0)
+	}
+	switch data.Type {
+	case CallbackPayment:
+		status, ok := pb.PaymentStatus_value[data.Status]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown payment status %q", ErrInvalidCallback, data.Status)
+		}
+		callback.PaymentStatus = pb.PaymentStatus(status)
+	case CallbackRefund:
+		status, ok := pb.RefundStatus_value[data.Status]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown refund status %q", ErrInvalidCallback, data.Status)
+		}
+		callback.RefundStatus = pb.RefundStatus(status)
+	default:
+		return nil, fmt.Errorf("%w: unknown callback type %q", ErrInvalidCallback, data.Type)
+	}
+	return callback, nil
+}
+
+// Sign 返回回调内容的签名，用于构造测试回调
+func (p *SandboxProvider) Sign(payload []byte) (string, error) {
+	return p.signer.Sign(payload)
+}
+
+// outcomeLocked 返回指定的结果，未指定时按金额的最后两位决定
+func (p *SandboxProvider) outcomeLocked(id string, amount money.Money) SandboxOutcome {
+	if outcome, ok := p.outcomes[id]; ok {
+		return outcome
+	}
+	switch amount.Amount % 100 {
+	case 51:
+		return SandboxFail
+	case 52:
+		return SandboxTimeout
+	default:
+		return SandboxSucceed
+	}
+}
+
+// after 在延迟后异步执行 fn，模拟渠道的异步通知
+func (p *SandboxProvider) after(fn func(ctx context.Context) error) {
+	time.AfterFunc(p.delay, func() {
+		if err := fn(context.Background()); err != nil {
+			log.Printf("Sandbox provider %s failed to deliver callback: %v", p.name, err)
+		}
+	})
+}
+
+func (p *SandboxProvider) deliver(ctx context.Context, callback sandboxCallback) error {
+	payload, err := json.Marshal(callback)
+	if err != nil {
+		return fmt.Errorf("failed to encode sandbox callback: %w", err)
+	}
+
+	p.mu.Lock()
+	fn := p.callback
+	p.mu.Unlock()
+	if fn == nil {
+		return nil
+	}
+	signature, err := p.Sign(payload)
+	if err != nil {
+		return err
+	}
+	return fn(ctx, payload, signature)
+}
@@ -0,0 +1,218 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/bytedance-youthcamp/demo/api/payment"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+// callbackRecorder 记录沙箱渠道发出并验证通过的回调
+type callbackRecorder struct {
+	provider  *SandboxProvider
+	callbacks chan *Callback
+}
+
+func newCallbackRecorder(t *testing.T, provider *SandboxProvider) *callbackRecorder {
+	r := &callbackRecorder{provider: provider, callbacks: make(chan *Callback, 10)}
+	provider.OnCallback(func(ctx context.Context, payload []byte, signature string) error {
+		callback, err := provider.VerifyCallback(ctx, payload, signature)
+		if err != nil {
+			t.Errorf("invalid sandbox callback: %v", err)
+			return err
+		}
+		r.callbacks <- callback
+		return nil
+	})
+	return r
+}
+
+func (r *callbackRecorder) next(t *testing.T) *Callback {
+	select {
+	case callback := <-r.callbacks:
+		return callback
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for sandbox callback")
+		return nil
+	}
+}
+
+func sign(t *testing.T, provider *SandboxProvider, payload []byte) string {
+	signature, err := provider.Sign(payload)
+	require.NoError(t, err)
+	return signature
+}
+
+func TestSandboxCallbackSignature(t *testing.T) {
+	ctx := context.Background()
+	provider := NewSandboxProvider(WithSandboxSecret("secret"))
+
+	payload := []byte(`{"type":"payment","id":"p1","status":"PAYMENT_STATUS_SUCCESS","transaction_id":"t1","amount":100,"currency":"CNY"}`)
+	callback, err := provider.VerifyCallback(ctx, payload, sign(t, provider, payload))
+	require.NoError(t, err)
+	assert.Equal(t, CallbackPayment, callback.Type)
+	assert.Equal(t, "p1", callback.ID)
+	assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_SUCCESS, callback.PaymentStatus)
+	assert.Equal(t, money.New(100, "CNY"), callback.Amount)
+
+	// 篡改内容或使用其他密钥签名都会被拒绝
+	tampered := []byte(`{"type":"payment","id":"p1","status":"PAYMENT_STATUS_SUCCESS","transaction_id":"t1","amount":1,"currency":"CNY"}`)
+	_, err = provider.VerifyCallback(ctx, tampered, sign(t, provider, payload))
+	assert.True(t, errors.Is(err, ErrInvalidCallback))
+
+	other := NewSandboxProvider(WithSandboxSecret("other"))
+	_, err = provider.VerifyCallback(ctx, payload, sign(t, other, payload))
+	assert.True(t, errors.Is(err, ErrInvalidCallback))
+
+	_, err = provider.VerifyCallback(ctx, payload, "not-hex")
+	assert.True(t, errors.Is(err, ErrInvalidCallback))
+
+	t.Run("rsa", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		provider := NewSandboxProvider(WithSandboxRSAKey(key))
+
+		callback, err := provider.VerifyCallback(ctx, payload, sign(t, provider, payload))
+		require.NoError(t, err)
+		assert.Equal(t, "p1", callback.ID)
+
+		_, err = provider.VerifyCallback(ctx, tampered, sign(t, provider, payload))
+		assert.True(t, errors.Is(err, ErrInvalidCallback))
+
+		// HMAC 签名不能通过 RSA 验证
+		_, err = provider.VerifyCallback(ctx, payload, sign(t, other, payload))
+		assert.True(t, errors.Is(err, ErrInvalidCallback))
+	})
+}
+
+func TestSandboxAutoComplete(t *testing.T) {
+	ctx := context.Background()
+	provider := NewSandboxProvider(WithSandboxAutoComplete(10 * time.Millisecond))
+	recorder := newCallbackRecorder(t, provider)
+
+	t.Run("succeeds", func(t *testing.T) {
+		charge, err := provider.CreateCharge(ctx, ChargeRequest{PaymentID: "success", Amount: money.New(10000, "CNY")})
+		require.NoError(t, err)
+		assert.Contains(t, charge.PaymentURL, "id=success")
+
+		status, err := provider.QueryCharge(ctx, "success")
+		require.NoError(t, err)
+		assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_PENDING, status.Status)
+
+		callback := recorder.next(t)
+		assert.Equal(t, "success", callback.ID)
+		assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_SUCCESS, callback.PaymentStatus)
+		assert.Equal(t, charge.TransactionID, callback.TransactionID)
+
+		status, err = provider.QueryCharge(ctx, "success")
+		require.NoError(t, err)
+		assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_SUCCESS, status.Status)
+	})
+
+	t.Run("fails by amount", func(t *testing.T) {
+		_, err := provider.CreateCharge(ctx, ChargeRequest{PaymentID: "failure", Amount: money.New(10051, "CNY")})
+		require.NoError(t, err)
+
+		callback := recorder.next(t)
+		assert.Equal(t, "failure", callback.ID)
+		assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_FAILED, callback.PaymentStatus)
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		provider.SetOutcome("timeout", SandboxTimeout)
+		_, err := provider.CreateCharge(ctx, ChargeRequest{PaymentID: "timeout", Amount: money.New(10000, "CNY")})
+		require.NoError(t, err)
+
+		select {
+		case callback := <-recorder.callbacks:
+			t.Fatalf("unexpected callback for %s", callback.ID)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		status, err := provider.QueryCharge(ctx, "timeout")
+		require.NoError(t, err)
+		assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_PENDING, status.Status)
+	})
+
+	t.Run("refunds", func(t *testing.T) {
+		_, err := provider.Refund(ctx, RefundRequest{RefundID: "too-much", PaymentID: "success", Amount: money.New(20000, "CNY")})
+		assert.Error(t, err)
+
+		refund, err := provider.Refund(ctx, RefundRequest{RefundID: "refund", PaymentID: "success", Amount: money.New(4000, "CNY")})
+		require.NoError(t, err)
+		assert.Equal(t, pb.RefundStatus_REFUND_STATUS_PENDING, refund.Status)
+
+		callback := recorder.next(t)
+		assert.Equal(t, CallbackRefund, callback.Type)
+		assert.Equal(t, "refund", callback.ID)
+		assert.Equal(t, pb.RefundStatus_REFUND_STATUS_SUCCESS, callback.RefundStatus)
+		assert.Equal(t, money.New(4000, "CNY"), callback.Amount)
+
+		_, err = provider.Refund(ctx, RefundRequest{RefundID: "failed-charge", PaymentID: "failure", Amount: money.New(100, "CNY")})
+		assert.Error(t, err)
+	})
+}
+
+func TestSandboxManualComplete(t *testing.T) {
+	ctx := context.Background()
+	provider := NewSandboxProvider()
+	recorder := newCallbackRecorder(t, provider)
+
+	_, err := provider.CreateCharge(ctx, ChargeRequest{PaymentID: "manual", Amount: money.New(500, "CNY")})
+	require.NoError(t, err)
+
+	require.NoError(t, provider.Complete(ctx, "manual", pb.PaymentStatus_PAYMENT_STATUS_SUCCESS))
+	assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_SUCCESS, recorder.next(t).PaymentStatus)
+
+	// 重复完成时重新发出回调，不能改为其他结果
+	require.NoError(t, provider.Complete(ctx, "manual", pb.PaymentStatus_PAYMENT_STATUS_SUCCESS))
+	assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_SUCCESS, recorder.next(t).PaymentStatus)
+	assert.Error(t, provider.Complete(ctx, "manual", pb.PaymentStatus_PAYMENT_STATUS_FAILED))
+
+	err = provider.Complete(ctx, "missing", pb.PaymentStatus_PAYMENT_STATUS_SUCCESS)
+	assert.True(t, errors.Is(err, ErrUnknownCharge))
+}
+
+func TestSandboxCancelCharge(t *testing.T) {
+	ctx := context.Background()
+	provider := NewSandboxProvider()
+	newCallbackRecorder(t, provider)
+
+	for _, id := range []string{"p1", "p2"} {
+		_, err := provider.CreateCharge(ctx, ChargeRequest{PaymentID: id, OrderID: 1, Amount: money.New(100, "CNY")})
+		require.NoError(t, err)
+	}
+
+	// 关闭后的收款不能再完成支付，重复关闭视为成功
+	require.NoError(t, provider.CancelCharge(ctx, "p1"))
+	require.NoError(t, provider.CancelCharge(ctx, "p1"))
+	assert.Error(t, provider.Complete(ctx, "p1", pb.PaymentStatus_PAYMENT_STATUS_SUCCESS))
+	status, err := provider.QueryCharge(ctx, "p1")
+	require.NoError(t, err)
+	assert.Equal(t, pb.PaymentStatus_PAYMENT_STATUS_FAILED, status.Status)
+
+	// 已支付的收款不能关闭
+	require.NoError(t, provider.Complete(ctx, "p2", pb.PaymentStatus_PAYMENT_STATUS_SUCCESS))
+	assert.True(t, errors.Is(provider.CancelCharge(ctx, "p2"), ErrChargeCompleted))
+	assert.True(t, errors.Is(provider.CancelCharge(ctx, "unknown"), ErrUnknownCharge))
+}
+
+// 指定渠道后未指定渠道的支付方式不可用，在访问数据库之前被拒绝
+func TestSandboxOnlyForConfiguredMethods(t *testing.T) {
+	service := NewPaymentService(nil, WithProvider(pb.PaymentMethod_PAYMENT_METHOD_ALIPAY, NewSandboxProvider()))
+
+	_, err := service.CreatePayment(context.Background(), &pb.CreatePaymentRequest{
+		OrderId:     1,
+		AmountMoney: &pb.Money{Amount: 100, Currency: "CNY"},
+		Method:      pb.PaymentMethod_PAYMENT_METHOD_WECHAT,
+	})
+	assert.ErrorContains(t, err, "unsupported payment method")
+}
@@ -0,0 +1,131 @@
+package payment
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// CallbackSigner 为渠道回调签名
+type CallbackSigner interface {
+	Sign(payload []byte) (string, error)
+}
+
+// CallbackVerifier 验证渠道回调的签名，签名无效时返回 ErrInvalidCallback
+type CallbackVerifier interface {
+	Verify(payload []byte, signature string) error
+}
+
+// HMACSignature 使用共享密钥的 HMAC-SHA256 签名，签名为十六进制字符串
+type HMACSignature struct {
+	secret []byte
+}
+
+func NewHMACSignature(secret string) *HMACSignature {
+	return &HMACSignature{secret: []byte(secret)}
+}
+
+func (h *HMACSignature) Sign(payload []byte) (string, error) {
+	return hex.EncodeToString(h.sum(payload)), nil
+}
+
+func (h *HMACSignature) Verify(payload []byte, signature string) error {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, h.sum(payload)) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidCallback)
+	}
+	return nil
+}
+
+func (h *HMACSignature) sum(payload []byte) []byte {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// RSASigner 使用渠道私钥的 RSA-SHA256 (PKCS #1 v1.5) 签名，签名为 Base64 字符串
+type RSASigner struct {
+	key *rsa.PrivateKey
+}
+
+func NewRSASigner(key *rsa.PrivateKey) *RSASigner {
+	return &RSASigner{key: key}
+}
+
+func (s *RSASigner) Sign(payload []byte) (string, error) {
+	digest := sha256.Sum256(payload)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign callback: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// RSAVerifier 使用渠道公钥验证 RSA-SHA256 签名
+type RSAVerifier struct {
+	key *rsa.PublicKey
+}
+
+func NewRSAVerifier(key *rsa.PublicKey) *RSAVerifier {
+	return &RSAVerifier{key: key}
+}
+
+func (v *RSAVerifier) Verify(payload []byte, signature string) error {
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidCallback)
+	}
+	digest := sha256.Sum256(payload)
+	if err := rsa.VerifyPKCS1v15(v.key, crypto.SHA256, digest[:], raw); err != nil {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidCallback)
+	}
+	return nil
+}
+
+// ParseRSAPrivateKey 解析 PEM 格式的 PKCS #1 或 PKCS #8 RSA 私钥
+func ParseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// ParseRSAPublicKey 解析 PEM 格式的 PKIX 或 PKCS #1 RSA 公钥
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+	return rsaKey, nil
+}
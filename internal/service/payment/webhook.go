@@ -0,0 +1,59 @@
+package payment
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// SignatureHeader 渠道回调携带签名的请求头
+	SignatureHeader = "X-Payment-Signature"
+	// maxWebhookBodySize 回调请求体的最大字节数
+	maxWebhookBodySize = 1 << 20
+)
+
+// WebhookHandler 返回接收支付渠道回调的 HTTP 处理器，路径为 POST /webhooks/{provider}。
+// 处理成功返回 200；签名无效或超出时间窗口返回 401，内容与支付记录不一致返回 400，
+// 支付记录不存在返回 404，这些回调重试也不会成功。其他错误返回 500，由渠道稍后重试
+func (s *PaymentService) WebhookHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhooks/{provider}", s.handleWebhook)
+	return mux
+}
+
+func (s *PaymentService) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	provider := r.PathValue("provider")
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	err = s.HandleProviderCallback(r.Context(), provider, payload, r.Header.Get(SignatureHeader))
+	if err != nil {
+		log.Printf("Rejected callback from payment provider %s: %v", provider, err)
+		http.Error(w, http.StatusText(webhookStatus(err)), webhookStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("success"))
+}
+
+func webhookStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidCallback), errors.Is(err, ErrCallbackExpired):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrCallbackMismatch):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnknownProvider), errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
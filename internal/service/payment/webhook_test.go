@@ -0,0 +1,66 @@
+package payment
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/bytedance-youthcamp/demo/api/payment"
+)
+
+// 回调在访问数据库之前被拒绝的情况，不需要数据库
+func TestWebhookRejectsCallbacks(t *testing.T) {
+	sandbox := NewSandboxProvider(WithSandboxSecret("secret"))
+	service := NewPaymentService(nil, WithProvider(pb.PaymentMethod_PAYMENT_METHOD_ALIPAY, sandbox), WithCallbackReplayWindow(time.Minute))
+	handler := service.WebhookHandler()
+
+	payload := func(timestamp time.Time) []byte {
+		return []byte(fmt.Sprintf(
+			`{"type":"payment","id":"p1","order_id":1,"status":"PAYMENT_STATUS_SUCCESS","transaction_id":"t1","amount":100,"currency":"CNY","timestamp":%d}`,
+			timestamp.Unix()))
+	}
+	post := func(path string, body []byte, signature string) int {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set(SignatureHeader, signature)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	current := payload(time.Now())
+	stale := payload(time.Now().Add(-2 * time.Minute))
+	future := payload(time.Now().Add(2 * time.Minute))
+	untimed := []byte(`{"type":"payment","id":"p1","status":"PAYMENT_STATUS_SUCCESS","amount":100,"currency":"CNY"}`)
+
+	testCases := []struct {
+		name      string
+		path      string
+		body      []byte
+		signature string
+		status    int
+	}{
+		{"unknown provider", "/webhooks/unknown", current, sign(t, sandbox, current), http.StatusNotFound},
+		{"missing signature", "/webhooks/sandbox", current, "", http.StatusUnauthorized},
+		{"wrong signature", "/webhooks/sandbox", current, sign(t, sandbox, stale), http.StatusUnauthorized},
+		{"replayed callback", "/webhooks/sandbox", stale, sign(t, sandbox, stale), http.StatusUnauthorized},
+		{"callback from the future", "/webhooks/sandbox", future, sign(t, sandbox, future), http.StatusUnauthorized},
+		{"missing timestamp", "/webhooks/sandbox", untimed, sign(t, sandbox, untimed), http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.status, post(tc.path, tc.body, tc.signature))
+		})
+	}
+
+	t.Run("only accepts POST", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhooks/sandbox", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
@@ -9,6 +9,7 @@ import (
 
 	productapi "github.com/bytedance-youthcamp/demo/api/product"
 	"github.com/bytedance-youthcamp/demo/internal/config"
+	"github.com/bytedance-youthcamp/demo/internal/money"
 	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -83,7 +84,8 @@ func createTestTables(db *sql.DB) error {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			name TEXT NOT NULL,
 			description TEXT,
-			price REAL NOT NULL,
+			price_minor INTEGER NOT NULL,
+			currency TEXT NOT NULL DEFAULT 'CNY',
 			stock INTEGER NOT NULL,
 			category TEXT,
 			image_url TEXT,
@@ -91,6 +93,57 @@ func createTestTables(db *sql.DB) error {
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		);
 	`)
+	if err != nil {
+		return err
+	}
+
+	// 创建库存预留表
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS stock_reservations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			order_id INTEGER NOT NULL,
+			product_id INTEGER NOT NULL,
+			quantity INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (order_id, product_id)
+		);
+	`)
+	if err != nil {
+		return err
+	}
+
+	// 创建库存恢复记录表
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS stock_restorations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			request_id TEXT NOT NULL,
+			product_id INTEGER NOT NULL,
+			quantity INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (request_id, product_id)
+		);
+	`)
+	if err != nil {
+		return err
+	}
+
+	// 创建事件发件箱表
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS outbox_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id TEXT NOT NULL UNIQUE,
+			event_type TEXT NOT NULL,
+			aggregate_type TEXT NOT NULL,
+			aggregate_id TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			occurred_at DATETIME NOT NULL,
+			published_at DATETIME,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT ''
+		);
+	`)
 	return err
 }
 
@@ -111,7 +164,7 @@ func (s *ProductService) Close() {
 // GetProduct 获取单个商品信息
 func (s *ProductService) GetProduct(ctx context.Context, req *productapi.GetProductRequest) (*productapi.GetProductResponse, error) {
 	query := `
-		SELECT id, name, description, price, stock, category, image_url, 
+		SELECT id, name, description, price_minor, currency, stock, category, image_url, 
 		       created_at, updated_at 
 		FROM products 
 		WHERE id = $1
@@ -119,12 +172,15 @@ func (s *ProductService) GetProduct(ctx context.Context, req *productapi.GetProd
 
 	var product productapi.Product
 	var createdAt, updatedAt time.Time
+	var price int64
+	var currency string
 
 	err := s.db.QueryRowContext(ctx, query, req.ProductId).Scan(
 		&product.Id,
 		&product.Name,
 		&product.Description,
-		&product.Price,
+		&price,
+		&currency,
 		&product.Stock,
 		&product.Category,
 		&product.ImageUrl,
@@ -143,6 +199,7 @@ func (s *ProductService) GetProduct(ctx context.Context, req *productapi.GetProd
 
 	product.CreatedAt = createdAt.Format(time.RFC3339)
 	product.UpdatedAt = updatedAt.Format(time.RFC3339)
+	setProductPrice(&product, money.New(price, currency))
 
 	return &productapi.GetProductResponse{
 		Product: &product,
@@ -199,7 +256,7 @@ func (s *ProductService) GetProducts(ctx context.Context, req *productapi.GetPro
 
 	// 查询商品
 	query := fmt.Sprintf(`
-		SELECT id, name, description, price, stock, category, image_url, 
+		SELECT id, name, description, price_minor, currency, stock, category, image_url, 
 		       created_at, updated_at 
 		FROM products 
 		%s 
@@ -219,12 +276,15 @@ func (s *ProductService) GetProducts(ctx context.Context, req *productapi.GetPro
 	for rows.Next() {
 		var product productapi.Product
 		var createdAt, updatedAt time.Time
+		var price int64
+		var currency string
 
 		err := rows.Scan(
 			&product.Id,
 			&product.Name,
 			&product.Description,
-			&product.Price,
+			&price,
+			&currency,
 			&product.Stock,
 			&product.Category,
 			&product.ImageUrl,
@@ -237,6 +297,7 @@ func (s *ProductService) GetProducts(ctx context.Context, req *productapi.GetPro
 
 		product.CreatedAt = createdAt.Format(time.RFC3339)
 		product.UpdatedAt = updatedAt.Format(time.RFC3339)
+		setProductPrice(&product, money.New(price, currency))
 		products = append(products, &product)
 	}
 
@@ -261,7 +322,8 @@ func (s *ProductService) CreateProduct(ctx context.Context, req *productapi.Crea
 		}, nil
 	}
 
-	if req.Price < 0 {
+	price := requestPrice(req.PriceMoney, req.Price)
+	if price.IsNegative() {
 		return &productapi.CreateProductResponse{
 			Success:      false,
 			ErrorMessage: "商品价格不能为负数",
@@ -277,8 +339,8 @@ func (s *ProductService) CreateProduct(ctx context.Context, req *productapi.Crea
 
 	// 插入商品到数据库
 	query := `
-		INSERT INTO products (name, description, price, stock, category, image_url, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO products (name, description, price_minor, currency, stock, category, image_url, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id
 	`
 
@@ -288,7 +350,8 @@ func (s *ProductService) CreateProduct(ctx context.Context, req *productapi.Crea
 	err := s.db.QueryRowContext(ctx, query,
 		req.Name,
 		req.Description,
-		req.Price,
+		price.Amount,
+		price.Currency,
 		req.Stock,
 		req.Category,
 		req.ImageUrl,
@@ -322,7 +385,8 @@ func (s *ProductService) UpdateProduct(ctx context.Context, req *productapi.Upda
 	}
 
 	// 验证商品信息
-	if req.Price < 0 {
+	price := requestPrice(req.PriceMoney, req.Price)
+	if price.IsNegative() {
 		return &productapi.UpdateProductResponse{
 			Success:      false,
 			ErrorMessage: "商品价格不能为负数",
@@ -339,15 +403,16 @@ func (s *ProductService) UpdateProduct(ctx context.Context, req *productapi.Upda
 	// 更新商品信息
 	query := `
 		UPDATE products
-		SET name = $1, description = $2, price = $3, stock = $4, category = $5, image_url = $6, updated_at = $7
-		WHERE id = $8
+		SET name = $1, description = $2, price_minor = $3, currency = $4, stock = $5, category = $6, image_url = $7, updated_at = $8
+		WHERE id = $9
 	`
 
 	now := time.Now()
 	_, err = s.db.ExecContext(ctx, query,
 		req.Name,
 		req.Description,
-		req.Price,
+		price.Amount,
+		price.Currency,
 		req.Stock,
 		req.Category,
 		req.ImageUrl,
@@ -389,3 +454,20 @@ func (s *ProductService) DeleteProduct(ctx context.Context, req *productapi.Dele
 		Success: true,
 	}, nil
 }
+
+// requestPrice 读取请求中的商品价格，优先使用精确金额
+func requestPrice(exact *productapi.Money, price float64) money.Money {
+	if exact != nil {
+		return money.New(exact.Amount, exact.Currency)
+	}
+	return money.FromFloat(price, money.DefaultCurrency)
+}
+
+// setProductPrice 同时填充商品的浮点价格和精确价格
+func setProductPrice(product *productapi.Product, price money.Money) {
+	product.Price = price.Float64()
+	product.PriceMoney = &productapi.Money{
+		Amount:   price.Amount,
+		Currency: price.Currency,
+	}
+}
@@ -3,9 +3,11 @@ package product
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"testing"
 
 	productapi "github.com/bytedance-youthcamp/demo/api/product"
+	"github.com/bytedance-youthcamp/demo/internal/outbox"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,7 +26,8 @@ func setupTestDatabase(t *testing.T) *sql.DB {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			name TEXT NOT NULL,
 			description TEXT,
-			price REAL NOT NULL,
+			price_minor INTEGER NOT NULL,
+			currency TEXT NOT NULL DEFAULT 'CNY',
 			stock INTEGER NOT NULL,
 			category TEXT,
 			image_url TEXT,
@@ -34,6 +37,51 @@ func setupTestDatabase(t *testing.T) *sql.DB {
 	`)
 	require.NoError(t, err, "Failed to create tables")
 
+	// 创建库存预留表
+	_, err = db.Exec(`
+		CREATE TABLE stock_reservations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			order_id INTEGER NOT NULL,
+			product_id INTEGER NOT NULL,
+			quantity INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (order_id, product_id)
+		);
+	`)
+	require.NoError(t, err, "Failed to create tables")
+
+	// 创建库存恢复记录表
+	_, err = db.Exec(`
+		CREATE TABLE stock_restorations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			request_id TEXT NOT NULL,
+			product_id INTEGER NOT NULL,
+			quantity INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (request_id, product_id)
+		);
+	`)
+	require.NoError(t, err, "Failed to create tables")
+
+	// 创建事件发件箱表
+	_, err = db.Exec(`
+		CREATE TABLE outbox_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id TEXT NOT NULL UNIQUE,
+			event_type TEXT NOT NULL,
+			aggregate_type TEXT NOT NULL,
+			aggregate_id TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			occurred_at DATETIME NOT NULL,
+			published_at DATETIME,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT ''
+		);
+	`)
+	require.NoError(t, err, "Failed to create tables")
+
 	t.Cleanup(func() {
 		err := db.Close()
 		if err != nil {
@@ -165,6 +213,8 @@ func TestGetProduct(t *testing.T) {
 	assert.Equal(t, productId, getResp.Product.Id)
 	assert.Equal(t, "Test Product", getResp.Product.Name)
 	assert.Equal(t, "This is a test product", getResp.Product.Description)
+	assert.Equal(t, int64(1999), getResp.Product.PriceMoney.Amount)
+	assert.Equal(t, "CNY", getResp.Product.PriceMoney.Currency)
 	assert.Equal(t, 19.99, getResp.Product.Price)
 	assert.Equal(t, int32(100), getResp.Product.Stock)
 	assert.Equal(t, "Test Category", getResp.Product.Category)
@@ -300,3 +350,269 @@ func TestDeleteProduct(t *testing.T) {
 	assert.False(t, nonExistentResp.Success)
 	assert.Equal(t, "商品不存在", nonExistentResp.ErrorMessage)
 }
+
+func getStock(t *testing.T, productService *ProductService, productId int32) int32 {
+	getResp, err := productService.GetProduct(context.Background(), &productapi.GetProductRequest{
+		ProductId: productId,
+	})
+	require.NoError(t, err)
+	require.True(t, getResp.Success)
+	return getResp.Product.Stock
+}
+
+func TestReduceStock(t *testing.T) {
+	productService := setupProductService(t)
+	ctx := context.Background()
+
+	productA := createTestProduct(t, productService)
+	productB := createTestProduct(t, productService)
+
+	// 批量扣减
+	reduceResp, err := productService.ReduceStock(ctx, &productapi.ReduceStockRequest{
+		OrderId: 1,
+		Items: []*productapi.StockItem{
+			{ProductId: productA, Quantity: 10},
+			{ProductId: productB, Quantity: 20},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, reduceResp.Success)
+	assert.Equal(t, int32(90), getStock(t, productService, productA))
+	assert.Equal(t, int32(80), getStock(t, productService, productB))
+
+	// 同一订单重试不会重复扣减
+	reduceResp, err = productService.ReduceStock(ctx, &productapi.ReduceStockRequest{
+		OrderId: 1,
+		Items: []*productapi.StockItem{
+			{ProductId: productA, Quantity: 10},
+			{ProductId: productB, Quantity: 20},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, reduceResp.Success)
+	assert.Equal(t, int32(90), getStock(t, productService, productA))
+	assert.Equal(t, int32(80), getStock(t, productService, productB))
+
+	// 任一商品库存不足时整体回滚
+	reduceResp, err = productService.ReduceStock(ctx, &productapi.ReduceStockRequest{
+		OrderId: 2,
+		Items: []*productapi.StockItem{
+			{ProductId: productA, Quantity: 10},
+			{ProductId: productB, Quantity: 81},
+		},
+	})
+	require.NoError(t, err)
+	assert.False(t, reduceResp.Success)
+	assert.Equal(t, "商品库存不足", reduceResp.ErrorMessage)
+	assert.Equal(t, productB, reduceResp.FailedProductId)
+	assert.Equal(t, int32(90), getStock(t, productService, productA))
+	assert.Equal(t, int32(80), getStock(t, productService, productB))
+
+	// 兼容单个商品的请求
+	reduceResp, err = productService.ReduceStock(ctx, &productapi.ReduceStockRequest{
+		OrderId:   3,
+		ProductId: productA,
+		Quantity:  5,
+	})
+	require.NoError(t, err)
+	assert.True(t, reduceResp.Success)
+	assert.Equal(t, int32(85), getStock(t, productService, productA))
+}
+
+func TestReserveAndReleaseStock(t *testing.T) {
+	productService := setupProductService(t)
+	ctx := context.Background()
+
+	productId := createTestProduct(t, productService)
+
+	reserveResp, err := productService.ReserveStock(ctx, &productapi.ReserveStockRequest{
+		OrderId: 1,
+		Items:   []*productapi.StockItem{{ProductId: productId, Quantity: 30}},
+	})
+	require.NoError(t, err)
+	assert.True(t, reserveResp.Success)
+	assert.Equal(t, int32(70), getStock(t, productService, productId))
+
+	// 释放预留库存，重复释放不会重复归还
+	for i := 0; i < 2; i++ {
+		releaseResp, err := productService.ReleaseStock(ctx, &productapi.ReleaseStockRequest{OrderId: 1})
+		require.NoError(t, err)
+		assert.True(t, releaseResp.Success)
+		assert.Equal(t, int32(100), getStock(t, productService, productId))
+	}
+
+	// 已释放的预留不能再确认
+	commitResp, err := productService.CommitReservation(ctx, &productapi.CommitReservationRequest{OrderId: 1})
+	require.NoError(t, err)
+	assert.False(t, commitResp.Success)
+	assert.Equal(t, "预留库存已释放", commitResp.ErrorMessage)
+}
+
+func TestCommitReservation(t *testing.T) {
+	productService := setupProductService(t)
+	ctx := context.Background()
+
+	productId := createTestProduct(t, productService)
+
+	reserveResp, err := productService.ReserveStock(ctx, &productapi.ReserveStockRequest{
+		OrderId: 1,
+		Items:   []*productapi.StockItem{{ProductId: productId, Quantity: 30}},
+	})
+	require.NoError(t, err)
+	assert.True(t, reserveResp.Success)
+
+	commitResp, err := productService.CommitReservation(ctx, &productapi.CommitReservationRequest{OrderId: 1})
+	require.NoError(t, err)
+	assert.True(t, commitResp.Success)
+
+	// 确认后的库存不会被释放
+	releaseResp, err := productService.ReleaseStock(ctx, &productapi.ReleaseStockRequest{OrderId: 1})
+	require.NoError(t, err)
+	assert.True(t, releaseResp.Success)
+	assert.Equal(t, int32(70), getStock(t, productService, productId))
+
+	// 不存在的预留
+	commitResp, err = productService.CommitReservation(ctx, &productapi.CommitReservationRequest{OrderId: 2})
+	require.NoError(t, err)
+	assert.False(t, commitResp.Success)
+	assert.Equal(t, "预留记录不存在", commitResp.ErrorMessage)
+}
+
+func TestStockEvents(t *testing.T) {
+	productService := setupProductService(t)
+	ctx := context.Background()
+
+	productId := createTestProduct(t, productService)
+
+	reserveResp, err := productService.ReserveStock(ctx, &productapi.ReserveStockRequest{
+		OrderId: 1,
+		Items:   []*productapi.StockItem{{ProductId: productId, Quantity: 30}},
+	})
+	require.NoError(t, err)
+	assert.True(t, reserveResp.Success)
+
+	commitResp, err := productService.CommitReservation(ctx, &productapi.CommitReservationRequest{OrderId: 1})
+	require.NoError(t, err)
+	assert.True(t, commitResp.Success)
+
+	// 已确认的预留不会再释放，不产生库存事件
+	releaseResp, err := productService.ReleaseStock(ctx, &productapi.ReleaseStockRequest{OrderId: 1})
+	require.NoError(t, err)
+	assert.True(t, releaseResp.Success)
+
+	broker := outbox.NewMemoryBroker()
+	published, err := outbox.NewRelay(productService.db, broker).PublishPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+
+	events := broker.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, outbox.StockReserved, events[0].Type)
+	assert.Equal(t, outbox.StockReduced, events[1].Type)
+	assert.Equal(t, "1", events[1].AggregateID)
+
+	var event StockEvent
+	require.NoError(t, json.Unmarshal(events[1].Payload, &event))
+	assert.Equal(t, int32(1), event.OrderID)
+	assert.Equal(t, []StockEventItem{{ProductID: productId, Quantity: 30}}, event.Items)
+}
+
+func TestAdjustReservation(t *testing.T) {
+	productService := setupProductService(t)
+	ctx := context.Background()
+
+	productA := createTestProduct(t, productService)
+	productB := createTestProduct(t, productService)
+	productC := createTestProduct(t, productService)
+
+	// 没有预留的订单不做调整
+	adjustResp, err := productService.AdjustReservation(ctx, &productapi.AdjustReservationRequest{
+		OrderId: 1,
+		Items:   []*productapi.StockItem{{ProductId: productA, Quantity: 5}},
+	})
+	require.NoError(t, err)
+	assert.True(t, adjustResp.Success)
+	assert.False(t, adjustResp.Reserved)
+	assert.Equal(t, int32(100), getStock(t, productService, productA))
+
+	reserveResp, err := productService.ReserveStock(ctx, &productapi.ReserveStockRequest{
+		OrderId: 1,
+		Items: []*productapi.StockItem{
+			{ProductId: productA, Quantity: 10},
+			{ProductId: productB, Quantity: 10},
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, reserveResp.Success)
+
+	// 减少 A、移除 B、新增 C
+	adjustResp, err = productService.AdjustReservation(ctx, &productapi.AdjustReservationRequest{
+		OrderId: 1,
+		Items: []*productapi.StockItem{
+			{ProductId: productA, Quantity: 4},
+			{ProductId: productC, Quantity: 3},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, adjustResp.Success)
+	assert.True(t, adjustResp.Reserved)
+	assert.Equal(t, int32(96), getStock(t, productService, productA))
+	assert.Equal(t, int32(100), getStock(t, productService, productB))
+	assert.Equal(t, int32(97), getStock(t, productService, productC))
+
+	// 库存不足时整体回滚
+	adjustResp, err = productService.AdjustReservation(ctx, &productapi.AdjustReservationRequest{
+		OrderId: 1,
+		Items: []*productapi.StockItem{
+			{ProductId: productA, Quantity: 1},
+			{ProductId: productC, Quantity: 500},
+		},
+	})
+	require.NoError(t, err)
+	assert.False(t, adjustResp.Success)
+	assert.Equal(t, productC, adjustResp.FailedProductId)
+	assert.Equal(t, int32(96), getStock(t, productService, productA))
+	assert.Equal(t, int32(97), getStock(t, productService, productC))
+
+	// 释放时归还调整后的预留数量
+	releaseResp, err := productService.ReleaseStock(ctx, &productapi.ReleaseStockRequest{OrderId: 1})
+	require.NoError(t, err)
+	assert.True(t, releaseResp.Success)
+	assert.Equal(t, int32(100), getStock(t, productService, productA))
+	assert.Equal(t, int32(100), getStock(t, productService, productC))
+
+	// 已释放的预留不能再调整
+	adjustResp, err = productService.AdjustReservation(ctx, &productapi.AdjustReservationRequest{
+		OrderId: 1,
+		Items:   []*productapi.StockItem{{ProductId: productA, Quantity: 1}},
+	})
+	require.NoError(t, err)
+	assert.False(t, adjustResp.Success)
+	assert.Equal(t, "预留库存已释放", adjustResp.ErrorMessage)
+}
+
+func TestRestoreStock(t *testing.T) {
+	productService := setupProductService(t)
+	ctx := context.Background()
+
+	productId := createTestProduct(t, productService)
+
+	// 重复的恢复请求只会生效一次
+	for i := 0; i < 2; i++ {
+		restoreResp, err := productService.RestoreStock(ctx, &productapi.RestoreStockRequest{
+			RequestId: "refund-1",
+			Items:     []*productapi.StockItem{{ProductId: productId, Quantity: 5}},
+		})
+		require.NoError(t, err)
+		assert.True(t, restoreResp.Success)
+		assert.Equal(t, int32(105), getStock(t, productService, productId))
+	}
+
+	restoreResp, err := productService.RestoreStock(ctx, &productapi.RestoreStockRequest{
+		RequestId: "refund-2",
+		Items:     []*productapi.StockItem{{ProductId: 9999, Quantity: 1}},
+	})
+	require.NoError(t, err)
+	assert.False(t, restoreResp.Success)
+	assert.Equal(t, "商品不存在", restoreResp.ErrorMessage)
+}
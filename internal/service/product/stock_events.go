@@ -0,0 +1,35 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/bytedance-youthcamp/demo/internal/outbox"
+)
+
+// StockEvent 库存领域事件的内容
+type StockEvent struct {
+	OrderID   int32            `json:"order_id,omitempty"`
+	RequestID string           `json:"request_id,omitempty"`
+	Items     []StockEventItem `json:"items"`
+}
+
+// StockEventItem 库存事件中每个商品变更的数量
+type StockEventItem struct {
+	ProductID int32 `json:"product_id"`
+	Quantity  int32 `json:"quantity"`
+}
+
+// recordReservationEvent 在库存事务中写入订单预留记录对应的库存事件，没有变更的记录时不写入
+func recordReservationEvent(ctx context.Context, tx *sql.Tx, eventType string, orderID int32, reservations []reservation) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+
+	event := StockEvent{OrderID: orderID}
+	for _, r := range reservations {
+		event.Items = append(event.Items, StockEventItem{ProductID: r.productID, Quantity: r.quantity})
+	}
+	return outbox.Record(ctx, tx, outbox.AggregateStock, strconv.Itoa(int(orderID)), eventType, event)
+}
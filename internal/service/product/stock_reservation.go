@@ -0,0 +1,591 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	productapi "github.com/bytedance-youthcamp/demo/api/product"
+	"github.com/bytedance-youthcamp/demo/internal/outbox"
+)
+
+// 库存预留状态
+const (
+	reservationStatusReserved  = "RESERVED"
+	reservationStatusCommitted = "COMMITTED"
+	reservationStatusReleased  = "RELEASED"
+)
+
+// stockResult 描述一次库存扣减的结果，失败时 ErrorMessage 非空
+type stockResult struct {
+	ErrorMessage    string
+	FailedProductID int32
+}
+
+// ReduceStock 按订单扣减库存，同一订单重复调用不会重复扣减
+func (s *ProductService) ReduceStock(ctx context.Context, req *productapi.ReduceStockRequest) (*productapi.ReduceStockResponse, error) {
+	items := req.Items
+	if len(items) == 0 && req.ProductId > 0 {
+		items = []*productapi.StockItem{{ProductId: req.ProductId, Quantity: req.Quantity}}
+	}
+
+	result, err := s.deductStock(ctx, req.OrderId, items, reservationStatusCommitted)
+	if err != nil {
+		return nil, err
+	}
+
+	return &productapi.ReduceStockResponse{
+		Success:         result.ErrorMessage == "",
+		ErrorMessage:    result.ErrorMessage,
+		FailedProductId: result.FailedProductID,
+	}, nil
+}
+
+// ReserveStock 为订单预留库存，预留的库存需要确认或释放
+func (s *ProductService) ReserveStock(ctx context.Context, req *productapi.ReserveStockRequest) (*productapi.ReserveStockResponse, error) {
+	result, err := s.deductStock(ctx, req.OrderId, req.Items, reservationStatusReserved)
+	if err != nil {
+		return nil, err
+	}
+
+	return &productapi.ReserveStockResponse{
+		Success:         result.ErrorMessage == "",
+		ErrorMessage:    result.ErrorMessage,
+		FailedProductId: result.FailedProductID,
+	}, nil
+}
+
+// ReleaseStock 释放订单尚未确认的预留库存
+func (s *ProductService) ReleaseStock(ctx context.Context, req *productapi.ReleaseStockRequest) (*productapi.ReleaseStockResponse, error) {
+	if req.OrderId <= 0 {
+		return &productapi.ReleaseStockResponse{
+			Success:      false,
+			ErrorMessage: "订单ID无效",
+		}, nil
+	}
+
+	// 开始事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	reservations, err := listReservations(ctx, tx, req.OrderId, reservationStatusReserved)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var released []reservation
+	for _, r := range reservations {
+		// 先切换预留状态，只有切换成功的一方才归还库存，避免并发释放时重复归还
+		result, err := tx.ExecContext(ctx,
+			"UPDATE stock_reservations SET status = $1, updated_at = $2 WHERE id = $3 AND status = $4",
+			reservationStatusReleased, now, r.id, reservationStatusReserved)
+		if err != nil {
+			return nil, fmt.Errorf("failed to release reservation: %w", err)
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE products SET stock = stock + $1, updated_at = $2 WHERE id = $3",
+			r.quantity, now, r.productID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore stock: %w", err)
+		}
+		released = append(released, r)
+	}
+
+	if err := recordReservationEvent(ctx, tx, outbox.StockReleased, req.OrderId, released); err != nil {
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &productapi.ReleaseStockResponse{
+		Success: true,
+	}, nil
+}
+
+// CommitReservation 确认订单的预留库存，确认后库存不再归还
+func (s *ProductService) CommitReservation(ctx context.Context, req *productapi.CommitReservationRequest) (*productapi.CommitReservationResponse, error) {
+	if req.OrderId <= 0 {
+		return &productapi.CommitReservationResponse{
+			Success:      false,
+			ErrorMessage: "订单ID无效",
+		}, nil
+	}
+
+	// 开始事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var total, released int
+	err = tx.QueryRowContext(ctx,
+		"SELECT COUNT(*), COALESCE(SUM(CASE WHEN status = $1 THEN 1 ELSE 0 END), 0) FROM stock_reservations WHERE order_id = $2",
+		reservationStatusReleased, req.OrderId).Scan(&total, &released)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check reservations: %w", err)
+	}
+	if total == 0 {
+		return &productapi.CommitReservationResponse{
+			Success:      false,
+			ErrorMessage: "预留记录不存在",
+		}, nil
+	}
+	if released > 0 {
+		return &productapi.CommitReservationResponse{
+			Success:      false,
+			ErrorMessage: "预留库存已释放",
+		}, nil
+	}
+
+	if err := commitReservations(ctx, tx, req.OrderId); err != nil {
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &productapi.CommitReservationResponse{
+		Success: true,
+	}, nil
+}
+
+// RestoreStock 归还库存（如退款），同一请求ID重复调用不会重复归还
+func (s *ProductService) RestoreStock(ctx context.Context, req *productapi.RestoreStockRequest) (*productapi.RestoreStockResponse, error) {
+	if req.RequestId == "" {
+		return &productapi.RestoreStockResponse{
+			Success:      false,
+			ErrorMessage: "请求ID不能为空",
+		}, nil
+	}
+	if len(req.Items) == 0 {
+		return &productapi.RestoreStockResponse{
+			Success:      false,
+			ErrorMessage: "库存变更项不能为空",
+		}, nil
+	}
+
+	// 开始事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 幂等检查：请求已处理过则直接返回
+	var processed bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM stock_restorations WHERE request_id = $1)", req.RequestId).Scan(&processed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check restoration: %w", err)
+	}
+	if processed {
+		return &productapi.RestoreStockResponse{
+			Success: true,
+		}, nil
+	}
+
+	// 合并同一商品的数量
+	quantities := make(map[int32]int32)
+	var productIDs []int32
+	for _, item := range req.Items {
+		if item.ProductId <= 0 || item.Quantity <= 0 {
+			return &productapi.RestoreStockResponse{
+				Success:      false,
+				ErrorMessage: "库存变更项无效",
+			}, nil
+		}
+		if _, ok := quantities[item.ProductId]; !ok {
+			productIDs = append(productIDs, item.ProductId)
+		}
+		quantities[item.ProductId] += item.Quantity
+	}
+
+	now := time.Now()
+	for _, productID := range productIDs {
+		quantity := quantities[productID]
+
+		// 记录恢复请求，唯一约束防止并发的重复请求
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO stock_restorations (request_id, product_id, quantity, created_at)
+			VALUES ($1, $2, $3, $4)
+		`, req.RequestId, productID, quantity, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record restoration: %w", err)
+		}
+
+		result, err := tx.ExecContext(ctx,
+			"UPDATE products SET stock = stock + $1, updated_at = $2 WHERE id = $3",
+			quantity, now, productID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore stock: %w", err)
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return &productapi.RestoreStockResponse{
+				Success:      false,
+				ErrorMessage: "商品不存在",
+			}, nil
+		}
+	}
+
+	event := StockEvent{RequestID: req.RequestId}
+	for _, productID := range productIDs {
+		event.Items = append(event.Items, StockEventItem{ProductID: productID, Quantity: quantities[productID]})
+	}
+	if err := outbox.Record(ctx, tx, outbox.AggregateStock, req.RequestId, outbox.StockRestored, event); err != nil {
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &productapi.RestoreStockResponse{
+		Success: true,
+	}, nil
+}
+
+// AdjustReservation 将订单尚未确认的预留库存调整为请求中的数量：减少的部分归还库存，增加的部分按库存条件扣减，
+// 任一商品库存不足则整体回滚。订单没有预留记录时不做修改并返回成功，预留已确认或已释放时返回失败
+func (s *ProductService) AdjustReservation(ctx context.Context, req *productapi.AdjustReservationRequest) (*productapi.AdjustReservationResponse, error) {
+	if req.OrderId <= 0 {
+		return &productapi.AdjustReservationResponse{
+			Success:      false,
+			ErrorMessage: "订单ID无效",
+		}, nil
+	}
+	if len(req.Items) == 0 {
+		return &productapi.AdjustReservationResponse{
+			Success:      false,
+			ErrorMessage: "库存变更项不能为空",
+		}, nil
+	}
+
+	// 合并同一商品的数量
+	targets := make(map[int32]int32)
+	for _, item := range req.Items {
+		if item.ProductId <= 0 {
+			return &productapi.AdjustReservationResponse{
+				Success:      false,
+				ErrorMessage: "商品ID无效",
+			}, nil
+		}
+		if item.Quantity <= 0 {
+			return &productapi.AdjustReservationResponse{
+				Success:         false,
+				ErrorMessage:    "商品数量必须大于0",
+				FailedProductId: item.ProductId,
+			}, nil
+		}
+		targets[item.ProductId] += item.Quantity
+	}
+
+	// 开始事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	existing, err := listReservations(ctx, tx, req.OrderId, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		return &productapi.AdjustReservationResponse{
+			Success:  true,
+			Reserved: false,
+		}, nil
+	}
+
+	current := make(map[int32]reservation, len(existing))
+	for _, r := range existing {
+		switch r.status {
+		case reservationStatusCommitted:
+			return &productapi.AdjustReservationResponse{
+				Success:      false,
+				ErrorMessage: "预留库存已确认",
+			}, nil
+		case reservationStatusReleased:
+			return &productapi.AdjustReservationResponse{
+				Success:      false,
+				ErrorMessage: "预留库存已释放",
+			}, nil
+		}
+		current[r.productID] = r
+	}
+
+	// 按商品ID排序以固定加锁顺序
+	productIDs := make([]int32, 0, len(targets)+len(current))
+	for id := range targets {
+		productIDs = append(productIDs, id)
+	}
+	for id := range current {
+		if _, ok := targets[id]; !ok {
+			productIDs = append(productIDs, id)
+		}
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	now := time.Now()
+	for _, productID := range productIDs {
+		target := targets[productID]
+		r, reserved := current[productID]
+		delta := target - r.quantity
+
+		switch {
+		case delta > 0:
+			// 条件扣减新增的数量，库存不足时不会更新任何行
+			result, err := tx.ExecContext(ctx,
+				"UPDATE products SET stock = stock - $1, updated_at = $2 WHERE id = $3 AND stock >= $1",
+				delta, now, productID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to reduce stock: %w", err)
+			}
+			if affected, _ := result.RowsAffected(); affected == 0 {
+				var exists bool
+				err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)", productID).Scan(&exists)
+				if err != nil {
+					return nil, fmt.Errorf("failed to check product existence: %w", err)
+				}
+				errorMessage := "商品库存不足"
+				if !exists {
+					errorMessage = "商品不存在"
+				}
+				return &productapi.AdjustReservationResponse{
+					Success:         false,
+					ErrorMessage:    errorMessage,
+					FailedProductId: productID,
+				}, nil
+			}
+		case delta < 0:
+			_, err := tx.ExecContext(ctx,
+				"UPDATE products SET stock = stock + $1, updated_at = $2 WHERE id = $3",
+				-delta, now, productID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to restore stock: %w", err)
+			}
+		default:
+			continue
+		}
+
+		// 条件更新预留记录，预留已被并发释放或确认时整体回滚
+		var result sql.Result
+		switch {
+		case !reserved:
+			result, err = tx.ExecContext(ctx, `
+				INSERT INTO stock_reservations (order_id, product_id, quantity, status, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6)
+			`, req.OrderId, productID, target, reservationStatusReserved, now, now)
+		case target == 0:
+			result, err = tx.ExecContext(ctx,
+				"DELETE FROM stock_reservations WHERE id = $1 AND status = $2",
+				r.id, reservationStatusReserved)
+		default:
+			result, err = tx.ExecContext(ctx,
+				"UPDATE stock_reservations SET quantity = $1, updated_at = $2 WHERE id = $3 AND status = $4",
+				target, now, r.id, reservationStatusReserved)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to adjust reservation: %w", err)
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return &productapi.AdjustReservationResponse{
+				Success:      false,
+				ErrorMessage: "预留库存已变更",
+			}, nil
+		}
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &productapi.AdjustReservationResponse{
+		Success:  true,
+		Reserved: true,
+	}, nil
+}
+
+// deductStock 在一个事务中对每个商品执行条件扣减并记录预留，
+// 任一商品库存不足则整体回滚；订单已有预留记录时视为重试直接返回
+func (s *ProductService) deductStock(ctx context.Context, orderID int32, items []*productapi.StockItem, status string) (*stockResult, error) {
+	if orderID <= 0 {
+		return &stockResult{ErrorMessage: "订单ID无效"}, nil
+	}
+	if len(items) == 0 {
+		return &stockResult{ErrorMessage: "库存变更项不能为空"}, nil
+	}
+
+	// 合并同一商品的数量，并按商品ID排序以固定加锁顺序
+	quantities := make(map[int32]int32)
+	for _, item := range items {
+		if item.ProductId <= 0 {
+			return &stockResult{ErrorMessage: "商品ID无效"}, nil
+		}
+		if item.Quantity <= 0 {
+			return &stockResult{ErrorMessage: "商品数量必须大于0", FailedProductID: item.ProductId}, nil
+		}
+		quantities[item.ProductId] += item.Quantity
+	}
+	productIDs := make([]int32, 0, len(quantities))
+	for id := range quantities {
+		productIDs = append(productIDs, id)
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	// 开始事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 幂等检查：订单已有预留记录说明是重试
+	existing, err := listReservations(ctx, tx, orderID, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		for _, r := range existing {
+			if r.status == reservationStatusReleased {
+				return &stockResult{ErrorMessage: "预留库存已释放"}, nil
+			}
+		}
+		// 扣减请求遇到已预留的订单时直接确认预留
+		if status == reservationStatusCommitted {
+			if err := commitReservations(ctx, tx, orderID); err != nil {
+				return nil, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return &stockResult{}, nil
+	}
+
+	now := time.Now()
+	deducted := make([]reservation, 0, len(productIDs))
+	for _, productID := range productIDs {
+		quantity := quantities[productID]
+
+		// 条件扣减，库存不足时不会更新任何行
+		result, err := tx.ExecContext(ctx,
+			"UPDATE products SET stock = stock - $1, updated_at = $2 WHERE id = $3 AND stock >= $1",
+			quantity, now, productID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reduce stock: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if affected == 0 {
+			var exists bool
+			err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)", productID).Scan(&exists)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check product existence: %w", err)
+			}
+			if !exists {
+				return &stockResult{ErrorMessage: "商品不存在", FailedProductID: productID}, nil
+			}
+			return &stockResult{ErrorMessage: "商品库存不足", FailedProductID: productID}, nil
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO stock_reservations (order_id, product_id, quantity, status, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, orderID, productID, quantity, status, now, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record reservation: %w", err)
+		}
+		deducted = append(deducted, reservation{productID: productID, quantity: quantity, status: status})
+	}
+
+	eventType := outbox.StockReserved
+	if status == reservationStatusCommitted {
+		eventType = outbox.StockReduced
+	}
+	if err := recordReservationEvent(ctx, tx, eventType, orderID, deducted); err != nil {
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &stockResult{}, nil
+}
+
+// commitReservations 确认订单所有尚未确认的预留并写入 StockReduced 事件
+func commitReservations(ctx context.Context, tx *sql.Tx, orderID int32) error {
+	reserved, err := listReservations(ctx, tx, orderID, reservationStatusReserved)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE stock_reservations SET status = $1, updated_at = $2 WHERE order_id = $3 AND status = $4",
+		reservationStatusCommitted, time.Now(), orderID, reservationStatusReserved)
+	if err != nil {
+		return fmt.Errorf("failed to commit reservation: %w", err)
+	}
+
+	return recordReservationEvent(ctx, tx, outbox.StockReduced, orderID, reserved)
+}
+
+type reservation struct {
+	id        int64
+	productID int32
+	quantity  int32
+	status    string
+}
+
+// listReservations 查询订单的预留记录，status 为空时返回全部状态
+func listReservations(ctx context.Context, tx *sql.Tx, orderID int32, status string) ([]reservation, error) {
+	query := "SELECT id, product_id, quantity, status FROM stock_reservations WHERE order_id = $1"
+	args := []interface{}{orderID}
+	if status != "" {
+		query += " AND status = $2"
+		args = append(args, status)
+	}
+	query += " ORDER BY product_id"
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reservations: %w", err)
+	}
+	defer rows.Close()
+
+	var reservations []reservation
+	for rows.Next() {
+		var r reservation
+		if err := rows.Scan(&r.id, &r.productID, &r.quantity, &r.status); err != nil {
+			return nil, fmt.Errorf("failed to scan reservation: %w", err)
+		}
+		reservations = append(reservations, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reservations: %w", err)
+	}
+
+	return reservations, nil
+}
@@ -0,0 +1,231 @@
+package promotion
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	promotionapi "github.com/bytedance-youthcamp/demo/api/promotion"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+// coupon 是优惠券规则，金额字段为 0 表示不限，与 Currency 币种相同
+type coupon struct {
+	ID               int64
+	Code             string
+	Description      string
+	DiscountType     promotionapi.DiscountType
+	PercentOff       int32
+	AmountOffMinor   int64
+	MaxDiscountMinor int64
+	MinSpendMinor    int64
+	Currency         string
+	ProductIDs       []int32
+	Categories       []string
+	UsageLimit       int32
+	PerUserLimit     int32
+	UsedCount        int32
+	StartsAt         *time.Time
+	EndsAt           *time.Time
+	Active           bool
+}
+
+// pricingItem 是待计价的商品
+type pricingItem struct {
+	ProductID int32
+	Category  string
+	Quantity  int32
+	Price     money.Money
+}
+
+// itemDiscount 是分摊到一个商品上的优惠
+type itemDiscount struct {
+	ProductID int32
+	Amount    money.Money
+}
+
+// breakdown 是计价结果
+type breakdown struct {
+	Subtotal      money.Money
+	Discount      money.Money
+	Total         money.Money
+	ItemDiscounts []itemDiscount
+}
+
+// validate 检查优惠券规则是否合法，返回的错误信息非空表示不合法
+func (c *coupon) validate() string {
+	if c.Code == "" {
+		return "优惠券代码不能为空"
+	}
+	switch c.DiscountType {
+	case promotionapi.DiscountType_DISCOUNT_TYPE_PERCENTAGE:
+		if c.PercentOff <= 0 || c.PercentOff > 100 {
+			return "折扣比例必须在1到100之间"
+		}
+	case promotionapi.DiscountType_DISCOUNT_TYPE_FIXED:
+		if c.AmountOffMinor <= 0 {
+			return "减免金额必须大于0"
+		}
+	default:
+		return "不支持的优惠方式"
+	}
+	if c.MaxDiscountMinor < 0 || c.MinSpendMinor < 0 {
+		return "优惠券金额不能为负数"
+	}
+	if c.UsageLimit < 0 || c.PerUserLimit < 0 {
+		return "使用次数上限不能为负数"
+	}
+	if c.StartsAt != nil && c.EndsAt != nil && !c.StartsAt.Before(*c.EndsAt) {
+		return "优惠券有效期无效"
+	}
+	return ""
+}
+
+// available 检查优惠券在 now 时是否可用，不检查使用次数
+func (c *coupon) available(now time.Time) string {
+	if !c.Active {
+		return "优惠券已停用"
+	}
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return "优惠券尚未生效"
+	}
+	if c.EndsAt != nil && !now.Before(*c.EndsAt) {
+		return "优惠券已过期"
+	}
+	return ""
+}
+
+// applies 判断优惠券是否适用于商品，未限定商品和分类时适用所有商品
+func (c *coupon) applies(item pricingItem) bool {
+	if len(c.ProductIDs) == 0 && len(c.Categories) == 0 {
+		return true
+	}
+	for _, id := range c.ProductIDs {
+		if id == item.ProductID {
+			return true
+		}
+	}
+	for _, category := range c.Categories {
+		if category != "" && category == item.Category {
+			return true
+		}
+	}
+	return false
+}
+
+// subtotal 计算商品原价合计，商品必须使用相同币种
+func subtotal(items []pricingItem) (money.Money, string) {
+	if len(items) == 0 {
+		return money.Money{}, "商品列表不能为空"
+	}
+	total := money.Zero(items[0].Price.Currency)
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return money.Money{}, "商品数量必须大于0"
+		}
+		var err error
+		total, err = total.Add(item.Price.Mul(int64(item.Quantity)))
+		if errors.Is(err, money.ErrCurrencyMismatch) {
+			return money.Money{}, "商品币种不一致"
+		}
+	}
+	return total, ""
+}
+
+// price 计算商品使用优惠券后的价格，c 为 nil 时只计算原价。
+// 优惠只作用于适用的商品，并按各商品金额比例分摊，分摊后的合计等于优惠总额
+func price(items []pricingItem, c *coupon, now time.Time) (*breakdown, string) {
+	total, errorMessage := subtotal(items)
+	if errorMessage != "" {
+		return nil, errorMessage
+	}
+	currency := total.Currency
+	result := &breakdown{Subtotal: total, Discount: money.Zero(currency), Total: total}
+	if c == nil {
+		return result, ""
+	}
+
+	if errorMessage := c.available(now); errorMessage != "" {
+		return nil, errorMessage
+	}
+	if c.Currency != "" && c.Currency != currency && (c.AmountOffMinor > 0 || c.MaxDiscountMinor > 0 || c.MinSpendMinor > 0) {
+		return nil, "优惠券币种与商品不一致"
+	}
+
+	// 适用商品的金额
+	var eligible []int
+	var eligibleTotal int64
+	for i, item := range items {
+		if c.applies(item) {
+			eligible = append(eligible, i)
+			eligibleTotal += item.Price.Mul(int64(item.Quantity)).Amount
+		}
+	}
+	if len(eligible) == 0 {
+		return nil, "优惠券不适用于所选商品"
+	}
+	if eligibleTotal < c.MinSpendMinor {
+		return nil, "未达到优惠券最低消费金额"
+	}
+
+	var discount int64
+	switch c.DiscountType {
+	case promotionapi.DiscountType_DISCOUNT_TYPE_PERCENTAGE:
+		discount = eligibleTotal * int64(c.PercentOff) / 100
+		if c.MaxDiscountMinor > 0 {
+			discount = min(discount, c.MaxDiscountMinor)
+		}
+	case promotionapi.DiscountType_DISCOUNT_TYPE_FIXED:
+		discount = min(c.AmountOffMinor, eligibleTotal)
+	}
+	if discount <= 0 {
+		return result, ""
+	}
+
+	result.Discount = money.New(discount, currency)
+	result.Total = money.New(total.Amount-discount, currency)
+	result.ItemDiscounts = allocate(items, eligible, eligibleTotal, discount)
+	return result, ""
+}
+
+// allocate 按金额比例把优惠分摊到适用的商品上，舍去的零头按余数从大到小逐一补足
+func allocate(items []pricingItem, eligible []int, eligibleTotal, discount int64) []itemDiscount {
+	type share struct {
+		index     int
+		amount    int64
+		remainder int64
+	}
+	shares := make([]share, len(eligible))
+	allocated := int64(0)
+	for i, index := range eligible {
+		line := items[index].Price.Mul(int64(items[index].Quantity)).Amount
+		shares[i] = share{
+			index:     index,
+			amount:    line * discount / eligibleTotal,
+			remainder: line * discount % eligibleTotal,
+		}
+		allocated += shares[i].amount
+	}
+
+	order := make([]int, len(shares))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return shares[order[a]].remainder > shares[order[b]].remainder })
+	for i := 0; allocated < discount; i++ {
+		shares[order[i%len(order)]].amount++
+		allocated++
+	}
+
+	var result []itemDiscount
+	for _, s := range shares {
+		if s.amount > 0 {
+			item := items[s.index]
+			result = append(result, itemDiscount{
+				ProductID: item.ProductID,
+				Amount:    money.New(s.amount, item.Price.Currency),
+			})
+		}
+	}
+	return result
+}
@@ -0,0 +1,412 @@
+package promotion
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	cartpb "github.com/bytedance-youthcamp/demo/api/cart"
+	productpb "github.com/bytedance-youthcamp/demo/api/product"
+	promotionapi "github.com/bytedance-youthcamp/demo/api/promotion"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+)
+
+// PromotionService 管理优惠券并计算购物车和订单的优惠，
+// 订单服务下单时通过 ApplyCoupon 占用优惠券，取消订单时通过 ReleaseCoupon 归还
+type PromotionService struct {
+	promotionapi.UnimplementedPromotionServiceServer
+	store         *couponStore
+	cartClient    cartpb.CartServiceClient
+	productClient productpb.ProductServiceClient
+	now           func() time.Time
+}
+
+type Option func(*PromotionService)
+
+func WithDatabase(db *sql.DB) Option {
+	return func(s *PromotionService) {
+		s.store = &couponStore{db: db}
+	}
+}
+
+// WithCartClient 注入购物车服务客户端，未注入时不能按购物车ID计价
+func WithCartClient(client cartpb.CartServiceClient) Option {
+	return func(s *PromotionService) {
+		s.cartClient = client
+	}
+}
+
+// WithProductClient 注入商品服务客户端，用于查询购物车中商品的分类
+func WithProductClient(client productpb.ProductServiceClient) Option {
+	return func(s *PromotionService) {
+		s.productClient = client
+	}
+}
+
+func NewPromotionService(opts ...Option) (*PromotionService, error) {
+	service := &PromotionService{
+		now: time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(service)
+	}
+
+	if service.store == nil {
+		return nil, errors.New("promotion service requires a database")
+	}
+	if (service.cartClient == nil) != (service.productClient == nil) {
+		return nil, errors.New("promotion service requires both cart and product clients to price carts")
+	}
+
+	return service, nil
+}
+
+// CreateCoupon 创建优惠券，优惠券代码不区分大小写
+func (s *PromotionService) CreateCoupon(ctx context.Context, req *promotionapi.CreateCouponRequest) (*promotionapi.CreateCouponResponse, error) {
+	if req.Coupon == nil {
+		return &promotionapi.CreateCouponResponse{
+			Success:      false,
+			ErrorMessage: "优惠券信息不能为空",
+		}, nil
+	}
+
+	c, errorMessage := fromAPICoupon(req.Coupon)
+	if errorMessage == "" {
+		errorMessage = c.validate()
+	}
+	if errorMessage != "" {
+		return &promotionapi.CreateCouponResponse{
+			Success:      false,
+			ErrorMessage: errorMessage,
+		}, nil
+	}
+
+	if _, err := s.store.getByCode(ctx, c.Code); err == nil {
+		return &promotionapi.CreateCouponResponse{
+			Success:      false,
+			ErrorMessage: "优惠券代码已存在",
+		}, nil
+	} else if !errors.Is(err, errCouponNotFound) {
+		return nil, err
+	}
+
+	id, err := s.store.create(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &promotionapi.CreateCouponResponse{
+		Success:  true,
+		CouponId: id,
+	}, nil
+}
+
+// GetCoupon 按代码查询优惠券
+func (s *PromotionService) GetCoupon(ctx context.Context, req *promotionapi.GetCouponRequest) (*promotionapi.GetCouponResponse, error) {
+	c, err := s.store.getByCode(ctx, normalizeCode(req.Code))
+	if errors.Is(err, errCouponNotFound) {
+		return &promotionapi.GetCouponResponse{
+			Success:      false,
+			ErrorMessage: "优惠券不存在",
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &promotionapi.GetCouponResponse{
+		Success: true,
+		Coupon:  toAPICoupon(c),
+	}, nil
+}
+
+// PriceCart 计算购物车或商品列表的价格，指定优惠券时校验优惠券是否可用并计算优惠，
+// 只用于展示，不占用优惠券的使用次数
+func (s *PromotionService) PriceCart(ctx context.Context, req *promotionapi.PriceCartRequest) (*promotionapi.PriceCartResponse, error) {
+	items := fromAPIItems(req.Items)
+	if req.CartId > 0 {
+		if s.cartClient == nil {
+			return &promotionapi.PriceCartResponse{
+				Success:      false,
+				ErrorMessage: "暂不支持按购物车计价",
+			}, nil
+		}
+		var errorMessage string
+		var err error
+		items, errorMessage, err = s.loadCart(ctx, req.CartId)
+		if err != nil {
+			return nil, err
+		}
+		if errorMessage != "" {
+			return &promotionapi.PriceCartResponse{
+				Success:      false,
+				ErrorMessage: errorMessage,
+			}, nil
+		}
+	}
+
+	var c *coupon
+	if code := normalizeCode(req.CouponCode); code != "" {
+		var err error
+		c, err = s.store.getByCode(ctx, code)
+		if errors.Is(err, errCouponNotFound) {
+			return &promotionapi.PriceCartResponse{
+				Success:      false,
+				ErrorMessage: "优惠券不存在",
+			}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c.UsageLimit > 0 && c.UsedCount >= c.UsageLimit {
+			return &promotionapi.PriceCartResponse{
+				Success:      false,
+				ErrorMessage: "优惠券使用次数已达上限",
+			}, nil
+		}
+	}
+
+	result, errorMessage := price(items, c, s.now())
+	if errorMessage != "" {
+		return &promotionapi.PriceCartResponse{
+			Success:      false,
+			ErrorMessage: errorMessage,
+		}, nil
+	}
+
+	return &promotionapi.PriceCartResponse{
+		Success:   true,
+		Breakdown: toAPIBreakdown(result),
+	}, nil
+}
+
+// ApplyCoupon 计算商品使用优惠券后的价格并占用一次使用次数，
+// 订单创建失败或取消时调用方需要通过 ReleaseCoupon 释放
+func (s *PromotionService) ApplyCoupon(ctx context.Context, req *promotionapi.ApplyCouponRequest) (*promotionapi.ApplyCouponResponse, error) {
+	code := normalizeCode(req.CouponCode)
+	if code == "" {
+		return &promotionapi.ApplyCouponResponse{
+			Success:      false,
+			ErrorMessage: "优惠券代码不能为空",
+		}, nil
+	}
+	if req.UserId <= 0 {
+		return &promotionapi.ApplyCouponResponse{
+			Success:      false,
+			ErrorMessage: "用户ID无效",
+		}, nil
+	}
+
+	items := fromAPIItems(req.Items)
+	now := s.now()
+	r, errorMessage, err := s.store.redeem(ctx, code, req.UserId, func(c *coupon) (*breakdown, string) {
+		return price(items, c, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if errorMessage != "" {
+		return &promotionapi.ApplyCouponResponse{
+			Success:      false,
+			ErrorMessage: errorMessage,
+		}, nil
+	}
+
+	log.Printf("Coupon %s applied for user %d, redemption %s, discount %s", code, req.UserId, r.ID, r.Discount)
+	return &promotionapi.ApplyCouponResponse{
+		Success:      true,
+		RedemptionId: r.ID,
+		Breakdown:    toAPIBreakdown(r.Breakdown),
+	}, nil
+}
+
+// ReleaseCoupon 释放优惠券使用记录并归还使用次数，已释放的记录直接返回成功
+func (s *PromotionService) ReleaseCoupon(ctx context.Context, req *promotionapi.ReleaseCouponRequest) (*promotionapi.ReleaseCouponResponse, error) {
+	err := s.store.release(ctx, req.RedemptionId)
+	if errors.Is(err, errRedemptionNotFound) {
+		return &promotionapi.ReleaseCouponResponse{
+			Success:      false,
+			ErrorMessage: "优惠券使用记录不存在",
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &promotionapi.ReleaseCouponResponse{
+		Success: true,
+	}, nil
+}
+
+// loadCart 查询购物车中的商品，并从商品服务补充商品分类
+func (s *PromotionService) loadCart(ctx context.Context, cartID int32) ([]pricingItem, string, error) {
+	cartResp, err := s.cartClient.GetCart(ctx, &cartpb.GetCartRequest{CartId: cartID})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get cart: %w", err)
+	}
+	if !cartResp.Success || cartResp.Cart == nil {
+		return nil, "购物车不存在", nil
+	}
+	if len(cartResp.Cart.Items) == 0 {
+		return nil, "购物车为空", nil
+	}
+
+	productIDs := make([]int32, len(cartResp.Cart.Items))
+	for i, item := range cartResp.Cart.Items {
+		productIDs[i] = item.ProductId
+	}
+	productsResp, err := s.productClient.GetProducts(ctx, &productpb.GetProductsRequest{ProductIds: productIDs})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get products: %w", err)
+	}
+	if !productsResp.Success {
+		return nil, "查询商品信息失败", nil
+	}
+	categories := make(map[int32]string, len(productsResp.Products))
+	for _, product := range productsResp.Products {
+		categories[product.Id] = product.Category
+	}
+
+	items := make([]pricingItem, len(cartResp.Cart.Items))
+	for i, item := range cartResp.Cart.Items {
+		var price money.Money
+		if item.PriceMoney != nil {
+			price = money.New(item.PriceMoney.Amount, item.PriceMoney.Currency)
+		} else {
+			price = money.FromFloat(item.Price, money.DefaultCurrency)
+		}
+		items[i] = pricingItem{
+			ProductID: item.ProductId,
+			Category:  categories[item.ProductId],
+			Quantity:  item.Quantity,
+			Price:     price,
+		}
+	}
+	return items, "", nil
+}
+
+// normalizeCode 优惠券代码不区分大小写，统一保存为大写
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func fromAPICoupon(c *promotionapi.Coupon) (*coupon, string) {
+	result := &coupon{
+		Code:         normalizeCode(c.Code),
+		Description:  c.Description,
+		DiscountType: c.DiscountType,
+		PercentOff:   c.PercentOff,
+		ProductIDs:   c.ProductIds,
+		Categories:   c.Categories,
+		UsageLimit:   c.UsageLimit,
+		PerUserLimit: c.PerUserLimit,
+		Active:       c.Active,
+	}
+
+	// 金额字段必须使用相同币种
+	for _, amount := range []struct {
+		value *promotionapi.Money
+		dest  *int64
+	}{
+		{c.AmountOff, &result.AmountOffMinor},
+		{c.MaxDiscount, &result.MaxDiscountMinor},
+		{c.MinSpend, &result.MinSpendMinor},
+	} {
+		if amount.value == nil || amount.value.Amount == 0 {
+			continue
+		}
+		currency := strings.ToUpper(amount.value.Currency)
+		if currency == "" {
+			return nil, "优惠券金额缺少币种"
+		}
+		if result.Currency != "" && result.Currency != currency {
+			return nil, "优惠券金额币种不一致"
+		}
+		result.Currency = currency
+		*amount.dest = amount.value.Amount
+	}
+
+	if c.StartsAt > 0 {
+		startsAt := time.UnixMilli(c.StartsAt)
+		result.StartsAt = &startsAt
+	}
+	if c.EndsAt > 0 {
+		endsAt := time.UnixMilli(c.EndsAt)
+		result.EndsAt = &endsAt
+	}
+	return result, ""
+}
+
+func toAPICoupon(c *coupon) *promotionapi.Coupon {
+	result := &promotionapi.Coupon{
+		Id:           c.ID,
+		Code:         c.Code,
+		Description:  c.Description,
+		DiscountType: c.DiscountType,
+		PercentOff:   c.PercentOff,
+		ProductIds:   c.ProductIDs,
+		Categories:   c.Categories,
+		UsageLimit:   c.UsageLimit,
+		PerUserLimit: c.PerUserLimit,
+		UsedCount:    c.UsedCount,
+		Active:       c.Active,
+	}
+	if c.AmountOffMinor > 0 {
+		result.AmountOff = toAPIMoney(money.New(c.AmountOffMinor, c.Currency))
+	}
+	if c.MaxDiscountMinor > 0 {
+		result.MaxDiscount = toAPIMoney(money.New(c.MaxDiscountMinor, c.Currency))
+	}
+	if c.MinSpendMinor > 0 {
+		result.MinSpend = toAPIMoney(money.New(c.MinSpendMinor, c.Currency))
+	}
+	if c.StartsAt != nil {
+		result.StartsAt = c.StartsAt.UnixMilli()
+	}
+	if c.EndsAt != nil {
+		result.EndsAt = c.EndsAt.UnixMilli()
+	}
+	return result
+}
+
+func fromAPIItems(items []*promotionapi.PricingItem) []pricingItem {
+	result := make([]pricingItem, len(items))
+	for i, item := range items {
+		result[i] = pricingItem{
+			ProductID: item.ProductId,
+			Category:  item.Category,
+			Quantity:  item.Quantity,
+		}
+		if item.Price != nil {
+			result[i].Price = money.New(item.Price.Amount, item.Price.Currency)
+		}
+	}
+	return result
+}
+
+func toAPIBreakdown(b *breakdown) *promotionapi.PriceBreakdown {
+	result := &promotionapi.PriceBreakdown{
+		Subtotal: toAPIMoney(b.Subtotal),
+		Discount: toAPIMoney(b.Discount),
+		Total:    toAPIMoney(b.Total),
+	}
+	for _, item := range b.ItemDiscounts {
+		result.ItemDiscounts = append(result.ItemDiscounts, &promotionapi.ItemDiscount{
+			ProductId: item.ProductID,
+			Amount:    toAPIMoney(item.Amount),
+		})
+	}
+	return result
+}
+
+func toAPIMoney(m money.Money) *promotionapi.Money {
+	return &promotionapi.Money{
+		Amount:   m.Amount,
+		Currency: m.Currency,
+	}
+}
@@ -0,0 +1,305 @@
+package promotion
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	cartpb "github.com/bytedance-youthcamp/demo/api/cart"
+	productpb "github.com/bytedance-youthcamp/demo/api/product"
+	promotionapi "github.com/bytedance-youthcamp/demo/api/promotion"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type mockCartClient struct {
+	mock.Mock
+	cartpb.CartServiceClient
+}
+
+func (m *mockCartClient) GetCart(ctx context.Context, in *cartpb.GetCartRequest, opts ...grpc.CallOption) (*cartpb.GetCartResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*cartpb.GetCartResponse), args.Error(1)
+}
+
+type mockProductClient struct {
+	mock.Mock
+	productpb.ProductServiceClient
+}
+
+func (m *mockProductClient) GetProducts(ctx context.Context, in *productpb.GetProductsRequest, opts ...grpc.CallOption) (*productpb.GetProductsResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*productpb.GetProductsResponse), args.Error(1)
+}
+
+func setupTestDatabase(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err, "Failed to open database")
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE coupons (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			code TEXT NOT NULL UNIQUE,
+			description TEXT NOT NULL DEFAULT '',
+			discount_type INTEGER NOT NULL,
+			percent_off INTEGER NOT NULL DEFAULT 0,
+			amount_off_minor INTEGER NOT NULL DEFAULT 0,
+			max_discount_minor INTEGER NOT NULL DEFAULT 0,
+			min_spend_minor INTEGER NOT NULL DEFAULT 0,
+			currency TEXT NOT NULL DEFAULT '',
+			product_ids TEXT,
+			categories TEXT,
+			usage_limit INTEGER NOT NULL DEFAULT 0,
+			per_user_limit INTEGER NOT NULL DEFAULT 0,
+			used_count INTEGER NOT NULL DEFAULT 0,
+			starts_at DATETIME,
+			ends_at DATETIME,
+			active BOOLEAN NOT NULL DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE coupon_redemptions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			redemption_id TEXT NOT NULL UNIQUE,
+			coupon_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			discount_minor INTEGER NOT NULL,
+			currency TEXT NOT NULL,
+			status TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			released_at DATETIME
+		);
+	`)
+	require.NoError(t, err, "Failed to create tables")
+
+	return db
+}
+
+func setupPromotionService(t *testing.T, opts ...Option) *PromotionService {
+	service, err := NewPromotionService(append([]Option{WithDatabase(setupTestDatabase(t))}, opts...)...)
+	require.NoError(t, err, "Failed to create PromotionService")
+	return service
+}
+
+func cny(amount int64) *promotionapi.Money {
+	return &promotionapi.Money{Amount: amount, Currency: "CNY"}
+}
+
+func createCoupon(t *testing.T, service *PromotionService, c *promotionapi.Coupon) {
+	resp, err := service.CreateCoupon(context.Background(), &promotionapi.CreateCouponRequest{Coupon: c})
+	require.NoError(t, err)
+	require.True(t, resp.Success, resp.ErrorMessage)
+}
+
+func testItems() []*promotionapi.PricingItem {
+	return []*promotionapi.PricingItem{
+		{ProductId: 1, Category: "electronics", Quantity: 2, Price: cny(10000)},
+		{ProductId: 2, Category: "electronics", Quantity: 1, Price: cny(5001)},
+		{ProductId: 3, Category: "books", Quantity: 1, Price: cny(3000)},
+	}
+}
+
+func TestPrice(t *testing.T) {
+	now := time.Now()
+	items := fromAPIItems(testItems())
+
+	t.Run("percentage discount on category is allocated exactly", func(t *testing.T) {
+		c := &coupon{DiscountType: promotionapi.DiscountType_DISCOUNT_TYPE_PERCENTAGE, PercentOff: 10, Categories: []string{"electronics"}, Active: true}
+		result, errorMessage := price(items, c, now)
+		require.Empty(t, errorMessage)
+		assert.Equal(t, int64(28001), result.Subtotal.Amount)
+		assert.Equal(t, int64(2500), result.Discount.Amount)
+		assert.Equal(t, int64(25501), result.Total.Amount)
+
+		require.Len(t, result.ItemDiscounts, 2)
+		assert.Equal(t, int32(1), result.ItemDiscounts[0].ProductID)
+		assert.Equal(t, int32(2), result.ItemDiscounts[1].ProductID)
+		assert.Equal(t, int64(2500), result.ItemDiscounts[0].Amount.Amount+result.ItemDiscounts[1].Amount.Amount)
+	})
+
+	t.Run("percentage discount is capped", func(t *testing.T) {
+		c := &coupon{DiscountType: promotionapi.DiscountType_DISCOUNT_TYPE_PERCENTAGE, PercentOff: 50, MaxDiscountMinor: 1000, Currency: "CNY", Active: true}
+		result, errorMessage := price(items, c, now)
+		require.Empty(t, errorMessage)
+		assert.Equal(t, int64(1000), result.Discount.Amount)
+	})
+
+	t.Run("fixed discount does not exceed eligible amount", func(t *testing.T) {
+		c := &coupon{DiscountType: promotionapi.DiscountType_DISCOUNT_TYPE_FIXED, AmountOffMinor: 5000, Currency: "CNY", ProductIDs: []int32{3}, Active: true}
+		result, errorMessage := price(items, c, now)
+		require.Empty(t, errorMessage)
+		assert.Equal(t, int64(3000), result.Discount.Amount)
+		require.Len(t, result.ItemDiscounts, 1)
+		assert.Equal(t, int32(3), result.ItemDiscounts[0].ProductID)
+	})
+
+	t.Run("rejects unusable coupons", func(t *testing.T) {
+		past := now.Add(-time.Hour)
+		future := now.Add(time.Hour)
+		for _, tc := range []struct {
+			coupon       *coupon
+			errorMessage string
+		}{
+			{&coupon{DiscountType: promotionapi.DiscountType_DISCOUNT_TYPE_PERCENTAGE, PercentOff: 10}, "优惠券已停用"},
+			{&coupon{DiscountType: promotionapi.DiscountType_DISCOUNT_TYPE_PERCENTAGE, PercentOff: 10, Active: true, StartsAt: &future}, "优惠券尚未生效"},
+			{&coupon{DiscountType: promotionapi.DiscountType_DISCOUNT_TYPE_PERCENTAGE, PercentOff: 10, Active: true, EndsAt: &past}, "优惠券已过期"},
+			{&coupon{DiscountType: promotionapi.DiscountType_DISCOUNT_TYPE_PERCENTAGE, PercentOff: 10, Active: true, Categories: []string{"toys"}}, "优惠券不适用于所选商品"},
+			{&coupon{DiscountType: promotionapi.DiscountType_DISCOUNT_TYPE_FIXED, AmountOffMinor: 100, MinSpendMinor: 5000, Currency: "CNY", Active: true, ProductIDs: []int32{3}}, "未达到优惠券最低消费金额"},
+			{&coupon{DiscountType: promotionapi.DiscountType_DISCOUNT_TYPE_FIXED, AmountOffMinor: 100, Currency: "USD", Active: true}, "优惠券币种与商品不一致"},
+		} {
+			_, errorMessage := price(items, tc.coupon, now)
+			assert.Equal(t, tc.errorMessage, errorMessage)
+		}
+	})
+}
+
+func TestCreateCoupon(t *testing.T) {
+	service := setupPromotionService(t)
+	ctx := context.Background()
+
+	createCoupon(t, service, &promotionapi.Coupon{
+		Code:         "save10",
+		DiscountType: promotionapi.DiscountType_DISCOUNT_TYPE_PERCENTAGE,
+		PercentOff:   10,
+		MaxDiscount:  cny(2000),
+		Categories:   []string{"electronics"},
+		Active:       true,
+	})
+
+	getResp, err := service.GetCoupon(ctx, &promotionapi.GetCouponRequest{Code: "SAVE10"})
+	require.NoError(t, err)
+	require.True(t, getResp.Success, getResp.ErrorMessage)
+	assert.Equal(t, "SAVE10", getResp.Coupon.Code)
+	assert.Equal(t, int64(2000), getResp.Coupon.MaxDiscount.Amount)
+	assert.Equal(t, []string{"electronics"}, getResp.Coupon.Categories)
+
+	for _, tc := range []struct {
+		coupon       *promotionapi.Coupon
+		errorMessage string
+	}{
+		{&promotionapi.Coupon{Code: "SAVE10", PercentOff: 10}, "优惠券代码已存在"},
+		{&promotionapi.Coupon{Code: "BAD", PercentOff: 120}, "折扣比例必须在1到100之间"},
+		{&promotionapi.Coupon{Code: "BAD", DiscountType: promotionapi.DiscountType_DISCOUNT_TYPE_FIXED}, "减免金额必须大于0"},
+		{&promotionapi.Coupon{Code: "BAD", DiscountType: promotionapi.DiscountType_DISCOUNT_TYPE_FIXED, AmountOff: cny(100), MinSpend: &promotionapi.Money{Amount: 100, Currency: "USD"}}, "优惠券金额币种不一致"},
+	} {
+		resp, err := service.CreateCoupon(ctx, &promotionapi.CreateCouponRequest{Coupon: tc.coupon})
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Equal(t, tc.errorMessage, resp.ErrorMessage)
+	}
+}
+
+func TestPriceCart(t *testing.T) {
+	cartClient := new(mockCartClient)
+	productClient := new(mockProductClient)
+	service := setupPromotionService(t, WithCartClient(cartClient), WithProductClient(productClient))
+	ctx := context.Background()
+
+	createCoupon(t, service, &promotionapi.Coupon{
+		Code:         "BOOKS",
+		DiscountType: promotionapi.DiscountType_DISCOUNT_TYPE_FIXED,
+		AmountOff:    cny(500),
+		Categories:   []string{"books"},
+		Active:       true,
+	})
+
+	cartClient.On("GetCart", mock.Anything, mock.Anything).Return(&cartpb.GetCartResponse{
+		Success: true,
+		Cart: &cartpb.Cart{
+			Id: 1,
+			Items: []*cartpb.CartItem{
+				{ProductId: 1, Quantity: 1, PriceMoney: &cartpb.Money{Amount: 10000, Currency: "CNY"}},
+				{ProductId: 3, Quantity: 2, PriceMoney: &cartpb.Money{Amount: 3000, Currency: "CNY"}},
+			},
+		},
+	}, nil)
+	productClient.On("GetProducts", mock.Anything, mock.Anything).Return(&productpb.GetProductsResponse{
+		Success: true,
+		Products: []*productpb.Product{
+			{Id: 1, Category: "electronics"},
+			{Id: 3, Category: "books"},
+		},
+	}, nil)
+
+	resp, err := service.PriceCart(ctx, &promotionapi.PriceCartRequest{UserId: 1, CartId: 1, CouponCode: "books"})
+	require.NoError(t, err)
+	require.True(t, resp.Success, resp.ErrorMessage)
+	assert.Equal(t, int64(16000), resp.Breakdown.Subtotal.Amount)
+	assert.Equal(t, int64(500), resp.Breakdown.Discount.Amount)
+	assert.Equal(t, int64(15500), resp.Breakdown.Total.Amount)
+	require.Len(t, resp.Breakdown.ItemDiscounts, 1)
+	assert.Equal(t, int32(3), resp.Breakdown.ItemDiscounts[0].ProductId)
+
+	// 计价不占用使用次数
+	getResp, err := service.GetCoupon(ctx, &promotionapi.GetCouponRequest{Code: "BOOKS"})
+	require.NoError(t, err)
+	assert.Zero(t, getResp.Coupon.UsedCount)
+
+	resp, err = service.PriceCart(ctx, &promotionapi.PriceCartRequest{UserId: 1, CartId: 1, CouponCode: "missing"})
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.Equal(t, "优惠券不存在", resp.ErrorMessage)
+}
+
+func TestApplyAndReleaseCoupon(t *testing.T) {
+	service := setupPromotionService(t)
+	ctx := context.Background()
+
+	createCoupon(t, service, &promotionapi.Coupon{
+		Code:         "ONCE",
+		DiscountType: promotionapi.DiscountType_DISCOUNT_TYPE_FIXED,
+		AmountOff:    cny(1000),
+		UsageLimit:   2,
+		PerUserLimit: 1,
+		Active:       true,
+	})
+
+	apply := func(userID int32) *promotionapi.ApplyCouponResponse {
+		resp, err := service.ApplyCoupon(ctx, &promotionapi.ApplyCouponRequest{UserId: userID, CouponCode: "ONCE", Items: testItems()})
+		require.NoError(t, err)
+		return resp
+	}
+	usedCount := func() int32 {
+		resp, err := service.GetCoupon(ctx, &promotionapi.GetCouponRequest{Code: "ONCE"})
+		require.NoError(t, err)
+		return resp.Coupon.UsedCount
+	}
+
+	first := apply(1)
+	require.True(t, first.Success, first.ErrorMessage)
+	assert.NotEmpty(t, first.RedemptionId)
+	assert.Equal(t, int64(1000), first.Breakdown.Discount.Amount)
+	assert.Equal(t, int64(27001), first.Breakdown.Total.Amount)
+
+	// 同一用户超过每人上限时不占用次数
+	resp := apply(1)
+	assert.False(t, resp.Success)
+	assert.Equal(t, "已达到该优惠券每个用户的使用次数上限", resp.ErrorMessage)
+	assert.Equal(t, int32(1), usedCount())
+
+	require.True(t, apply(2).Success)
+	resp = apply(3)
+	assert.False(t, resp.Success)
+	assert.Equal(t, "优惠券使用次数已达上限", resp.ErrorMessage)
+
+	// 释放后归还次数，重复释放不会重复归还
+	for i := 0; i < 2; i++ {
+		releaseResp, err := service.ReleaseCoupon(ctx, &promotionapi.ReleaseCouponRequest{RedemptionId: first.RedemptionId})
+		require.NoError(t, err)
+		assert.True(t, releaseResp.Success)
+	}
+	assert.Equal(t, int32(1), usedCount())
+	require.True(t, apply(1).Success)
+
+	releaseResp, err := service.ReleaseCoupon(ctx, &promotionapi.ReleaseCouponRequest{RedemptionId: "missing"})
+	require.NoError(t, err)
+	assert.False(t, releaseResp.Success)
+	assert.Equal(t, "优惠券使用记录不存在", releaseResp.ErrorMessage)
+}
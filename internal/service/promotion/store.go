@@ -0,0 +1,267 @@
+package promotion
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	promotionapi "github.com/bytedance-youthcamp/demo/api/promotion"
+	"github.com/bytedance-youthcamp/demo/internal/money"
+	"github.com/google/uuid"
+)
+
+// 优惠券使用记录状态
+const (
+	redemptionStatusApplied  = "APPLIED"
+	redemptionStatusReleased = "RELEASED"
+)
+
+// errCouponNotFound 表示优惠券不存在
+var errCouponNotFound = errors.New("coupon not found")
+
+// errRedemptionNotFound 表示优惠券使用记录不存在
+var errRedemptionNotFound = errors.New("coupon redemption not found")
+
+// couponStore 负责优惠券和使用记录的持久化，使用次数通过条件更新控制，不会超过上限
+type couponStore struct {
+	db *sql.DB
+}
+
+// redemption 是一次优惠券使用记录
+type redemption struct {
+	ID        string
+	CouponID  int64
+	UserID    int32
+	Discount  money.Money
+	Breakdown *breakdown
+}
+
+func (st *couponStore) create(ctx context.Context, c *coupon) (int64, error) {
+	productIDs, err := json.Marshal(c.ProductIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal coupon products: %w", err)
+	}
+	categories, err := json.Marshal(c.Categories)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal coupon categories: %w", err)
+	}
+
+	now := time.Now()
+	result, err := st.db.ExecContext(ctx, `
+		INSERT INTO coupons (code, description, discount_type, percent_off, amount_off_minor, max_discount_minor,
+			min_spend_minor, currency, product_ids, categories, usage_limit, per_user_limit, used_count,
+			starts_at, ends_at, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?)
+	`, c.Code, c.Description, int32(c.DiscountType), c.PercentOff, c.AmountOffMinor, c.MaxDiscountMinor,
+		c.MinSpendMinor, c.Currency, string(productIDs), string(categories), c.UsageLimit, c.PerUserLimit,
+		nullTime(c.StartsAt), nullTime(c.EndsAt), c.Active, now, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create coupon: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get coupon id: %w", err)
+	}
+	return id, nil
+}
+
+func (st *couponStore) getByCode(ctx context.Context, code string) (*coupon, error) {
+	return getCoupon(ctx, st.db, code)
+}
+
+// redeem 在一个事务中占用优惠券的一次使用次数并记录使用情况。
+// evaluate 根据加锁后的优惠券计算优惠，返回的错误信息非空时放弃使用；
+// 返回的错误信息非空表示优惠券不可用
+func (st *couponStore) redeem(ctx context.Context, code string, userID int32, evaluate func(c *coupon) (*breakdown, string)) (*redemption, string, error) {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 先更新使用次数锁定优惠券，并发的使用请求在此排队，之后的读取能看到已提交的使用记录
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE coupons SET used_count = used_count + 1, updated_at = ?
+		WHERE code = ? AND (usage_limit = 0 OR used_count < usage_limit)
+	`, now, code)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to update coupon usage: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	c, err := getCoupon(ctx, tx, code)
+	if errors.Is(err, errCouponNotFound) {
+		return nil, "优惠券不存在", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if affected == 0 {
+		return nil, "优惠券使用次数已达上限", nil
+	}
+
+	priced, errorMessage := evaluate(c)
+	if errorMessage != "" {
+		return nil, errorMessage, nil
+	}
+
+	if c.PerUserLimit > 0 {
+		var used int32
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM coupon_redemptions
+			WHERE coupon_id = ? AND user_id = ? AND status = ?
+		`, c.ID, userID, redemptionStatusApplied).Scan(&used)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to count coupon redemptions: %w", err)
+		}
+		if used >= c.PerUserLimit {
+			return nil, "已达到该优惠券每个用户的使用次数上限", nil
+		}
+	}
+
+	r := &redemption{
+		ID:        uuid.New().String(),
+		CouponID:  c.ID,
+		UserID:    userID,
+		Discount:  priced.Discount,
+		Breakdown: priced,
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO coupon_redemptions (redemption_id, coupon_id, user_id, discount_minor, currency, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, r.ID, r.CouponID, r.UserID, r.Discount.Amount, r.Discount.Currency, redemptionStatusApplied, now)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create coupon redemption: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r, "", nil
+}
+
+// release 释放使用记录并归还优惠券的使用次数，已释放的记录直接返回
+func (st *couponStore) release(ctx context.Context, redemptionID string) error {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var couponID int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT coupon_id FROM coupon_redemptions WHERE redemption_id = ?
+	`, redemptionID).Scan(&couponID)
+	if err == sql.ErrNoRows {
+		return errRedemptionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get coupon redemption: %w", err)
+	}
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE coupon_redemptions SET status = ?, released_at = ?
+		WHERE redemption_id = ? AND status = ?
+	`, redemptionStatusReleased, now, redemptionID, redemptionStatusApplied)
+	if err != nil {
+		return fmt.Errorf("failed to release coupon redemption: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		// 已经释放过
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE coupons SET used_count = used_count - 1, updated_at = ?
+		WHERE id = ? AND used_count > 0
+	`, now, couponID)
+	if err != nil {
+		return fmt.Errorf("failed to update coupon usage: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func getCoupon(ctx context.Context, q queryer, code string) (*coupon, error) {
+	var c coupon
+	var discountType int32
+	var productIDs, categories sql.NullString
+	var startsAt, endsAt sql.NullTime
+	err := q.QueryRowContext(ctx, `
+		SELECT id, code, description, discount_type, percent_off, amount_off_minor, max_discount_minor,
+			min_spend_minor, currency, product_ids, categories, usage_limit, per_user_limit, used_count,
+			starts_at, ends_at, active
+		FROM coupons
+		WHERE code = ?
+	`, code).Scan(
+		&c.ID,
+		&c.Code,
+		&c.Description,
+		&discountType,
+		&c.PercentOff,
+		&c.AmountOffMinor,
+		&c.MaxDiscountMinor,
+		&c.MinSpendMinor,
+		&c.Currency,
+		&productIDs,
+		&categories,
+		&c.UsageLimit,
+		&c.PerUserLimit,
+		&c.UsedCount,
+		&startsAt,
+		&endsAt,
+		&c.Active,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errCouponNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coupon: %w", err)
+	}
+
+	c.DiscountType = promotionapi.DiscountType(discountType)
+	if productIDs.Valid && productIDs.String != "" {
+		if err := json.Unmarshal([]byte(productIDs.String), &c.ProductIDs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal coupon products: %w", err)
+		}
+	}
+	if categories.Valid && categories.String != "" {
+		if err := json.Unmarshal([]byte(categories.String), &c.Categories); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal coupon categories: %w", err)
+		}
+	}
+	if startsAt.Valid {
+		c.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		c.EndsAt = &endsAt.Time
+	}
+	return &c, nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
 
 	userapi "github.com/bytedance-youthcamp/demo/api/user"
+	"github.com/bytedance-youthcamp/demo/internal/outbox"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -93,6 +95,12 @@ func (s *UserService) DeleteUser(ctx context.Context, req *userapi.DeleteUserReq
 		}, nil
 	}
 
+	// 删除事件不包含用户的个人信息
+	err = outbox.Record(ctx, tx, outbox.AggregateUser, strconv.Itoa(int(userID)), outbox.UserDeleted, UserEvent{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+
 	// 提交事务
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -23,6 +23,8 @@ func SetupTestDatabase(t *testing.T) *sql.DB {
 	require.NoError(t, err)
 	_, err = db.Exec(`DROP TABLE IF EXISTS orders`)
 	require.NoError(t, err)
+	_, err = db.Exec(`DROP TABLE IF EXISTS outbox_events`)
+	require.NoError(t, err)
 	_, err = db.Exec(`DROP TABLE IF EXISTS users`)
 	require.NoError(t, err)
 
@@ -86,6 +88,22 @@ func SetupTestDatabase(t *testing.T) *sql.DB {
 	`)
 	require.NoError(t, err)
 
+	_, err = db.Exec(`
+		CREATE TABLE outbox_events (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			event_id VARCHAR(36) NOT NULL UNIQUE,
+			event_type VARCHAR(64) NOT NULL,
+			aggregate_type VARCHAR(32) NOT NULL,
+			aggregate_id VARCHAR(64) NOT NULL,
+			payload TEXT NOT NULL,
+			occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			published_at TIMESTAMP NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			last_error VARCHAR(255) NOT NULL DEFAULT ''
+		)
+	`)
+	require.NoError(t, err)
+
 	return db
 }
 
@@ -52,6 +52,19 @@ func setupSqliteTestDatabase(t *testing.T) *sql.DB {
 			FOREIGN KEY(user_id) REFERENCES users(id)
 		);
 
+		CREATE TABLE outbox_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id TEXT UNIQUE NOT NULL,
+			event_type TEXT NOT NULL,
+			aggregate_type TEXT NOT NULL,
+			aggregate_id TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			occurred_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			published_at DATETIME,
+			attempts INTEGER DEFAULT 0,
+			last_error TEXT DEFAULT ''
+		);
+
 		-- 创建角色表
 		CREATE TABLE roles (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
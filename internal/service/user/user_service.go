@@ -7,12 +7,14 @@ import (
 	"log"
 	"os"
 	"regexp"
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
 
 	userapi "github.com/bytedance-youthcamp/demo/api/user"
 	"github.com/bytedance-youthcamp/demo/internal/config"
+	"github.com/bytedance-youthcamp/demo/internal/outbox"
 	"github.com/bytedance-youthcamp/demo/internal/service/auth"
 	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
@@ -160,6 +162,19 @@ func createTestTables(db *sql.DB) error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY(user_id) REFERENCES users(id)
 		);
+
+		CREATE TABLE IF NOT EXISTS outbox_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id TEXT UNIQUE NOT NULL,
+			event_type TEXT NOT NULL,
+			aggregate_type TEXT NOT NULL,
+			aggregate_id TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			occurred_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			published_at DATETIME,
+			attempts INTEGER DEFAULT 0,
+			last_error TEXT DEFAULT ''
+		);
 	`)
 	return err
 }
@@ -194,14 +209,20 @@ func (s *UserService) Register(ctx context.Context, req *userapi.RegisterRequest
 	// 对密码进行哈希处理
 	hashedPassword := s.hashPassword(req.Password)
 
-	// 插入用户到数据库
+	// 插入用户到数据库，并在同一事务中写入注册事件
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	var userID int32
 	query := `
 		INSERT INTO users (username, password_hash, email, phone, created_at)
 		VALUES (?, ?, ?, ?, ?)
 	`
 
-	result, err := s.db.ExecContext(ctx, query,
+	result, err := tx.ExecContext(ctx, query,
 		req.Username,
 		hashedPassword,
 		req.Email,
@@ -226,12 +247,33 @@ func (s *UserService) Register(ctx context.Context, req *userapi.RegisterRequest
 	}
 	userID = int32(lastID)
 
+	err = outbox.Record(ctx, tx, outbox.AggregateUser, strconv.Itoa(int(userID)), outbox.UserRegistered, UserEvent{
+		UserID:   userID,
+		Username: req.Username,
+		Email:    req.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return &userapi.RegisterResponse{
 		UserId:  userID,
 		Success: true,
 	}, nil
 }
 
+// UserEvent 用户领域事件的内容
+type UserEvent struct {
+	UserID   int32  `json:"user_id"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
 func (s *UserService) Login(ctx context.Context, req *userapi.LoginRequest) (*userapi.LoginResponse, error) {
 	// 查询用户
 	query := `
@@ -367,6 +409,26 @@ func (s *UserService) UpdateUserInfo(ctx context.Context, req *userapi.UpdateUse
 	}, nil
 }
 
+// CheckPermission 检查用户是否拥有资源的操作权限，供其他服务鉴权使用
+func (s *UserService) CheckPermission(ctx context.Context, req *userapi.CheckPermissionRequest) (*userapi.CheckPermissionResponse, error) {
+	if req.UserId <= 0 || req.Resource == "" || req.Action == "" {
+		return &userapi.CheckPermissionResponse{
+			Success:      false,
+			ErrorMessage: "用户、资源和操作不能为空",
+		}, nil
+	}
+
+	allowed, err := s.rbacManager.HasPermission(ctx, req.UserId, req.Resource, req.Action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permission: %w", err)
+	}
+
+	return &userapi.CheckPermissionResponse{
+		Success: true,
+		Allowed: allowed,
+	}, nil
+}
+
 func (s *UserService) validateRegistration(req *userapi.RegisterRequest) error {
 	// 验证用户名
 	if len(req.Username) < 3 {
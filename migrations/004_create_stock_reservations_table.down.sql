@@ -0,0 +1,2 @@
+-- 删除库存预留表
+DROP TABLE IF EXISTS stock_reservations;
@@ -0,0 +1,14 @@
+-- 创建库存预留表
+CREATE TABLE stock_reservations (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (order_id, product_id)
+);
+
+-- 创建索引
+CREATE INDEX idx_stock_reservations_status ON stock_reservations(status);
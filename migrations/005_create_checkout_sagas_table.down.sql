@@ -0,0 +1,2 @@
+-- 删除结账流程表
+DROP TABLE IF EXISTS checkout_sagas;
@@ -0,0 +1,22 @@
+-- 创建结账流程表，记录每次结账的步骤状态，用于崩溃后恢复和补偿
+CREATE TABLE checkout_sagas (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    cart_id INT NOT NULL,
+    payment_method VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    current_step VARCHAR(50) NOT NULL,
+    items TEXT,
+    total_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    order_id INT NOT NULL DEFAULT 0,
+    payment_id VARCHAR(64) NOT NULL DEFAULT '',
+    payment_url VARCHAR(255) NOT NULL DEFAULT '',
+    error_message TEXT,
+    version INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- 创建索引，恢复任务按状态扫描未完成的结账
+CREATE INDEX idx_checkout_sagas_status ON checkout_sagas(status);
+CREATE INDEX idx_checkout_sagas_user_id ON checkout_sagas(user_id);
@@ -0,0 +1,2 @@
+-- 删除库存恢复记录表
+DROP TABLE IF EXISTS stock_restorations;
@@ -0,0 +1,9 @@
+-- 创建库存恢复记录表，保证同一请求（如退款）只归还一次库存
+CREATE TABLE stock_restorations (
+    id SERIAL PRIMARY KEY,
+    request_id VARCHAR(64) NOT NULL,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (request_id, product_id)
+);
@@ -0,0 +1,26 @@
+-- 恢复浮点金额字段
+ALTER TABLE products ADD COLUMN price DECIMAL(10,2);
+UPDATE products SET price = price_minor / 100.0;
+ALTER TABLE products DROP COLUMN price_minor;
+ALTER TABLE products DROP COLUMN currency;
+
+ALTER TABLE carts ADD COLUMN total_price DECIMAL(10,2) DEFAULT 0;
+UPDATE carts SET total_price = total_price_minor / 100.0;
+ALTER TABLE carts DROP COLUMN total_price_minor;
+ALTER TABLE carts DROP COLUMN currency;
+
+ALTER TABLE cart_items ADD COLUMN price DECIMAL(10,2);
+UPDATE cart_items SET price = price_minor / 100.0;
+ALTER TABLE cart_items DROP COLUMN price_minor;
+ALTER TABLE cart_items DROP COLUMN currency;
+
+ALTER TABLE orders ADD COLUMN total_amount DECIMAL(10,2);
+UPDATE orders SET total_amount = total_amount_minor / 100.0;
+ALTER TABLE orders MODIFY COLUMN total_amount DECIMAL(10,2) NOT NULL;
+ALTER TABLE orders DROP COLUMN total_amount_minor;
+ALTER TABLE orders DROP COLUMN currency;
+
+ALTER TABLE order_items ADD COLUMN price DECIMAL(10,2);
+UPDATE order_items SET price = price_minor / 100.0;
+ALTER TABLE order_items MODIFY COLUMN price DECIMAL(10,2) NOT NULL;
+ALTER TABLE order_items DROP COLUMN price_minor;
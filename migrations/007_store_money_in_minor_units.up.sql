@@ -0,0 +1,28 @@
+-- 金额改为以最小货币单位（分）存储，避免浮点误差
+ALTER TABLE products ADD COLUMN price_minor BIGINT;
+ALTER TABLE products ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'CNY';
+UPDATE products SET price_minor = ROUND(price * 100);
+ALTER TABLE products MODIFY COLUMN price_minor BIGINT NOT NULL;
+ALTER TABLE products DROP COLUMN price;
+
+ALTER TABLE carts ADD COLUMN total_price_minor BIGINT DEFAULT 0;
+ALTER TABLE carts ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'CNY';
+UPDATE carts SET total_price_minor = ROUND(total_price * 100);
+ALTER TABLE carts DROP COLUMN total_price;
+
+ALTER TABLE cart_items ADD COLUMN price_minor BIGINT;
+ALTER TABLE cart_items ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'CNY';
+UPDATE cart_items SET price_minor = ROUND(price * 100);
+ALTER TABLE cart_items MODIFY COLUMN price_minor BIGINT NOT NULL;
+ALTER TABLE cart_items DROP COLUMN price;
+
+ALTER TABLE orders ADD COLUMN total_amount_minor BIGINT;
+ALTER TABLE orders ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'CNY';
+UPDATE orders SET total_amount_minor = ROUND(total_amount * 100);
+ALTER TABLE orders MODIFY COLUMN total_amount_minor BIGINT NOT NULL;
+ALTER TABLE orders DROP COLUMN total_amount;
+
+ALTER TABLE order_items ADD COLUMN price_minor BIGINT;
+UPDATE order_items SET price_minor = ROUND(price * 100);
+ALTER TABLE order_items MODIFY COLUMN price_minor BIGINT NOT NULL;
+ALTER TABLE order_items DROP COLUMN price;
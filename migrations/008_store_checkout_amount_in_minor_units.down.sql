@@ -0,0 +1,5 @@
+-- 恢复浮点结账金额字段
+ALTER TABLE checkout_sagas ADD COLUMN total_amount DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER items;
+UPDATE checkout_sagas SET total_amount = total_amount_minor / 100;
+ALTER TABLE checkout_sagas DROP COLUMN total_amount_minor;
+ALTER TABLE checkout_sagas DROP COLUMN currency;
@@ -0,0 +1,5 @@
+-- 结账金额改为以最小货币单位（分）存储
+ALTER TABLE checkout_sagas ADD COLUMN total_amount_minor BIGINT NOT NULL DEFAULT 0 AFTER items;
+ALTER TABLE checkout_sagas ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'CNY' AFTER total_amount_minor;
+UPDATE checkout_sagas SET total_amount_minor = ROUND(total_amount * 100);
+ALTER TABLE checkout_sagas DROP COLUMN total_amount;
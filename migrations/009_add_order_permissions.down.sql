@@ -0,0 +1,5 @@
+-- 删除订单权限
+DELETE FROM role_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name IN ('order:read', 'order:write'));
+
+DELETE FROM permissions WHERE name IN ('order:read', 'order:write');
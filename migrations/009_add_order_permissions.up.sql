@@ -0,0 +1,12 @@
+-- 添加订单权限，拥有权限的用户可以查看或管理所有用户的订单
+INSERT INTO permissions (name, description, resource, action) VALUES
+('order:read', '查看所有订单', 'order', 'read'),
+('order:write', '管理所有订单', 'order', 'write');
+
+-- 为管理员角色分配订单权限
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT
+    (SELECT id FROM roles WHERE name = 'admin'),
+    id
+FROM permissions
+WHERE name IN ('order:read', 'order:write');
@@ -0,0 +1,4 @@
+-- 删除订单项分类和订单导出使用的索引
+DROP INDEX idx_orders_created_at ON orders;
+
+ALTER TABLE order_items DROP COLUMN category;
@@ -0,0 +1,5 @@
+-- 订单项记录下单时的商品分类，用于按分类统计销售额
+ALTER TABLE order_items ADD COLUMN category VARCHAR(100) NOT NULL DEFAULT '';
+
+-- 订单导出按创建时间分页，支付时间的索引在 017 添加 paid_at 列时创建
+CREATE INDEX idx_orders_created_at ON orders (created_at, id);
@@ -0,0 +1,4 @@
+-- 删除订单项变更记录和已取消数量
+DROP TABLE IF EXISTS order_item_changes;
+
+ALTER TABLE order_items DROP COLUMN cancelled_quantity;
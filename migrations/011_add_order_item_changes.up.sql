@@ -0,0 +1,19 @@
+-- 订单项记录支付后取消的数量，取消的商品不再发货
+ALTER TABLE order_items ADD COLUMN cancelled_quantity INT NOT NULL DEFAULT 0;
+
+-- 订单项变更记录：支付前修改数量或删除商品，支付后取消商品
+CREATE TABLE IF NOT EXISTS order_item_changes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    product_id VARCHAR(50) NOT NULL,
+    change_type INT NOT NULL,
+    old_quantity INT NOT NULL,
+    new_quantity INT NOT NULL,
+    amount_minor BIGINT NOT NULL,
+    refund_id VARCHAR(64),
+    actor VARCHAR(64) NOT NULL,
+    reason VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_order_item_changes_order_id (order_id),
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+-- 删除优惠券使用记录表和优惠券表
+DROP TABLE IF EXISTS coupon_redemptions;
+DROP TABLE IF EXISTS coupons;
@@ -0,0 +1,39 @@
+-- 创建优惠券表，金额以最小货币单位（分）存储，0 表示不限
+CREATE TABLE coupons (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    code VARCHAR(64) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    discount_type INT NOT NULL,
+    percent_off INT NOT NULL DEFAULT 0,
+    amount_off_minor BIGINT NOT NULL DEFAULT 0,
+    max_discount_minor BIGINT NOT NULL DEFAULT 0,
+    min_spend_minor BIGINT NOT NULL DEFAULT 0,
+    currency CHAR(3) NOT NULL DEFAULT '',
+    product_ids TEXT,
+    categories TEXT,
+    usage_limit INT NOT NULL DEFAULT 0,
+    per_user_limit INT NOT NULL DEFAULT 0,
+    used_count INT NOT NULL DEFAULT 0,
+    starts_at DATETIME NULL,
+    ends_at DATETIME NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- 创建优惠券使用记录表，释放后状态变为 RELEASED 并归还使用次数
+CREATE TABLE coupon_redemptions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    redemption_id VARCHAR(64) NOT NULL UNIQUE,
+    coupon_id BIGINT NOT NULL,
+    user_id INT NOT NULL,
+    discount_minor BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    released_at TIMESTAMP NULL,
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id)
+);
+
+-- 创建索引，按用户统计优惠券的使用次数
+CREATE INDEX idx_coupon_redemptions_coupon_user ON coupon_redemptions(coupon_id, user_id, status);
@@ -0,0 +1,8 @@
+-- 删除订单和结账流程的优惠券字段
+ALTER TABLE checkout_sagas DROP COLUMN coupon_code;
+
+ALTER TABLE order_items DROP COLUMN discount_minor;
+
+ALTER TABLE orders DROP COLUMN discount_amount_minor;
+ALTER TABLE orders DROP COLUMN coupon_redemption_id;
+ALTER TABLE orders DROP COLUMN coupon_code;
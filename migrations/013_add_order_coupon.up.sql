@@ -0,0 +1,10 @@
+-- 订单记录使用的优惠券和优惠金额，订单金额为优惠后的应付金额
+ALTER TABLE orders ADD COLUMN coupon_code VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN coupon_redemption_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN discount_amount_minor BIGINT NOT NULL DEFAULT 0;
+
+-- 订单项记录分摊到该商品的优惠金额，用于按商品退款
+ALTER TABLE order_items ADD COLUMN discount_minor BIGINT NOT NULL DEFAULT 0;
+
+-- 结账流程记录使用的优惠券
+ALTER TABLE checkout_sagas ADD COLUMN coupon_code VARCHAR(64) NOT NULL DEFAULT '' AFTER payment_method;
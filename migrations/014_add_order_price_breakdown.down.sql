@@ -0,0 +1,5 @@
+-- 删除订单的价格明细字段
+ALTER TABLE orders DROP COLUMN shipping_fee_minor;
+ALTER TABLE orders DROP COLUMN tax_amount_minor;
+ALTER TABLE orders DROP COLUMN subtotal_amount_minor;
+ALTER TABLE orders DROP COLUMN region;
@@ -0,0 +1,8 @@
+-- 订单保存定价流水线计算的价格明细，订单金额 = 商品小计 - 优惠 + 税费 + 运费
+ALTER TABLE orders ADD COLUMN region VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN subtotal_amount_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN tax_amount_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN shipping_fee_minor BIGINT NOT NULL DEFAULT 0;
+
+-- 已有订单没有税费和运费，商品小计为优惠前的金额
+UPDATE orders SET subtotal_amount_minor = total_amount_minor + discount_amount_minor;
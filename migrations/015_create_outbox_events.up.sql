@@ -0,0 +1,17 @@
+-- 创建事务性发件箱表，领域事件与业务数据在同一个事务中写入，由中继按 id 顺序发布。
+-- 每个写入事件的服务数据库（订单、商品、支付、购物车、用户）都需要创建
+CREATE TABLE outbox_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event_id VARCHAR(36) NOT NULL UNIQUE,
+    event_type VARCHAR(64) NOT NULL,
+    aggregate_type VARCHAR(32) NOT NULL,
+    aggregate_id VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    published_at TIMESTAMP NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+-- 创建索引，中继按聚合类型查询未发布的事件
+CREATE INDEX idx_outbox_events_pending ON outbox_events(published_at, aggregate_type, id);
@@ -0,0 +1,3 @@
+-- 删除用户订单统计表
+DROP TABLE IF EXISTS user_order_summaries;
+DROP TABLE IF EXISTS user_order_stats;
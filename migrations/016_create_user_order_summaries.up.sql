@@ -0,0 +1,29 @@
+-- 用户订单统计，每个用户每个状态和币种一行，随订单创建和状态变更在同一事务中增量更新
+CREATE TABLE user_order_stats (
+    user_id VARCHAR(50) NOT NULL,
+    status INT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    order_count INT NOT NULL DEFAULT 0,
+    amount_minor BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, status, currency)
+);
+
+-- 用户最近一笔订单，订单ID随创建时间递增
+CREATE TABLE user_order_summaries (
+    user_id VARCHAR(50) NOT NULL PRIMARY KEY,
+    last_order_id BIGINT NOT NULL,
+    last_order_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- 根据已有订单初始化统计，之后可以使用 order-summary-rebuild 命令重新计算
+INSERT INTO user_order_stats (user_id, status, currency, order_count, amount_minor, updated_at)
+SELECT user_id, status, currency, COUNT(*), SUM(total_amount_minor), NOW()
+FROM orders
+GROUP BY user_id, status, currency;
+
+INSERT INTO user_order_summaries (user_id, last_order_id, last_order_at, updated_at)
+SELECT o.user_id, o.id, o.created_at, NOW()
+FROM orders o
+JOIN (SELECT user_id, MAX(id) AS id FROM orders GROUP BY user_id) latest ON latest.id = o.id;
@@ -0,0 +1,22 @@
+-- 删除幂等键、物流和订单状态变更记录表
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS shipment_events;
+DROP TABLE IF EXISTS shipment_items;
+DROP TABLE IF EXISTS shipments;
+DROP TABLE IF EXISTS order_status_history;
+
+DROP INDEX idx_order_items_product_id ON order_items;
+DROP INDEX idx_order_items_order_id ON order_items;
+
+DROP INDEX idx_orders_paid_at ON orders;
+DROP INDEX idx_orders_status_auto_cancel_at ON orders;
+DROP INDEX idx_orders_status_created_at ON orders;
+DROP INDEX idx_orders_user_id_created_at ON orders;
+
+ALTER TABLE orders DROP COLUMN auto_cancel_at;
+ALTER TABLE orders DROP COLUMN completed_at;
+ALTER TABLE orders DROP COLUMN cancelled_at;
+ALTER TABLE orders DROP COLUMN paid_at;
+ALTER TABLE orders DROP COLUMN cancel_reason;
+ALTER TABLE orders DROP COLUMN contact_phone;
+ALTER TABLE orders DROP COLUMN contact_name;
@@ -0,0 +1,82 @@
+-- 订单保存联系人、取消原因和各状态的时间，自动取消时间持久化后服务重启不会丢失
+ALTER TABLE orders ADD COLUMN contact_name VARCHAR(100);
+ALTER TABLE orders ADD COLUMN contact_phone VARCHAR(20);
+ALTER TABLE orders ADD COLUMN cancel_reason VARCHAR(255);
+ALTER TABLE orders ADD COLUMN paid_at TIMESTAMP NULL;
+ALTER TABLE orders ADD COLUMN cancelled_at TIMESTAMP NULL;
+ALTER TABLE orders ADD COLUMN completed_at TIMESTAMP NULL;
+ALTER TABLE orders ADD COLUMN auto_cancel_at TIMESTAMP NULL;
+
+-- 已有的待支付（0）和支付失败（7）订单按创建后 30 分钟自动取消
+UPDATE orders SET auto_cancel_at = DATE_ADD(created_at, INTERVAL 30 MINUTE) WHERE status IN (0, 7);
+
+-- 订单按用户分页查询，超时取消任务按状态和自动取消时间查找订单
+CREATE INDEX idx_orders_user_id_created_at ON orders (user_id, created_at, id);
+CREATE INDEX idx_orders_status_created_at ON orders (status, created_at);
+CREATE INDEX idx_orders_status_auto_cancel_at ON orders (status, auto_cancel_at);
+
+-- 销售额报表按支付时间统计
+CREATE INDEX idx_orders_paid_at ON orders (paid_at);
+
+-- 订单项按订单读取，订单搜索按商品过滤
+CREATE INDEX idx_order_items_order_id ON order_items (order_id);
+CREATE INDEX idx_order_items_product_id ON order_items (product_id);
+
+-- 订单状态变更记录
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    from_status INT NOT NULL,
+    to_status INT NOT NULL,
+    actor VARCHAR(64) NOT NULL,
+    reason VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_order_status_history_order_id (order_id),
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+-- 物流单、物流单包含的商品和物流轨迹
+CREATE TABLE IF NOT EXISTS shipments (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    carrier VARCHAR(64) NOT NULL,
+    tracking_number VARCHAR(64),
+    status INT NOT NULL,
+    shipped_at TIMESTAMP NULL,
+    delivered_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_shipments_order_id (order_id),
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS shipment_items (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    shipment_id BIGINT NOT NULL,
+    product_id VARCHAR(50) NOT NULL,
+    quantity INT NOT NULL,
+    INDEX idx_shipment_items_shipment_id (shipment_id),
+    FOREIGN KEY (shipment_id) REFERENCES shipments(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS shipment_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    shipment_id BIGINT NOT NULL,
+    status INT NOT NULL,
+    location VARCHAR(255),
+    description VARCHAR(255),
+    event_time TIMESTAMP NOT NULL,
+    INDEX idx_shipment_events_shipment_id (shipment_id),
+    FOREIGN KEY (shipment_id) REFERENCES shipments(id) ON DELETE CASCADE
+);
+
+-- 下单和结算请求的幂等键，相同的键重放第一次的响应；scope 为请求类型和调用方
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope VARCHAR(96) NOT NULL,
+    idempotency_key VARCHAR(128) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    response BLOB NULL,
+    created_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP NULL,
+    PRIMARY KEY (scope, idempotency_key)
+);
@@ -42,8 +42,15 @@ go run main.go &
 PAYMENT_PID=$!
 sleep 2
 
+# 启动 Checkout 服务
+echo "启动 Checkout 服务..."
+cd /Users/Apple/Desktop/demo/cmd/checkout
+go run main.go &
+CHECKOUT_PID=$!
+sleep 2
+
 echo "所有服务已启动"
 echo "按 Ctrl+C 停止所有服务"
 
 # 等待所有后台进程
-wait $AUTH_PID $USER_PID $PRODUCT_PID $CART_PID $ORDER_PID $PAYMENT_PID
+wait $AUTH_PID $USER_PID $PRODUCT_PID $CART_PID $ORDER_PID $PAYMENT_PID $CHECKOUT_PID